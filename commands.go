@@ -0,0 +1,148 @@
+package main
+
+import (
+	"authGo/config"
+	"authGo/user"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const commandUsage = `Usage:
+  authGo                      start the server
+  authGo export-users [flags] write the users to a csv or json file
+  authGo import-users [flags] FILE
+                              add the users of a csv or json file, - reads the standard input
+
+Run a command with -h to see its flags. The commands use the database of AUTH_DATABASE_PATH.
+`
+
+// runCommand runs the command line tools instead of the server and returns the exit code.
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "export-users":
+		return exportUsersCommand(cfg, args[1:])
+	case "import-users":
+		return importUsersCommand(cfg, args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(commandUsage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %s\n\n%s", args[0], commandUsage)
+	return 2
+}
+
+func exportUsersCommand(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("export-users", flag.ContinueOnError)
+	format := flags.String("format", "", "csv or json, guessed from the output file extension and csv by default")
+	organizationId := flags.String("organization", "", "organization to export, every organization when empty")
+	passwordHashes := flags.Bool("password-hashes", false, "include the password hashes, only for trusted migrations")
+	output := flags.String("output", "", "file to write, the standard output when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	fileFormat, err := commandFormat(*format, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if cfg.DatabasePath == "" {
+		fmt.Fprintln(os.Stderr, "AUTH_DATABASE_PATH is required to export the users")
+		return 1
+	}
+
+	records, err := configureUserService(cfg).ExportUsers(*organizationId, *passwordHashes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting users: %s\n", err)
+		return 1
+	}
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %s\n", *output, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	if err = user.WriteUserRecords(w, fileFormat, records); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing users: %s\n", err)
+		return 1
+	}
+	if *output != "" {
+		fmt.Printf("%d users exported to %s\n", len(records), *output)
+	}
+	return 0
+}
+
+func importUsersCommand(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	format := flags.String("format", "", "csv or json, guessed from the file extension and csv by default")
+	organizationId := flags.String("organization", "", "organization of the users without one, the default organization when empty")
+	dryRun := flags.Bool("dry-run", false, "validate the users without adding them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "import-users needs the file to import")
+		return 2
+	}
+	path := flags.Arg(0)
+	fileFormat, err := commandFormat(*format, path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if cfg.DatabasePath == "" {
+		fmt.Fprintln(os.Stderr, "AUTH_DATABASE_PATH is required to import the users")
+		return 1
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening %s: %s\n", path, err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+	records, err := user.ReadUserRecords(r, fileFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %s\n", path, err)
+		return 1
+	}
+
+	result := configureUserService(cfg).ImportUsers(records, user.ImportOptions{OrganizationId: *organizationId, DryRun: *dryRun})
+	for _, importErr := range result.Errors {
+		fmt.Fprintln(os.Stderr, importErr)
+	}
+	if result.DryRun {
+		fmt.Printf("Dry run: %d of %d users would be imported, %d refused\n", result.Created, result.Total, len(result.Errors))
+	} else {
+		fmt.Printf("%d of %d users imported, %d refused\n", result.Created, result.Total, len(result.Errors))
+	}
+	if len(result.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// commandFormat returns the format given by the flag, or the one of the file extension.
+func commandFormat(format string, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if !user.IsValidFormat(format) {
+			format = user.FormatCSV
+		}
+	}
+	if !user.IsValidFormat(format) {
+		return "", fmt.Errorf("unknown format %s, must be csv or json", format)
+	}
+	return format, nil
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Port                  string
+	AccessTokenKey        string
+	AccessTokenDuration   time.Duration
+	RefreshTokenKey       string
+	RefreshTokenDuration  time.Duration
+	RedisAddress          string
+	RedisPassword         string
+	RedisDB               int
+	DatabasePath          string
+	AdminPassword         string
+	MetadataSchemaPath    string
+	PasswordPolicy        PasswordPolicyConfig
+	PasswordHash          PasswordHashConfig
+	PasswordResetURL      string
+	PasswordResetDuration time.Duration
+	Mail                  MailConfig
+	EmailVerification     EmailVerificationConfig
+	Lockout               LockoutConfig
+	LoginHistory          LoginHistoryConfig
+	RateLimit             RateLimitConfig
+	MFA                   MFAConfig
+	WebAuthn              WebAuthnConfig
+	Invitation            InvitationConfig
+	Registration          RegistrationConfig
+}
+
+// RegistrationConfig holds the self-service registration on /auth/register, Organizations lists the
+// organizations accepting it.
+type RegistrationConfig struct {
+	Enabled         bool
+	RequireApproval bool
+	Organizations   []string
+}
+
+// InvitationConfig holds the signed invitation links, Duration is the default and longest lifetime of
+// an invitation.
+type InvitationConfig struct {
+	Key      string
+	Duration time.Duration
+	URL      string
+}
+
+type WebAuthnConfig struct {
+	RelyingPartyId    string
+	RelyingPartyName  string
+	Origins           []string
+	ChallengeKey      string
+	ChallengeDuration time.Duration
+}
+
+// MFAConfig holds the second login step, RecoveryCodeKey signs the stored digests of the recovery codes.
+type MFAConfig struct {
+	ChallengeKey      string
+	ChallengeDuration time.Duration
+	TOTPIssuer        string
+	RecoveryCodeKey   string
+}
+
+// RateLimitConfig holds the limits as requests/period, like 10/1m. An empty limit is disabled.
+type RateLimitConfig struct {
+	LoginIP   string
+	LoginName string
+	// MFAUser limits the second login steps per user, whichever challenge token they use.
+	MFAUser         string
+	RefreshIP       string
+	MailIP          string
+	PasswordResetIP string
+	// RegistrationIP limits the registrations per client IP, RegistrationName per user name and
+	// RegistrationEmail per email address.
+	RegistrationIP    string
+	RegistrationName  string
+	RegistrationEmail string
+}
+
+type LockoutConfig struct {
+	MaxFailedAttempts int
+	Duration          time.Duration
+	MaxDuration       time.Duration
+}
+
+// LoginHistoryConfig limits the login attempts kept for every user, zero doesn't limit them.
+type LoginHistoryConfig struct {
+	MaxAge      time.Duration
+	MaxAttempts int
+}
+
+type EmailVerificationConfig struct {
+	Key      string
+	Duration time.Duration
+	URL      string
+	Required bool
+}
+
+type MailConfig struct {
+	From         string
+	SMTPAddress  string
+	SMTPUsername string
+	SMTPPassword string
+	FilePath     string
+}
+
+type PasswordPolicyConfig struct {
+	MinLength               int
+	MaxLength               int
+	RequireLowercase        bool
+	RequireUppercase        bool
+	RequireDigit            bool
+	RequireSymbol           bool
+	RejectSimilarToUsername bool
+	BreachedPasswordsPath   string
+}
+
+// PasswordHashConfig holds how the new passwords are hashed, Argon2Memory is in KiB. Peppers are
+// written as id:secret and PepperId selects the one of the new hashes.
+type PasswordHashConfig struct {
+	Algorithm         string
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+	BcryptCost        int
+	Peppers           []string
+	PepperId          string
+}
+
+// Load reads the configuration from the environment, the variables not set keep their default value.
+func Load() *Config {
+	return &Config{
+		Port:                 getEnv("AUTH_PORT", ":8181"),
+		AccessTokenKey:       getEnv("AUTH_ACCESS_TOKEN_KEY", "accessKey"),
+		AccessTokenDuration:  getEnvDuration("AUTH_ACCESS_TOKEN_DURATION", time.Minute*2),
+		RefreshTokenKey:      getEnv("AUTH_REFRESH_TOKEN_KEY", "refreshKey"),
+		RefreshTokenDuration: getEnvDuration("AUTH_REFRESH_TOKEN_DURATION", time.Hour*24*365),
+		RedisAddress:         getEnv("AUTH_REDIS_ADDRESS", ""),
+		RedisPassword:        getEnv("AUTH_REDIS_PASSWORD", ""),
+		RedisDB:              getEnvInt("AUTH_REDIS_DB", 0),
+		DatabasePath:         getEnv("AUTH_DATABASE_PATH", ""),
+		AdminPassword:        getEnv("AUTH_ADMIN_PASSWORD", ""),
+		MetadataSchemaPath:   getEnv("AUTH_USER_METADATA_SCHEMA_PATH", ""),
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength:               getEnvInt("AUTH_PASSWORD_MIN_LENGTH", 8),
+			MaxLength:               getEnvInt("AUTH_PASSWORD_MAX_LENGTH", 72),
+			RequireLowercase:        getEnvBool("AUTH_PASSWORD_REQUIRE_LOWERCASE", false),
+			RequireUppercase:        getEnvBool("AUTH_PASSWORD_REQUIRE_UPPERCASE", false),
+			RequireDigit:            getEnvBool("AUTH_PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol:           getEnvBool("AUTH_PASSWORD_REQUIRE_SYMBOL", false),
+			RejectSimilarToUsername: getEnvBool("AUTH_PASSWORD_REJECT_SIMILAR_TO_USERNAME", true),
+			BreachedPasswordsPath:   getEnv("AUTH_PASSWORD_BREACHED_LIST_PATH", ""),
+		},
+		PasswordHash: PasswordHashConfig{
+			Algorithm:         getEnv("AUTH_PASSWORD_HASH_ALGORITHM", "argon2id"),
+			Argon2Memory:      getEnvInt("AUTH_ARGON2_MEMORY", 64*1024),
+			Argon2Iterations:  getEnvInt("AUTH_ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: getEnvInt("AUTH_ARGON2_PARALLELISM", 4),
+			BcryptCost:        getEnvInt("AUTH_BCRYPT_COST", 10),
+			Peppers:           getEnvList("AUTH_PASSWORD_PEPPERS", nil),
+			PepperId:          getEnv("AUTH_PASSWORD_PEPPER_ID", ""),
+		},
+		PasswordResetURL:      getEnv("AUTH_PASSWORD_RESET_URL", "http://localhost:4200/reset-password"),
+		PasswordResetDuration: getEnvDuration("AUTH_PASSWORD_RESET_DURATION", time.Minute*15),
+		Mail: MailConfig{
+			From:         getEnv("AUTH_MAIL_FROM", "no-reply@localhost"),
+			SMTPAddress:  getEnv("AUTH_SMTP_ADDRESS", ""),
+			SMTPUsername: getEnv("AUTH_SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("AUTH_SMTP_PASSWORD", ""),
+			FilePath:     getEnv("AUTH_MAIL_FILE_PATH", ""),
+		},
+		EmailVerification: EmailVerificationConfig{
+			Key:      getEnv("AUTH_EMAIL_VERIFICATION_KEY", "emailVerificationKey"),
+			Duration: getEnvDuration("AUTH_EMAIL_VERIFICATION_DURATION", time.Hour*24),
+			URL:      getEnv("AUTH_EMAIL_VERIFICATION_URL", "http://localhost:4200/verify-email"),
+			Required: getEnvBool("AUTH_REQUIRE_VERIFIED_EMAIL", false),
+		},
+		Lockout: LockoutConfig{
+			MaxFailedAttempts: getEnvInt("AUTH_LOCKOUT_MAX_FAILED_ATTEMPTS", 5),
+			Duration:          getEnvDuration("AUTH_LOCKOUT_DURATION", time.Minute),
+			MaxDuration:       getEnvDuration("AUTH_LOCKOUT_MAX_DURATION", time.Hour),
+		},
+		LoginHistory: LoginHistoryConfig{
+			MaxAge:      getEnvDuration("AUTH_LOGIN_HISTORY_MAX_AGE", time.Hour*24*90),
+			MaxAttempts: getEnvInt("AUTH_LOGIN_HISTORY_MAX_ATTEMPTS", 100),
+		},
+		RateLimit: RateLimitConfig{
+			LoginIP:           getEnv("AUTH_RATE_LIMIT_LOGIN_IP", "20/1m"),
+			LoginName:         getEnv("AUTH_RATE_LIMIT_LOGIN_NAME", "10/1m"),
+			MFAUser:           getEnv("AUTH_RATE_LIMIT_MFA_USER", "10/1m"),
+			RefreshIP:         getEnv("AUTH_RATE_LIMIT_REFRESH_IP", "60/1m"),
+			MailIP:            getEnv("AUTH_RATE_LIMIT_MAIL_IP", "5/1m"),
+			PasswordResetIP:   getEnv("AUTH_RATE_LIMIT_PASSWORD_RESET_IP", "10/1m"),
+			RegistrationIP:    getEnv("AUTH_RATE_LIMIT_REGISTRATION_IP", "10/1h"),
+			RegistrationName:  getEnv("AUTH_RATE_LIMIT_REGISTRATION_NAME", "3/1h"),
+			RegistrationEmail: getEnv("AUTH_RATE_LIMIT_REGISTRATION_EMAIL", "3/1h"),
+		},
+		MFA: MFAConfig{
+			ChallengeKey:      getEnv("AUTH_MFA_CHALLENGE_KEY", "mfaChallengeKey"),
+			ChallengeDuration: getEnvDuration("AUTH_MFA_CHALLENGE_DURATION", time.Minute*5),
+			TOTPIssuer:        getEnv("AUTH_TOTP_ISSUER", "authGo"),
+			RecoveryCodeKey:   getEnv("AUTH_RECOVERY_CODE_KEY", "recoveryCodeKey"),
+		},
+		WebAuthn: WebAuthnConfig{
+			RelyingPartyId:    getEnv("AUTH_WEBAUTHN_RP_ID", "localhost"),
+			RelyingPartyName:  getEnv("AUTH_WEBAUTHN_RP_NAME", "authGo"),
+			Origins:           getEnvList("AUTH_WEBAUTHN_ORIGINS", []string{"http://localhost:4200"}),
+			ChallengeKey:      getEnv("AUTH_WEBAUTHN_CHALLENGE_KEY", "webAuthnChallengeKey"),
+			ChallengeDuration: getEnvDuration("AUTH_WEBAUTHN_CHALLENGE_DURATION", time.Minute*5),
+		},
+		Invitation: InvitationConfig{
+			Key:      getEnv("AUTH_INVITATION_KEY", "invitationKey"),
+			Duration: getEnvDuration("AUTH_INVITATION_DURATION", time.Hour*24*7),
+			URL:      getEnv("AUTH_INVITATION_URL", "http://localhost:4200/accept-invitation"),
+		},
+		Registration: RegistrationConfig{
+			Enabled:         getEnvBool("AUTH_REGISTRATION_ENABLED", false),
+			RequireApproval: getEnvBool("AUTH_REGISTRATION_REQUIRE_APPROVAL", false),
+			Organizations:   getEnvList("AUTH_REGISTRATION_ORGANIZATIONS", []string{"default"}),
+		},
+	}
+}
+
+func getEnv(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma separated list, the empty items are skipped.
+func getEnvList(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
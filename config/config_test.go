@@ -0,0 +1,127 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	c := Load()
+	if c.Port != ":8181" {
+		t.Errorf("expected Port to be :8181, got %s", c.Port)
+	}
+	if c.AccessTokenDuration != time.Minute*2 {
+		t.Errorf("expected AccessTokenDuration to be 2m, got %s", c.AccessTokenDuration)
+	}
+	if c.RedisAddress != "" {
+		t.Errorf("expected RedisAddress to be empty, got %s", c.RedisAddress)
+	}
+	if c.PasswordPolicy.MinLength != 8 || !c.PasswordPolicy.RejectSimilarToUsername || c.PasswordPolicy.RequireSymbol {
+		t.Errorf("unexpected default password policy, got %+v", c.PasswordPolicy)
+	}
+	if c.PasswordHash.Algorithm != "argon2id" || c.PasswordHash.Argon2Memory != 65536 || c.PasswordHash.Argon2Iterations != 3 || c.PasswordHash.BcryptCost != 10 || len(c.PasswordHash.Peppers) != 0 {
+		t.Errorf("unexpected default password hash, got %+v", c.PasswordHash)
+	}
+	if c.EmailVerification.Duration != time.Hour*24 || c.EmailVerification.Required {
+		t.Errorf("unexpected default email verification, got %+v", c.EmailVerification)
+	}
+	if c.Lockout.MaxFailedAttempts != 5 || c.Lockout.Duration != time.Minute || c.Lockout.MaxDuration != time.Hour {
+		t.Errorf("unexpected default lockout, got %+v", c.Lockout)
+	}
+	if c.LoginHistory.MaxAge != time.Hour*24*90 || c.LoginHistory.MaxAttempts != 100 {
+		t.Errorf("unexpected default login history, got %+v", c.LoginHistory)
+	}
+	if c.RateLimit.LoginIP != "20/1m" || c.RateLimit.LoginName != "10/1m" || c.RateLimit.RefreshIP != "60/1m" || c.RateLimit.MailIP != "5/1m" ||
+		c.RateLimit.MFAUser != "10/1m" || c.RateLimit.PasswordResetIP != "10/1m" || c.RateLimit.RegistrationIP != "10/1h" || c.RateLimit.RegistrationName != "3/1h" || c.RateLimit.RegistrationEmail != "3/1h" {
+		t.Errorf("unexpected default rate limits, got %+v", c.RateLimit)
+	}
+	if c.MFA.ChallengeDuration != time.Minute*5 || c.MFA.TOTPIssuer != "authGo" {
+		t.Errorf("unexpected default mfa, got %+v", c.MFA)
+	}
+	if c.WebAuthn.RelyingPartyId != "localhost" || len(c.WebAuthn.Origins) != 1 || c.WebAuthn.Origins[0] != "http://localhost:4200" {
+		t.Errorf("unexpected default webauthn, got %+v", c.WebAuthn)
+	}
+	if c.Invitation.Duration != time.Hour*24*7 || c.Invitation.URL != "http://localhost:4200/accept-invitation" {
+		t.Errorf("unexpected default invitation, got %+v", c.Invitation)
+	}
+	if c.Registration.Enabled || c.Registration.RequireApproval || len(c.Registration.Organizations) != 1 || c.Registration.Organizations[0] != "default" {
+		t.Errorf("unexpected default registration, got %+v", c.Registration)
+	}
+}
+
+func TestLoadEnvironment(t *testing.T) {
+	t.Setenv("AUTH_PORT", ":9000")
+	t.Setenv("AUTH_REFRESH_TOKEN_DURATION", "48h")
+	t.Setenv("AUTH_REDIS_ADDRESS", "localhost:6379")
+	t.Setenv("AUTH_REDIS_DB", "2")
+	t.Setenv("AUTH_USER_METADATA_SCHEMA_PATH", "/etc/auth/metadata.json")
+	t.Setenv("AUTH_ACCESS_TOKEN_DURATION", "not a duration")
+	t.Setenv("AUTH_PASSWORD_REQUIRE_DIGIT", "true")
+	t.Setenv("AUTH_PASSWORD_REJECT_SIMILAR_TO_USERNAME", "false")
+	t.Setenv("AUTH_PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("AUTH_BCRYPT_COST", "12")
+	t.Setenv("AUTH_PASSWORD_PEPPERS", "v1:first secret,v2:second secret")
+	t.Setenv("AUTH_PASSWORD_PEPPER_ID", "v2")
+	t.Setenv("AUTH_REQUIRE_VERIFIED_EMAIL", "true")
+	t.Setenv("AUTH_LOCKOUT_MAX_FAILED_ATTEMPTS", "0")
+	t.Setenv("AUTH_LOGIN_HISTORY_MAX_AGE", "720h")
+	t.Setenv("AUTH_LOGIN_HISTORY_MAX_ATTEMPTS", "0")
+	t.Setenv("AUTH_RATE_LIMIT_LOGIN_NAME", "")
+	t.Setenv("AUTH_RATE_LIMIT_REFRESH_IP", "100/1h")
+	t.Setenv("AUTH_RATE_LIMIT_MFA_USER", "3/1m")
+	t.Setenv("AUTH_RATE_LIMIT_REGISTRATION_NAME", "1/24h")
+	t.Setenv("AUTH_RATE_LIMIT_REGISTRATION_EMAIL", "")
+	t.Setenv("AUTH_TOTP_ISSUER", "Example")
+	t.Setenv("AUTH_INVITATION_DURATION", "72h")
+	t.Setenv("AUTH_REGISTRATION_ENABLED", "true")
+	t.Setenv("AUTH_REGISTRATION_REQUIRE_APPROVAL", "true")
+	t.Setenv("AUTH_REGISTRATION_ORGANIZATIONS", "default,acme")
+	t.Setenv("AUTH_WEBAUTHN_ORIGINS", "https://example.com, ,https://app.example.com")
+
+	c := Load()
+	if c.Port != ":9000" {
+		t.Errorf("expected Port to be :9000, got %s", c.Port)
+	}
+	if c.RefreshTokenDuration != time.Hour*48 {
+		t.Errorf("expected RefreshTokenDuration to be 48h, got %s", c.RefreshTokenDuration)
+	}
+	if c.RedisAddress != "localhost:6379" || c.RedisDB != 2 {
+		t.Errorf("unexpected redis configuration, got %s %d", c.RedisAddress, c.RedisDB)
+	}
+	if c.MetadataSchemaPath != "/etc/auth/metadata.json" {
+		t.Errorf("expected MetadataSchemaPath to be /etc/auth/metadata.json, got %s", c.MetadataSchemaPath)
+	}
+	if c.AccessTokenDuration != time.Minute*2 {
+		t.Errorf("expected an invalid duration to keep the default value, got %s", c.AccessTokenDuration)
+	}
+	if !c.PasswordPolicy.RequireDigit || c.PasswordPolicy.RejectSimilarToUsername {
+		t.Errorf("unexpected password policy, got %+v", c.PasswordPolicy)
+	}
+	if c.PasswordHash.Algorithm != "bcrypt" || c.PasswordHash.BcryptCost != 12 || len(c.PasswordHash.Peppers) != 2 || c.PasswordHash.Peppers[1] != "v2:second secret" || c.PasswordHash.PepperId != "v2" {
+		t.Errorf("unexpected password hash, got %+v", c.PasswordHash)
+	}
+	if !c.EmailVerification.Required {
+		t.Error("expected the verified email to be required")
+	}
+	if c.Lockout.MaxFailedAttempts != 0 {
+		t.Errorf("expected the lockout to be disabled, got %+v", c.Lockout)
+	}
+	if c.LoginHistory.MaxAge != time.Hour*720 || c.LoginHistory.MaxAttempts != 0 {
+		t.Errorf("unexpected login history, got %+v", c.LoginHistory)
+	}
+	if c.RateLimit.LoginName != "" || c.RateLimit.RefreshIP != "100/1h" || c.RateLimit.MFAUser != "3/1m" || c.RateLimit.RegistrationName != "1/24h" || c.RateLimit.RegistrationEmail != "" {
+		t.Errorf("unexpected rate limits, got %+v", c.RateLimit)
+	}
+	if c.MFA.TOTPIssuer != "Example" {
+		t.Errorf("expected TOTPIssuer to be Example, got %s", c.MFA.TOTPIssuer)
+	}
+	if len(c.WebAuthn.Origins) != 2 || c.WebAuthn.Origins[1] != "https://app.example.com" {
+		t.Errorf("unexpected webauthn origins, got %v", c.WebAuthn.Origins)
+	}
+	if c.Invitation.Duration != time.Hour*72 {
+		t.Errorf("expected the invitation duration to be 72h, got %s", c.Invitation.Duration)
+	}
+	if !c.Registration.Enabled || !c.Registration.RequireApproval || len(c.Registration.Organizations) != 2 || c.Registration.Organizations[1] != "acme" {
+		t.Errorf("unexpected registration, got %+v", c.Registration)
+	}
+}
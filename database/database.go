@@ -0,0 +1,20 @@
+package database
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// OpenSqlite opens the SQLite database at the given path, creating it if it doesn't exist.
+func OpenSqlite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrMigrationVersionOrder = errors.New("database migration: versions must be unique and ascending")
+	ErrMigrationFailed       = errors.New("database migration: migration failed")
+)
+
+// Migration changes the schema with its statements, Apply runs afterwards in the same transaction for
+// the changes that SQL alone can't make. Apply is optional.
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+	Apply      func(tx *sql.Tx) error
+}
+
+// Migrate applies the migrations whose version is newer than the schema one, each of them in its own
+// transaction. The applied versions are kept in the schema_migrations table.
+func Migrate(db *sql.DB, migrations []Migration) error {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return fmt.Errorf("%w, got %d after %d", ErrMigrationVersionOrder, migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		if err = apply(db, migration); err != nil {
+			return fmt.Errorf("%w, version %d %s: %s", ErrMigrationFailed, migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+func apply(db *sql.DB, migration Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range migration.Statements {
+		if _, err = tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if migration.Apply != nil {
+		if err = migration.Apply(tx); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", migration.Version, migration.Name, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
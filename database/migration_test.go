@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create items", Statements: []string{"CREATE TABLE items (id TEXT PRIMARY KEY)"}},
+	{Version: 2, Name: "add item name", Statements: []string{"ALTER TABLE items ADD COLUMN name TEXT NOT NULL DEFAULT ''"}},
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = Migrate(db, testMigrations[:1])
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	version, err := SchemaVersion(db)
+	if err != nil || version != 1 {
+		t.Errorf("expected version to be 1, got %d %s", version, err)
+	}
+
+	err = Migrate(db, testMigrations)
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	version, err = SchemaVersion(db)
+	if err != nil || version != 2 {
+		t.Errorf("expected version to be 2, got %d %s", version, err)
+	}
+	if _, err = db.Exec("INSERT INTO items (id, name) VALUES ('1', 'item1')"); err != nil {
+		t.Errorf("expected the migrated schema to have a name column, got %s", err)
+	}
+
+	err = Migrate(db, testMigrations)
+	if err != nil {
+		t.Errorf("expected migrating twice to be a no-op, got %s", err)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	db, err := OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = Migrate(db, []Migration{testMigrations[1], testMigrations[0]})
+	if !errors.Is(err, ErrMigrationVersionOrder) {
+		t.Errorf("expected err to be ErrMigrationVersionOrder, got %s", err)
+	}
+
+	broken := []Migration{
+		testMigrations[0],
+		{Version: 2, Name: "broken", Statements: []string{"CREATE TABLE other (id TEXT)", "NOT SQL"}},
+	}
+	err = Migrate(db, broken)
+	if !errors.Is(err, ErrMigrationFailed) {
+		t.Errorf("expected err to be ErrMigrationFailed, got %s", err)
+	}
+	version, err := SchemaVersion(db)
+	if err != nil || version != 1 {
+		t.Errorf("expected version to stay at 1, got %d %s", version, err)
+	}
+	if _, err = db.Exec("INSERT INTO other (id) VALUES ('1')"); err == nil {
+		t.Error("expected the failed migration to be rolled back")
+	}
+}
+
+func TestMigrateApply(t *testing.T) {
+	db, err := OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = Migrate(db, testMigrations); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("INSERT INTO items (id, name) VALUES ('1', 'Item1')"); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := append(testMigrations, Migration{Version: 3, Name: "failing", Statements: []string{"ALTER TABLE items ADD COLUMN key TEXT NOT NULL DEFAULT ''"},
+		Apply: func(tx *sql.Tx) error { return errors.New("failed") }})
+	if err = Migrate(db, failing); !errors.Is(err, ErrMigrationFailed) {
+		t.Errorf("expected err to be ErrMigrationFailed, got %s", err)
+	}
+	if version, _ := SchemaVersion(db); version != 2 {
+		t.Errorf("expected version to stay at 2, got %d", version)
+	}
+
+	lowercase := append(testMigrations, Migration{Version: 3, Name: "add item key", Statements: []string{"ALTER TABLE items ADD COLUMN key TEXT NOT NULL DEFAULT ''"},
+		Apply: func(tx *sql.Tx) error {
+			_, err := tx.Exec("UPDATE items SET key = lower(name)")
+			return err
+		}})
+	if err = Migrate(db, lowercase); err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	var key string
+	if err = db.QueryRow("SELECT key FROM items WHERE id = '1'").Scan(&key); err != nil || key != "item1" {
+		t.Errorf("expected the key to be set by Apply, got %q %v", key, err)
+	}
+}
@@ -1,9 +1,31 @@
 module authGo
 
-go 1.18
+go 1.20
 
 require (
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/alicebob/miniredis/v2 v2.37.0
+	github.com/google/uuid v1.6.0
 	github.com/gorilla/mux v1.8.0
-	golang.org/x/crypto v0.0.0-20220112180741-5e0467b6c7ce // indirect
+	github.com/redis/go-redis/v9 v9.9.0
+	golang.org/x/crypto v0.0.0-20220112180741-5e0467b6c7ce
+	golang.org/x/text v0.14.0
+	modernc.org/sqlite v1.33.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
+	modernc.org/strutil v1.2.0 // indirect
+	modernc.org/token v1.1.0 // indirect
 )
@@ -0,0 +1,409 @@
+// Package jsonschema validates JSON values against the subset of JSON Schema (draft 2020-12) needed to
+// describe simple documents: type, enum, const, the object, array, string and number keywords, and the
+// annotations. The other keywords, like $ref or the combinators, are refused when compiling so a schema
+// never validates less than its author expects.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidSchema      = errors.New("jsonschema: invalid schema")
+	ErrUnsupportedKeyword = errors.New("jsonschema: unsupported keyword")
+)
+
+// annotations don't change the validation, format is only an annotation by default in draft 2020-12.
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true, "default": true,
+	"examples": true, "deprecated": true, "readOnly": true, "writeOnly": true, "format": true,
+}
+
+var types = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// ValidationError is the first keyword the value doesn't follow, Path is the JSON pointer of the
+// value inside the validated document.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("jsonschema: %s %s", path, e.Message)
+}
+
+type Schema struct {
+	// boolean is set by the true and false schemas, which accept every value or none.
+	boolean *bool
+
+	types    []string
+	enum     []any
+	constant *any
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	minProperties        *int
+	maxProperties        *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+}
+
+// Compile reads a JSON schema document.
+func Compile(data []byte) (*Schema, error) {
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidSchema, err)
+	}
+	return compile(document, "")
+}
+
+func compile(document any, path string) (*Schema, error) {
+	if boolean, ok := document.(bool); ok {
+		return &Schema{boolean: &boolean}, nil
+	}
+	keywords, ok := document.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w, %s must be an object or a boolean", ErrInvalidSchema, schemaPath(path))
+	}
+
+	s := &Schema{}
+	for _, keyword := range sortedKeys(keywords) {
+		value := keywords[keyword]
+		keywordPath := path + "/" + keyword
+		var err error
+		switch keyword {
+		case "type":
+			s.types, err = compileTypes(value, keywordPath)
+		case "enum":
+			values, ok := value.([]any)
+			if !ok || len(values) == 0 {
+				err = fmt.Errorf("%w, %s must be a non-empty array", ErrInvalidSchema, keywordPath)
+			}
+			s.enum = values
+		case "const":
+			s.constant = &value
+		case "properties":
+			s.properties, err = compileProperties(value, keywordPath)
+		case "required":
+			s.required, err = compileStrings(value, keywordPath)
+		case "additionalProperties":
+			s.additionalProperties, err = compile(value, keywordPath)
+		case "minProperties":
+			s.minProperties, err = compileCount(value, keywordPath)
+		case "maxProperties":
+			s.maxProperties, err = compileCount(value, keywordPath)
+		case "items":
+			s.items, err = compile(value, keywordPath)
+		case "minItems":
+			s.minItems, err = compileCount(value, keywordPath)
+		case "maxItems":
+			s.maxItems, err = compileCount(value, keywordPath)
+		case "uniqueItems":
+			unique, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("%w, %s must be a boolean", ErrInvalidSchema, keywordPath)
+			}
+			s.uniqueItems = unique
+		case "minLength":
+			s.minLength, err = compileCount(value, keywordPath)
+		case "maxLength":
+			s.maxLength, err = compileCount(value, keywordPath)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("%w, %s must be a string", ErrInvalidSchema, keywordPath)
+				break
+			}
+			if s.pattern, err = regexp.Compile(pattern); err != nil {
+				err = fmt.Errorf("%w, %s: %s", ErrInvalidSchema, keywordPath, err)
+			}
+		case "minimum":
+			s.minimum, err = compileNumber(value, keywordPath)
+		case "maximum":
+			s.maximum, err = compileNumber(value, keywordPath)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = compileNumber(value, keywordPath)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = compileNumber(value, keywordPath)
+		default:
+			if !annotations[keyword] {
+				err = fmt.Errorf("%w %s", ErrUnsupportedKeyword, keywordPath)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(value any, path string) ([]string, error) {
+	if name, ok := value.(string); ok {
+		value = []any{name}
+	}
+	names, err := compileStrings(value, path)
+	if err != nil || len(names) == 0 {
+		return nil, fmt.Errorf("%w, %s must be a type or an array of types", ErrInvalidSchema, path)
+	}
+	for _, name := range names {
+		if !types[name] {
+			return nil, fmt.Errorf("%w, %s has an unknown type %q", ErrInvalidSchema, path, name)
+		}
+	}
+	return names, nil
+}
+
+func compileProperties(value any, path string) (map[string]*Schema, error) {
+	documents, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w, %s must be an object", ErrInvalidSchema, path)
+	}
+	properties := make(map[string]*Schema, len(documents))
+	for name, document := range documents {
+		property, err := compile(document, path+"/"+escapePointer(name))
+		if err != nil {
+			return nil, err
+		}
+		properties[name] = property
+	}
+	return properties, nil
+}
+
+func compileStrings(value any, path string) ([]string, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w, %s must be an array of strings", ErrInvalidSchema, path)
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w, %s must be an array of strings", ErrInvalidSchema, path)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func compileCount(value any, path string) (*int, error) {
+	number, ok := value.(float64)
+	if !ok || number < 0 || number != math.Trunc(number) {
+		return nil, fmt.Errorf("%w, %s must be a non-negative integer", ErrInvalidSchema, path)
+	}
+	count := int(number)
+	return &count, nil
+}
+
+func compileNumber(value any, path string) (*float64, error) {
+	number, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w, %s must be a number", ErrInvalidSchema, path)
+	}
+	return &number, nil
+}
+
+// Validate checks a value decoded by encoding/json into an any, returning a *ValidationError when it
+// doesn't follow the schema.
+func (s *Schema) Validate(value any) error {
+	return s.validate(value, "")
+}
+
+func (s *Schema) validate(value any, path string) error {
+	if s.boolean != nil {
+		if !*s.boolean {
+			return &ValidationError{Path: path, Message: "is not allowed"}
+		}
+		return nil
+	}
+	if len(s.types) > 0 && !s.hasType(value) {
+		return &ValidationError{Path: path, Message: "must be of type " + strings.Join(s.types, " or ")}
+	}
+	if s.enum != nil && !containsValue(s.enum, value) {
+		return &ValidationError{Path: path, Message: "must be one of the enum values"}
+	}
+	if s.constant != nil && !reflect.DeepEqual(*s.constant, value) {
+		return &ValidationError{Path: path, Message: "must be the const value"}
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return s.validateObject(v, path)
+	case []any:
+		return s.validateArray(v, path)
+	case string:
+		return s.validateString(v, path)
+	case float64:
+		return s.validateNumber(v, path)
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(object map[string]any, path string) error {
+	if s.minProperties != nil && len(object) < *s.minProperties {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d properties", *s.minProperties)}
+	}
+	if s.maxProperties != nil && len(object) > *s.maxProperties {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d properties", *s.maxProperties)}
+	}
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("is missing the required property %q", name)}
+		}
+	}
+	for _, name := range sortedKeys(object) {
+		property, ok := s.properties[name]
+		if !ok {
+			property = s.additionalProperties
+		}
+		if property == nil {
+			continue
+		}
+		if err := property.validate(object[name], path+"/"+escapePointer(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(array []any, path string) error {
+	if s.minItems != nil && len(array) < *s.minItems {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d items", *s.minItems)}
+	}
+	if s.maxItems != nil && len(array) > *s.maxItems {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d items", *s.maxItems)}
+	}
+	if s.uniqueItems {
+		for i := range array {
+			if containsValue(array[:i], array[i]) {
+				return &ValidationError{Path: path, Message: "must have unique items"}
+			}
+		}
+	}
+	if s.items == nil {
+		return nil
+	}
+	for i, item := range array {
+		if err := s.items.validate(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateString(value string, path string) error {
+	length := utf8.RuneCountInString(value)
+	if s.minLength != nil && length < *s.minLength {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d characters", *s.minLength)}
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d characters", *s.maxLength)}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		return &ValidationError{Path: path, Message: "must match the pattern " + s.pattern.String()}
+	}
+	return nil
+}
+
+func (s *Schema) validateNumber(value float64, path string) error {
+	if s.minimum != nil && value < *s.minimum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be at least %g", *s.minimum)}
+	}
+	if s.maximum != nil && value > *s.maximum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be at most %g", *s.maximum)}
+	}
+	if s.exclusiveMinimum != nil && value <= *s.exclusiveMinimum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be greater than %g", *s.exclusiveMinimum)}
+	}
+	if s.exclusiveMaximum != nil && value >= *s.exclusiveMaximum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be less than %g", *s.exclusiveMaximum)}
+	}
+	return nil
+}
+
+func (s *Schema) hasType(value any) bool {
+	for _, name := range s.types {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case map[string]any:
+			if name == "object" {
+				return true
+			}
+		case []any:
+			if name == "array" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || (name == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys makes the reported error the same on every run.
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func schemaPath(path string) string {
+	if path == "" {
+		return "the schema"
+	}
+	return path
+}
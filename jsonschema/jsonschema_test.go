@@ -0,0 +1,114 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const profileSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Metadata",
+	"type": "object",
+	"properties": {
+		"department": {"type": "string", "enum": ["sales", "support"]},
+		"employeeId": {"type": "integer", "minimum": 1},
+		"score": {"type": "number", "exclusiveMaximum": 10},
+		"phone": {"type": ["string", "null"], "pattern": "^\\+[0-9]+$", "maxLength": 16},
+		"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "maxItems": 3, "uniqueItems": true},
+		"a/b": {"const": true}
+	},
+	"required": ["department"],
+	"additionalProperties": false
+}`
+
+func decode(t *testing.T, document string) any {
+	var value any
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(profileSchema))
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	validateTests := []struct {
+		document string
+		valid    bool
+		path     string
+	}{
+		{`{"department": "sales"}`, true, ""},
+		{`{"department": "support", "employeeId": 12, "score": 9.5, "phone": "+3412", "tags": ["a", "b"], "a/b": true}`, true, ""},
+		{`{"department": "sales", "phone": null}`, true, ""},
+		{`[]`, false, ""},
+		{`{}`, false, ""},
+		{`{"department": "marketing"}`, false, "/department"},
+		{`{"department": "sales", "employeeId": 1.5}`, false, "/employeeId"},
+		{`{"department": "sales", "employeeId": 0}`, false, "/employeeId"},
+		{`{"department": "sales", "score": 10}`, false, "/score"},
+		{`{"department": "sales", "phone": "12"}`, false, "/phone"},
+		{`{"department": "sales", "tags": ["a", ""]}`, false, "/tags/1"},
+		{`{"department": "sales", "tags": ["a", "a"]}`, false, "/tags"},
+		{`{"department": "sales", "tags": ["a", "b", "c", "d"]}`, false, "/tags"},
+		{`{"department": "sales", "a/b": false}`, false, "/a~1b"},
+		{`{"department": "sales", "other": 1}`, false, "/other"},
+	}
+	for _, validateTest := range validateTests {
+		err := schema.Validate(decode(t, validateTest.document))
+		if validateTest.valid {
+			if err != nil {
+				t.Errorf("%s: expected err to be nil, got %s", validateTest.document, err)
+			}
+			continue
+		}
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: expected a ValidationError, got %v", validateTest.document, err)
+			continue
+		}
+		if validationErr.Path != validateTest.path {
+			t.Errorf("%s: expected the error at %s, got %s", validateTest.document, validateTest.path, err)
+		}
+	}
+}
+
+func TestBooleanSchema(t *testing.T) {
+	schema, err := Compile([]byte(`{"properties": {"locked": false, "free": true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = schema.Validate(decode(t, `{"free": [1, "a"], "other": 1}`)); err != nil {
+		t.Errorf("expected err to be nil, got %s", err)
+	}
+	if err = schema.Validate(decode(t, `{"locked": 1}`)); err == nil {
+		t.Error("expected the false schema to refuse the property")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	compileTests := []struct {
+		schema string
+		err    error
+	}{
+		{`{"type": "object"`, ErrInvalidSchema},
+		{`"object"`, ErrInvalidSchema},
+		{`{"type": "date"}`, ErrInvalidSchema},
+		{`{"type": []}`, ErrInvalidSchema},
+		{`{"enum": []}`, ErrInvalidSchema},
+		{`{"required": "name"}`, ErrInvalidSchema},
+		{`{"maxLength": -1}`, ErrInvalidSchema},
+		{`{"maxItems": 1.5}`, ErrInvalidSchema},
+		{`{"pattern": "("}`, ErrInvalidSchema},
+		{`{"properties": {"name": 1}}`, ErrInvalidSchema},
+		{`{"$ref": "#/definitions/name"}`, ErrUnsupportedKeyword},
+		{`{"properties": {"name": {"oneOf": []}}}`, ErrUnsupportedKeyword},
+	}
+	for _, compileTest := range compileTests {
+		if _, err := Compile([]byte(compileTest.schema)); !errors.Is(err, compileTest.err) {
+			t.Errorf("%s: expected err to be %s, got %v", compileTest.schema, compileTest.err, err)
+		}
+	}
+}
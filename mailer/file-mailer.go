@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// FileMailer appends the messages to a file instead of sending them, it's meant for development and tests.
+type FileMailer struct {
+	Path  string
+	From  string
+	mutex sync.Mutex
+}
+
+func (m *FileMailer) Send(message *Message) error {
+	if err := validateRecipient(message.To); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	file, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(format(m.From, message) + "\r\n")
+	return err
+}
+
+// LogMailer writes the messages to the application log instead of sending them.
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(message *Message) error {
+	if err := validateRecipient(message.To); err != nil {
+		return err
+	}
+	log.Printf("Mail not sent:\n%s", format(m.From, message))
+	return nil
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+var (
+	ErrInvalidRecipient = errors.New("mailer: invalid recipient address")
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message *Message) error
+}
+
+func validateRecipient(to string) error {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("%w, %s", ErrInvalidRecipient, err)
+	}
+	return nil
+}
+
+func format(from string, message *Message) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", message.Subject)
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	builder.WriteString("\r\n")
+	return builder.String()
+}
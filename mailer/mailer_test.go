@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	m := &FileMailer{Path: path, From: "auth@example.com"}
+
+	for _, subject := range []string{"first", "second"} {
+		err := m.Send(&Message{To: "user1@example.com", Subject: subject, Body: "line1\nline2"})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"From: auth@example.com\r\n", "To: user1@example.com\r\n", "Subject: first\r\n", "Subject: second\r\n", "line1\r\nline2\r\n"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected the file to contain %q, got %q", expected, content)
+		}
+	}
+
+	err = m.Send(&Message{To: "not an address", Subject: "third"})
+	if !errors.Is(err, ErrInvalidRecipient) {
+		t.Errorf("expected err to be ErrInvalidRecipient, got %s", err)
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	m := &LogMailer{From: "auth@example.com"}
+	err := m.Send(&Message{To: "user1@example.com", Subject: "subject", Body: "body"})
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if !strings.Contains(output.String(), "Subject: subject") {
+		t.Errorf("expected the message to be logged, got %s", output.String())
+	}
+}
+
+func TestSMTPMailerInvalidRecipient(t *testing.T) {
+	m := &SMTPMailer{Address: "localhost:25", From: "auth@example.com"}
+	err := m.Send(&Message{To: "", Subject: "subject"})
+	if !errors.Is(err, ErrInvalidRecipient) {
+		t.Errorf("expected err to be ErrInvalidRecipient, got %s", err)
+	}
+}
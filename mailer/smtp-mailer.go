@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	Address  string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(message *Message) error {
+	if err := validateRecipient(message.To); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Address, auth, m.From, []string{message.To}, []byte(format(m.From, message)))
+}
@@ -1,32 +1,61 @@
 package main
 
 import (
+	"authGo/config"
+	"authGo/database"
+	"authGo/jsonschema"
+	"authGo/mailer"
+	"authGo/password"
+	"authGo/ratelimit"
 	"authGo/router"
 	"authGo/session"
 	"authGo/token"
 	"authGo/user"
 	"authGo/validator"
+	"authGo/webauthn"
+	"context"
+	"crypto/rand"
+	b64 "encoding/base64"
 	"log"
 	"net/http"
-	"time"
+	"os"
 
 	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
-	port := ":8181"
+	cfg := config.Load()
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1:]))
+	}
 
-	userService := user.NewUserService()
-	userService.CreateUser("admin", "admin", true)
-	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
-	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
-	sessionHandler := session.NewSessionHandler()
+	userService := configureUserService(cfg)
+	createAdminUser(cfg, userService)
+	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte(cfg.AccessTokenKey), Duration: cfg.AccessTokenDuration}
+	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte(cfg.RefreshTokenKey), Duration: cfg.RefreshTokenDuration}
+	emailVerificationTokenGenerator := &token.TokenGenerator[token.EmailVerificationPayload]{Password: []byte(cfg.EmailVerification.Key), Duration: cfg.EmailVerification.Duration}
+	mfaChallengeTokenGenerator := &token.TokenGenerator[token.MFAChallengePayload]{Password: []byte(cfg.MFA.ChallengeKey), Duration: cfg.MFA.ChallengeDuration}
+	webAuthnChallengeTokenGenerator := &token.TokenGenerator[token.WebAuthnChallengePayload]{Password: []byte(cfg.WebAuthn.ChallengeKey), Duration: cfg.WebAuthn.ChallengeDuration}
+	invitationTokenGenerator := &token.TokenGenerator[token.InvitationPayload]{Password: []byte(cfg.Invitation.Key), Duration: cfg.Invitation.Duration}
+	sessionHandler := createSessionHandler(cfg)
 
 	services := &validator.Services{
-		UserService:           userService,
-		AccessTokenGenerator:  accessTokenGenerator,
-		RefreshTokenGenerator: refreshTokenGenerator,
-		SessionsHandler:       sessionHandler,
+		UserService:                     userService,
+		AccessTokenGenerator:            accessTokenGenerator,
+		RefreshTokenGenerator:           refreshTokenGenerator,
+		SessionsHandler:                 sessionHandler,
+		Mailer:                          createMailer(cfg),
+		EmailVerificationTokenGenerator: emailVerificationTokenGenerator,
+		MFAChallengeTokenGenerator:      mfaChallengeTokenGenerator,
+		WebAuthn: &webauthn.RelyingParty{
+			ID:      cfg.WebAuthn.RelyingPartyId,
+			Name:    cfg.WebAuthn.RelyingPartyName,
+			Origins: cfg.WebAuthn.Origins,
+			Timeout: cfg.WebAuthn.ChallengeDuration,
+		},
+		WebAuthnChallengeTokenGenerator: webAuthnChallengeTokenGenerator,
+		InvitationTokenGenerator:        invitationTokenGenerator,
 	}
 	loginRouter := &router.LoginRouter{
 		Services: services,
@@ -34,22 +63,300 @@ func main() {
 	refreshRouter := &router.RefreshRouter{
 		Services: services,
 	}
+	emailVerificationRouter := &router.EmailVerificationRouter{
+		Services:        services,
+		VerificationURL: cfg.EmailVerification.URL,
+	}
 	userRouter := &router.UserRouter{
-		Services: services,
+		Services:          services,
+		EmailVerification: emailVerificationRouter,
 	}
 	sessionRouter := &router.SessionRouter{
 		Services: services,
 	}
+	roleRouter := &router.RoleRouter{
+		Services: services,
+	}
+	organizationRouter := &router.OrganizationRouter{
+		Services: services,
+	}
+	totpRouter := &router.TOTPRouter{
+		Services: services,
+		Issuer:   cfg.MFA.TOTPIssuer,
+	}
+	webAuthnRouter := &router.WebAuthnRouter{
+		Services: services,
+	}
+	recoveryCodeRouter := &router.RecoveryCodeRouter{
+		Services: services,
+	}
+	profileRouter := &router.ProfileRouter{
+		Services: services,
+	}
+	loginHistoryRouter := &router.LoginHistoryRouter{
+		Services: services,
+	}
+	registrationRouter := &router.RegistrationRouter{
+		Services:          services,
+		Enabled:           cfg.Registration.Enabled,
+		RequireApproval:   cfg.Registration.RequireApproval,
+		Organizations:     cfg.Registration.Organizations,
+		EmailVerification: emailVerificationRouter,
+	}
+	invitationRouter := &router.InvitationRouter{
+		Services:      services,
+		InvitationURL: cfg.Invitation.URL,
+	}
+	passwordResetRouter := &router.PasswordResetRouter{
+		Services: services,
+		ResetURL: cfg.PasswordResetURL,
+	}
+
+	loginIPLimit := createRateLimit("AUTH_RATE_LIMIT_LOGIN_IP", cfg.RateLimit.LoginIP, router.ClientIPKey)
+	loginNameLimit := createRateLimit("AUTH_RATE_LIMIT_LOGIN_NAME", cfg.RateLimit.LoginName, router.LoginNameKey)
+	mfaUserLimit := createRateLimit("AUTH_RATE_LIMIT_MFA_USER", cfg.RateLimit.MFAUser, router.MFAUserKey(services))
+	refreshIPLimit := createRateLimit("AUTH_RATE_LIMIT_REFRESH_IP", cfg.RateLimit.RefreshIP, router.ClientIPKey)
+	mailIPLimit := createRateLimit("AUTH_RATE_LIMIT_MAIL_IP", cfg.RateLimit.MailIP, router.ClientIPKey)
+	passwordResetIPLimit := createRateLimit("AUTH_RATE_LIMIT_PASSWORD_RESET_IP", cfg.RateLimit.PasswordResetIP, router.ClientIPKey)
+	registrationIPLimit := createRateLimit("AUTH_RATE_LIMIT_REGISTRATION_IP", cfg.RateLimit.RegistrationIP, router.ClientIPKey)
+	registrationNameLimit := createRateLimit("AUTH_RATE_LIMIT_REGISTRATION_NAME", cfg.RateLimit.RegistrationName, router.RegistrationNameKey)
+	registrationEmailLimit := createRateLimit("AUTH_RATE_LIMIT_REGISTRATION_EMAIL", cfg.RateLimit.RegistrationEmail, router.RegistrationEmailKey)
+	rateLimited := router.RateLimited
+	// the bodies are only read once the client IP is allowed
+	mfaHandler := router.ReadMFALogin(rateLimited(loginRouter.MFAHandler, mfaUserLimit))
+	registrationHandler := router.ReadRegistration(rateLimited(registrationRouter.RegisterHandler, registrationNameLimit, registrationEmailLimit))
 
 	router := mux.NewRouter()
-	router.HandleFunc("/auth/login", loginRouter.Handler).Methods("POST")
-	router.HandleFunc("/auth/refresh", refreshRouter.Handler).Methods("POST")
+	router.Handle("/auth/login", rateLimited(loginRouter.Handler, loginIPLimit, loginNameLimit)).Methods("POST")
+	router.Handle("/auth/login/mfa", rateLimited(mfaHandler.ServeHTTP, loginIPLimit)).Methods("POST")
+	router.Handle("/auth/login/webauthn/options", rateLimited(loginRouter.WebAuthnOptionsHandler, loginIPLimit)).Methods("POST")
+	router.Handle("/auth/login/webauthn", rateLimited(loginRouter.WebAuthnHandler, loginIPLimit)).Methods("POST")
+	router.Handle("/auth/refresh", rateLimited(refreshRouter.Handler, refreshIPLimit)).Methods("POST")
+	router.Handle("/auth/password-reset", rateLimited(passwordResetRouter.RequestResetHandler, mailIPLimit)).Methods("POST")
+	router.Handle("/auth/password-reset/complete", rateLimited(passwordResetRouter.CompleteResetHandler, passwordResetIPLimit)).Methods("POST")
+	router.Handle("/auth/email-verification", rateLimited(emailVerificationRouter.RequestVerificationHandler, mailIPLimit)).Methods("POST")
+	router.HandleFunc("/auth/email-verification/complete", emailVerificationRouter.CompleteVerificationHandler).Methods("POST")
+	router.Handle("/auth/register", rateLimited(registrationHandler.ServeHTTP, registrationIPLimit)).Methods("POST")
+	router.Handle("/auth/invitations/accept", rateLimited(invitationRouter.AcceptHandler, loginIPLimit)).Methods("POST")
 	router.HandleFunc("/users", userRouter.GetUsersHandler).Methods("GET")
 	router.HandleFunc("/users", userRouter.NewUserHandler).Methods("POST")
+	router.HandleFunc("/users/export", userRouter.ExportUsersHandler).Methods("GET")
+	router.HandleFunc("/users/import", userRouter.ImportUsersHandler).Methods("POST")
+	router.HandleFunc("/users/me/password", userRouter.ChangePasswordHandler).Methods("POST")
+	router.HandleFunc("/users/me/totp", totpRouter.EnrollHandler).Methods("POST")
+	router.HandleFunc("/users/me/totp/confirm", totpRouter.ConfirmHandler).Methods("POST")
+	router.HandleFunc("/users/me/totp", totpRouter.DisableHandler).Methods("DELETE")
+	router.HandleFunc("/users/me/webauthn/registration/options", webAuthnRouter.RegistrationOptionsHandler).Methods("POST")
+	router.HandleFunc("/users/me/webauthn/registration", webAuthnRouter.RegistrationHandler).Methods("POST")
+	router.HandleFunc("/users/me/webauthn/credentials", webAuthnRouter.GetCredentialsHandler).Methods("GET")
+	router.HandleFunc("/users/me/webauthn/credentials/{id}", webAuthnRouter.DeleteCredentialHandler).Methods("DELETE")
+	router.HandleFunc("/users/me/recovery-codes", recoveryCodeRouter.CountHandler).Methods("GET")
+	router.HandleFunc("/users/me/recovery-codes", recoveryCodeRouter.RegenerateHandler).Methods("POST")
+	router.HandleFunc("/users/me/profile", profileRouter.GetHandler).Methods("GET")
+	router.HandleFunc("/users/me/profile", profileRouter.UpdateHandler).Methods("PATCH")
+	router.HandleFunc("/users/me/logins", loginHistoryRouter.GetHandler).Methods("GET")
+	router.HandleFunc("/users/{id}", userRouter.UpdateUserHandler).Methods("PATCH")
+	router.HandleFunc("/users/{id}/profile", userRouter.UpdateProfileHandler).Methods("PATCH")
 	router.HandleFunc("/users/{id}", userRouter.DeleteUserHandler).Methods("DELETE")
+	router.HandleFunc("/users/{id}/unlock", userRouter.UnlockUserHandler).Methods("POST")
+	router.HandleFunc("/users/{id}/status", userRouter.SetStatusHandler).Methods("PUT")
+	router.HandleFunc("/users/{id}/approve", userRouter.ApproveUserHandler).Methods("POST")
+	router.HandleFunc("/users/{id}/totp", userRouter.DisableTOTPHandler).Methods("DELETE")
+	router.HandleFunc("/users/{id}/logins", userRouter.GetLoginHistoryHandler).Methods("GET")
+	router.HandleFunc("/roles", roleRouter.GetRolesHandler).Methods("GET")
+	router.HandleFunc("/roles", roleRouter.NewRoleHandler).Methods("POST")
+	router.HandleFunc("/roles/{name}", roleRouter.UpdateRoleHandler).Methods("PUT")
+	router.HandleFunc("/roles/{name}", roleRouter.DeleteRoleHandler).Methods("DELETE")
+	router.HandleFunc("/organizations", organizationRouter.GetOrganizationsHandler).Methods("GET")
+	router.HandleFunc("/organizations", organizationRouter.NewOrganizationHandler).Methods("POST")
+	router.HandleFunc("/invitations", invitationRouter.GetInvitationsHandler).Methods("GET")
+	router.HandleFunc("/invitations", invitationRouter.CreateHandler).Methods("POST")
+	router.HandleFunc("/invitations/{id}", invitationRouter.DeleteHandler).Methods("DELETE")
 	router.HandleFunc("/sessions", sessionRouter.GetSessionsHandler).Methods("GET")
 	router.HandleFunc("/sessions/{id}", sessionRouter.DeleteSessionHandler).Methods("DELETE")
 	http.Handle("/", router)
-	log.Printf("Application listening on port %s", port)
-	log.Fatal(http.ListenAndServe(port, router))
+	log.Printf("Application listening on port %s", cfg.Port)
+	log.Fatal(http.ListenAndServe(cfg.Port, router))
+}
+
+// configureUserService creates the user service with the password, lockout and profile settings.
+func configureUserService(cfg *config.Config) *user.UserService {
+	userService := createUserService(cfg)
+	userService.SetPasswordPolicy(createPasswordPolicy(cfg))
+	userService.SetPasswordHasher(createPasswordHasher(cfg))
+	userService.SetPasswordResetDuration(cfg.PasswordResetDuration)
+	userService.SetRequireVerifiedEmail(cfg.EmailVerification.Required)
+	userService.SetLockoutPolicy(createLockoutPolicy(cfg))
+	userService.SetMetadataSchema(loadMetadataSchema(cfg))
+	userService.SetInvitationDuration(cfg.Invitation.Duration)
+	userService.SetRecoveryCodeKey([]byte(cfg.MFA.RecoveryCodeKey))
+	userService.SetLoginHistoryRetention(user.LoginHistoryRetention{
+		MaxAge:      cfg.LoginHistory.MaxAge,
+		MaxAttempts: cfg.LoginHistory.MaxAttempts,
+	})
+	return userService
+}
+
+func createUserService(cfg *config.Config) *user.UserService {
+	if cfg.DatabasePath == "" {
+		return user.NewUserService()
+	}
+	db, err := database.OpenSqlite(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Error opening database %s: %s", cfg.DatabasePath, err)
+	}
+	store, err := user.NewSqlUserRepository(db)
+	if err != nil {
+		log.Fatalf("Error migrating database %s: %s", cfg.DatabasePath, err)
+	}
+	log.Printf("Users stored in %s", cfg.DatabasePath)
+	userService := user.NewUserServiceWithStore(store)
+	userService.SetRoleStore(user.NewSqlRoleRepository(db))
+	userService.SetOrganizationStore(user.NewSqlOrganizationRepository(db))
+	userService.SetLoginFailureStore(user.NewSqlLoginFailureRepository(db))
+	userService.SetTOTPStore(user.NewSqlTOTPRepository(db))
+	userService.SetWebAuthnCredentialStore(user.NewSqlWebAuthnCredentialRepository(db))
+	userService.SetWebAuthnChallengeStore(user.NewSqlWebAuthnChallengeRepository(db))
+	userService.SetRecoveryCodeStore(user.NewSqlRecoveryCodeRepository(db))
+	userService.SetPasswordResetStore(user.NewSqlPasswordResetRepository(db))
+	userService.SetInvitationStore(user.NewSqlInvitationRepository(db))
+	userService.SetLoginHistoryStore(user.NewSqlLoginHistoryRepository(db))
+	return userService
+}
+
+func createPasswordHasher(cfg *config.Config) *password.Hasher {
+	hasher := password.NewDefaultHasher()
+	hasher.Algorithm = cfg.PasswordHash.Algorithm
+	hasher.Argon2.Memory = uint32(cfg.PasswordHash.Argon2Memory)
+	hasher.Argon2.Iterations = uint32(cfg.PasswordHash.Argon2Iterations)
+	hasher.Argon2.Parallelism = uint8(cfg.PasswordHash.Argon2Parallelism)
+	hasher.BcryptCost = cfg.PasswordHash.BcryptCost
+	if cfg.PasswordHash.Argon2Memory <= 0 || cfg.PasswordHash.Argon2Iterations <= 0 || cfg.PasswordHash.Argon2Parallelism <= 0 || cfg.PasswordHash.Argon2Parallelism > 255 {
+		log.Fatal("Password hash argon2 parameters must be positive, with a parallelism up to 255")
+	}
+	peppers, err := password.ParsePeppers(cfg.PasswordHash.Peppers)
+	if err != nil {
+		log.Fatalf("Error reading AUTH_PASSWORD_PEPPERS: %s", err)
+	}
+	hasher.Peppers = peppers
+	hasher.PepperId = cfg.PasswordHash.PepperId
+	if err = hasher.Validate(); err != nil {
+		log.Fatalf("Error in the password hash configuration: %s", err)
+	}
+	return hasher
+}
+
+func createPasswordPolicy(cfg *config.Config) *password.Policy {
+	policy := &password.Policy{
+		MinLength:               cfg.PasswordPolicy.MinLength,
+		MaxLength:               cfg.PasswordPolicy.MaxLength,
+		RequireLowercase:        cfg.PasswordPolicy.RequireLowercase,
+		RequireUppercase:        cfg.PasswordPolicy.RequireUppercase,
+		RequireDigit:            cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol:           cfg.PasswordPolicy.RequireSymbol,
+		RejectSimilarToUsername: cfg.PasswordPolicy.RejectSimilarToUsername,
+	}
+	if cfg.PasswordHash.Algorithm == password.AlgorithmBcrypt && (policy.MaxLength <= 0 || policy.MaxLength > password.BcryptMaxLength) {
+		log.Printf("Password max length limited to %d bytes", password.BcryptMaxLength)
+		policy.MaxLength = password.BcryptMaxLength
+	}
+	if cfg.PasswordPolicy.BreachedPasswordsPath != "" {
+		if err := policy.LoadBreachedPasswords(cfg.PasswordPolicy.BreachedPasswordsPath); err != nil {
+			log.Fatalf("Error loading breached passwords from %s: %s", cfg.PasswordPolicy.BreachedPasswordsPath, err)
+		}
+	}
+	return policy
+}
+
+func loadMetadataSchema(cfg *config.Config) *jsonschema.Schema {
+	if cfg.MetadataSchemaPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cfg.MetadataSchemaPath)
+	if err != nil {
+		log.Fatalf("Error reading the metadata schema %s: %s", cfg.MetadataSchemaPath, err)
+	}
+	schema, err := jsonschema.Compile(data)
+	if err != nil {
+		log.Fatalf("Error in the metadata schema %s: %s", cfg.MetadataSchemaPath, err)
+	}
+	return schema
+}
+
+func createLockoutPolicy(cfg *config.Config) *user.LockoutPolicy {
+	if cfg.Lockout.MaxFailedAttempts <= 0 {
+		log.Print("Account lockout disabled")
+		return nil
+	}
+	return &user.LockoutPolicy{
+		MaxFailedAttempts: cfg.Lockout.MaxFailedAttempts,
+		Duration:          cfg.Lockout.Duration,
+		MaxDuration:       cfg.Lockout.MaxDuration,
+	}
+}
+
+// createRateLimit returns nil when the limit is empty, so the route is not limited.
+func createRateLimit(name string, value string, key func(r *http.Request) string) *router.RateLimit {
+	if value == "" {
+		log.Printf("Rate limit %s disabled", name)
+		return nil
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return &router.RateLimit{Limiter: ratelimit.NewLimiter(limit), Key: key}
+}
+
+// createAdminUser creates the admin user of the default organization the first time the application
+// starts, it's a super administrator. When no password is configured a random one is generated and
+// logged.
+func createAdminUser(cfg *config.Config, userService *user.UserService) {
+	if _, err := userService.GetRepository().GetByName(user.DefaultOrganizationId, "admin"); err == nil {
+		return
+	}
+	adminPassword := cfg.AdminPassword
+	if adminPassword == "" {
+		randomPassword := make([]byte, 18)
+		if _, err := rand.Read(randomPassword); err != nil {
+			log.Fatal(err)
+		}
+		adminPassword = b64.RawURLEncoding.EncodeToString(randomPassword)
+	}
+	admin := user.NewUser{Name: "admin", Password: adminPassword, Roles: []string{user.AdminRole, user.SuperAdminRole}}
+	if _, err := userService.AddUser(admin); err != nil {
+		log.Fatalf("Error creating user admin: %s", err)
+	}
+	if cfg.AdminPassword == "" {
+		log.Printf("Created user admin with password %s", adminPassword)
+	}
+}
+
+func createMailer(cfg *config.Config) mailer.Mailer {
+	if cfg.Mail.SMTPAddress != "" {
+		return &mailer.SMTPMailer{
+			Address:  cfg.Mail.SMTPAddress,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		}
+	}
+	if cfg.Mail.FilePath != "" {
+		log.Printf("Mails written to %s", cfg.Mail.FilePath)
+		return &mailer.FileMailer{Path: cfg.Mail.FilePath, From: cfg.Mail.From}
+	}
+	log.Print("No SMTP server configured, mails written to the log")
+	return &mailer.LogMailer{From: cfg.Mail.From}
+}
+
+func createSessionHandler(cfg *config.Config) *session.SessionsHandler {
+	if cfg.RedisAddress == "" {
+		return session.NewSessionHandler()
+	}
+	client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress, Password: cfg.RedisPassword, DB: cfg.RedisDB})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		log.Fatalf("Error connecting to redis at %s: %s", cfg.RedisAddress, err)
+	}
+	log.Printf("Sessions stored in redis at %s", cfg.RedisAddress)
+	return session.NewSessionHandlerWithStore(session.NewRedisStore(client, cfg.RefreshTokenDuration))
 }
@@ -0,0 +1,315 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrHashMismatch         = errors.New("password hasher: password does not match")
+	ErrUnknownAlgorithm     = errors.New("password hasher: unknown algorithm")
+	ErrMalformedHash        = errors.New("password hasher: malformed hash")
+	ErrArgon2ParamsNotValid = errors.New("password hasher: argon2 parameters not valid")
+	ErrUnknownPepper        = errors.New("password hasher: unknown pepper")
+	ErrPepperNotValid       = errors.New("password hasher: pepper not valid")
+)
+
+// bcryptHashLength is the length of every bcrypt hash in the modular crypt format.
+const bcryptHashLength = 60
+
+// maxArgon2CostFactor limits the cost of the argon2id hashes that are verified to this many times the
+// configured cost, or the default one when it's higher, so a stored hash can't exhaust the memory or CPU.
+const maxArgon2CostFactor = 4
+
+// pepperPrefix starts the hashes of peppered passwords, followed by the pepper id and the hash.
+const pepperPrefix = "$pepper$"
+
+// Argon2Params are the cost of an argon2id hash, Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the second recommended option of RFC 9106 for memory constrained
+// environments.
+var DefaultArgon2Params = Argon2Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+
+// Hasher hashes the new passwords with Algorithm and verifies the hashes of every supported algorithm.
+// The hashes carry their algorithm and parameters, in the PHC string format for argon2id and the
+// modular crypt format for bcrypt, so the outdated ones can be recognized and replaced.
+//
+// When PepperId is set the password is first signed with HMAC-SHA256 and that pepper, a secret kept out
+// of the database, and the hash is tagged with the pepper id. The previous peppers stay in Peppers so
+// their hashes keep verifying until they are replaced.
+type Hasher struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+	Peppers    map[string][]byte
+	PepperId   string
+}
+
+func NewDefaultHasher() *Hasher {
+	return &Hasher{Algorithm: AlgorithmArgon2id, Argon2: DefaultArgon2Params, BcryptCost: bcrypt.DefaultCost}
+}
+
+// Validate checks the configured algorithm, its parameters and the current pepper.
+func (h *Hasher) Validate() error {
+	if h.PepperId != "" {
+		if _, ok := h.Peppers[h.PepperId]; !ok {
+			return ErrUnknownPepper
+		}
+	}
+	switch h.Algorithm {
+	case AlgorithmArgon2id:
+		if h.Argon2.Memory < 8*uint32(h.Argon2.Parallelism) || h.Argon2.Iterations < 1 || h.Argon2.Parallelism < 1 || h.Argon2.SaltLength < 8 || h.Argon2.KeyLength < 16 {
+			return ErrArgon2ParamsNotValid
+		}
+		return nil
+	case AlgorithmBcrypt:
+		if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("password hasher: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return nil
+	}
+	return ErrUnknownAlgorithm
+}
+
+func (h *Hasher) Hash(password []byte) ([]byte, error) {
+	if h.PepperId == "" {
+		return h.hash(password)
+	}
+	pepper, ok := h.Peppers[h.PepperId]
+	if !ok {
+		return nil, ErrUnknownPepper
+	}
+	hash, err := h.hash(applyPepper(pepper, password))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(pepperPrefix+h.PepperId), hash...), nil
+}
+
+func (h *Hasher) hash(password []byte) ([]byte, error) {
+	switch h.Algorithm {
+	case AlgorithmArgon2id:
+		salt := make([]byte, h.Argon2.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key := argon2.IDKey(password, salt, h.Argon2.Iterations, h.Argon2.Memory, h.Argon2.Parallelism, h.Argon2.KeyLength)
+		return []byte(encodeArgon2(h.Argon2, salt, key)), nil
+	case AlgorithmBcrypt:
+		return bcrypt.GenerateFromPassword(password, h.BcryptCost)
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// Compare returns nil when the password matches the hash, whichever algorithm and pepper made it.
+func (h *Hasher) Compare(hash []byte, password []byte) error {
+	pepperId, hash, err := splitPepper(hash)
+	if err != nil {
+		return err
+	}
+	if pepperId != "" {
+		pepper, ok := h.Peppers[pepperId]
+		if !ok {
+			return ErrUnknownPepper
+		}
+		password = applyPepper(pepper, password)
+	}
+	switch hashAlgorithm(hash) {
+	case AlgorithmArgon2id:
+		params, salt, key, err := decodeArgon2(string(hash))
+		if err != nil {
+			return err
+		}
+		if err = h.checkArgon2Params(params); err != nil {
+			return err
+		}
+		other := argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrHashMismatch
+		}
+		return nil
+	case AlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword(hash, password)
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrHashMismatch
+		}
+		return err
+	}
+	return ErrUnknownAlgorithm
+}
+
+// ValidateHash checks that the hash can be verified by the hasher, it's meant for the hashes imported
+// from another system. The bcrypt and argon2id hashes are accepted, peppered only with a known pepper.
+func (h *Hasher) ValidateHash(hash []byte) error {
+	pepperId, hash, err := splitPepper(hash)
+	if err != nil {
+		return err
+	}
+	if _, ok := h.Peppers[pepperId]; pepperId != "" && !ok {
+		return ErrUnknownPepper
+	}
+	switch hashAlgorithm(hash) {
+	case AlgorithmArgon2id:
+		params, _, _, err := decodeArgon2(string(hash))
+		if err != nil {
+			return err
+		}
+		return h.checkArgon2Params(params)
+	case AlgorithmBcrypt:
+		if _, err = bcrypt.Cost(hash); err != nil || len(hash) != bcryptHashLength {
+			return ErrMalformedHash
+		}
+		return nil
+	}
+	return ErrUnknownAlgorithm
+}
+
+// NeedsRehash returns whether the hash was made with another algorithm, parameters or pepper than the
+// ones configured, in which case the password should be hashed again once it's known to be valid.
+func (h *Hasher) NeedsRehash(hash []byte) bool {
+	pepperId, hash, err := splitPepper(hash)
+	if err != nil || pepperId != h.PepperId {
+		return true
+	}
+	algorithm := hashAlgorithm(hash)
+	if algorithm != h.Algorithm {
+		return true
+	}
+	switch algorithm {
+	case AlgorithmArgon2id:
+		params, salt, key, err := decodeArgon2(string(hash))
+		if err != nil {
+			return true
+		}
+		params.SaltLength = uint32(len(salt))
+		params.KeyLength = uint32(len(key))
+		return params != h.Argon2
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost(hash)
+		return err != nil || cost != h.BcryptCost
+	}
+	return true
+}
+
+// checkArgon2Params returns ErrArgon2ParamsNotValid for the parameters of a hash that argon2id refuses,
+// or that cost more than maxArgon2CostFactor times the configured or default ones.
+func (h *Hasher) checkArgon2Params(params Argon2Params) error {
+	if params.Parallelism < 1 || params.Iterations < 1 || params.Memory < 8*uint32(params.Parallelism) {
+		return ErrArgon2ParamsNotValid
+	}
+	limit := DefaultArgon2Params
+	if h.Argon2.Memory > limit.Memory {
+		limit.Memory = h.Argon2.Memory
+	}
+	if h.Argon2.Iterations > limit.Iterations {
+		limit.Iterations = h.Argon2.Iterations
+	}
+	if h.Argon2.Parallelism > limit.Parallelism {
+		limit.Parallelism = h.Argon2.Parallelism
+	}
+	if uint64(params.Memory) > maxArgon2CostFactor*uint64(limit.Memory) || uint64(params.Iterations) > maxArgon2CostFactor*uint64(limit.Iterations) ||
+		uint64(params.Parallelism) > maxArgon2CostFactor*uint64(limit.Parallelism) {
+		return ErrArgon2ParamsNotValid
+	}
+	return nil
+}
+
+// ParsePeppers reads peppers written as id:secret, the ids can't contain $ or : and the secrets must
+// have at least 16 bytes.
+func ParsePeppers(values []string) (map[string][]byte, error) {
+	peppers := make(map[string][]byte, len(values))
+	for _, value := range values {
+		id, secret, found := strings.Cut(value, ":")
+		if !found || id == "" || strings.Contains(id, "$") || len(secret) < 16 {
+			return nil, fmt.Errorf("%w: %q", ErrPepperNotValid, id)
+		}
+		if _, ok := peppers[id]; ok {
+			return nil, fmt.Errorf("%w: duplicated id %q", ErrPepperNotValid, id)
+		}
+		peppers[id] = []byte(secret)
+	}
+	return peppers, nil
+}
+
+// applyPepper signs the password with the pepper. The signature is encoded so bcrypt, which stops at
+// 72 bytes, gets printable characters under its limit.
+func applyPepper(pepper []byte, password []byte) []byte {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write(password)
+	return []byte(b64.RawStdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// splitPepper returns the pepper id of the hash, empty when it's not peppered, and the hash itself.
+func splitPepper(hash []byte) (string, []byte, error) {
+	s := string(hash)
+	if !strings.HasPrefix(s, pepperPrefix) {
+		return "", hash, nil
+	}
+	end := strings.Index(s[len(pepperPrefix):], "$")
+	if end <= 0 {
+		return "", nil, ErrMalformedHash
+	}
+	end += len(pepperPrefix)
+	return s[len(pepperPrefix):end], []byte(s[end:]), nil
+}
+
+func hashAlgorithm(hash []byte) string {
+	s := string(hash)
+	if strings.HasPrefix(s, "$argon2id$") {
+		return AlgorithmArgon2id
+	}
+	if strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$") {
+		return AlgorithmBcrypt
+	}
+	return ""
+}
+
+func encodeArgon2(params Argon2Params, salt []byte, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		b64.RawStdEncoding.EncodeToString(salt), b64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	salt, err := b64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	key, err := b64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+	return params, salt, key, nil
+}
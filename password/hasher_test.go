@@ -0,0 +1,244 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func createTestHasher() *Hasher {
+	hasher := NewDefaultHasher()
+	hasher.Argon2 = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hasher.BcryptCost = bcrypt.MinCost
+	return hasher
+}
+
+func TestHasherArgon2id(t *testing.T) {
+	hasher := createTestHasher()
+	hash, err := hasher.Hash([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash format %s", hash)
+	}
+	other, _ := hasher.Hash([]byte("password"))
+	if string(other) == string(hash) {
+		t.Error("expected a different salt for each hash")
+	}
+	if err = hasher.Compare(hash, []byte("password")); err != nil {
+		t.Errorf("expected the password to match, got %v", err)
+	}
+	if err = hasher.Compare(hash, []byte("Password")); err != ErrHashMismatch {
+		t.Errorf("expected error to be ErrHashMismatch, got %v", err)
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Error("expected the hash to be up to date")
+	}
+
+	stronger := createTestHasher()
+	stronger.Argon2.Iterations = 2
+	if !stronger.NeedsRehash(hash) {
+		t.Error("expected a hash with less iterations to need a rehash")
+	}
+	if err = stronger.Compare(hash, []byte("password")); err != nil {
+		t.Errorf("expected the parameters of the hash to be used, got %v", err)
+	}
+}
+
+func TestHasherBcrypt(t *testing.T) {
+	hasher := createTestHasher()
+	hasher.Algorithm = AlgorithmBcrypt
+	hash, err := hasher.Hash([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Error("expected the hash to be up to date")
+	}
+	if err = hasher.Compare(hash, []byte("wrong")); err != ErrHashMismatch {
+		t.Errorf("expected error to be ErrHashMismatch, got %v", err)
+	}
+
+	argon2Hasher := createTestHasher()
+	if err = argon2Hasher.Compare(hash, []byte("password")); err != nil {
+		t.Errorf("expected the bcrypt hashes to still be verified, got %v", err)
+	}
+	if !argon2Hasher.NeedsRehash(hash) {
+		t.Error("expected a bcrypt hash to need a rehash")
+	}
+	hasher.BcryptCost = bcrypt.MinCost + 1
+	if !hasher.NeedsRehash(hash) {
+		t.Error("expected a hash with a lower cost to need a rehash")
+	}
+}
+
+func TestHasherMalformed(t *testing.T) {
+	hasher := createTestHasher()
+	hashes := []string{
+		"",
+		"plain",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	}
+	for _, hash := range hashes {
+		if err := hasher.Compare([]byte(hash), []byte("password")); err == nil {
+			t.Errorf("%q: expected an error", hash)
+		}
+		if !hasher.NeedsRehash([]byte(hash)) {
+			t.Errorf("%q: expected a rehash", hash)
+		}
+	}
+}
+
+func TestHasherValidateHash(t *testing.T) {
+	hasher := createTestHasher()
+	hasher.Peppers = map[string][]byte{"v1": []byte("first pepper secret")}
+	argon2Hash, _ := hasher.Hash([]byte("password"))
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	hasher.PepperId = "v1"
+	pepperedHash, _ := hasher.Hash([]byte("password"))
+
+	hashTests := []struct {
+		hash string
+		err  error
+	}{
+		{string(argon2Hash), nil},
+		{string(bcryptHash), nil},
+		{string(pepperedHash), nil},
+		{strings.Replace(string(pepperedHash), "$v1$", "$v2$", 1), ErrUnknownPepper},
+		{string(bcryptHash[:50]), ErrMalformedHash},
+		{"$2b$99$" + string(bcryptHash[7:]), ErrMalformedHash},
+		{"$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5", ErrMalformedHash},
+		{"$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5", ErrArgon2ParamsNotValid},
+		{"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5", ErrArgon2ParamsNotValid},
+		{"$argon2id$v=19$m=4,t=1,p=1$c2FsdA$a2V5", ErrArgon2ParamsNotValid},
+		{"$argon2id$v=19$m=4194304,t=1,p=1$c2FsdA$a2V5", ErrArgon2ParamsNotValid},
+		{"$argon2id$v=19$m=1024,t=100,p=1$c2FsdA$a2V5", ErrArgon2ParamsNotValid},
+		{"$argon2id$v=19$m=65536,t=1,p=64$c2FsdA$a2V5", ErrArgon2ParamsNotValid},
+		{"$1$md5crypt", ErrUnknownAlgorithm},
+		{"plain", ErrUnknownAlgorithm},
+	}
+	for _, hashTest := range hashTests {
+		if err := hasher.ValidateHash([]byte(hashTest.hash)); !errors.Is(err, hashTest.err) {
+			t.Errorf("%q: expected err to be %v, got %v", hashTest.hash, hashTest.err, err)
+		}
+	}
+}
+
+func TestHasherArgon2Bounds(t *testing.T) {
+	hasher := createTestHasher()
+	hashes := []string{
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5",
+		"$argon2id$v=19$m=4194304,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=4294967295,p=1$c2FsdA$a2V5",
+	}
+	for _, hash := range hashes {
+		if err := hasher.Compare([]byte(hash), []byte("password")); err != ErrArgon2ParamsNotValid {
+			t.Errorf("%q: expected error to be ErrArgon2ParamsNotValid, got %v", hash, err)
+		}
+	}
+
+	stronger := createTestHasher()
+	stronger.Argon2.Memory = 128 * 1024
+	hash, _ := stronger.Hash([]byte("password"))
+	if err := hasher.Compare(hash, []byte("password")); err != nil {
+		t.Errorf("expected the hashes within the default cost to be verified, got %v", err)
+	}
+}
+
+func TestHasherValidate(t *testing.T) {
+	hasherTests := []struct {
+		hasher *Hasher
+		valid  bool
+	}{
+		{NewDefaultHasher(), true},
+		{&Hasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.DefaultCost}, true},
+		{&Hasher{Algorithm: AlgorithmBcrypt, BcryptCost: 50}, false},
+		{&Hasher{Algorithm: AlgorithmArgon2id, Argon2: Argon2Params{Memory: 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}}, false},
+		{&Hasher{Algorithm: "scrypt"}, false},
+	}
+	for i, hasherTest := range hasherTests {
+		if err := hasherTest.hasher.Validate(); (err == nil) != hasherTest.valid {
+			t.Errorf("%d: expected valid to be %t, got %v", i, hasherTest.valid, err)
+		}
+	}
+}
+
+func TestHasherPepper(t *testing.T) {
+	plain := createTestHasher()
+	plainHash, _ := plain.Hash([]byte("password"))
+
+	hasher := createTestHasher()
+	hasher.Peppers = map[string][]byte{"v1": []byte("first pepper secret")}
+	hasher.PepperId = "v1"
+	hash, err := hasher.Hash([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "$pepper$v1$argon2id$") {
+		t.Errorf("unexpected hash format %s", hash)
+	}
+	if err = hasher.Compare(hash, []byte("password")); err != nil {
+		t.Errorf("expected the password to match, got %v", err)
+	}
+	if err = hasher.Compare(hash, []byte("wrong")); err != ErrHashMismatch {
+		t.Errorf("expected error to be ErrHashMismatch, got %v", err)
+	}
+	if err = plain.Compare(hash, []byte("password")); err != ErrUnknownPepper {
+		t.Errorf("expected error to be ErrUnknownPepper, got %v", err)
+	}
+	if hasher.NeedsRehash(hash) || !hasher.NeedsRehash(plainHash) || !plain.NeedsRehash(hash) {
+		t.Error("expected the hashes with another pepper to need a rehash")
+	}
+	if err = hasher.Compare(plainHash, []byte("password")); err != nil {
+		t.Errorf("expected the hashes without pepper to keep verifying, got %v", err)
+	}
+
+	hasher.Peppers["v2"] = []byte("second pepper secret")
+	hasher.PepperId = "v2"
+	if !hasher.NeedsRehash(hash) {
+		t.Error("expected the hash of the previous pepper to need a rehash")
+	}
+	if err = hasher.Compare(hash, []byte("password")); err != nil {
+		t.Errorf("expected the hash of the previous pepper to keep verifying, got %v", err)
+	}
+	rotated, _ := hasher.Hash([]byte("password"))
+	if !strings.HasPrefix(string(rotated), "$pepper$v2$") || hasher.NeedsRehash(rotated) {
+		t.Errorf("expected the current pepper, got %s", rotated)
+	}
+
+	hasher.Algorithm = AlgorithmBcrypt
+	long := []byte(strings.Repeat("a", 72) + "b")
+	bcryptHash, _ := hasher.Hash(long)
+	if err = hasher.Compare(bcryptHash, []byte(strings.Repeat("a", 72)+"c")); err != ErrHashMismatch {
+		t.Errorf("expected the whole password to be used with the pepper, got %v", err)
+	}
+}
+
+func TestParsePeppers(t *testing.T) {
+	peppers, err := ParsePeppers([]string{"v1:0123456789abcdef", "v2:secret:with:colons"})
+	if err != nil || string(peppers["v1"]) != "0123456789abcdef" || string(peppers["v2"]) != "secret:with:colons" {
+		t.Errorf("unexpected peppers %v %v", peppers, err)
+	}
+	invalid := [][]string{
+		{"0123456789abcdef"},
+		{":0123456789abcdef"},
+		{"v$1:0123456789abcdef"},
+		{"v1:short"},
+		{"v1:0123456789abcdef", "v1:fedcba9876543210"},
+	}
+	for _, values := range invalid {
+		if _, err = ParsePeppers(values); !errors.Is(err, ErrPepperNotValid) {
+			t.Errorf("%v: expected error to be ErrPepperNotValid, got %v", values, err)
+		}
+	}
+	hasher := &Hasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost, PepperId: "v3", Peppers: peppers}
+	if err = hasher.Validate(); err != ErrUnknownPepper {
+		t.Errorf("expected error to be ErrUnknownPepper, got %v", err)
+	}
+}
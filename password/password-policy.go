@@ -0,0 +1,197 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// BcryptMaxLength is the number of bytes bcrypt takes into account, the rest are silently ignored.
+	BcryptMaxLength = 72
+
+	CodeTooShort          = "tooShort"
+	CodeTooLong           = "tooLong"
+	CodeMissingLowercase  = "missingLowercase"
+	CodeMissingUppercase  = "missingUppercase"
+	CodeMissingDigit      = "missingDigit"
+	CodeMissingSymbol     = "missingSymbol"
+	CodeBreached          = "breached"
+	CodeSimilarToUsername = "similarToUsername"
+)
+
+var (
+	ErrPasswordPolicy = errors.New("password policy: password not valid")
+)
+
+type Policy struct {
+	MinLength               int
+	MaxLength               int
+	RequireLowercase        bool
+	RequireUppercase        bool
+	RequireDigit            bool
+	RequireSymbol           bool
+	RejectSimilarToUsername bool
+	breachedPasswords       map[string]struct{}
+}
+
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return fmt.Sprintf("%s, %s", ErrPasswordPolicy, strings.Join(messages, ", "))
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPasswordPolicy
+}
+
+func NewDefaultPolicy() *Policy {
+	return &Policy{MinLength: 8, MaxLength: BcryptMaxLength, RejectSimilarToUsername: true}
+}
+
+// LoadBreachedPasswords reads a list of known breached passwords, one per line. Empty lines and lines
+// starting with # are ignored.
+func (p *Policy) LoadBreachedPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	breachedPasswords := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breachedPasswords[line] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	p.breachedPasswords = breachedPasswords
+	return nil
+}
+
+// Validate checks the password of the given user against every rule of the policy, the returned
+// *PolicyError has a violation for each broken rule.
+func (p *Policy) Validate(username string, password string) error {
+	violations := make([]Violation, 0)
+	addViolation := func(code string, message string) {
+		violations = append(violations, Violation{Field: "password", Code: code, Message: message})
+	}
+
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		addViolation(CodeTooShort, fmt.Sprintf("Password must have at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		addViolation(CodeTooLong, fmt.Sprintf("Password must have at most %d bytes", p.MaxLength))
+	}
+
+	var hasLowercase, hasUppercase, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLowercase = true
+		case unicode.IsUpper(r):
+			hasUppercase = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLowercase && !hasLowercase {
+		addViolation(CodeMissingLowercase, "Password must have a lowercase letter")
+	}
+	if p.RequireUppercase && !hasUppercase {
+		addViolation(CodeMissingUppercase, "Password must have an uppercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		addViolation(CodeMissingDigit, "Password must have a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		addViolation(CodeMissingSymbol, "Password must have a symbol")
+	}
+
+	if _, breached := p.breachedPasswords[password]; breached {
+		addViolation(CodeBreached, "Password has appeared in a data breach")
+	}
+	if p.RejectSimilarToUsername && isSimilar(username, password) {
+		addViolation(CodeSimilarToUsername, "Password is too similar to the user name")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isSimilar reports whether the password contains the username, forwards or backwards, or is only a
+// few edits away from it. The comparison ignores case.
+func isSimilar(username string, password string) bool {
+	username = strings.ToLower(strings.TrimSpace(username))
+	password = strings.ToLower(password)
+	if username == "" {
+		return false
+	}
+	if utf8.RuneCountInString(username) >= 3 && (strings.Contains(password, username) || strings.Contains(password, reverse(username))) {
+		return true
+	}
+	return levenshtein(username, password) <= 2
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(first int, values ...int) int {
+	for _, value := range values {
+		if value < first {
+			first = value
+		}
+	}
+	return first
+}
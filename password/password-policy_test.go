@@ -0,0 +1,99 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func violationCodes(err error) []string {
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	codes := make([]string, len(policyErr.Violations))
+	for i, violation := range policyErr.Violations {
+		codes[i] = violation.Code
+	}
+	return codes
+}
+
+func TestValidate(t *testing.T) {
+	policy := &Policy{
+		MinLength:               8,
+		MaxLength:               BcryptMaxLength,
+		RequireLowercase:        true,
+		RequireUppercase:        true,
+		RequireDigit:            true,
+		RequireSymbol:           true,
+		RejectSimilarToUsername: true,
+	}
+	policyTests := []struct {
+		username string
+		password string
+		want     []string
+	}{
+		{"user1", "Correct-Horse-7", nil},
+		{"user1", "Ab1!", []string{CodeTooShort}},
+		{"user1", "Ab1!" + strings.Repeat("a", 69), []string{CodeTooLong}},
+		{"user1", "ÄBCDEFGH1!", []string{CodeMissingLowercase}},
+		{"user1", "correct-horse-7", []string{CodeMissingUppercase}},
+		{"user1", "Correct-Horse", []string{CodeMissingDigit}},
+		{"user1", "CorrectHorse7", []string{CodeMissingSymbol}},
+		{"alice", "My-Alice-2022", []string{CodeSimilarToUsername}},
+		{"alice", "My-ecila-2022", []string{CodeSimilarToUsername}},
+		{"administrator", "Administrat0r!", []string{CodeSimilarToUsername}},
+		{"al", "Correct-al-Horse-7", nil},
+		{"user1", "abc", []string{CodeTooShort, CodeMissingUppercase, CodeMissingDigit, CodeMissingSymbol}},
+	}
+	for _, policyTest := range policyTests {
+		err := policy.Validate(policyTest.username, policyTest.password)
+		got := violationCodes(err)
+		if strings.Join(got, ",") != strings.Join(policyTest.want, ",") {
+			t.Errorf("%s/%s: expected violations %v, got %v", policyTest.username, policyTest.password, policyTest.want, got)
+		}
+		if policyTest.want != nil && !errors.Is(err, ErrPasswordPolicy) {
+			t.Errorf("%s/%s: expected err to be ErrPasswordPolicy, got %s", policyTest.username, policyTest.password, err)
+		}
+	}
+}
+
+func TestValidateCountsCharacters(t *testing.T) {
+	policy := &Policy{MinLength: 8, MaxLength: 20}
+	if err := policy.Validate("user1", "ñññññññ"); err == nil {
+		t.Error("expected 7 characters to be too short")
+	}
+	if err := policy.Validate("user1", "ññññññññ"); err != nil {
+		t.Errorf("expected 8 characters to be valid, got %s", err)
+	}
+	if err := policy.Validate("user1", "ñññññññññññ"); err == nil {
+		t.Error("expected 22 bytes to be too long")
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(path, []byte("# top passwords\npassword123\r\n\nqwertyuiop\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewDefaultPolicy()
+	if err = policy.LoadBreachedPasswords(path); err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+
+	for _, breached := range []string{"password123", "qwertyuiop"} {
+		if codes := violationCodes(policy.Validate("user1", breached)); len(codes) != 1 || codes[0] != CodeBreached {
+			t.Errorf("expected %s to be breached, got %v", breached, codes)
+		}
+	}
+	if err = policy.Validate("user1", "# top passwords"); err != nil {
+		t.Errorf("expected comments to be ignored, got %s", err)
+	}
+
+	if err = policy.LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected err to not be nil")
+	}
+}
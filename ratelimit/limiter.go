@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("rate limit: invalid limit, must be requests/period like 10/1m")
+
+// Limit allows Requests requests every Period, all of them can be made at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads a limit written as requests/period, like 10/1m.
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("%w, got %s", ErrInvalidLimit, value)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("%w, got %s", ErrInvalidLimit, value)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("%w, got %s", ErrInvalidLimit, value)
+	}
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter is a token bucket for every key. A bucket holds Requests tokens and gets them back at a
+// steady rate along the Period, every request takes one. The buckets left untouched for a whole
+// period are full again, so they are removed to keep the memory bounded.
+type Limiter struct {
+	limit       Limit
+	mutex       sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{limit: limit, buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow takes a token of the bucket of the key. When the bucket is empty the request is not allowed
+// and the time until the next token is returned.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	l.cleanup(now)
+
+	capacity := float64(l.limit.Requests)
+	perSecond := capacity / l.limit.Period.Seconds()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+}
+
+// Len returns the number of buckets kept.
+func (l *Limiter) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.buckets)
+}
+
+// cleanup removes the full buckets, at most once every period.
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.limit.Period {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.limit.Period {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func createTestLimiter(limit Limit) (*Limiter, *time.Time) {
+	now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(limit)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("10/1m")
+	if err != nil || limit.Requests != 10 || limit.Period != time.Minute {
+		t.Errorf("unexpected limit, got %+v %v", limit, err)
+	}
+	for _, value := range []string{"", "10", "0/1m", "ten/1m", "10/minute", "10/0s", "10/1m/1s"} {
+		if _, err = ParseLimit(value); !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("%s: expected err to be ErrInvalidLimit, got %v", value, err)
+		}
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	limiter, now := createTestLimiter(Limit{Requests: 3, Period: time.Minute})
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow("ip1"); !allowed {
+			t.Errorf("expected request %d to be allowed", i)
+		}
+	}
+	allowed, retryAfter := limiter.Allow("ip1")
+	if allowed || retryAfter != time.Second*20 {
+		t.Errorf("expected the empty bucket to wait 20s for a token, got %v %s", allowed, retryAfter)
+	}
+	if allowed, _ = limiter.Allow("ip2"); !allowed {
+		t.Error("expected other keys to have their own bucket")
+	}
+
+	*now = now.Add(time.Second * 20)
+	if allowed, _ = limiter.Allow("ip1"); !allowed {
+		t.Error("expected a token to be back after 20s")
+	}
+	if allowed, _ = limiter.Allow("ip1"); allowed {
+		t.Error("expected a single token to be back after 20s")
+	}
+}
+
+func TestLimiterCleanup(t *testing.T) {
+	limiter, now := createTestLimiter(Limit{Requests: 3, Period: time.Minute})
+	limiter.Allow("ip1")
+	limiter.Allow("ip2")
+	*now = now.Add(time.Second * 30)
+	limiter.Allow("ip2")
+	if limiter.Len() != 2 {
+		t.Errorf("expected 2 buckets, got %d", limiter.Len())
+	}
+
+	*now = now.Add(time.Second * 31)
+	limiter.Allow("ip3")
+	if limiter.Len() != 2 {
+		t.Errorf("expected the full bucket to be removed, got %d buckets", limiter.Len())
+	}
+	if _, ok := limiter.buckets["ip1"]; ok {
+		t.Error("expected the bucket of ip1 to be removed")
+	}
+}
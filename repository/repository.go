@@ -27,6 +27,10 @@ func (r *Repository[T]) Add(item *T) {
 	r.items = append(r.items, item)
 }
 
+func (r *Repository[T]) Update(index int, item *T) {
+	r.items[index] = item
+}
+
 func (r *Repository[T]) Delete(index int) {
 	lastIndex := len(r.items) - 1
 	r.items[index] = r.items[lastIndex]
@@ -97,3 +97,16 @@ func TestDeleteItem(t *testing.T) {
 		t.Errorf("expected index to be -1, got %d", i)
 	}
 }
+
+func TestUpdateItem(t *testing.T) {
+	r := createRepository()
+	_, i := r.GetItem(getById, "2")
+	r.Update(i, &TestItem{Id: "2", Name: "updated"})
+	item, _ := r.GetItem(getById, "2")
+	if item.Name != "updated" {
+		t.Errorf("expected name to be updated, got %s", item.Name)
+	}
+	if len(r.GetAll()) != 3 {
+		t.Errorf("expected length to be 3, got: %d", len(r.GetAll()))
+	}
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"authGo/mailer"
+	response "authGo/router/response"
+	"authGo/token"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type EmailVerificationRouter struct {
+	Services        *validator.Services
+	VerificationURL string
+}
+
+// RequestVerificationHandler sends the verification link again to the email address of the user. As
+// the login may be blocked until the address is verified it doesn't require an access token, so it
+// always answers with a valid response to not reveal the registered users.
+func (e *EmailVerificationRouter) RequestVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.EmailVerificationValidator{Validator: validator.Validator{Writer: w, Request: r, Services: e.Services}}
+
+	verificationRequest, err := v.GetVerificationRequest()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Email verification data not valid")
+		return
+	}
+
+	u, err := e.Services.UserService.GetRepository().GetByName(v.Validator.GetOrganizationId(), verificationRequest.Name)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if u.Email == "" || u.EmailVerified {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err = e.SendVerification(u); err != nil {
+		log.Print(err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (e *EmailVerificationRouter) CompleteVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.EmailVerificationValidator{Validator: validator.Validator{Writer: w, Request: r, Services: e.Services}}
+
+	payload, err := v.GetVerification()
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrEmailVerificationInvalid) {
+			response.WriteError(w, "Email verification token not valid")
+		} else {
+			response.WriteError(w, "Email verification data not valid")
+		}
+		return
+	}
+
+	u, err := e.Services.UserService.VerifyEmail(payload.UserId, payload.Email)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrEmailVerificationNotValid) {
+			response.WriteError(w, "Email verification token not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	response.WriteUser(w, u)
+}
+
+// SendVerification mails the user a signed link to verify their email address, the link stops being
+// valid when it expires or the address changes.
+func (e *EmailVerificationRouter) SendVerification(u *user.User) error {
+	tokenGenerator := e.Services.EmailVerificationTokenGenerator
+	verificationToken, err := tokenGenerator.CreateToken(&token.EmailVerificationPayload{UserId: u.Id, Email: u.Email, IssuedAtTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	return e.Services.Mailer.Send(&mailer.Message{
+		To:      u.Email,
+		Subject: "Email verification",
+		Body: fmt.Sprintf("This email address was added to the user %s.\n\nUse the following link to verify it, it expires in %s:\n%s\n\nIf you don't know this user you can ignore this message.",
+			u.Name, tokenGenerator.Duration, linkWithToken(e.VerificationURL, verificationToken)),
+	})
+}
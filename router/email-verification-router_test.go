@@ -0,0 +1,164 @@
+package router
+
+import (
+	"authGo/mailer"
+	"authGo/session"
+	"authGo/token"
+	"authGo/user"
+	"authGo/validator"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createEmailVerificationRouter(t *testing.T) (*EmailVerificationRouter, string) {
+	userService := user.NewUserService()
+	userService.CreateUser("admin", "admin", true)
+	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
+	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
+	emailVerificationTokenGenerator := &token.TokenGenerator[token.EmailVerificationPayload]{Password: []byte("emailKey"), Duration: time.Hour * 24}
+	sessionHandler := session.NewSessionHandler()
+	mailPath := filepath.Join(t.TempDir(), "mail.txt")
+
+	services := &validator.Services{
+		UserService:                     userService,
+		AccessTokenGenerator:            accessTokenGenerator,
+		RefreshTokenGenerator:           refreshTokenGenerator,
+		SessionsHandler:                 sessionHandler,
+		Mailer:                          &mailer.FileMailer{Path: mailPath, From: "auth@example.com"},
+		EmailVerificationTokenGenerator: emailVerificationTokenGenerator,
+	}
+
+	return &EmailVerificationRouter{
+		Services:        services,
+		VerificationURL: "http://localhost:4200/verify-email",
+	}, mailPath
+}
+
+func serveEmailVerification(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/auth/email-verification", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func serveLogin(services *validator.Services, name string, password string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/auth/login", nil)
+	req.SetBasicAuth(name, password)
+	rr := httptest.NewRecorder()
+	loginRouter := &LoginRouter{Services: services}
+	http.HandlerFunc(loginRouter.Handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestEmailVerificationOnNewUser(t *testing.T) {
+	emailVerificationRouter, mailPath := createEmailVerificationRouter(t)
+	services := emailVerificationRouter.Services
+	services.UserService.SetRequireVerifiedEmail(true)
+	userRouter := &UserRouter{Services: services, EmailVerification: emailVerificationRouter}
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "/users", strings.NewReader(`{"name": "user2", "password": "user2", "email": "User2@Example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "accessToken", Value: createAccessToken(t, services, adminUser)})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(userRouter.NewUserHandler).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	content, err := os.ReadFile(mailPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "To: user2@example.com") {
+		t.Errorf("expected the verification to be sent to the user, got %s", content)
+	}
+
+	rr = serveLogin(services, "user2", "user2")
+	expected := `{"error":"Email not verified"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the login to be blocked, got %v %s", rr.Code, body)
+	}
+	rr = serveLogin(services, "user2", "wrong password")
+	expected = `{"error":"Password not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); body != expected {
+		t.Errorf("expected the password to be checked first, got %s", body)
+	}
+
+	verificationToken := readMailedToken(t, mailPath, emailVerificationRouter.VerificationURL)
+	rr = serveEmailVerification(emailVerificationRouter.CompleteVerificationHandler, fmt.Sprintf(`{"token": "%s"}`, verificationToken))
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	if body := rr.Body.String(); !strings.Contains(body, `"emailVerified":true`) {
+		t.Errorf("expected the verified user in the body, got %s", body)
+	}
+
+	rr = serveLogin(services, "user2", "user2")
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("expected the login to be allowed once verified, got %v %s", status, rr.Body.String())
+	}
+	rr = serveLogin(services, "admin", "admin")
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("expected the users without email to log in, got %v %s", status, rr.Body.String())
+	}
+}
+
+func TestEmailVerificationChangedEmail(t *testing.T) {
+	emailVerificationRouter, mailPath := createEmailVerificationRouter(t)
+	services := emailVerificationRouter.Services
+	u, err := services.UserService.AddUser(user.NewUser{Name: "user2", Password: "user2", Email: "user2@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serveEmailVerification(emailVerificationRouter.RequestVerificationHandler, `{"name": "user2"}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	oldToken := readMailedToken(t, mailPath, emailVerificationRouter.VerificationURL)
+
+	email := "new@example.com"
+	if _, err = services.UserService.UpdateUser(u.Id, user.UserUpdate{Email: &email}); err != nil {
+		t.Fatal(err)
+	}
+	rr = serveEmailVerification(emailVerificationRouter.CompleteVerificationHandler, fmt.Sprintf(`{"token": "%s"}`, oldToken))
+	expected := `{"error":"Email verification token not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the link of the previous address to not be valid, got %v %s", rr.Code, body)
+	}
+}
+
+func TestEmailVerificationRequestWithoutMail(t *testing.T) {
+	emailVerificationRouter, mailPath := createEmailVerificationRouter(t)
+	services := emailVerificationRouter.Services
+	if _, err := services.UserService.AddUser(user.NewUser{Name: "user2", Password: "user2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"nobody", "user2"} {
+		rr := serveEmailVerification(emailVerificationRouter.RequestVerificationHandler, fmt.Sprintf(`{"name": "%s"}`, name))
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", name, status, http.StatusOK)
+		}
+	}
+	if _, err := os.Stat(mailPath); !os.IsNotExist(err) {
+		t.Error("expected no mail to be sent")
+	}
+
+	rr := serveEmailVerification(emailVerificationRouter.CompleteVerificationHandler, `{"token": "123.123.123"}`)
+	expected := `{"error":"Email verification token not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the token to not be valid, got %v %s", rr.Code, body)
+	}
+}
@@ -0,0 +1,228 @@
+package router
+
+import (
+	"authGo/mailer"
+	"authGo/password"
+	response "authGo/router/response"
+	"authGo/token"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// InvitationRouter lets the administrators invite new users with a single-use link, instead of
+// choosing and sharing their passwords.
+type InvitationRouter struct {
+	Services      *validator.Services
+	InvitationURL string
+}
+
+// CreateHandler creates an invitation to the caller's organization and returns its link, which is also
+// mailed when the invitation has an email address.
+func (i *InvitationRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: i.Services}}
+	invitationV := validator.InvitationValidator{Validator: validator.Validator{Writer: w, Request: r, Services: i.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	input, err := invitationV.GetNewInvitation()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Invitation data not valid")
+		return
+	}
+
+	organizationId := input.OrganizationId
+	if organizationId == "" {
+		organizationId = payload.OrganizationId
+	}
+	if !canAccessOrganization(payload, organizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+	if len(input.Roles) > 0 && !payload.HasPermission(user.PermissionRolesWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+	if containsString(input.Roles, user.SuperAdminRole) && !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	newInvitation := user.NewInvitation{OrganizationId: organizationId, Email: input.Email, Roles: input.Roles, CreatedBy: payload.UserId}
+	if input.ExpiresAt != nil {
+		newInvitation.ExpiresAt = *input.ExpiresAt
+	}
+	invitation, err := i.Services.UserService.CreateInvitation(newInvitation)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrEmailNotValid) {
+			response.WriteError(w, "Email not valid")
+		} else if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+			response.WriteError(w, "Email already registered")
+		} else if errors.Is(err, user.ErrRoleNotFound) {
+			response.WriteError(w, "Role not found")
+		} else if errors.Is(err, user.ErrOrganizationNotFound) {
+			response.WriteError(w, "Organization not found")
+		} else if errors.Is(err, user.ErrInvitationExpiryNotValid) {
+			response.WriteError(w, "Invitation expiry not valid")
+		} else {
+			response.WriteError(w, "Error creating invitation")
+		}
+		return
+	}
+
+	invitationToken, err := i.Services.InvitationTokenGenerator.CreateToken(&token.InvitationPayload{InvitationId: invitation.Id, IssuedAtTime: time.Now()})
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+	link := linkWithToken(i.InvitationURL, invitationToken)
+	if invitation.Email != "" {
+		if err = i.sendInvitation(invitation, link); err != nil {
+			log.Print(err)
+		}
+	}
+
+	response.WriteInvitation(w, invitation, link)
+}
+
+// GetInvitationsHandler lists the invitations of the caller's organization, or of the organizationId
+// query parameter for the super administrators.
+func (i *InvitationRouter) GetInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: i.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersRead) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	organizationId := r.URL.Query().Get("organizationId")
+	if organizationId == "" {
+		organizationId = payload.OrganizationId
+	}
+	if !canAccessOrganization(payload, organizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	invitations, err := i.Services.UserService.GetInvitations(organizationId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteInvitationList(w, invitations)
+}
+
+// DeleteHandler revokes an invitation of the caller's organization, its link stops working.
+func (i *InvitationRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: i.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	invitation, err := i.Services.UserService.GetInvitation(id)
+	if err == nil && !canAccessOrganization(payload, invitation.OrganizationId) {
+		err = user.ErrInvitationNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Invitation id not valid")
+		return
+	}
+
+	if err = i.Services.UserService.DeleteInvitation(id); err != nil {
+		log.Print(err)
+		response.WriteError(w, "Error deleting invitation")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AcceptHandler creates the user of the invitation with the chosen name and password, and logs them in
+// like a valid login.
+func (i *InvitationRouter) AcceptHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.InvitationValidator{Validator: validator.Validator{Writer: w, Request: r, Services: i.Services}}
+
+	acceptance, payload, err := v.GetAcceptance()
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrInvitationInvalid) {
+			response.WriteError(w, "Invitation not valid")
+		} else {
+			response.WriteError(w, "Invitation data not valid")
+		}
+		return
+	}
+
+	u, err := i.Services.UserService.AcceptInvitation(payload.InvitationId, acceptance.Name, acceptance.Password)
+	if err != nil {
+		log.Print(err)
+		var policyErr *password.PolicyError
+		if errors.Is(err, user.ErrInvitationNotValid) {
+			response.WriteError(w, "Invitation not valid")
+		} else if errors.As(err, &policyErr) {
+			response.WritePasswordPolicyError(w, policyErr)
+		} else if errors.Is(err, user.ErrUserAlreadyRegistered) {
+			response.WriteError(w, "User name already registered")
+		} else if errors.Is(err, user.ErrUserNameNotValid) {
+			response.WriteError(w, "User name not valid")
+		} else if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+			response.WriteError(w, "Email already registered")
+		} else if errors.Is(err, user.ErrRoleNotFound) {
+			response.WriteError(w, "Role not found")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	loginRouter := &LoginRouter{Services: i.Services}
+	loginRouter.createSession(w, &validator.LoginValidator{Validator: v.Validator}, u, user.LoginMethodInvitation)
+}
+
+func (i *InvitationRouter) sendInvitation(invitation *user.Invitation, link string) error {
+	return i.Services.Mailer.Send(&mailer.Message{
+		To:      invitation.Email,
+		Subject: "Invitation",
+		Body: fmt.Sprintf("You were invited to create a user.\n\nUse the following link to choose your user name and password, it can only be used once and expires on %s:\n%s\n\nIf you didn't expect this invitation you can ignore this message.",
+			invitation.ExpiresAt.UTC().Format(time.RFC1123), link),
+	})
+}
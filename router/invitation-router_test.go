@@ -0,0 +1,194 @@
+package router
+
+import (
+	"authGo/mailer"
+	"authGo/session"
+	"authGo/token"
+	"authGo/user"
+	"authGo/validator"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func createInvitationRouter(t *testing.T) (*InvitationRouter, string) {
+	userService := user.NewUserService()
+	userService.CreateUser("admin", "admin", true)
+	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
+	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
+	invitationTokenGenerator := &token.TokenGenerator[token.InvitationPayload]{Password: []byte("invitationKey"), Duration: user.DefaultInvitationDuration}
+	sessionHandler := session.NewSessionHandler()
+	mailPath := filepath.Join(t.TempDir(), "mail.txt")
+
+	services := &validator.Services{
+		UserService:              userService,
+		AccessTokenGenerator:     accessTokenGenerator,
+		RefreshTokenGenerator:    refreshTokenGenerator,
+		SessionsHandler:          sessionHandler,
+		Mailer:                   &mailer.FileMailer{Path: mailPath, From: "auth@example.com"},
+		InvitationTokenGenerator: invitationTokenGenerator,
+	}
+
+	return &InvitationRouter{
+		Services:      services,
+		InvitationURL: "http://localhost:4200/accept-invitation",
+	}, mailPath
+}
+
+func serveInvitation(invitationRouter *InvitationRouter, method string, url string, accessToken string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/invitations", invitationRouter.GetInvitationsHandler).Methods("GET")
+	router.HandleFunc("/invitations", invitationRouter.CreateHandler).Methods("POST")
+	router.HandleFunc("/invitations/{id}", invitationRouter.DeleteHandler).Methods("DELETE")
+	router.HandleFunc("/auth/invitations/accept", invitationRouter.AcceptHandler).Methods("POST")
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestInvitationRouter(t *testing.T) {
+	invitationRouter, mailPath := createInvitationRouter(t)
+	services := invitationRouter.Services
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, adminUser)
+	accessToken := createAccessToken(t, services, adminUser)
+
+	rr := serveInvitation(invitationRouter, "POST", "/invitations", accessToken, `{"email": "New.User@example.com", "roles": ["admin"]}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	var created struct {
+		Id    string `json:"id"`
+		Email string `json:"email"`
+		Link  string `json:"link"`
+	}
+	if err = json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Email != "new.user@example.com" || !strings.HasPrefix(created.Link, invitationRouter.InvitationURL+"?token=") {
+		t.Errorf("unexpected invitation, got %s", rr.Body.String())
+	}
+	invitationToken := readMailedToken(t, mailPath, invitationRouter.InvitationURL)
+
+	rr = serveInvitation(invitationRouter, "GET", "/invitations", accessToken, "")
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, created.Id) || strings.Contains(body, "token") {
+		t.Errorf("expected the invitation to be listed without its link, got %s", body)
+	}
+
+	rr = serveInvitation(invitationRouter, "POST", "/auth/invitations/accept", "", fmt.Sprintf(`{"token": "%s", "name": "newuser", "password": "newuser"}`, invitationToken))
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	if cookies := strings.Join(rr.Header().Values("Set-Cookie"), ";"); !strings.Contains(cookies, "accessToken=") || !strings.Contains(cookies, "refreshToken=") {
+		t.Errorf("expected the new user to be logged in, got %s", cookies)
+	}
+	newUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "newuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newUser.HasRole(user.AdminRole) || newUser.Email != "new.user@example.com" || !newUser.EmailVerified {
+		t.Errorf("expected the user to get the invitation roles and a verified email, got %+v", newUser)
+	}
+
+	rr = serveInvitation(invitationRouter, "POST", "/auth/invitations/accept", "", fmt.Sprintf(`{"token": "%s", "name": "other", "password": "other"}`, invitationToken))
+	expected := `{"error":"Invitation not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the invitation to be single use, got %v %s", rr.Code, body)
+	}
+}
+
+func TestInvitationRouterDelete(t *testing.T) {
+	invitationRouter, _ := createInvitationRouter(t)
+	services := invitationRouter.Services
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, adminUser)
+	accessToken := createAccessToken(t, services, adminUser)
+	otherAdmin := addOrganizationAdmin(t, services, "other")
+	otherAccessToken := createAccessToken(t, services, otherAdmin)
+
+	invitation, err := services.UserService.CreateInvitation(user.NewInvitation{OrganizationId: user.DefaultOrganizationId, CreatedBy: adminUser.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invitationToken, err := services.InvitationTokenGenerator.CreateToken(&token.InvitationPayload{InvitationId: invitation.Id, IssuedAtTime: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serveInvitation(invitationRouter, "DELETE", "/invitations/"+invitation.Id, otherAccessToken, "")
+	expected := `{"error":"Invitation id not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the invitations of other organizations to be hidden, got %v %s", rr.Code, body)
+	}
+
+	rr = serveInvitation(invitationRouter, "DELETE", "/invitations/"+invitation.Id, accessToken, "")
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	rr = serveInvitation(invitationRouter, "POST", "/auth/invitations/accept", "", fmt.Sprintf(`{"token": "%s", "name": "newuser", "password": "newuser"}`, invitationToken))
+	expected = `{"error":"Invitation not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the revoked invitation to be refused, got %v %s", rr.Code, body)
+	}
+}
+
+func TestInvitationRouterErrors(t *testing.T) {
+	invitationRouter, _ := createInvitationRouter(t)
+	services := invitationRouter.Services
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, adminUser)
+	accessToken := createAccessToken(t, services, adminUser)
+	normalAccessToken := createAccessToken(t, services, normalUser)
+
+	invitationTests := []struct {
+		name        string
+		method      string
+		url         string
+		accessToken string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"no permission", "POST", "/invitations", normalAccessToken, `{}`, http.StatusForbidden, ""},
+		{"other organization", "POST", "/invitations", accessToken, `{"organizationId": "other"}`, http.StatusForbidden, ""},
+		{"super admin role", "POST", "/invitations", accessToken, fmt.Sprintf(`{"roles": ["%s"]}`, user.SuperAdminRole), http.StatusForbidden, ""},
+		{"unknown role", "POST", "/invitations", accessToken, `{"roles": ["unknown"]}`, http.StatusBadRequest, `{"error":"Role not found"}`},
+		{"invalid email", "POST", "/invitations", accessToken, `{"email": "invalid"}`, http.StatusBadRequest, `{"error":"Email not valid"}`},
+		{"expiry", "POST", "/invitations", accessToken, `{"expiresAt": "2000-01-01T00:00:00Z"}`, http.StatusBadRequest, `{"error":"Invitation expiry not valid"}`},
+		{"list without permission", "GET", "/invitations", normalAccessToken, "", http.StatusForbidden, ""},
+		{"empty token", "POST", "/auth/invitations/accept", "", `{"name": "a", "password": "b"}`, http.StatusBadRequest, `{"error":"Invitation data not valid"}`},
+		{"forged token", "POST", "/auth/invitations/accept", "", `{"token": "a.b.c", "name": "a", "password": "b"}`, http.StatusBadRequest, `{"error":"Invitation not valid"}`},
+	}
+	for _, invitationTest := range invitationTests {
+		rr := serveInvitation(invitationRouter, invitationTest.method, invitationTest.url, invitationTest.accessToken, invitationTest.body)
+		body := strings.TrimSpace(rr.Body.String())
+		if rr.Code != invitationTest.status || body != invitationTest.expected {
+			t.Errorf("%s: got %v %s, want %v %s", invitationTest.name, rr.Code, body, invitationTest.status, invitationTest.expected)
+		}
+	}
+}
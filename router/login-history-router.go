@@ -0,0 +1,34 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/validator"
+	"log"
+	"net/http"
+)
+
+// LoginHistoryRouter lets the users see their own login attempts, the administrators see the ones of
+// any user with UserRouter.GetLoginHistoryHandler.
+type LoginHistoryRouter struct {
+	Services *validator.Services
+}
+
+func (l *LoginHistoryRouter) GetHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: l.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	attempts, err := l.Services.UserService.GetLoginHistory(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteLoginHistory(w, attempts)
+}
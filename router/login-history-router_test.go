@@ -0,0 +1,83 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func serveLoginHistory(loginHistoryRouter *LoginHistoryRouter, userRouter *UserRouter, url string, accessToken string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/me/logins", loginHistoryRouter.GetHandler).Methods("GET")
+	router.HandleFunc("/users/{id}/logins", userRouter.GetLoginHistoryHandler).Methods("GET")
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestLoginHistoryRouter(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	loginHistoryRouter := &LoginHistoryRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAdmin := addOrganizationAdmin(t, services, "other")
+
+	serveLogin(services, "user2", "wrong")
+	serveLogin(services, "nobody", "wrong")
+	if rr := serveLogin(services, "user2", "user2"); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	rr := serveLoginHistory(loginHistoryRouter, userRouter, "/users/me/logins", createAccessToken(t, services, normalUser))
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	var history struct {
+		Logins []*user.LoginAttempt `json:"logins"`
+	}
+	if err = json.Unmarshal(rr.Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Logins) != 2 {
+		t.Fatalf("expected the 2 logins of the user, got %s", rr.Body.String())
+	}
+	success, failure := history.Logins[0], history.Logins[1]
+	if success.Outcome != user.LoginOutcomeSuccess || success.Method != user.LoginMethodPassword || success.UserId != normalUser.Id {
+		t.Errorf("expected the newest login to be the success, got %+v", success)
+	}
+	if failure.Outcome != user.LoginOutcomeFailure || failure.FailureReason != "password not valid" {
+		t.Errorf("expected the oldest login to be the failure, got %+v", failure)
+	}
+	if loggedIn, _ := services.UserService.GetRepository().GetById(normalUser.Id); loggedIn.LastLoginAt == nil || !loggedIn.LastLoginAt.Equal(success.Time) {
+		t.Errorf("expected the last login of the user to be recorded, got %v", loggedIn.LastLoginAt)
+	}
+
+	rr = serveLoginHistory(loginHistoryRouter, userRouter, "/users/"+normalUser.Id+"/logins", createAccessToken(t, services, adminUser))
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	if err = json.Unmarshal(rr.Body.Bytes(), &history); err != nil || len(history.Logins) != 2 {
+		t.Errorf("expected the administrators to see the logins of the user, got %s", rr.Body.String())
+	}
+
+	if rr = serveLoginHistory(loginHistoryRouter, userRouter, "/users/"+adminUser.Id+"/logins", createAccessToken(t, services, normalUser)); rr.Code != http.StatusForbidden {
+		t.Errorf("expected users without users:read to be forbidden, got %v", rr.Code)
+	}
+	rr = serveLoginHistory(loginHistoryRouter, userRouter, "/users/"+normalUser.Id+"/logins", createAccessToken(t, services, otherAdmin))
+	expected := `{"error":"User id not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the users of other organizations to be hidden, got %v %s", rr.Code, body)
+	}
+}
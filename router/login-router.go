@@ -2,6 +2,7 @@ package router
 
 import (
 	response "authGo/router/response"
+	"authGo/user"
 	"authGo/validator"
 	"errors"
 	"log"
@@ -22,25 +23,200 @@ func (l *LoginRouter) Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := v.GetUser(loginDetails)
+	u, err := v.GetUser(loginDetails)
 	if err != nil {
 		log.Print(err)
+		l.recordLogin(&v, v.GetLoginUserId(loginDetails), user.LoginMethodPassword, err)
 		if errors.Is(err, validator.ErrLoginRouterUserNotFound) {
 			response.WriteError(w, "User doesn't exist")
 		} else if errors.Is(err, validator.ErrLoginRouterPasswordNotValid) {
 			response.WriteError(w, "Password not valid")
+		} else if errors.Is(err, validator.ErrLoginRouterEmailNotVerified) {
+			response.WriteError(w, "Email not verified")
+		} else if errors.Is(err, validator.ErrLoginRouterUserLocked) {
+			response.WriteError(w, "Account temporarily locked")
+		} else if errors.Is(err, validator.ErrLoginRouterUserDisabled) {
+			response.WriteError(w, "Account disabled")
+		} else if errors.Is(err, validator.ErrLoginRouterUserSuspended) {
+			response.WriteError(w, "Account suspended")
+		} else if errors.Is(err, validator.ErrLoginRouterUserPending) {
+			response.WriteError(w, "Account pending approval")
+		} else {
+			response.WriteGeneralError(w)
 		}
 		return
 	}
 
-	tokens, err := v.CreateTokens(user)
+	mfaV := validator.MFAValidator{Validator: v.Validator}
+	secondFactors, err := mfaV.GetSecondFactors(u)
 	if err != nil {
 		log.Print(err)
 		response.WriteGeneralError(w)
 		return
 	}
+	if len(secondFactors) > 0 {
+		challengeToken, err := mfaV.CreateChallenge(u)
+		if err != nil {
+			log.Print(err)
+			response.WriteGeneralError(w)
+			return
+		}
+		response.WriteMFAChallenge(w, challengeToken, secondFactors)
+		return
+	}
+
+	l.createSession(w, &v, u, user.LoginMethodPassword)
+}
+
+// MFAHandler finishes the login of the users with a second factor, it expects the challenge token
+// given by Handler and a code of the second factor or one of the recovery codes.
+func (l *LoginRouter) MFAHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.LoginValidator{Validator: validator.Validator{Writer: w, Request: r, Services: l.Services}}
+	mfaV := validator.MFAValidator{Validator: v.Validator}
+
+	mfaLogin, challenge, err := mfaV.GetMFALogin()
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrMFAChallengeInvalid) {
+			response.WriteError(w, "Challenge token not valid")
+		} else {
+			response.WriteError(w, "Second factor data not valid")
+		}
+		return
+	}
+
+	var u *user.User
+	method := user.LoginMethodTOTP
+	if mfaLogin.RecoveryCode != "" {
+		method = user.LoginMethodRecoveryCode
+		u, err = mfaV.VerifyRecoveryCode(challenge, mfaLogin.RecoveryCode)
+	} else {
+		u, err = mfaV.VerifyCode(challenge, mfaLogin.Code)
+	}
+	if err != nil {
+		log.Print(err)
+		if !errors.Is(err, validator.ErrLoginRouterUserNotFound) {
+			l.recordLogin(&v, challenge.UserId, method, err)
+		}
+		if errors.Is(err, validator.ErrMFACodeNotValid) {
+			response.WriteError(w, "Code not valid")
+		} else if errors.Is(err, validator.ErrLoginRouterUserLocked) {
+			response.WriteError(w, "Account temporarily locked")
+		} else if errors.Is(err, validator.ErrLoginRouterUserDisabled) {
+			response.WriteError(w, "Account disabled")
+		} else if errors.Is(err, validator.ErrLoginRouterUserSuspended) {
+			response.WriteError(w, "Account suspended")
+		} else if errors.Is(err, validator.ErrLoginRouterUserPending) {
+			response.WriteError(w, "Account pending approval")
+		} else if errors.Is(err, validator.ErrLoginRouterUserNotFound) || errors.Is(err, validator.ErrMFAChallengeNotExpected) {
+			response.WriteError(w, "Challenge token not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	l.createSession(w, &v, u, method)
+}
+
+// WebAuthnOptionsHandler starts a login with a passkey. With the challenge token of Handler the passkey
+// is the second factor of the user, without it the login is passwordless.
+func (l *LoginRouter) WebAuthnOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	webAuthnV := validator.WebAuthnValidator{Validator: validator.Validator{Writer: w, Request: r, Services: l.Services}}
+
+	user, err := webAuthnV.GetLoginOptionsUser()
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrMFAChallengeInvalid) {
+			response.WriteError(w, "Challenge token not valid")
+		} else {
+			response.WriteError(w, "Login data not valid")
+		}
+		return
+	}
 
-	l.Services.SessionsHandler.AddNewSession(tokens.RefreshPayload, v.GetDeviceData())
+	challengeToken, options, err := webAuthnV.CreateLoginOptions(user)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrMFAChallengeNotExpected) {
+			response.WriteError(w, "Challenge token not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	response.WriteWebAuthnRequestOptions(w, challengeToken, options)
+}
+
+// WebAuthnHandler finishes the login with the assertion of a passkey for the challenge token of
+// WebAuthnOptionsHandler.
+func (l *LoginRouter) WebAuthnHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.LoginValidator{Validator: validator.Validator{Writer: w, Request: r, Services: l.Services}}
+	webAuthnV := validator.WebAuthnValidator{Validator: v.Validator}
+
+	login, challenge, err := webAuthnV.GetLogin()
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrWebAuthnChallengeInvalid) {
+			response.WriteError(w, "Challenge token not valid")
+		} else {
+			response.WriteError(w, "Login data not valid")
+		}
+		return
+	}
+
+	u, err := webAuthnV.VerifyLogin(login, challenge)
+	if err != nil {
+		log.Print(err)
+		if !errors.Is(err, validator.ErrLoginRouterUserNotFound) {
+			l.recordLogin(&v, webAuthnV.GetLoginUserId(login, challenge), user.LoginMethodWebAuthn, err)
+		}
+		if errors.Is(err, validator.ErrWebAuthnCredentialNotValid) || errors.Is(err, validator.ErrLoginRouterUserNotFound) {
+			response.WriteError(w, "Credential not valid")
+		} else if errors.Is(err, validator.ErrLoginRouterEmailNotVerified) {
+			response.WriteError(w, "Email not verified")
+		} else if errors.Is(err, validator.ErrLoginRouterUserDisabled) {
+			response.WriteError(w, "Account disabled")
+		} else if errors.Is(err, validator.ErrLoginRouterUserSuspended) {
+			response.WriteError(w, "Account suspended")
+		} else if errors.Is(err, validator.ErrLoginRouterUserPending) {
+			response.WriteError(w, "Account pending approval")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	l.createSession(w, &v, u, user.LoginMethodWebAuthn)
+}
+
+// createSession finishes the login with the given method, recording it in the login history of the user.
+func (l *LoginRouter) createSession(w http.ResponseWriter, v *validator.LoginValidator, u *user.User, method string) {
+	tokens, err := v.CreateTokens(u)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	if err = l.Services.SessionsHandler.AddNewSession(tokens.RefreshPayload, v.GetDeviceData()); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+	l.recordLogin(v, u.Id, method, nil)
 
 	response.WriteSuccessfulLogin(w, tokens)
 }
+
+// recordLogin adds the attempt to the login history when the user is known, the errors are only logged
+// so they don't change the response of the login.
+func (l *LoginRouter) recordLogin(v *validator.LoginValidator, userId string, method string, loginErr error) {
+	if userId == "" {
+		return
+	}
+	if err := v.RecordLoginAttempt(userId, method, loginErr); err != nil {
+		log.Print(err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"authGo/token"
 	"authGo/user"
 	"authGo/validator"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -17,13 +18,15 @@ func createLoginRouter() *LoginRouter {
 	userService.CreateUser("admin", "admin", true)
 	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
 	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
+	mfaChallengeTokenGenerator := &token.TokenGenerator[token.MFAChallengePayload]{Password: []byte("mfaChallengeKey"), Duration: time.Minute * 5}
 	sessionHandler := session.NewSessionHandler()
 
 	services := &validator.Services{
-		UserService:           userService,
-		AccessTokenGenerator:  accessTokenGenerator,
-		RefreshTokenGenerator: refreshTokenGenerator,
-		SessionsHandler:       sessionHandler,
+		UserService:                userService,
+		AccessTokenGenerator:       accessTokenGenerator,
+		RefreshTokenGenerator:      refreshTokenGenerator,
+		SessionsHandler:            sessionHandler,
+		MFAChallengeTokenGenerator: mfaChallengeTokenGenerator,
 	}
 
 	return &LoginRouter{
@@ -66,7 +69,10 @@ func TestLoginRouterHandler(t *testing.T) {
 		t.Errorf("refreshToken cookie not found, got %s", cookies)
 	}
 
-	sessions := loginRouter.Services.SessionsHandler.GetAllSessions()
+	sessions, err := loginRouter.Services.SessionsHandler.GetAllSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(sessions) != 1 {
 		t.Errorf("expected sessions len to be 1, got %v", sessions)
 	}
@@ -77,6 +83,36 @@ func TestLoginRouterHandler(t *testing.T) {
 	}
 }
 
+// failingSessionStore refuses to store the sessions.
+type failingSessionStore struct {
+	*session.MemoryStore
+}
+
+func (f failingSessionStore) Add(s *session.Session) error {
+	return errors.New("session store unavailable")
+}
+
+func TestHandlerSessionNotStored(t *testing.T) {
+	req, err := http.NewRequest("POST", "/auth/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("admin", "admin")
+
+	loginRouter := createLoginRouter()
+	loginRouter.Services.SessionsHandler = session.NewSessionHandlerWithStore(failingSessionStore{session.NewMemoryStore()})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(loginRouter.Handler).ServeHTTP(rr, req)
+
+	expected := `{"error":"Something went wrong. Please try again later"}`
+	if body := strings.TrimSpace(rr.Body.String()); body != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", body, expected)
+	}
+	if cookies := rr.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no cookies without a session, got %v", cookies)
+	}
+}
+
 func TestHandlerEmptyLogin(t *testing.T) {
 	req, err := http.NewRequest("POST", "/auth/login", nil)
 	if err != nil {
@@ -147,3 +183,56 @@ func TestHandlerPasswordNotValid(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", want, expected)
 	}
 }
+
+func TestLoginRouterOrganization(t *testing.T) {
+	loginRouter := createLoginRouter()
+	userService := loginRouter.Services.UserService
+	if _, err := userService.CreateOrganization(&user.Organization{Id: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	acmeAdmin, err := userService.AddUser(user.NewUser{OrganizationId: "acme", Name: "admin", Password: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loginTests := []struct {
+		organizationId string
+		password       string
+		status         int
+	}{
+		{"acme", "acme", http.StatusOK},
+		{"acme", "admin", http.StatusBadRequest},
+		{"", "admin", http.StatusOK},
+		{"nobody", "admin", http.StatusBadRequest},
+	}
+	for _, loginTest := range loginTests {
+		req, _ := http.NewRequest("POST", "/auth/login", nil)
+		req.SetBasicAuth("admin", loginTest.password)
+		if loginTest.organizationId != "" {
+			req.Header.Set(validator.OrganizationHeader, loginTest.organizationId)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(loginRouter.Handler).ServeHTTP(rr, req)
+		if status := rr.Code; status != loginTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", loginTest.organizationId, status, loginTest.status)
+		}
+	}
+
+	sessions, err := loginRouter.Services.SessionsHandler.GetUserSessions(acmeAdmin.Id)
+	if err != nil || len(sessions) != 1 || sessions[0].UserToken.OrganizationId != "acme" {
+		t.Errorf("expected the session to belong to the organization, got %v %v", sessions, err)
+	}
+}
+
+func TestLoginRouterCanonicalName(t *testing.T) {
+	loginRouter := createLoginRouter()
+	for _, name := range []string{"ADMIN", " Admin ", "ａｄｍｉｎ"} {
+		req, _ := http.NewRequest("POST", "/auth/login", nil)
+		req.SetBasicAuth(name, "admin")
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(loginRouter.Handler).ServeHTTP(rr, req)
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("%q: handler returned wrong status code: got %v want %v, body %s", name, status, http.StatusOK, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/token"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// OrganizationRouter manages the organizations, only the super administrators can use it.
+type OrganizationRouter struct {
+	Services *validator.Services
+}
+
+func (o *OrganizationRouter) GetOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: o.Services}}
+
+	payload, err := v.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	organizations, err := o.Services.UserService.GetOrganizations()
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+	response.WriteOrganizationList(w, organizations)
+}
+
+func (o *OrganizationRouter) NewOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: o.Services}}
+	organizationV := validator.OrganizationValidator{Validator: validator.Validator{Writer: w, Request: r, Services: o.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	newOrganization, err := organizationV.GetNewOrganization()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Organization data not valid")
+		return
+	}
+
+	organization, err := o.Services.UserService.CreateOrganization(&user.Organization{Id: newOrganization.Id, Name: newOrganization.Name})
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrOrganizationAlreadyExists) {
+			response.WriteError(w, "Organization already exists")
+		} else if errors.Is(err, user.ErrOrganizationNotValid) {
+			response.WriteError(w, "Organization data not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+	response.WriteOrganization(w, organization)
+}
+
+// isSuperAdmin tells whether the user of the token can act on every organization.
+func isSuperAdmin(payload *token.AccessTokenPayload) bool {
+	return containsString(payload.Roles, user.SuperAdminRole)
+}
+
+// canAccessOrganization tells whether the user of the token can act on the users and sessions of the
+// organization, which is only their own one unless they are super administrators.
+func canAccessOrganization(payload *token.AccessTokenPayload, organizationId string) bool {
+	return isSuperAdmin(payload) || payload.OrganizationId == organizationId
+}
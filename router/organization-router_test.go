@@ -0,0 +1,224 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// addSuperAdmin adds a user of the default organization with the super-admin role.
+func addSuperAdmin(t *testing.T, services *validator.Services) *user.User {
+	superAdmin, err := services.UserService.AddUser(user.NewUser{Name: "superadmin", Password: "superadmin", Roles: []string{user.SuperAdminRole}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return superAdmin
+}
+
+// addOrganizationAdmin creates the organization with an admin user that has a session.
+func addOrganizationAdmin(t *testing.T, services *validator.Services, organizationId string) *user.User {
+	if _, err := services.UserService.CreateOrganization(&user.Organization{Id: organizationId}); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := services.UserService.AddUser(user.NewUser{OrganizationId: organizationId, Name: "admin", Password: "admin", Roles: []string{user.AdminRole}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, admin)
+	return admin
+}
+
+func serveOrganizationScoped(userRouter *UserRouter, method string, url string, accessToken string, body string) *httptest.ResponseRecorder {
+	sessionRouter := &SessionRouter{Services: userRouter.Services}
+	organizationRouter := &OrganizationRouter{Services: userRouter.Services}
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/organizations", organizationRouter.GetOrganizationsHandler).Methods("GET")
+	router.HandleFunc("/organizations", organizationRouter.NewOrganizationHandler).Methods("POST")
+	router.HandleFunc("/users", userRouter.GetUsersHandler).Methods("GET")
+	router.HandleFunc("/users", userRouter.NewUserHandler).Methods("POST")
+	router.HandleFunc("/users/{id}", userRouter.UpdateUserHandler).Methods("PATCH")
+	router.HandleFunc("/users/{id}", userRouter.DeleteUserHandler).Methods("DELETE")
+	router.HandleFunc("/sessions", sessionRouter.GetSessionsHandler).Methods("GET")
+	router.HandleFunc("/sessions/{id}", sessionRouter.DeleteSessionHandler).Methods("DELETE")
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestOrganizationRouter(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	superAdminToken := createAccessToken(t, services, addSuperAdmin(t, services))
+	admin, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := createAccessToken(t, services, admin)
+
+	organizationTests := []struct {
+		name        string
+		method      string
+		accessToken string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"create", "POST", superAdminToken, `{"id": "acme", "name": "Acme"}`, http.StatusOK, `{"id":"acme","name":"Acme"}`},
+		{"create as organization admin", "POST", adminToken, `{"id": "other"}`, http.StatusForbidden, ""},
+		{"list as organization admin", "GET", adminToken, "", http.StatusForbidden, ""},
+		{"already exists", "POST", superAdminToken, `{"id": "acme"}`, http.StatusBadRequest, `{"error":"Organization already exists"}`},
+		{"id not valid", "POST", superAdminToken, `{"id": "Not Valid"}`, http.StatusBadRequest, `{"error":"Organization data not valid"}`},
+		{"empty id", "POST", superAdminToken, `{"name": "Acme"}`, http.StatusBadRequest, `{"error":"Organization data not valid"}`},
+		{"invalid access token", "GET", "123.123.123", "", http.StatusUnauthorized, ""},
+	}
+	for _, organizationTest := range organizationTests {
+		rr := serveOrganizationScoped(userRouter, organizationTest.method, "/organizations", organizationTest.accessToken, organizationTest.body)
+		if status := rr.Code; status != organizationTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", organizationTest.name, status, organizationTest.status)
+		}
+		if organizationTest.expected == "" {
+			continue
+		}
+		if body := strings.TrimSpace(rr.Body.String()); body != organizationTest.expected {
+			t.Errorf("%s: handler returned unexpected body: got %v want %v", organizationTest.name, body, organizationTest.expected)
+		}
+	}
+
+	rr := serveOrganizationScoped(userRouter, "GET", "/organizations", superAdminToken, "")
+	var organizationResponse response.OrganizationResponse
+	json.Unmarshal(rr.Body.Bytes(), &organizationResponse)
+	if len(organizationResponse.Organizations) != 2 {
+		t.Errorf("expected the default and the new organization, got %s", rr.Body.String())
+	}
+}
+
+func TestOrganizationScopedUsers(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	superAdminToken := createAccessToken(t, services, addSuperAdmin(t, services))
+	acmeAdmin := addOrganizationAdmin(t, services, "acme")
+	acmeToken := createAccessToken(t, services, acmeAdmin)
+	defaultUser := addUserAndSession(t, *services, "user2", "user2", false)
+
+	scopeTests := []struct {
+		name        string
+		method      string
+		url         string
+		accessToken string
+		body        string
+		status      int
+		users       int
+	}{
+		{"list own organization", "GET", "/users", acmeToken, "", http.StatusOK, 1},
+		{"list other organization", "GET", "/users?organizationId=default", acmeToken, "", http.StatusForbidden, 0},
+		{"super admin lists every organization", "GET", "/users", superAdminToken, "", http.StatusOK, 4},
+		{"super admin lists one organization", "GET", "/users?organizationId=acme", superAdminToken, "", http.StatusOK, 1},
+		{"update other organization", "PATCH", "/users/" + defaultUser.Id, acmeToken, `{"name": "renamed"}`, http.StatusBadRequest, 0},
+		{"delete other organization", "DELETE", "/users/" + defaultUser.Id, acmeToken, "", http.StatusBadRequest, 0},
+		{"create in other organization", "POST", "/users", acmeToken, `{"organizationId": "default", "name": "user3", "password": "user3"}`, http.StatusForbidden, 0},
+		{"assign super admin", "POST", "/users", acmeToken, `{"name": "user3", "password": "user3", "roles": ["super-admin"]}`, http.StatusForbidden, 0},
+		{"create with a name of other organization", "POST", "/users", acmeToken, `{"name": "user2", "password": "user2"}`, http.StatusOK, 0},
+		{"super admin creates in unknown organization", "POST", "/users", superAdminToken, `{"organizationId": "nobody", "name": "user3", "password": "user3"}`, http.StatusBadRequest, 0},
+	}
+	for _, scopeTest := range scopeTests {
+		rr := serveOrganizationScoped(userRouter, scopeTest.method, scopeTest.url, scopeTest.accessToken, scopeTest.body)
+		if status := rr.Code; status != scopeTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v, body %s", scopeTest.name, status, scopeTest.status, rr.Body.String())
+		}
+		if scopeTest.method != "GET" || scopeTest.status != http.StatusOK {
+			continue
+		}
+		var userResponse response.UserResponse
+		json.Unmarshal(rr.Body.Bytes(), &userResponse)
+		if len(userResponse.Users) != scopeTest.users {
+			t.Errorf("%s: expected %d users, got %d", scopeTest.name, scopeTest.users, len(userResponse.Users))
+		}
+	}
+
+	acmeUser, err := services.UserService.GetRepository().GetByName("acme", "user2")
+	if err != nil || acmeUser.Id == defaultUser.Id {
+		t.Errorf("expected the user to be created in the organization of the admin, got %+v %v", acmeUser, err)
+	}
+	if current, _ := services.UserService.GetRepository().GetById(defaultUser.Id); current.Name != "user2" {
+		t.Errorf("expected the user of the other organization to not be updated, got %s", current.Name)
+	}
+}
+
+func TestOrganizationScopedSessions(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	superAdminToken := createAccessToken(t, services, addSuperAdmin(t, services))
+	acmeAdmin := addOrganizationAdmin(t, services, "acme")
+	acmeToken := createAccessToken(t, services, acmeAdmin)
+	defaultUser := addUserAndSession(t, *services, "user2", "user2", false)
+	defaultSessions, err := services.SessionsHandler.GetUserSessions(defaultUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for accessToken, want := range map[string]int{acmeToken: 1, superAdminToken: 2} {
+		rr := serveOrganizationScoped(userRouter, "GET", "/sessions", accessToken, "")
+		var sessionResponse response.SessionResponse
+		json.Unmarshal(rr.Body.Bytes(), &sessionResponse)
+		if rr.Code != http.StatusOK || len(sessionResponse.Sessions) != want {
+			t.Errorf("expected %d sessions, got %v %s", want, rr.Code, rr.Body.String())
+		}
+	}
+	rr := serveOrganizationScoped(userRouter, "GET", "/sessions?organizationId=default", acmeToken, "")
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected the sessions of other organization to be forbidden, got %v", rr.Code)
+	}
+	rr = serveOrganizationScoped(userRouter, "DELETE", "/sessions/"+defaultSessions[0].Id, acmeToken, "")
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected the sessions of other organization to not be revoked, got %v", rr.Code)
+	}
+	rr = serveOrganizationScoped(userRouter, "DELETE", "/sessions/"+defaultSessions[0].Id, superAdminToken, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected the super admin to revoke the session, got %v", rr.Code)
+	}
+}
+
+func TestSuperAdminProtectedFromOrganizationAdmins(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	superAdmin := addSuperAdmin(t, services)
+	superAdminToken := createAccessToken(t, services, superAdmin)
+	admin, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := createAccessToken(t, services, admin)
+
+	rr := serveOrganizationScoped(userRouter, "PATCH", "/users/"+superAdmin.Id, adminToken, `{"email": "taken@example.com"}`)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected an organization admin to not update a super admin, got %v", rr.Code)
+	}
+	rr = serveOrganizationScoped(userRouter, "DELETE", "/users/"+superAdmin.Id, adminToken, "")
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected an organization admin to not delete a super admin, got %v", rr.Code)
+	}
+	rr = serveOrganizationScoped(userRouter, "PATCH", "/users/"+admin.Id, adminToken, `{"roles": ["admin", "super-admin"]}`)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected an organization admin to not become super admin, got %v", rr.Code)
+	}
+	rr = serveOrganizationScoped(userRouter, "PATCH", "/users/"+superAdmin.Id, superAdminToken, `{"roles": []}`)
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"error":"An user cannot remove their own admin role"}` {
+		t.Errorf("expected a super admin to keep their role, got %s", body)
+	}
+	rr = serveOrganizationScoped(userRouter, "PATCH", "/users/"+admin.Id, superAdminToken, `{"roles": ["admin", "super-admin"]}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected a super admin to assign the super-admin role, got %v %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"authGo/mailer"
+	"authGo/password"
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+type PasswordResetRouter struct {
+	Services *validator.Services
+	ResetURL string
+}
+
+// RequestResetHandler always answers with a valid response, whether the user exists or not, so that
+// it can't be used to find out the registered users.
+func (p *PasswordResetRouter) RequestResetHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.PasswordResetValidator{Validator: validator.Validator{Writer: w, Request: r, Services: p.Services}}
+
+	resetRequest, err := v.GetResetRequest()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Password reset data not valid")
+		return
+	}
+
+	resetToken, u, err := p.Services.UserService.RequestPasswordReset(v.Validator.GetOrganizationId(), resetRequest.Name)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	message := &mailer.Message{
+		To:      u.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("A password reset was requested for the user %s.\n\nUse the following link to choose a new password, it can only be used once and expires in %s:\n%s\n\nIf you didn't request it you can ignore this message.",
+			u.Name, p.Services.UserService.GetPasswordResetDuration(), linkWithToken(p.ResetURL, resetToken)),
+	}
+	if err = p.Services.Mailer.Send(message); err != nil {
+		log.Print(err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *PasswordResetRouter) CompleteResetHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.PasswordResetValidator{Validator: validator.Validator{Writer: w, Request: r, Services: p.Services}}
+
+	resetCompletion, err := v.GetResetCompletion()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Password reset data not valid")
+		return
+	}
+
+	u, err := p.Services.UserService.CompletePasswordReset(resetCompletion.Token, resetCompletion.NewPassword)
+	if err != nil {
+		log.Print(err)
+		var policyErr *password.PolicyError
+		if errors.Is(err, user.ErrPasswordResetTokenNotValid) {
+			response.WriteError(w, "Password reset token not valid")
+		} else if errors.As(err, &policyErr) {
+			response.WritePasswordPolicyError(w, policyErr)
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	err = p.Services.SessionsHandler.RevokeUserSessions(u.Id, "")
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Password changed but there was an error revoking the user sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// linkWithToken adds the token to the query parameters of the link.
+func linkWithToken(baseURL string, linkToken string) string {
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL + "?token=" + url.QueryEscape(linkToken)
+	}
+	query := link.Query()
+	query.Set("token", linkToken)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"authGo/mailer"
+	"authGo/session"
+	"authGo/token"
+	"authGo/user"
+	"authGo/validator"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createPasswordResetRouter(t *testing.T) (*PasswordResetRouter, string) {
+	userService := user.NewUserService()
+	userService.CreateUser("admin", "admin", true)
+	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
+	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
+	sessionHandler := session.NewSessionHandler()
+	mailPath := filepath.Join(t.TempDir(), "mail.txt")
+
+	services := &validator.Services{
+		UserService:           userService,
+		AccessTokenGenerator:  accessTokenGenerator,
+		RefreshTokenGenerator: refreshTokenGenerator,
+		SessionsHandler:       sessionHandler,
+		Mailer:                &mailer.FileMailer{Path: mailPath, From: "auth@example.com"},
+	}
+
+	return &PasswordResetRouter{
+		Services: services,
+		ResetURL: "http://localhost:4200/reset-password",
+	}, mailPath
+}
+
+func servePasswordReset(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/auth/password-reset", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// readMailedToken returns the token of the last link to baseURL written to the mail file.
+func readMailedToken(t *testing.T, mailPath string, baseURL string) string {
+	content, err := os.ReadFile(mailPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := regexp.MustCompile(regexp.QuoteMeta(baseURL+"?token=")+`\S+`).FindAll(content, -1)
+	if links == nil {
+		t.Fatalf("link to %s not found in %s", baseURL, content)
+	}
+	parsed, err := url.Parse(string(links[len(links)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
+
+func TestPasswordResetRouter(t *testing.T) {
+	passwordResetRouter, mailPath := createPasswordResetRouter(t)
+	services := passwordResetRouter.Services
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	addSession(t, *services, normalUser)
+	email := "user2@example.com"
+	if _, err := services.UserService.UpdateUser(normalUser.Id, user.UserUpdate{Email: &email}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.UserService.VerifyEmail(normalUser.Id, email); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := servePasswordReset(passwordResetRouter.RequestResetHandler, `{"name": "user2"}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	content, err := os.ReadFile(mailPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "To: user2@example.com") {
+		t.Errorf("expected the mail to be sent to the user, got %s", content)
+	}
+	resetToken := readMailedToken(t, mailPath, passwordResetRouter.ResetURL)
+
+	rr = servePasswordReset(passwordResetRouter.CompleteResetHandler, fmt.Sprintf(`{"token": "%s", "newPassword": "new password"}`, resetToken))
+	if status := rr.Code; status != http.StatusOK {
+		body := strings.TrimSpace(rr.Body.String())
+		t.Fatalf("handler returned wrong status code: got %v want %v , body %s", status, http.StatusOK, body)
+	}
+	if !services.UserService.IsPasswordValid(user.DefaultOrganizationId, "user2", "new password") {
+		t.Error("expected the password to be changed")
+	}
+	sessions, err := services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected every session of the user to be revoked, got %v", sessions)
+	}
+
+	rr = servePasswordReset(passwordResetRouter.CompleteResetHandler, fmt.Sprintf(`{"token": "%s", "newPassword": "another password"}`, resetToken))
+	expected := `{"error":"Password reset token not valid"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the token to be single use, got %v %s", rr.Code, body)
+	}
+}
+
+func TestPasswordResetRouterUnknownUser(t *testing.T) {
+	passwordResetRouter, mailPath := createPasswordResetRouter(t)
+
+	addUserAndSession(t, *passwordResetRouter.Services, "user2", "user2", false)
+
+	for _, name := range []string{"nobody", "user2"} {
+		rr := servePasswordReset(passwordResetRouter.RequestResetHandler, fmt.Sprintf(`{"name": "%s"}`, name))
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", name, status, http.StatusOK)
+		}
+	}
+	if _, err := os.Stat(mailPath); !os.IsNotExist(err) {
+		t.Error("expected no mail to be sent to unknown users or users without a verified email")
+	}
+}
+
+func TestPasswordResetRouterErrors(t *testing.T) {
+	passwordResetRouter, _ := createPasswordResetRouter(t)
+
+	resetTests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		body     string
+		expected string
+	}{
+		{"empty name", passwordResetRouter.RequestResetHandler, `{"name": ""}`, `{"error":"Password reset data not valid"}`},
+		{"empty token", passwordResetRouter.CompleteResetHandler, `{"newPassword": "new password"}`, `{"error":"Password reset data not valid"}`},
+		{"invalid token", passwordResetRouter.CompleteResetHandler, `{"token": "abc", "newPassword": "new password"}`, `{"error":"Password reset token not valid"}`},
+	}
+	for _, resetTest := range resetTests {
+		rr := servePasswordReset(resetTest.handler, resetTest.body)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", resetTest.name, status, http.StatusBadRequest)
+		}
+		if body := strings.TrimSpace(rr.Body.String()); body != resetTest.expected {
+			t.Errorf("%s: handler returned unexpected body: got %v want %v", resetTest.name, body, resetTest.expected)
+		}
+	}
+}
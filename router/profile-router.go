@@ -0,0 +1,82 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ProfileRouter lets the users read and edit their own profile.
+type ProfileRouter struct {
+	Services *validator.Services
+}
+
+func (p *ProfileRouter) GetHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: p.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	current, err := p.Services.UserService.GetRepository().GetById(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteUser(w, current)
+}
+
+func (p *ProfileRouter) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: p.Services}}
+	profileV := validator.ProfileValidator{Validator: validator.Validator{Writer: w, Request: r, Services: p.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	update, err := profileV.GetProfileUpdate()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Profile data not valid")
+		return
+	}
+
+	updated, err := p.Services.UserService.UpdateProfile(payload.UserId, *update)
+	if err != nil {
+		log.Print(err)
+		writeProfileError(w, err)
+		return
+	}
+
+	response.WriteUser(w, updated)
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	var metadataErr *user.MetadataError
+	if errors.As(err, &metadataErr) {
+		response.WriteError(w, "Metadata not valid, "+metadataErr.Reason)
+	} else if errors.Is(err, user.ErrDisplayNameNotValid) {
+		response.WriteError(w, "Display name not valid")
+	} else if errors.Is(err, user.ErrAvatarURLNotValid) {
+		response.WriteError(w, "Avatar URL not valid")
+	} else if errors.Is(err, user.ErrLocaleNotValid) {
+		response.WriteError(w, "Locale not valid")
+	} else if errors.Is(err, user.ErrTimezoneNotValid) {
+		response.WriteError(w, "Timezone not valid")
+	} else if errors.Is(err, user.ErrUserNotFound) {
+		response.WriteError(w, "User id not valid")
+	} else {
+		response.WriteError(w, "Error updating profile")
+	}
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"authGo/jsonschema"
+	"authGo/user"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func serveProfile(handler http.HandlerFunc, method string, accessToken string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/users/me/profile", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestProfileRouter(t *testing.T) {
+	services := createUserRouter().Services
+	schema, err := jsonschema.Compile([]byte(`{"type": "object", "properties": {"department": {"type": "string"}}, "additionalProperties": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	services.UserService.SetMetadataSchema(schema)
+	profileRouter := &ProfileRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	accessToken := createAccessToken(t, services, normalUser)
+
+	rr := serveProfile(profileRouter.UpdateHandler, "PATCH", accessToken, `{"displayName": "User Two", "locale": "es", "metadata": {"department": "sales"}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	rr = serveProfile(profileRouter.GetHandler, "GET", accessToken, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var profile user.User
+	if err = json.Unmarshal(rr.Body.Bytes(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Id != normalUser.Id || profile.DisplayName != "User Two" || profile.Locale != "es" || profile.Metadata["department"] != "sales" {
+		t.Errorf("unexpected profile, got %+v", profile)
+	}
+
+	profileTests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"no fields", `{}`, `{"error":"Profile data not valid"}`},
+		{"unknown field", `{"roles": ["admin"]}`, `{"error":"Profile data not valid"}`},
+		{"invalid timezone", `{"timezone": "Nowhere"}`, `{"error":"Timezone not valid"}`},
+		{"invalid metadata", `{"metadata": {"department": 1}}`, `{"error":"Metadata not valid, /department must be of type string"}`},
+	}
+	for _, profileTest := range profileTests {
+		rr := serveProfile(profileRouter.UpdateHandler, "PATCH", accessToken, profileTest.body)
+		if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != profileTest.expected {
+			t.Errorf("%s: expected %s, got %v %s", profileTest.name, profileTest.expected, rr.Code, body)
+		}
+	}
+	if rr := serveProfile(profileRouter.GetHandler, "GET", "123.123.123", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected an invalid access token to fail, got %v", rr.Code)
+	}
+}
+
+func TestUserRouterUpdateProfileHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(accessToken string, id string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/users/%s/profile", id), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{id}/profile", userRouter.UpdateProfileHandler)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := update(createAccessToken(t, services, normalUser), adminUser.Id, `{"displayName": "Admin"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("expected users without users:write to be forbidden, got %v", rr.Code)
+	}
+	adminToken := createAccessToken(t, services, adminUser)
+	if rr := update(adminToken, "12345", `{"displayName": "Nobody"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown user to fail, got %v", rr.Code)
+	}
+	rr := update(adminToken, normalUser.Id, `{"displayName": "User Two", "avatarUrl": "https://example.com/2.png"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	updated, err := services.UserService.GetRepository().GetById(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.DisplayName != "User Two" || updated.AvatarURL != "https://example.com/2.png" {
+		t.Errorf("expected the profile to be updated, got %+v", updated)
+	}
+}
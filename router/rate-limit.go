@@ -0,0 +1,156 @@
+package router
+
+import (
+	"authGo/ratelimit"
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+)
+
+// MaxRateLimitBodySize is the largest body read by ReadRegistration and ReadMFALogin, in bytes.
+const MaxRateLimitBodySize = 64 << 10
+
+// bodyContextKey keys the body read by readBody in the request context.
+type bodyContextKey[T any] struct{}
+
+// RateLimit throttles the requests sharing the same key, the requests without a key are not limited.
+type RateLimit struct {
+	Limiter *ratelimit.Limiter
+	Key     func(r *http.Request) string
+}
+
+func (l *RateLimit) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.Key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if allowed, retryAfter := l.Limiter.Allow(key); !allowed {
+			response.WriteTooManyRequests(w, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimited wraps the handler with every rate limit, the nil ones are skipped.
+func RateLimited(handler http.HandlerFunc, limits ...*RateLimit) http.Handler {
+	var limited http.Handler = handler
+	for i := len(limits) - 1; i >= 0; i-- {
+		if limits[i] != nil {
+			limited = limits[i].Middleware(limited)
+		}
+	}
+	return limited
+}
+
+// ClientIPKey returns the IP address of the client. The forwarded headers are ignored as they can
+// be set by anyone, a proxy in front of the service must rate limit by itself.
+func ClientIPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// LoginNameKey returns the organization and the canonical user name of the basic auth login, so the
+// variants of a name share the limit.
+func LoginNameKey(r *http.Request) string {
+	name, _, ok := r.BasicAuth()
+	if !ok || name == "" {
+		return ""
+	}
+	v := validator.Validator{Request: r}
+	return v.GetOrganizationId() + "/" + user.CanonicalName(name)
+}
+
+// RegistrationNameKey returns the organization and the canonical user name of the registration, so the
+// attempts to register a name share the limit whichever IP address they come from. The registration
+// comes from ReadRegistration.
+func RegistrationNameKey(r *http.Request) string {
+	registration := getBody[validator.RegistrationInput](r)
+	if registration == nil || registration.Name == "" {
+		return ""
+	}
+	v := validator.Validator{Request: r}
+	return v.GetOrganizationId() + "/" + user.CanonicalName(registration.Name)
+}
+
+// RegistrationEmailKey returns the email address of the registration, which gets the verification
+// emails. The registration comes from ReadRegistration.
+func RegistrationEmailKey(r *http.Request) string {
+	registration := getBody[validator.RegistrationInput](r)
+	if registration == nil {
+		return ""
+	}
+	email, err := user.NormalizeEmail(registration.Email)
+	if err != nil {
+		return ""
+	}
+	return email
+}
+
+// MFAUserKey returns the user of the challenge token of the second login step, so the attempts on
+// every challenge of a user share the limit. The challenge comes from ReadMFALogin, the tokens that are
+// not valid are left to the handler to refuse.
+func MFAUserKey(services *validator.Services) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		mfaLogin := getBody[validator.MFALoginInput](r)
+		if mfaLogin == nil || mfaLogin.ChallengeToken == "" {
+			return ""
+		}
+		v := validator.MFAValidator{Validator: validator.Validator{Request: r, Services: services}}
+		challenge, err := v.LoadChallenge(mfaLogin.ChallengeToken)
+		if err != nil {
+			return ""
+		}
+		return challenge.UserId
+	}
+}
+
+// ReadRegistration reads the registration body once for the registration rate limits and puts it back
+// for the handler. The bodies larger than MaxRateLimitBodySize are refused, the ones that are not valid
+// are left to the handler to refuse.
+func ReadRegistration(next http.Handler) http.Handler {
+	return readBody[validator.RegistrationInput](next, "Registration data not valid")
+}
+
+// ReadMFALogin reads the body of the second login step once for MFAUserKey, like ReadRegistration.
+func ReadMFALogin(next http.Handler) http.Handler {
+	return readBody[validator.MFALoginInput](next, "Second factor data not valid")
+}
+
+func readBody[T any](next http.Handler, bodyError string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxRateLimitBodySize))
+		if err != nil {
+			log.Print(err)
+			response.WriteError(w, bodyError)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		var input T
+		if err = json.Unmarshal(body, &input); err == nil {
+			r = r.WithContext(context.WithValue(r.Context(), bodyContextKey[T]{}, &input))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func getBody[T any](r *http.Request) *T {
+	input, _ := r.Context().Value(bodyContextKey[T]{}).(*T)
+	return input
+}
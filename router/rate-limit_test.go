@@ -0,0 +1,129 @@
+package router
+
+import (
+	"authGo/ratelimit"
+	"authGo/token"
+	"authGo/validator"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimited(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	ipLimit := &RateLimit{Limiter: ratelimit.NewLimiter(limit), Key: ClientIPKey}
+	nameLimit := &RateLimit{Limiter: ratelimit.NewLimiter(ratelimit.Limit{Requests: 1, Period: time.Minute}), Key: LoginNameKey}
+	handler := RateLimited(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, ipLimit, nil, nameLimit)
+
+	requestTests := []struct {
+		remoteAddr     string
+		name           string
+		organizationId string
+		status         int
+	}{
+		{"10.0.0.1:1234", "admin", "", http.StatusOK},
+		{"10.0.0.1:1235", "admin", "", http.StatusTooManyRequests},
+		{"10.0.0.1:1236", "admin", "acme", http.StatusTooManyRequests},
+		{"10.0.0.2:1234", "admin", "acme", http.StatusOK},
+		{"10.0.0.3:1234", "admin", "other", http.StatusOK},
+		{"10.0.0.3:1234", "", "", http.StatusOK},
+		{"10.0.0.4:1234", "ADMIN", "", http.StatusTooManyRequests},
+	}
+	for i, requestTest := range requestTests {
+		req, _ := http.NewRequest("POST", "/auth/login", nil)
+		req.RemoteAddr = requestTest.remoteAddr
+		if requestTest.name != "" {
+			req.SetBasicAuth(requestTest.name, "password")
+		}
+		if requestTest.organizationId != "" {
+			req.Header.Set(validator.OrganizationHeader, requestTest.organizationId)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != requestTest.status {
+			t.Errorf("request %d: handler returned wrong status code: got %v want %v", i, rr.Code, requestTest.status)
+		}
+		if rr.Code == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: expected the Retry-After header", i)
+		}
+	}
+}
+
+func TestRegistrationRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	nameLimit := &RateLimit{Limiter: ratelimit.NewLimiter(limit), Key: RegistrationNameKey}
+	emailLimit := &RateLimit{Limiter: ratelimit.NewLimiter(limit), Key: RegistrationEmailKey}
+	handler := ReadRegistration(RateLimited(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), "password") {
+			t.Errorf("expected the body to be kept for the handler, got %s", body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}, nameLimit, emailLimit))
+
+	requestTests := []struct {
+		body           string
+		organizationId string
+		status         int
+	}{
+		{`{"name": "john", "email": "john@example.com", "password": "password"}`, "", http.StatusOK},
+		{`{"name": "JOHN", "password": "password"}`, "", http.StatusTooManyRequests},
+		{`{"name": "john", "password": "password"}`, "acme", http.StatusOK},
+		{`{"name": "jane", "email": "John@Example.com", "password": "password"}`, "", http.StatusTooManyRequests},
+		{`{"name": "jim", "email": "jim@example.com", "password": "password"}`, "", http.StatusOK},
+		{`not json, password`, "", http.StatusOK},
+		{`{"name": "joe", "password": "password", "padding": "` + strings.Repeat("a", MaxRateLimitBodySize) + `"}`, "", http.StatusBadRequest},
+	}
+	for i, requestTest := range requestTests {
+		req, _ := http.NewRequest("POST", "/auth/register", strings.NewReader(requestTest.body))
+		req.Header.Set("Content-Type", "application/json")
+		if requestTest.organizationId != "" {
+			req.Header.Set(validator.OrganizationHeader, requestTest.organizationId)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != requestTest.status {
+			t.Errorf("request %d: handler returned wrong status code: got %v want %v", i, rr.Code, requestTest.status)
+		}
+	}
+}
+
+func TestMFARateLimit(t *testing.T) {
+	services := createLoginRouter().Services
+	userLimit := &RateLimit{Limiter: ratelimit.NewLimiter(ratelimit.Limit{Requests: 1, Period: time.Minute}), Key: MFAUserKey(services)}
+	handler := ReadMFALogin(RateLimited(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, userLimit))
+	createChallenge := func(userId string) string {
+		challengeToken, err := services.MFAChallengeTokenGenerator.CreateToken(&token.MFAChallengePayload{UserId: userId, IssuedAtTime: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return challengeToken
+	}
+
+	requestTests := []struct {
+		challengeToken string
+		status         int
+	}{
+		{createChallenge("1"), http.StatusOK},
+		{createChallenge("1"), http.StatusTooManyRequests},
+		{createChallenge("2"), http.StatusOK},
+		{"not valid", http.StatusOK},
+	}
+	for i, requestTest := range requestTests {
+		body := `{"challengeToken": "` + requestTest.challengeToken + `", "code": "123456"}`
+		req, _ := http.NewRequest("POST", "/auth/login/mfa", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != requestTest.status {
+			t.Errorf("request %d: handler returned wrong status code: got %v want %v", i, rr.Code, requestTest.status)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// RecoveryCodeRouter lets the users with a second factor see how many recovery codes they have left
+// and replace them.
+type RecoveryCodeRouter struct {
+	Services *validator.Services
+}
+
+func (c *RecoveryCodeRouter) CountHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: c.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	remaining, err := c.Services.UserService.CountRecoveryCodes(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteRecoveryCodeCount(w, remaining)
+}
+
+// RegenerateHandler replaces the recovery codes of the caller, the codes given before stop working.
+func (c *RecoveryCodeRouter) RegenerateHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: c.Services}}
+	recoveryCodeV := validator.RecoveryCodeValidator{Validator: validator.Validator{Writer: w, Request: r, Services: c.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	input, err := recoveryCodeV.GetRegeneration()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Recovery code data not valid")
+		return
+	}
+
+	codes, err := c.Services.UserService.RegenerateRecoveryCodes(payload.UserId, input.Password)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrUserPasswordNotValid) {
+			response.WriteError(w, "Password not valid")
+		} else if errors.Is(err, user.ErrUserLocked) {
+			response.WriteError(w, "Account temporarily locked")
+		} else if errors.Is(err, user.ErrSecondFactorNotEnabled) {
+			response.WriteError(w, "No second factor enabled")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	response.WriteRecoveryCodes(w, codes)
+}
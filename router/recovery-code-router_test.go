@@ -0,0 +1,87 @@
+package router
+
+import (
+	"authGo/totp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveRecoveryCodeLogin(loginRouter *LoginRouter, challengeToken string, recoveryCode string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"challengeToken":%q,"recoveryCode":%q}`, challengeToken, recoveryCode)
+	req, _ := http.NewRequest("POST", "/auth/login/mfa", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(loginRouter.MFAHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRecoveryCodeRouter(t *testing.T) {
+	services := createUserRouter().Services
+	totpRouter := &TOTPRouter{Services: services, Issuer: "authGo"}
+	recoveryCodeRouter := &RecoveryCodeRouter{Services: services}
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	accessToken := createAccessToken(t, services, normalUser)
+
+	if rr := serveTOTP(recoveryCodeRouter.RegenerateHandler, "POST", accessToken, `{"password":"user2"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected no recovery codes without a second factor, got %v", rr.Code)
+	}
+
+	enrolled, err := services.UserService.EnrollTOTP(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.Code(enrolled.Secret, totp.Counter(time.Now()))
+	rr := serveTOTP(totpRouter.ConfirmHandler, "POST", accessToken, fmt.Sprintf(`{"code":%q}`, code))
+	var recoveryCodes struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	if err = json.NewDecoder(rr.Body).Decode(&recoveryCodes); err != nil || len(recoveryCodes.RecoveryCodes) != 10 {
+		t.Fatalf("expected the recovery codes with the confirmation, got %v %+v %v", rr.Code, recoveryCodes, err)
+	}
+
+	challengeToken := func() string {
+		var challenge struct {
+			ChallengeToken string `json:"challengeToken"`
+		}
+		json.NewDecoder(serveLogin(services, "user2", "user2").Body).Decode(&challenge)
+		return challenge.ChallengeToken
+	}
+	firstCode := recoveryCodes.RecoveryCodes[0]
+	if rr = serveRecoveryCodeLogin(loginRouter, challengeToken(), "aaaa-bbbb-cccc-dddd"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a wrong recovery code to be rejected, got %v", rr.Code)
+	}
+	if rr = serveRecoveryCodeLogin(loginRouter, challengeToken(), strings.ToUpper(firstCode)); rr.Code != http.StatusOK {
+		t.Errorf("expected the recovery code to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+	if rr = serveRecoveryCodeLogin(loginRouter, challengeToken(), firstCode); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a used recovery code to be rejected, got %v", rr.Code)
+	}
+
+	rr = serveTOTP(recoveryCodeRouter.CountHandler, "GET", accessToken, "")
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"remaining":9}` {
+		t.Errorf("expected the remaining recovery codes, got %s", body)
+	}
+
+	if rr = serveTOTP(recoveryCodeRouter.RegenerateHandler, "POST", accessToken, `{"password":"wrong"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected the password to be required, got %v", rr.Code)
+	}
+	rr = serveTOTP(recoveryCodeRouter.RegenerateHandler, "POST", accessToken, `{"password":"user2"}`)
+	var newCodes struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	if err = json.NewDecoder(rr.Body).Decode(&newCodes); err != nil || len(newCodes.RecoveryCodes) != 10 {
+		t.Fatalf("expected new recovery codes, got %v %+v %v", rr.Code, newCodes, err)
+	}
+	if rr = serveRecoveryCodeLogin(loginRouter, challengeToken(), recoveryCodes.RecoveryCodes[1]); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected the old recovery codes to be invalidated, got %v", rr.Code)
+	}
+	if rr = serveRecoveryCodeLogin(loginRouter, challengeToken(), newCodes.RecoveryCodes[1]); rr.Code != http.StatusOK {
+		t.Errorf("expected the new recovery codes to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+}
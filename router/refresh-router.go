@@ -3,6 +3,7 @@ package router
 import (
 	response "authGo/router/response"
 	"authGo/validator"
+	"errors"
 	"log"
 	"net/http"
 )
@@ -27,6 +28,19 @@ func (ref *RefreshRouter) Handler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, "User not valid")
 		return
 	}
+	if err = v.Validator.CheckUserActive(user); err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrLoginRouterUserDisabled) {
+			response.WriteError(w, "Account disabled")
+		} else if errors.Is(err, validator.ErrLoginRouterUserSuspended) {
+			response.WriteError(w, "Account suspended")
+		} else if errors.Is(err, validator.ErrLoginRouterUserPending) {
+			response.WriteError(w, "Account pending approval")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
 
 	token, err := v.CreateAccessToken(user)
 	if err != nil {
@@ -34,7 +48,9 @@ func (ref *RefreshRouter) Handler(w http.ResponseWriter, r *http.Request) {
 		response.WriteGeneralError(w)
 		return
 	}
-	v.Validator.Services.SessionsHandler.RefreshLastUpdate(session)
+	if err = v.Validator.Services.SessionsHandler.RefreshLastUpdate(session); err != nil {
+		log.Print(err)
+	}
 
 	response.WriteSuccessfulRefresh(w, token)
 }
@@ -39,12 +39,20 @@ func TestRefreshRouterHandler(t *testing.T) {
 	}
 
 	refreshRouter := createRefreshRouter()
-	payload := &token.RefreshTokenPayload{UserId: refreshRouter.Services.UserService.GetRepository().GetAll()[0].Id, IssuedAtTime: time.Now()}
+	adminUser, err := refreshRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := &token.RefreshTokenPayload{UserId: adminUser.Id, OrganizationId: adminUser.OrganizationId, IssuedAtTime: time.Now()}
 	err = refreshRouter.Services.SessionsHandler.AddNewSession(*payload, session.DeviceData{IpAddress: "10.0.0.1", UserAgent: "vscode"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	session := refreshRouter.Services.SessionsHandler.GetAllSessions()[0]
+	sessions, err := refreshRouter.Services.SessionsHandler.GetAllSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := sessions[0]
 	lastSessionUpdate := session.LastUpdate
 	time.Sleep(1 * time.Millisecond)
 	refreshToken, err := refreshRouter.Services.RefreshTokenGenerator.CreateToken(&session.UserToken)
@@ -129,7 +137,11 @@ func TestRefreshRouterUserNotValid(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	session := refreshRouter.Services.SessionsHandler.GetAllSessions()[0]
+	sessions, err := refreshRouter.Services.SessionsHandler.GetAllSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := sessions[0]
 	refreshToken, err := refreshRouter.Services.RefreshTokenGenerator.CreateToken(&session.UserToken)
 	if err != nil {
 		t.Fatal(err)
@@ -153,3 +165,38 @@ func TestRefreshRouterUserNotValid(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", want, expected)
 	}
 }
+
+func TestRefreshRouterHandlerInactiveUser(t *testing.T) {
+	statusTests := []struct {
+		update   user.UserStatusUpdate
+		expected string
+	}{
+		{user.UserStatusUpdate{Status: user.UserStatusDisabled}, `{"error":"Account disabled"}`},
+		{user.UserStatusUpdate{Status: user.UserStatusSuspended, SuspendedUntil: time.Now().Add(time.Hour)}, `{"error":"Account suspended"}`},
+	}
+	for _, statusTest := range statusTests {
+		refreshRouter := createRefreshRouter()
+		services := refreshRouter.Services
+		normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+		sessions, err := services.SessionsHandler.GetUserSessions(normalUser.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refreshToken, err := services.RefreshTokenGenerator.CreateToken(&sessions[0].UserToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = services.UserService.SetUserStatus(normalUser.Id, statusTest.update); err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ := http.NewRequest("POST", "/auth/refresh", nil)
+		req.Header.Set("Cookie", fmt.Sprintf("refreshToken=%s", refreshToken))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(refreshRouter.Handler).ServeHTTP(rr, req)
+
+		if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != statusTest.expected {
+			t.Errorf("%s: expected the refresh to fail with %s, got %v %s", statusTest.update.Status, statusTest.expected, rr.Code, body)
+		}
+	}
+}
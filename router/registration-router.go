@@ -0,0 +1,85 @@
+package router
+
+import (
+	"authGo/password"
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// RegistrationRouter lets the users create their own account in the Organizations accepting it, the
+// accounts wait for an administrator's approval when RequireApproval is true.
+type RegistrationRouter struct {
+	Services        *validator.Services
+	Enabled         bool
+	RequireApproval bool
+	Organizations   []string
+	// EmailVerification sends the verification link when the user gives an email address, no link is
+	// sent when it's nil.
+	EmailVerification *EmailVerificationRouter
+}
+
+// RegisterHandler creates a user without roles in the organization of the X-Organization-Id header and
+// returns it, its status is pending when the registration requires an approval.
+func (reg *RegistrationRouter) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.RegistrationValidator{Validator: validator.Validator{Writer: w, Request: r, Services: reg.Services}}
+
+	if !reg.Enabled {
+		response.WriteForbidden(w)
+		return
+	}
+
+	registration, err := v.GetRegistration()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Registration data not valid")
+		return
+	}
+
+	organizationId := v.Validator.GetOrganizationId()
+	if !containsString(reg.Organizations, organizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	newUser := user.NewUser{
+		OrganizationId: organizationId,
+		Name:           registration.Name,
+		Password:       registration.Password,
+		Email:          registration.Email,
+	}
+	if reg.RequireApproval {
+		newUser.Status = user.UserStatusPending
+	}
+	createdUser, err := reg.Services.UserService.AddUser(newUser)
+	if err != nil {
+		log.Print(err)
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			response.WritePasswordPolicyError(w, policyErr)
+		} else if errors.Is(err, user.ErrUserAlreadyRegistered) {
+			response.WriteError(w, "User name already registered")
+		} else if errors.Is(err, user.ErrUserNameNotValid) {
+			response.WriteError(w, "User name not valid")
+		} else if errors.Is(err, user.ErrEmailNotValid) {
+			response.WriteError(w, "Email not valid")
+		} else if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+			response.WriteError(w, "Email already registered")
+		} else if errors.Is(err, user.ErrOrganizationNotFound) {
+			response.WriteError(w, "Organization not found")
+		} else {
+			response.WriteError(w, "Error registering user")
+		}
+		return
+	}
+
+	if reg.EmailVerification != nil && createdUser.Email != "" {
+		if err = reg.EmailVerification.SendVerification(createdUser); err != nil {
+			log.Print(err)
+		}
+	}
+	response.WriteUser(w, createdUser)
+}
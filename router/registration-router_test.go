@@ -0,0 +1,120 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func serveRegistration(registrationRouter *RegistrationRouter, organizationId string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/auth/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if organizationId != "" {
+		req.Header.Set("X-Organization-Id", organizationId)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(registrationRouter.RegisterHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func serveApproveUser(userRouter *UserRouter, accessToken string, id string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/users/%s/approve", id), nil)
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/approve", userRouter.ApproveUserHandler)
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRegistrationRouter(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	registrationRouter := &RegistrationRouter{Services: services, Enabled: true, Organizations: []string{user.DefaultOrganizationId}}
+
+	rr := serveRegistration(registrationRouter, "", `{"name": "user2", "password": "user2"}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	var registered user.User
+	if err := json.Unmarshal(rr.Body.Bytes(), &registered); err != nil {
+		t.Fatal(err)
+	}
+	if registered.Status != user.UserStatusEnabled || len(registered.Roles) != 0 || registered.OrganizationId != user.DefaultOrganizationId {
+		t.Errorf("expected an enabled user without roles, got %s", rr.Body.String())
+	}
+	if rr = serveLogin(services, "user2", "user2"); rr.Code != http.StatusOK {
+		t.Errorf("expected the registered user to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serveRegistration(registrationRouter, "", `{"name": "user2", "password": "other"}`)
+	expected := `{"error":"User name already registered"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the name to be refused, got %v %s", rr.Code, body)
+	}
+
+	rr = serveRegistration(registrationRouter, "other", `{"name": "user3", "password": "user3"}`)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected the registration to other organizations to be forbidden, got %v", rr.Code)
+	}
+
+	registrationRouter.Enabled = false
+	if rr = serveRegistration(registrationRouter, "", `{"name": "user3", "password": "user3"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("expected the disabled registration to be forbidden, got %v", rr.Code)
+	}
+}
+
+func TestRegistrationRouterApproval(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	registrationRouter := &RegistrationRouter{Services: services, Enabled: true, RequireApproval: true, Organizations: []string{user.DefaultOrganizationId}}
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, adminUser)
+	adminToken := createAccessToken(t, services, adminUser)
+
+	rr := serveRegistration(registrationRouter, "", `{"name": "user2", "password": "user2"}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	var registered user.User
+	if err = json.Unmarshal(rr.Body.Bytes(), &registered); err != nil {
+		t.Fatal(err)
+	}
+	if registered.Status != user.UserStatusPending {
+		t.Errorf("expected the user to be pending, got %s", rr.Body.String())
+	}
+
+	rr = serveLogin(services, "user2", "user2")
+	expected := `{"error":"Account pending approval"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the pending user not to log in, got %v %s", rr.Code, body)
+	}
+
+	normalUser := addUserAndSession(t, *services, "user3", "user3", false)
+	if rr = serveApproveUser(userRouter, createAccessToken(t, services, normalUser), registered.Id); rr.Code != http.StatusForbidden {
+		t.Errorf("expected users without users:write to be forbidden, got %v", rr.Code)
+	}
+
+	rr = serveApproveUser(userRouter, adminToken, registered.Id)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	if rr = serveLogin(services, "user2", "user2"); rr.Code != http.StatusOK {
+		t.Errorf("expected the approved user to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serveApproveUser(userRouter, adminToken, registered.Id)
+	expected = `{"error":"User not pending approval"}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+		t.Errorf("expected the approval to be refused, got %v %s", rr.Code, body)
+	}
+}
@@ -11,6 +11,19 @@ type AuthResponse struct {
 	UserData token.AccessTokenPayload `json:"userData"`
 }
 
+type MFAChallengeResponse struct {
+	MFARequired    bool     `json:"mfaRequired"`
+	ChallengeToken string   `json:"challengeToken"`
+	SecondFactors  []string `json:"secondFactors"`
+}
+
+// WriteMFAChallenge answers a valid password without session cookies, the login finishes when the
+// challenge token is sent back with one of the second factors.
+func WriteMFAChallenge(w http.ResponseWriter, challengeToken string, secondFactors []string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MFAChallengeResponse{MFARequired: true, ChallengeToken: challengeToken, SecondFactors: secondFactors})
+}
+
 func WriteSuccessfulLogin(w http.ResponseWriter, tokens *validator.JwtTokens) {
 	accessCookie := &http.Cookie{Name: "accessToken", Value: tokens.AccessToken, HttpOnly: true, Path: "/"}
 	refreshCookie := &http.Cookie{Name: "refreshToken", Value: tokens.RefreshToken, HttpOnly: true, Path: "/"}
@@ -1,12 +1,23 @@
 package router
 
 import (
+	"authGo/password"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func WriteError(w http.ResponseWriter, err string) {
@@ -15,6 +26,20 @@ func WriteError(w http.ResponseWriter, err string) {
 	json.NewEncoder(w).Encode(ErrorResponse{Error: err})
 }
 
+func WriteFieldErrors(w http.ResponseWriter, err string, fields []FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: err, Fields: fields})
+}
+
+func WritePasswordPolicyError(w http.ResponseWriter, err *password.PolicyError) {
+	fields := make([]FieldError, len(err.Violations))
+	for i, violation := range err.Violations {
+		fields[i] = FieldError{Field: violation.Field, Code: violation.Code, Message: violation.Message}
+	}
+	WriteFieldErrors(w, "Password not valid", fields)
+}
+
 func WriteGeneralError(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusBadRequest)
@@ -28,3 +53,15 @@ func WriteTokenError(w http.ResponseWriter) {
 func WriteForbidden(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusForbidden)
 }
+
+// WriteTooManyRequests tells the client how many seconds to wait before trying again.
+func WriteTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: "Too many requests"})
+}
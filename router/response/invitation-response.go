@@ -0,0 +1,27 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"net/http"
+)
+
+type InvitationResponse struct {
+	*user.Invitation
+	Link string `json:"link"`
+}
+
+type InvitationListResponse struct {
+	Invitations []*user.Invitation `json:"invitations"`
+}
+
+// WriteInvitation writes the new invitation along with its link, which can't be retrieved later.
+func WriteInvitation(w http.ResponseWriter, invitation *user.Invitation, link string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InvitationResponse{Invitation: invitation, Link: link})
+}
+
+func WriteInvitationList(w http.ResponseWriter, invitations []*user.Invitation) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InvitationListResponse{Invitations: invitations})
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"net/http"
+)
+
+type LoginHistoryResponse struct {
+	Logins []*user.LoginAttempt `json:"logins"`
+}
+
+func WriteLoginHistory(w http.ResponseWriter, attempts []*user.LoginAttempt) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginHistoryResponse{Logins: attempts})
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"net/http"
+)
+
+type OrganizationResponse struct {
+	Organizations []*user.Organization `json:"organizations"`
+}
+
+func WriteOrganizationList(w http.ResponseWriter, organizations []*user.Organization) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OrganizationResponse{Organizations: organizations})
+}
+
+func WriteOrganization(w http.ResponseWriter, organization *user.Organization) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(organization)
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type RecoveryCodeCountResponse struct {
+	Remaining int `json:"remaining"`
+}
+
+// WriteRecoveryCodes shows the new recovery codes, they are only stored hashed so this is the only
+// time they can be read.
+func WriteRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func WriteRecoveryCodeCount(w http.ResponseWriter, remaining int) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodeCountResponse{Remaining: remaining})
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"net/http"
+)
+
+type RoleResponse struct {
+	Roles []*user.Role `json:"roles"`
+}
+
+func WriteRoleList(w http.ResponseWriter, roles []*user.Role) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RoleResponse{Roles: roles})
+}
+
+func WriteRole(w http.ResponseWriter, role *user.Role) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
@@ -7,10 +7,11 @@ import (
 )
 
 type SessionResponse struct {
-	Sessions []*session.Session `json:"sessions"`
+	Sessions   []*session.Session `json:"sessions"`
+	NextCursor string             `json:"nextCursor,omitempty"`
 }
 
-func WriteSessionList(w http.ResponseWriter, sessions []*session.Session) {
+func WriteSessionList(w http.ResponseWriter, sessions []*session.Session, nextCursor string) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(SessionResponse{Sessions: sessions})
+	json.NewEncoder(w).Encode(SessionResponse{Sessions: sessions, NextCursor: nextCursor})
 }
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func WriteTOTPEnrollment(w http.ResponseWriter, secret string, uri string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TOTPEnrollmentResponse{Secret: secret, URI: uri})
+}
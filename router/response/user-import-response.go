@@ -0,0 +1,37 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"net/http"
+)
+
+type UserImportResponse struct {
+	DryRun  bool                 `json:"dryRun"`
+	Total   int                  `json:"total"`
+	Created int                  `json:"created"`
+	Errors  []UserImportRowError `json:"errors"`
+}
+
+type UserImportRowError struct {
+	Row   int    `json:"row"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// WriteUserExport writes the users as a file to download in the csv or json format.
+func WriteUserExport(w http.ResponseWriter, format string, records []*user.UserRecord) error {
+	if format == user.FormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/csv")
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="users.`+format+`"`)
+	return user.WriteUserRecords(w, format, records)
+}
+
+// WriteUserImport writes the result of an import, rowErrors has the message of every refused row.
+func WriteUserImport(w http.ResponseWriter, result *user.ImportResult, rowErrors []UserImportRowError) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserImportResponse{DryRun: result.DryRun, Total: result.Total, Created: result.Created, Errors: rowErrors})
+}
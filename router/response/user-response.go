@@ -7,10 +7,17 @@ import (
 )
 
 type UserResponse struct {
-	Users []*user.User `json:"users"`
+	Users      []*user.User `json:"users"`
+	Total      int          `json:"total"`
+	NextCursor string       `json:"nextCursor,omitempty"`
 }
 
-func WriteUserList(w http.ResponseWriter, users []*user.User) {
+func WriteUserList(w http.ResponseWriter, users []*user.User, total int, nextCursor string) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(UserResponse{Users: users})
+	json.NewEncoder(w).Encode(UserResponse{Users: users, Total: total, NextCursor: nextCursor})
+}
+
+func WriteUser(w http.ResponseWriter, user *user.User) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
 }
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"authGo/user"
+	"authGo/webauthn"
+	"encoding/json"
+	"net/http"
+)
+
+type WebAuthnCreationResponse struct {
+	ChallengeToken string                    `json:"challengeToken"`
+	PublicKey      *webauthn.CreationOptions `json:"publicKey"`
+}
+
+type WebAuthnRequestResponse struct {
+	ChallengeToken string                   `json:"challengeToken"`
+	PublicKey      *webauthn.RequestOptions `json:"publicKey"`
+}
+
+type WebAuthnRegistrationResponse struct {
+	*user.WebAuthnCredential
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type WebAuthnCredentialResponse struct {
+	Credentials []*user.WebAuthnCredential `json:"credentials"`
+}
+
+func WriteWebAuthnCreationOptions(w http.ResponseWriter, challengeToken string, options *webauthn.CreationOptions) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebAuthnCreationResponse{ChallengeToken: challengeToken, PublicKey: options})
+}
+
+func WriteWebAuthnRequestOptions(w http.ResponseWriter, challengeToken string, options *webauthn.RequestOptions) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebAuthnRequestResponse{ChallengeToken: challengeToken, PublicKey: options})
+}
+
+func WriteWebAuthnCredentialList(w http.ResponseWriter, credentials []*user.WebAuthnCredential) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebAuthnCredentialResponse{Credentials: credentials})
+}
+
+// WriteWebAuthnRegistration returns the new credential with the recovery codes when it's the first
+// second factor of the user.
+func WriteWebAuthnRegistration(w http.ResponseWriter, credential *user.WebAuthnCredential, recoveryCodes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebAuthnRegistrationResponse{WebAuthnCredential: credential, RecoveryCodes: recoveryCodes})
+}
@@ -0,0 +1,149 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// RoleRouter manages the roles. The custom roles are shared by every organization, so only the super
+// administrators can change them.
+type RoleRouter struct {
+	Services *validator.Services
+}
+
+func (ro *RoleRouter) GetRolesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: ro.Services}}
+
+	payload, err := v.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionRolesRead) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	roles, err := ro.Services.UserService.GetRoles()
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+	response.WriteRoleList(w, roles)
+}
+
+func (ro *RoleRouter) NewRoleHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: ro.Services}}
+	roleV := validator.RoleValidator{Validator: validator.Validator{Writer: w, Request: r, Services: ro.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionRolesWrite) || !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	newRole, err := roleV.GetNewRole()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Role data not valid")
+		return
+	}
+
+	role, err := ro.Services.UserService.CreateRole(&user.Role{Name: newRole.Name, Description: newRole.Description, Permissions: newRole.Permissions})
+	if err != nil {
+		log.Print(err)
+		writeRoleError(w, err)
+		return
+	}
+	response.WriteRole(w, role)
+}
+
+// UpdateRoleHandler replaces the description and permissions of a role, the users with the role get
+// the new permissions on their next refresh.
+func (ro *RoleRouter) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: ro.Services}}
+	roleV := validator.RoleValidator{Validator: validator.Validator{Writer: w, Request: r, Services: ro.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionRolesWrite) || !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	roleUpdate, err := roleV.GetRoleUpdate()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Role data not valid")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	role, err := ro.Services.UserService.UpdateRole(&user.Role{Name: name, Description: roleUpdate.Description, Permissions: roleUpdate.Permissions})
+	if err != nil {
+		log.Print(err)
+		writeRoleError(w, err)
+		return
+	}
+	response.WriteRole(w, role)
+}
+
+func (ro *RoleRouter) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: ro.Services}}
+
+	payload, err := v.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionRolesWrite) || !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if err = ro.Services.UserService.DeleteRole(name); err != nil {
+		log.Print(err)
+		writeRoleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeRoleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, user.ErrRoleNotFound) {
+		response.WriteError(w, "Role not found")
+	} else if errors.Is(err, user.ErrRoleAlreadyExists) {
+		response.WriteError(w, "Role already exists")
+	} else if errors.Is(err, user.ErrRoleNotValid) {
+		response.WriteError(w, "Role data not valid")
+	} else if errors.Is(err, user.ErrRoleBuiltIn) {
+		response.WriteError(w, "Built-in roles cannot be changed")
+	} else if errors.Is(err, user.ErrRoleInUse) {
+		response.WriteError(w, "Role assigned to users")
+	} else {
+		response.WriteGeneralError(w)
+	}
+}
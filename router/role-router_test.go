@@ -0,0 +1,268 @@
+package router
+
+import (
+	"authGo/user"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func serveRoleRouter(roleRouter *RoleRouter, method string, url string, accessToken string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/roles", roleRouter.GetRolesHandler).Methods("GET")
+	router.HandleFunc("/roles", roleRouter.NewRoleHandler).Methods("POST")
+	router.HandleFunc("/roles/{name}", roleRouter.UpdateRoleHandler).Methods("PUT")
+	router.HandleFunc("/roles/{name}", roleRouter.DeleteRoleHandler).Methods("DELETE")
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRoleRouter(t *testing.T) {
+	userRouter := createUserRouter()
+	roleRouter := &RoleRouter{Services: userRouter.Services}
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := createAccessToken(t, userRouter.Services, addSuperAdmin(t, userRouter.Services))
+	organizationAdminToken := createAccessToken(t, userRouter.Services, adminUser)
+
+	rr := serveRoleRouter(roleRouter, "POST", "/roles", organizationAdminToken, `{"name": "helpdesk", "permissions": ["sessions:read"]}`)
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("expected the roles shared by every organization to be changed only by super administrators, got %v", status)
+	}
+
+	rr = serveRoleRouter(roleRouter, "POST", "/roles", adminToken, `{"name": "helpdesk", "permissions": ["sessions:read", "sessions:revoke"]}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	rr = serveRoleRouter(roleRouter, "PUT", "/roles/helpdesk", adminToken, `{"description": "Support team", "permissions": ["sessions:revoke", "sessions:read", "users:read"]}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+	var role user.Role
+	json.Unmarshal(rr.Body.Bytes(), &role)
+	if role.Description != "Support team" || len(role.Permissions) != 3 {
+		t.Errorf("unexpected updated role, got %+v", role)
+	}
+
+	rr = serveRoleRouter(roleRouter, "GET", "/roles", adminToken, "")
+	expected := `"name":"helpdesk"`
+	if body := rr.Body.String(); rr.Code != http.StatusOK || !strings.Contains(body, expected) || !strings.Contains(body, `"name":"admin"`) {
+		t.Errorf("expected the role list, got %v %s", rr.Code, body)
+	}
+
+	rr = serveRoleRouter(roleRouter, "DELETE", "/roles/helpdesk", adminToken, "")
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v, body %s", status, http.StatusOK, rr.Body.String())
+	}
+}
+
+func TestRoleRouterErrors(t *testing.T) {
+	userRouter := createUserRouter()
+	roleRouter := &RoleRouter{Services: userRouter.Services}
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := createAccessToken(t, userRouter.Services, addSuperAdmin(t, userRouter.Services))
+	organizationAdminToken := createAccessToken(t, userRouter.Services, adminUser)
+	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
+	normalToken := createAccessToken(t, userRouter.Services, normalUser)
+
+	roleTests := []struct {
+		name        string
+		method      string
+		url         string
+		accessToken string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"list without permission", "GET", "/roles", normalToken, "", http.StatusForbidden, ""},
+		{"create without permission", "POST", "/roles", normalToken, `{"name": "helpdesk", "permissions": ["users:read"]}`, http.StatusForbidden, ""},
+		{"delete as organization admin", "DELETE", "/roles/nobody", organizationAdminToken, "", http.StatusForbidden, ""},
+		{"invalid access token", "POST", "/roles", "123.123.123", `{"name": "helpdesk", "permissions": ["users:read"]}`, http.StatusUnauthorized, ""},
+		{"empty permissions", "POST", "/roles", adminToken, `{"name": "helpdesk", "permissions": []}`, http.StatusBadRequest, `{"error":"Role data not valid"}`},
+		{"unknown permission", "POST", "/roles", adminToken, `{"name": "helpdesk", "permissions": ["users:fly"]}`, http.StatusBadRequest, `{"error":"Role data not valid"}`},
+		{"built-in name", "POST", "/roles", adminToken, `{"name": "admin", "permissions": ["users:read"]}`, http.StatusBadRequest, `{"error":"Role already exists"}`},
+		{"update built-in", "PUT", "/roles/admin", adminToken, `{"permissions": ["users:read"]}`, http.StatusBadRequest, `{"error":"Built-in roles cannot be changed"}`},
+		{"update unknown", "PUT", "/roles/nobody", adminToken, `{"permissions": ["users:read"]}`, http.StatusBadRequest, `{"error":"Role not found"}`},
+		{"delete built-in", "DELETE", "/roles/admin", adminToken, "", http.StatusBadRequest, `{"error":"Built-in roles cannot be changed"}`},
+		{"delete unknown", "DELETE", "/roles/nobody", adminToken, "", http.StatusBadRequest, `{"error":"Role not found"}`},
+	}
+	for _, roleTest := range roleTests {
+		rr := serveRoleRouter(roleRouter, roleTest.method, roleTest.url, roleTest.accessToken, roleTest.body)
+		if status := rr.Code; status != roleTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", roleTest.name, status, roleTest.status)
+		}
+		if body := strings.TrimSpace(rr.Body.String()); body != roleTest.expected {
+			t.Errorf("%s: handler returned unexpected body: got %v want %v", roleTest.name, body, roleTest.expected)
+		}
+	}
+}
+
+func TestHelpdeskRole(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	sessionRouter := &SessionRouter{Services: services}
+	if _, err := services.UserService.CreateRole(&user.Role{Name: "helpdesk", Permissions: []string{user.PermissionSessionsRead, user.PermissionSessionsRevoke}}); err != nil {
+		t.Fatal(err)
+	}
+	helpdeskUser, err := services.UserService.AddUser(user.NewUser{Name: "helpdesk", Password: "helpdesk", Roles: []string{"helpdesk"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	helpdeskToken := createAccessToken(t, services, helpdeskUser)
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	sessions, err := services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/sessions/%s", sessions[0].Id), nil)
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", helpdeskToken))
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/sessions/{id}", sessionRouter.DeleteSessionHandler)
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("expected the helpdesk to revoke the session, got %v", status)
+	}
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/users/%s", normalUser.Id), nil)
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", helpdeskToken))
+	rr = httptest.NewRecorder()
+	router = mux.NewRouter()
+	router.HandleFunc("/users/{id}", userRouter.DeleteUserHandler)
+	router.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("expected the helpdesk to not delete users, got %v", status)
+	}
+}
+
+func TestNewUserRolesRequireRolesWrite(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	if _, err := services.UserService.CreateRole(&user.Role{Name: "manager", Permissions: []string{user.PermissionUsersWrite}}); err != nil {
+		t.Fatal(err)
+	}
+	manager, err := services.UserService.AddUser(user.NewUser{Name: "manager", Password: "manager", Roles: []string{"manager"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	managerToken := createAccessToken(t, services, manager)
+
+	for body, status := range map[string]int{
+		`{"name": "user2", "password": "user2"}`:                     http.StatusOK,
+		`{"name": "user3", "password": "user3", "roles": ["admin"]}`: http.StatusForbidden,
+		`{"name": "user4", "password": "user4", "isAdmin": true}`:    http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest("POST", "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", managerToken))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(userRouter.NewUserHandler).ServeHTTP(rr, req)
+		if rr.Code != status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", body, rr.Code, status)
+		}
+	}
+}
+
+func TestManageUsersRequireRolesWrite(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	for _, role := range []*user.Role{
+		{Name: "manager", Permissions: []string{user.PermissionUsersRead, user.PermissionUsersWrite}},
+		{Name: "helpdesk", Permissions: []string{user.PermissionSessionsRevoke}},
+	} {
+		if _, err := services.UserService.CreateRole(role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addUser := func(name string, roles ...string) *user.User {
+		u, err := services.UserService.AddUser(user.NewUser{Name: name, Password: name, Roles: roles})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	managerToken := createAccessToken(t, services, addUser("manager", "manager"))
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := createAccessToken(t, services, adminUser)
+	targets := map[string]*user.User{
+		"admin":    addUser("other-admin", user.AdminRole),
+		"helpdesk": addUser("helpdesk", "helpdesk"),
+		"manager":  addUser("other-manager", "manager"),
+		"user":     addUser("user"),
+	}
+
+	serve := func(method string, url string, accessToken string, body string) int {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{id}", userRouter.UpdateUserHandler).Methods("PATCH")
+		router.HandleFunc("/users/{id}", userRouter.DeleteUserHandler).Methods("DELETE")
+		router.HandleFunc("/users/{id}/profile", userRouter.UpdateProfileHandler).Methods("PATCH")
+		router.HandleFunc("/users/{id}/status", userRouter.SetStatusHandler).Methods("PUT")
+		router.HandleFunc("/users/{id}/totp", userRouter.DisableTOTPHandler).Methods("DELETE")
+		router.HandleFunc("/users/{id}/unlock", userRouter.UnlockUserHandler).Methods("POST")
+		router.HandleFunc("/users/{id}/approve", userRouter.ApproveUserHandler).Methods("POST")
+		router.HandleFunc("/users/{id}/logins", userRouter.GetLoginHistoryHandler).Methods("GET")
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"PATCH", "", `{"email": "taken@example.com"}`},
+		{"PATCH", "/profile", `{"displayName": "Taken"}`},
+		{"PUT", "/status", `{"status": "disabled"}`},
+		{"DELETE", "/totp", ""},
+		{"POST", "/unlock", ""},
+		{"POST", "/approve", ""},
+		{"GET", "/logins", ""},
+		{"DELETE", "", ""},
+	}
+	for name, target := range map[string]*user.User{"admin": targets["admin"], "helpdesk": targets["helpdesk"]} {
+		for _, request := range requests {
+			if status := serve(request.method, "/users/"+target.Id+request.path, managerToken, request.body); status != http.StatusForbidden {
+				t.Errorf("%s %s: expected a manager without roles:write to be forbidden on %s, got %v", request.method, request.path, name, status)
+			}
+		}
+	}
+	for _, name := range []string{"manager", "user"} {
+		email := name + "@example.com"
+		if status := serve("PATCH", "/users/"+targets[name].Id, managerToken, `{"email": "`+email+`"}`); status != http.StatusOK {
+			t.Errorf("expected a manager to change the email of %s, got %v", name, status)
+		}
+		if status := serve("POST", "/users/"+targets[name].Id+"/unlock", managerToken, ""); status != http.StatusOK {
+			t.Errorf("expected a manager to unlock %s, got %v", name, status)
+		}
+		if status := serve("GET", "/users/"+targets[name].Id+"/logins", managerToken, ""); status != http.StatusOK {
+			t.Errorf("expected a manager to read the login history of %s, got %v", name, status)
+		}
+	}
+	if status := serve("PATCH", "/users/"+targets["admin"].Id, adminToken, `{"email": "admin2@example.com"}`); status != http.StatusOK {
+		t.Errorf("expected an administrator to change the email of another one, got %v", status)
+	}
+}
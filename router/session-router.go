@@ -2,7 +2,7 @@ package router
 
 import (
 	response "authGo/router/response"
-	"authGo/session"
+	"authGo/user"
 	"authGo/validator"
 	"log"
 	"net/http"
@@ -24,14 +24,37 @@ func (s *SessionRouter) GetSessionsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var sessions []*session.Session
-	if payload.IsAdmin {
-		sessions = s.Services.SessionsHandler.GetAllSessions()
-	} else {
-		sessions = s.Services.SessionsHandler.GetUserSessions(payload.UserId)
+	queryV := validator.SessionQueryValidator{Validator: v.Validator}
+	query, err := queryV.GetSessionQuery()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Session query not valid")
+		return
 	}
 
-	response.WriteSessionList(w, sessions)
+	if query.OrganizationId != "" && !canAccessOrganization(payload, query.OrganizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+	if !isSuperAdmin(payload) {
+		query.OrganizationId = payload.OrganizationId
+	}
+	if !payload.HasPermission(user.PermissionSessionsRead) {
+		if query.UserId != "" && query.UserId != payload.UserId {
+			response.WriteForbidden(w)
+			return
+		}
+		query.UserId = payload.UserId
+	}
+
+	sessions, nextCursor, err := s.Services.SessionsHandler.QuerySessions(query)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Session query not valid")
+		return
+	}
+
+	response.WriteSessionList(w, sessions, nextCursor)
 }
 
 func (s *SessionRouter) DeleteSessionHandler(w http.ResponseWriter, r *http.Request) {
@@ -53,8 +76,11 @@ func (s *SessionRouter) DeleteSessionHandler(w http.ResponseWriter, r *http.Requ
 		response.WriteError(w, "Session not found")
 		return
 	}
-	if !payload.IsAdmin && session.UserToken.UserId != payload.UserId {
+	isOwnSession := session.UserToken.UserId == payload.UserId
+	canRevoke := payload.HasPermission(user.PermissionSessionsRevoke) && canAccessOrganization(payload, session.UserToken.OrganizationId)
+	if !isOwnSession && !canRevoke {
 		response.WriteForbidden(w)
+		return
 	}
 
 	err = s.Services.SessionsHandler.DeleteSession(session.UserToken)
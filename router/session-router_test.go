@@ -42,7 +42,7 @@ func addUserAndSession(t *testing.T, services validator.Services, name string, p
 	if err != nil {
 		t.Fatal(err)
 	}
-	user, err := services.UserService.GetRepository().GetByName(name)
+	user, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, name)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -50,8 +50,16 @@ func addUserAndSession(t *testing.T, services validator.Services, name string, p
 	return user
 }
 
+func newTestAccessPayload(t *testing.T, services *validator.Services, u *user.User) *token.AccessTokenPayload {
+	accessPayload, err := validator.NewAccessTokenPayload(services, u, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accessPayload
+}
+
 func addSession(t *testing.T, services validator.Services, user *user.User) {
-	refreshPayload := &token.RefreshTokenPayload{UserId: user.Id, IssuedAtTime: time.Now()}
+	refreshPayload := &token.RefreshTokenPayload{UserId: user.Id, OrganizationId: user.OrganizationId, IssuedAtTime: time.Now()}
 	err := services.SessionsHandler.AddNewSession(*refreshPayload, session.DeviceData{IpAddress: "10.0.0.1", UserAgent: "vscode"})
 	if err != nil {
 		t.Fatal(err)
@@ -64,12 +72,12 @@ func TestSessionRouterHandlerAdmin(t *testing.T) {
 	}
 
 	sessionRouter := createSessionRouter()
-	user, err := sessionRouter.Services.UserService.GetRepository().GetByName("admin")
+	user, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: user.Id, IssuedAtTime: time.Now(), IsAdmin: user.IsAdmin}
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, user)
 	addSession(t, *sessionRouter.Services, user)
 	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -110,12 +118,12 @@ func TestSessionRouterHandlerNotAdmin(t *testing.T) {
 	}
 
 	sessionRouter := createSessionRouter()
-	adminUser, err := sessionRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 	normalUser := addUserAndSession(t, *sessionRouter.Services, "user2", "user2", false)
-	accessPayload := &token.AccessTokenPayload{UserId: normalUser.Id, IssuedAtTime: time.Now(), IsAdmin: normalUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, normalUser)
 	addSession(t, *sessionRouter.Services, adminUser)
 	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -171,12 +179,12 @@ func TestSessionRouterHandlerInvalidAccessToken(t *testing.T) {
 
 func TestDeleteSessionHandler(t *testing.T) {
 	sessionRouter := createSessionRouter()
-	user, err := sessionRouter.Services.UserService.GetRepository().GetByName("admin")
+	user, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: user.Id, IssuedAtTime: time.Now(), IsAdmin: user.IsAdmin}
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, user)
 	addSession(t, *sessionRouter.Services, user)
 	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -184,7 +192,11 @@ func TestDeleteSessionHandler(t *testing.T) {
 	}
 	addUserAndSession(t, *sessionRouter.Services, "user2", "user2", false)
 
-	adminSession := sessionRouter.Services.SessionsHandler.GetUserSessions(user.Id)[0]
+	adminSessions, err := sessionRouter.Services.SessionsHandler.GetUserSessions(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminSession := adminSessions[0]
 
 	req, err := http.NewRequest("DELETE", fmt.Sprintf("/sessions/%s", adminSession.Id), nil)
 	if err != nil {
@@ -235,12 +247,12 @@ func TestDeleteSessionHandlerNotFound(t *testing.T) {
 
 	sessionRouter := createSessionRouter()
 
-	user, err := sessionRouter.Services.UserService.GetRepository().GetByName("admin")
+	user, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: user.Id, IssuedAtTime: time.Now(), IsAdmin: user.IsAdmin}
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, user)
 	addSession(t, *sessionRouter.Services, user)
 	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -267,15 +279,19 @@ func TestDeleteSessionHandlerNotFound(t *testing.T) {
 
 func TestDeleteSessionHandlerForbidden(t *testing.T) {
 	sessionRouter := createSessionRouter()
-	adminUser, err := sessionRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 	addSession(t, *sessionRouter.Services, adminUser)
-	adminSession := sessionRouter.Services.SessionsHandler.GetUserSessions(adminUser.Id)[0]
+	adminSessions, err := sessionRouter.Services.SessionsHandler.GetUserSessions(adminUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminSession := adminSessions[0]
 
 	normalUser := addUserAndSession(t, *sessionRouter.Services, "user2", "user2", false)
-	accessPayload := &token.AccessTokenPayload{UserId: normalUser.Id, IssuedAtTime: time.Now(), IsAdmin: normalUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, normalUser)
 	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
 		t.Fatal(err)
@@ -300,3 +316,92 @@ func TestDeleteSessionHandlerForbidden(t *testing.T) {
 			status, http.StatusForbidden, body)
 	}
 }
+
+func TestSessionRouterHandlerPagination(t *testing.T) {
+	sessionRouter := createSessionRouter()
+	adminUser, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, adminUser)
+	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *sessionRouter.Services, adminUser)
+	normalUser := addUserAndSession(t, *sessionRouter.Services, "user2", "user2", false)
+	time.Sleep(1 * time.Millisecond)
+	addSession(t, *sessionRouter.Services, normalUser)
+
+	url := fmt.Sprintf("/sessions?userId=%s&limit=1", normalUser.Id)
+	var ids []string
+	for url != "" {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(sessionRouter.GetSessionsHandler)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var sessionResponse response.SessionResponse
+		json.Unmarshal(rr.Body.Bytes(), &sessionResponse)
+		for _, session := range sessionResponse.Sessions {
+			if session.UserToken.UserId != normalUser.Id {
+				t.Errorf("expected session to belong to %s, got %s", normalUser.Id, session.UserToken.UserId)
+			}
+			ids = append(ids, session.Id)
+		}
+		url = ""
+		if sessionResponse.NextCursor != "" {
+			url = fmt.Sprintf("/sessions?userId=%s&limit=1&cursor=%s", normalUser.Id, sessionResponse.NextCursor)
+		}
+	}
+	if len(ids) != 2 || ids[0] == ids[1] {
+		t.Errorf("expected 2 different sessions, got %v", ids)
+	}
+}
+
+func TestSessionRouterHandlerQueryErrors(t *testing.T) {
+	sessionRouter := createSessionRouter()
+	adminUser, err := sessionRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalUser := addUserAndSession(t, *sessionRouter.Services, "user2", "user2", false)
+	accessPayload := newTestAccessPayload(t, sessionRouter.Services, normalUser)
+	accessToken, err := sessionRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queryTests := []struct {
+		url    string
+		status int
+	}{
+		{"/sessions?sort=password", http.StatusBadRequest},
+		{"/sessions?cursor=abc", http.StatusBadRequest},
+		{fmt.Sprintf("/sessions?userId=%s", adminUser.Id), http.StatusForbidden},
+		{fmt.Sprintf("/sessions?userId=%s", normalUser.Id), http.StatusOK},
+	}
+	for _, queryTest := range queryTests {
+		req, err := http.NewRequest("GET", queryTest.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(sessionRouter.GetSessionsHandler)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != queryTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", queryTest.url, status, queryTest.status)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/totp"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// TOTPRouter lets the users set up an authenticator app as the second factor of their login.
+type TOTPRouter struct {
+	Services *validator.Services
+	// Issuer is the name the authenticator apps show next to the codes.
+	Issuer string
+}
+
+// EnrollHandler generates a new secret for the caller, it doesn't protect the login until it's
+// confirmed with a code.
+func (t *TOTPRouter) EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: t.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	u, err := t.Services.UserService.GetRepository().GetById(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	enrolled, err := t.Services.UserService.EnrollTOTP(u.Id)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrTOTPAlreadyEnabled) {
+			response.WriteError(w, "TOTP already enabled")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	response.WriteTOTPEnrollment(w, enrolled.Secret, totp.URI(t.Issuer, u.Name, enrolled.Secret))
+}
+
+// ConfirmHandler turns on the second factor once the authenticator app gives a valid code, the first
+// time a second factor is set up the recovery codes are returned too.
+func (t *TOTPRouter) ConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: t.Services}}
+	totpV := validator.TOTPValidator{Validator: validator.Validator{Writer: w, Request: r, Services: t.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	codeInput, err := totpV.GetCode()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "TOTP data not valid")
+		return
+	}
+
+	if err = t.Services.UserService.ConfirmTOTP(payload.UserId, codeInput.Code); err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrTOTPCodeNotValid) {
+			response.WriteError(w, "Code not valid")
+		} else if errors.Is(err, user.ErrTOTPAlreadyEnabled) {
+			response.WriteError(w, "TOTP already enabled")
+		} else if errors.Is(err, user.ErrTOTPNotEnabled) {
+			response.WriteError(w, "TOTP not enrolled")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	codes, err := t.Services.UserService.EnsureRecoveryCodes(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+	if len(codes) > 0 {
+		response.WriteRecoveryCodes(w, codes)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DisableHandler removes the authenticator app of the caller, a current code is required so a stolen
+// session can't remove the second factor.
+func (t *TOTPRouter) DisableHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: t.Services}}
+	totpV := validator.TOTPValidator{Validator: validator.Validator{Writer: w, Request: r, Services: t.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	codeInput, err := totpV.GetCode()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "TOTP data not valid")
+		return
+	}
+
+	if err = t.Services.UserService.VerifyTOTP(payload.UserId, codeInput.Code); err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrTOTPCodeNotValid) {
+			response.WriteError(w, "Code not valid")
+		} else if errors.Is(err, user.ErrTOTPNotEnabled) {
+			response.WriteError(w, "TOTP not enabled")
+		} else if errors.Is(err, user.ErrUserLocked) {
+			response.WriteError(w, "Account temporarily locked")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	if err = t.Services.UserService.DisableTOTP(payload.UserId); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
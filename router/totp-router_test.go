@@ -0,0 +1,122 @@
+package router
+
+import (
+	"authGo/totp"
+	"authGo/validator"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveTOTP(handler http.HandlerFunc, method string, accessToken string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/users/me/totp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func serveMFALogin(loginRouter *LoginRouter, challengeToken string, code string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"challengeToken":%q,"code":%q}`, challengeToken, code)
+	req, _ := http.NewRequest("POST", "/auth/login/mfa", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(loginRouter.MFAHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestTOTPRouterLogin(t *testing.T) {
+	services := createUserRouter().Services
+	totpRouter := &TOTPRouter{Services: services, Issuer: "authGo"}
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	accessToken := createAccessToken(t, services, normalUser)
+
+	rr := serveTOTP(totpRouter.EnrollHandler, "POST", "wrong", "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected an invalid access token to be rejected, got %v", rr.Code)
+	}
+	rr = serveTOTP(totpRouter.EnrollHandler, "POST", accessToken, "")
+	var enrollment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&enrollment); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("expected the enrollment, got %v %v", rr.Code, err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/authGo:user2?") {
+		t.Errorf("unexpected uri %s", enrollment.URI)
+	}
+	if rr = serveLogin(services, "user2", "user2"); strings.Contains(rr.Body.String(), "mfaRequired") {
+		t.Errorf("expected the TOTP not to be required before the confirmation, got %s", rr.Body.String())
+	}
+
+	counter := totp.Counter(time.Now())
+	previousCode, _ := totp.Code(enrollment.Secret, counter-1)
+	rr = serveTOTP(totpRouter.ConfirmHandler, "POST", accessToken, `{"code":"000000x"}`)
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"error":"Code not valid"}` {
+		t.Errorf("expected a wrong code to be rejected, got %s", body)
+	}
+	if rr = serveTOTP(totpRouter.ConfirmHandler, "POST", accessToken, fmt.Sprintf(`{"code":%q}`, previousCode)); rr.Code != http.StatusOK {
+		t.Fatalf("expected the confirmation, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serveLogin(services, "user2", "user2")
+	var challenge struct {
+		MFARequired    bool     `json:"mfaRequired"`
+		ChallengeToken string   `json:"challengeToken"`
+		SecondFactors  []string `json:"secondFactors"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&challenge); err != nil || !challenge.MFARequired || challenge.ChallengeToken == "" {
+		t.Fatalf("expected a challenge, got %+v %v", challenge, err)
+	}
+	if len(challenge.SecondFactors) != 2 || challenge.SecondFactors[0] != validator.SecondFactorTOTP || challenge.SecondFactors[1] != validator.SecondFactorRecoveryCode {
+		t.Errorf("expected the totp second factor and the recovery codes, got %v", challenge.SecondFactors)
+	}
+	if cookies := rr.Header().Values("Set-Cookie"); len(cookies) != 0 {
+		t.Errorf("expected no session cookies before the second factor, got %v", cookies)
+	}
+
+	code, _ := totp.Code(enrollment.Secret, counter)
+	mfaTests := []struct {
+		challengeToken string
+		code           string
+		status         int
+		body           string
+	}{
+		{challenge.ChallengeToken, "", http.StatusBadRequest, `{"error":"Second factor data not valid"}`},
+		{"aa.bb.cc", code, http.StatusBadRequest, `{"error":"Challenge token not valid"}`},
+		{challenge.ChallengeToken, previousCode, http.StatusBadRequest, `{"error":"Code not valid"}`},
+		{challenge.ChallengeToken, code, http.StatusOK, ""},
+		{challenge.ChallengeToken, code, http.StatusBadRequest, `{"error":"Code not valid"}`},
+	}
+	for i, mfaTest := range mfaTests {
+		rr = serveMFALogin(loginRouter, mfaTest.challengeToken, mfaTest.code)
+		if rr.Code != mfaTest.status {
+			t.Errorf("%d: handler returned wrong status code: got %v want %v", i, rr.Code, mfaTest.status)
+		}
+		if body := strings.TrimSpace(rr.Body.String()); mfaTest.body != "" && body != mfaTest.body {
+			t.Errorf("%d: handler returned unexpected body: got %v want %v", i, body, mfaTest.body)
+		}
+	}
+	sessions, err := services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil || len(sessions) != 3 {
+		t.Errorf("expected the second factor to create a session, got %v %v", sessions, err)
+	}
+
+	nextCode, _ := totp.Code(enrollment.Secret, counter+1)
+	if rr = serveTOTP(totpRouter.DisableHandler, "DELETE", accessToken, fmt.Sprintf(`{"code":%q}`, code)); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a used code not to disable the TOTP, got %v", rr.Code)
+	}
+	if rr = serveTOTP(totpRouter.DisableHandler, "DELETE", accessToken, fmt.Sprintf(`{"code":%q}`, nextCode)); rr.Code != http.StatusOK {
+		t.Errorf("expected the TOTP to be disabled, got %v %s", rr.Code, rr.Body.String())
+	}
+	if rr = serveLogin(services, "user2", "user2"); strings.Contains(rr.Body.String(), "mfaRequired") {
+		t.Errorf("expected the TOTP not to be required once disabled, got %s", rr.Body.String())
+	}
+}
@@ -1,30 +1,68 @@
 package router
 
 import (
+	"authGo/password"
 	response "authGo/router/response"
+	"authGo/token"
+	"authGo/user"
 	"authGo/validator"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 type UserRouter struct {
 	Services *validator.Services
+	// EmailVerification sends the verification link when a user gets a new email address, no link is
+	// sent when it's nil.
+	EmailVerification *EmailVerificationRouter
 }
 
 func (u *UserRouter) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 	v := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
 
-	_, err := v.ValidateAccessToken()
+	payload, err := v.ValidateAccessToken()
 	if err != nil {
 		log.Print(err)
 		response.WriteTokenError(w)
 		return
 	}
 
-	users := v.Validator.Services.UserService.GetRepository().GetAll()
-	response.WriteUserList(w, users)
+	if !payload.HasPermission(user.PermissionUsersRead) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	queryV := validator.UserQueryValidator{Validator: v.Validator}
+	query, err := queryV.GetUserQuery()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User query not valid")
+		return
+	}
+
+	if query.OrganizationId == "" && !isSuperAdmin(payload) {
+		query.OrganizationId = payload.OrganizationId
+	}
+	if query.OrganizationId != "" && !canAccessOrganization(payload, query.OrganizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	page, err := v.Validator.Services.UserService.QueryUsers(query)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrInvalidCursor) || errors.Is(err, user.ErrInvalidSortField) {
+			response.WriteError(w, "User query not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+	response.WriteUserList(w, page.Users, page.Total, page.NextCursor)
 }
 
 func (u *UserRouter) NewUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +76,7 @@ func (u *UserRouter) NewUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !payload.IsAdmin {
+	if !payload.HasPermission(user.PermissionUsersWrite) {
 		response.WriteForbidden(w)
 		return
 	}
@@ -50,17 +88,59 @@ func (u *UserRouter) NewUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = u.Services.UserService.CreateUser(newUser.Name, newUser.Password, newUser.IsAdmin)
+	organizationId := newUser.OrganizationId
+	if organizationId == "" {
+		organizationId = payload.OrganizationId
+	}
+	if !canAccessOrganization(payload, organizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	roles := newUser.GetRoles()
+	if len(roles) > 0 && !payload.HasPermission(user.PermissionRolesWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+	if containsString(roles, user.SuperAdminRole) && !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	createdUser, err := u.Services.UserService.AddUser(user.NewUser{
+		OrganizationId: organizationId,
+		Name:           newUser.Name,
+		Password:       newUser.Password,
+		Email:          newUser.Email,
+		Roles:          roles,
+	})
 	if err != nil {
 		log.Print(err)
-		response.WriteError(w, "Error creating new user")
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			response.WritePasswordPolicyError(w, policyErr)
+		} else if errors.Is(err, user.ErrUserNameNotValid) {
+			response.WriteError(w, "User name not valid")
+		} else if errors.Is(err, user.ErrEmailNotValid) {
+			response.WriteError(w, "Email not valid")
+		} else if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+			response.WriteError(w, "Email already registered")
+		} else if errors.Is(err, user.ErrRoleNotFound) {
+			response.WriteError(w, "Role not found")
+		} else if errors.Is(err, user.ErrOrganizationNotFound) {
+			response.WriteError(w, "Organization not found")
+		} else {
+			response.WriteError(w, "Error creating new user")
+		}
 		return
 	}
+	u.sendEmailVerification(createdUser)
 	w.WriteHeader(http.StatusOK)
 }
 
-func (u *UserRouter) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+func (u *UserRouter) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+	updateV := validator.UpdateUserValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
 
 	payload, err := tokenV.ValidateAccessToken()
 	if err != nil {
@@ -69,7 +149,7 @@ func (u *UserRouter) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !payload.IsAdmin {
+	if !payload.HasPermission(user.PermissionUsersWrite) {
 		response.WriteForbidden(w)
 		return
 	}
@@ -77,29 +157,217 @@ func (u *UserRouter) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	_, err = u.Services.UserService.GetRepository().GetById(id)
+	updateUser, err := updateV.GetUpdateUser()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User data not valid")
+		return
+	}
+
+	target, err := u.getOrganizationUser(payload, id)
 	if err != nil {
 		log.Print(err)
 		response.WriteError(w, "User id not valid")
 		return
 	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
 
-	if id == payload.UserId {
+	if updateUser.Roles != nil {
+		if !payload.HasPermission(user.PermissionRolesWrite) {
+			response.WriteForbidden(w)
+			return
+		}
+		if containsString(*updateUser.Roles, user.SuperAdminRole) && !isSuperAdmin(payload) {
+			response.WriteForbidden(w)
+			return
+		}
+		if id == payload.UserId && removesRole(payload.Roles, *updateUser.Roles, user.AdminRole, user.SuperAdminRole) {
+			response.WriteError(w, "An user cannot remove their own admin role")
+			return
+		}
+	}
+
+	updatedUser, err := u.Services.UserService.UpdateUser(id, user.UserUpdate{Name: updateUser.Name, Email: updateUser.Email, Roles: updateUser.Roles})
+	if err != nil {
 		log.Print(err)
-		response.WriteError(w, "An user cannot remove himself")
+		if errors.Is(err, user.ErrUserNotFound) {
+			response.WriteError(w, "User id not valid")
+		} else if errors.Is(err, user.ErrUserAlreadyRegistered) {
+			response.WriteError(w, "User name already registered")
+		} else if errors.Is(err, user.ErrUserNameNotValid) {
+			response.WriteError(w, "User name not valid")
+		} else if errors.Is(err, user.ErrEmailNotValid) {
+			response.WriteError(w, "Email not valid")
+		} else if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+			response.WriteError(w, "Email already registered")
+		} else if errors.Is(err, user.ErrRoleNotFound) {
+			response.WriteError(w, "Role not found")
+		} else {
+			response.WriteError(w, "Error updating user")
+		}
+		return
+	}
+	if updateUser.Email != nil {
+		u.sendEmailVerification(updatedUser)
+	}
+
+	response.WriteUser(w, updatedUser)
+}
+
+// UpdateProfileHandler edits the profile of a user of the caller's organization.
+func (u *UserRouter) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+	profileV := validator.ProfileValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+
+	update, err := profileV.GetProfileUpdate()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Profile data not valid")
+		return
+	}
+
+	updated, err := u.Services.UserService.UpdateProfile(id, *update)
+	if err != nil {
+		log.Print(err)
+		writeProfileError(w, err)
+		return
+	}
+
+	response.WriteUser(w, updated)
+}
+
+func (u *UserRouter) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+	passwordV := validator.ChangePasswordValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+	refreshV := validator.RefreshValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	changePassword, err := passwordV.GetChangePassword()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Password data not valid")
+		return
+	}
+
+	err = u.Services.UserService.ChangePassword(payload.UserId, changePassword.CurrentPassword, changePassword.NewPassword)
+	if err != nil {
+		log.Print(err)
+		var policyErr *password.PolicyError
+		if errors.Is(err, user.ErrUserPasswordNotValid) {
+			response.WriteError(w, "Current password not valid")
+		} else if errors.Is(err, user.ErrUserLocked) {
+			response.WriteError(w, "Account temporarily locked")
+		} else if errors.As(err, &policyErr) {
+			response.WritePasswordPolicyError(w, policyErr)
+		} else {
+			response.WriteError(w, "Error changing password")
+		}
 		return
 	}
 
-	sessions := u.Services.SessionsHandler.GetUserSessions(id)
-	for _, session := range sessions {
-		err := u.Services.SessionsHandler.DeleteSession(session.UserToken)
+	if *changePassword.RevokeOtherSessions {
+		var currentSessionId string
+		if currentSession, err := refreshV.ValidateRefreshToken(); err == nil && currentSession.UserToken.UserId == payload.UserId {
+			currentSessionId = currentSession.Id
+		}
+		err = u.Services.SessionsHandler.RevokeUserSessions(payload.UserId, currentSessionId)
 		if err != nil {
 			log.Print(err)
-			response.WriteError(w, "Error revoking user session before delete")
+			response.WriteError(w, "Password changed but there was an error revoking the other sessions")
 			return
 		}
 	}
 
+	w.WriteHeader(http.StatusOK)
+}
+
+func (u *UserRouter) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersDelete) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+
+	if id == payload.UserId {
+		log.Print(err)
+		response.WriteError(w, "An user cannot remove himself")
+		return
+	}
+
+	err = u.Services.SessionsHandler.RevokeUserSessions(id, "")
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Error revoking user session before delete")
+		return
+	}
+
 	err = u.Services.UserService.GetRepository().Delete(id)
 	if err != nil {
 		log.Print(err)
@@ -109,3 +377,475 @@ func (u *UserRouter) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// UnlockUserHandler ends the lockout caused by failed logins of a user of the caller's organization.
+func (u *UserRouter) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+
+	if err = u.Services.UserService.UnlockUser(id); err != nil {
+		log.Print(err)
+		response.WriteError(w, "Error unlocking user")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ExportUsersHandler downloads the users of the caller's organization as a csv or json file, super
+// administrators get every organization unless organizationId is given. Only super administrators can
+// include the password hashes.
+func (u *UserRouter) ExportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersRead) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	exportV := validator.UserImportValidator{Validator: tokenV.Validator}
+	input, err := exportV.GetExport()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Export not valid")
+		return
+	}
+
+	if input.OrganizationId == "" && !isSuperAdmin(payload) {
+		input.OrganizationId = payload.OrganizationId
+	}
+	if input.OrganizationId != "" && !canAccessOrganization(payload, input.OrganizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+	if input.IncludePasswordHashes && !isSuperAdmin(payload) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	records, err := u.Services.UserService.ExportUsers(input.OrganizationId, input.IncludePasswordHashes)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+	if err = response.WriteUserExport(w, input.Format, records); err != nil {
+		log.Print(err)
+	}
+}
+
+// ImportUsersHandler adds the users of a csv or json file to the caller's organization, every row is
+// validated on its own and the refused ones are listed in the response. Importing roles requires the
+// roles:write permission like the new users.
+func (u *UserRouter) ImportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	importV := validator.UserImportValidator{Validator: tokenV.Validator}
+	input, err := importV.GetImport()
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "Import file not valid")
+		return
+	}
+
+	if input.OrganizationId == "" {
+		input.OrganizationId = payload.OrganizationId
+	}
+	if !canAccessOrganization(payload, input.OrganizationId) {
+		response.WriteForbidden(w)
+		return
+	}
+	for _, record := range input.Records {
+		if record.OrganizationId != "" && !canAccessOrganization(payload, record.OrganizationId) {
+			response.WriteForbidden(w)
+			return
+		}
+		if len(record.Roles) > 0 && !payload.HasPermission(user.PermissionRolesWrite) {
+			response.WriteForbidden(w)
+			return
+		}
+		if containsString(record.Roles, user.SuperAdminRole) && !isSuperAdmin(payload) {
+			response.WriteForbidden(w)
+			return
+		}
+		// The password hashes are only exported to the super administrators, and only they can import one.
+		if record.PasswordHash != "" && !isSuperAdmin(payload) {
+			response.WriteForbidden(w)
+			return
+		}
+	}
+
+	result := u.Services.UserService.ImportUsers(input.Records, user.ImportOptions{OrganizationId: input.OrganizationId, DryRun: input.DryRun})
+	rowErrors := make([]response.UserImportRowError, len(result.Errors))
+	for i, importErr := range result.Errors {
+		log.Print(importErr)
+		rowErrors[i] = response.UserImportRowError{Row: importErr.Row, Name: importErr.Name, Error: importErrorMessage(importErr.Err)}
+	}
+	response.WriteUserImport(w, result, rowErrors)
+}
+
+func importErrorMessage(err error) string {
+	var policyErr *password.PolicyError
+	var metadataErr *user.MetadataError
+	if errors.As(err, &policyErr) {
+		messages := make([]string, len(policyErr.Violations))
+		for i, violation := range policyErr.Violations {
+			messages[i] = violation.Message
+		}
+		return "Password not valid, " + strings.Join(messages, ", ")
+	} else if errors.As(err, &metadataErr) {
+		return "Metadata not valid, " + metadataErr.Reason
+	} else if errors.Is(err, user.ErrImportNameMissing) {
+		return "Name required"
+	} else if errors.Is(err, user.ErrImportPasswordMissing) {
+		return "Either password or passwordHash required"
+	} else if errors.Is(err, user.ErrPasswordHashNotValid) {
+		return "Password hash not valid"
+	} else if errors.Is(err, user.ErrImportIdAlreadyRegistered) {
+		return "Id already registered"
+	} else if errors.Is(err, user.ErrUserAlreadyRegistered) {
+		return "User name already registered"
+	} else if errors.Is(err, user.ErrUserNameNotValid) {
+		return "User name not valid"
+	} else if errors.Is(err, user.ErrEmailNotValid) {
+		return "Email not valid"
+	} else if errors.Is(err, user.ErrEmailAlreadyRegistered) {
+		return "Email already registered"
+	} else if errors.Is(err, user.ErrRoleNotFound) {
+		return "Role not found"
+	} else if errors.Is(err, user.ErrOrganizationNotFound) {
+		return "Organization not found"
+	} else if errors.Is(err, user.ErrUserStatusNotValid) {
+		return "Status not valid"
+	} else if errors.Is(err, user.ErrDisplayNameNotValid) {
+		return "Display name not valid"
+	} else if errors.Is(err, user.ErrAvatarURLNotValid) {
+		return "Avatar URL not valid"
+	} else if errors.Is(err, user.ErrLocaleNotValid) {
+		return "Locale not valid"
+	} else if errors.Is(err, user.ErrTimezoneNotValid) {
+		return "Timezone not valid"
+	}
+	return "Error importing user"
+}
+
+// ApproveUserHandler enables a user of the caller's organization whose registration is pending.
+func (u *UserRouter) ApproveUserHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+
+	approved, err := u.Services.UserService.ApproveUser(id)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrUserNotPending) {
+			response.WriteError(w, "User not pending approval")
+		} else {
+			response.WriteError(w, "Error approving user")
+		}
+		return
+	}
+
+	response.WriteUser(w, approved)
+}
+
+// SetStatusHandler enables, disables or suspends a user of the caller's organization. Disabling or
+// suspending a user revokes their sessions.
+func (u *UserRouter) SetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+	if id == payload.UserId {
+		response.WriteError(w, "An user cannot change his own status")
+		return
+	}
+
+	v := validator.UserStatusValidator{Validator: tokenV.Validator}
+	update, err := v.GetUserStatus()
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrUserStatusInvalidStatus) {
+			response.WriteError(w, "Status must be enabled, disabled or suspended")
+		} else if errors.Is(err, validator.ErrUserStatusInvalidSuspendedUntil) {
+			response.WriteError(w, "A suspension needs a future suspendedUntil date")
+		} else {
+			response.WriteError(w, "Status data not valid")
+		}
+		return
+	}
+
+	updated, err := u.Services.UserService.SetUserStatus(id, *update)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrUserStatusNotValid) {
+			response.WriteError(w, "Status not valid")
+		} else {
+			response.WriteError(w, "Error changing the user status")
+		}
+		return
+	}
+
+	if updated.Status != user.UserStatusEnabled {
+		if err = u.Services.SessionsHandler.RevokeUserSessions(id, ""); err != nil {
+			log.Print(err)
+			response.WriteGeneralError(w)
+			return
+		}
+	}
+
+	response.WriteUser(w, updated)
+}
+
+// DisableTOTPHandler removes the authenticator app of a user of the caller's organization who lost it.
+func (u *UserRouter) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersWrite) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+
+	if err = u.Services.UserService.DisableTOTP(id); err != nil {
+		log.Print(err)
+		response.WriteError(w, "Error disabling TOTP")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetLoginHistoryHandler returns the login attempts of a user of the caller's organization.
+func (u *UserRouter) GetLoginHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: u.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if !payload.HasPermission(user.PermissionUsersRead) {
+		response.WriteForbidden(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	target, err := u.getOrganizationUser(payload, id)
+	if err != nil {
+		log.Print(err)
+		response.WriteError(w, "User id not valid")
+		return
+	}
+	if canManage, err := u.canManageUser(payload, target); err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	} else if !canManage {
+		response.WriteForbidden(w)
+		return
+	}
+
+	attempts, err := u.Services.UserService.GetLoginHistory(id)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteLoginHistory(w, attempts)
+}
+
+// sendEmailVerification sends the verification link of an unverified email address, failing to send
+// it doesn't fail the request as the user can ask for it again.
+func (u *UserRouter) sendEmailVerification(target *user.User) {
+	if u.EmailVerification == nil || target.Email == "" || target.EmailVerified {
+		return
+	}
+	if err := u.EmailVerification.SendVerification(target); err != nil {
+		log.Print(err)
+	}
+}
+
+// getOrganizationUser returns the user with the given id when the user of the token can access their
+// organization, the users of other organizations are not found.
+func (u *UserRouter) getOrganizationUser(payload *token.AccessTokenPayload, id string) (*user.User, error) {
+	target, err := u.Services.UserService.GetRepository().GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccessOrganization(payload, target.OrganizationId) {
+		return nil, user.ErrUserNotFound
+	}
+	return target, nil
+}
+
+// canManageUser tells whether the user of the token can change, disable, reset, unlock, approve or delete
+// the target, or read their login history.
+// Only the super administrators manage the other super administrators, and managing the administrators or
+// the users with permissions beyond the caller's requires the roles:write permission, as changing their
+// email or second factor would let the caller take over their account.
+func (u *UserRouter) canManageUser(payload *token.AccessTokenPayload, target *user.User) (bool, error) {
+	if isSuperAdmin(payload) {
+		return true, nil
+	}
+	if target.HasRole(user.SuperAdminRole) {
+		return false, nil
+	}
+	if payload.HasPermission(user.PermissionRolesWrite) {
+		return true, nil
+	}
+	if target.HasRole(user.AdminRole) {
+		return false, nil
+	}
+	permissions, err := u.Services.UserService.GetPermissions(target)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !payload.HasPermission(permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// removesRole tells whether any of the given roles is in the current roles but not in the new ones.
+func removesRole(current []string, updated []string, roles ...string) bool {
+	for _, role := range roles {
+		if containsString(current, role) && !containsString(updated, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
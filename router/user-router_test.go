@@ -1,9 +1,11 @@
 package router
 
 import (
+	"authGo/password"
 	response "authGo/router/response"
 	"authGo/session"
 	"authGo/token"
+	"authGo/totp"
 	"authGo/user"
 	"authGo/validator"
 	"bytes"
@@ -17,6 +19,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func createUserRouter() *UserRouter {
@@ -24,13 +27,15 @@ func createUserRouter() *UserRouter {
 	userService.CreateUser("admin", "admin", true)
 	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
 	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
+	mfaChallengeTokenGenerator := &token.TokenGenerator[token.MFAChallengePayload]{Password: []byte("mfaChallengeKey"), Duration: time.Minute * 5}
 	sessionHandler := session.NewSessionHandler()
 
 	services := &validator.Services{
-		UserService:           userService,
-		AccessTokenGenerator:  accessTokenGenerator,
-		RefreshTokenGenerator: refreshTokenGenerator,
-		SessionsHandler:       sessionHandler,
+		UserService:                userService,
+		AccessTokenGenerator:       accessTokenGenerator,
+		RefreshTokenGenerator:      refreshTokenGenerator,
+		SessionsHandler:            sessionHandler,
+		MFAChallengeTokenGenerator: mfaChallengeTokenGenerator,
 	}
 
 	return &UserRouter{
@@ -45,12 +50,12 @@ func TestUserRouterGetUsersHandler(t *testing.T) {
 	}
 
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -84,6 +89,62 @@ func TestUserRouterGetUsersHandler(t *testing.T) {
 
 }
 
+func TestUserRouterGetUsersHandlerQuery(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken := createAccessToken(t, services, adminUser)
+	for _, name := range []string{"alice", "albert", "bob", "carol"} {
+		if _, err = services.UserService.AddUser(user.NewUser{Name: name, Password: "password"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getUsers := func(query string) (*httptest.ResponseRecorder, response.UserResponse) {
+		req, _ := http.NewRequest("GET", "/users?"+query, nil)
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(userRouter.GetUsersHandler).ServeHTTP(rr, req)
+		var userResponse response.UserResponse
+		json.Unmarshal(rr.Body.Bytes(), &userResponse)
+		return rr, userResponse
+	}
+	names := func(users []*user.User) string {
+		names := make([]string, len(users))
+		for i, u := range users {
+			names[i] = u.Name
+		}
+		return strings.Join(names, ",")
+	}
+
+	_, userResponse := getUsers("prefix=AL")
+	if names(userResponse.Users) != "albert,alice" || userResponse.Total != 2 || userResponse.NextCursor != "" {
+		t.Errorf("unexpected users with the prefix, got %s %+v", names(userResponse.Users), userResponse)
+	}
+	_, userResponse = getUsers("admin=true")
+	if names(userResponse.Users) != "admin" {
+		t.Errorf("expected the admins, got %s", names(userResponse.Users))
+	}
+
+	_, userResponse = getUsers("sort=createdAt&order=desc&limit=3")
+	if names(userResponse.Users) != "carol,bob,albert" || userResponse.Total != 5 || userResponse.NextCursor == "" {
+		t.Errorf("unexpected first page, got %s %+v", names(userResponse.Users), userResponse)
+	}
+	_, userResponse = getUsers("sort=createdAt&order=desc&limit=3&cursor=" + userResponse.NextCursor)
+	if names(userResponse.Users) != "alice,admin" || userResponse.Total != 5 || userResponse.NextCursor != "" {
+		t.Errorf("unexpected last page, got %s %+v", names(userResponse.Users), userResponse)
+	}
+
+	for _, query := range []string{"sort=password", "limit=0", "cursor=abc", "admin=maybe"} {
+		if rr, _ := getUsers(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
 func TestUserRouterGetUsersHandlerInvalidAccessToken(t *testing.T) {
 	req, err := http.NewRequest("GET", "/users", nil)
 	if err != nil {
@@ -118,12 +179,12 @@ func TestUserRouterNewUserHandler(t *testing.T) {
 	req.Header.Add("Content-Type", "application/json")
 
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -144,7 +205,7 @@ func TestUserRouterNewUserHandler(t *testing.T) {
 			status, http.StatusOK, body)
 	}
 
-	_, err = userRouter.Services.UserService.GetRepository().GetByName("user2")
+	_, err = userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "user2")
 	if err != nil {
 		t.Error("new user not found")
 	}
@@ -179,7 +240,7 @@ func TestUserRouterNewUserHandlerNotAdmin(t *testing.T) {
 
 	userRouter := createUserRouter()
 	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
-	accessPayload := &token.AccessTokenPayload{UserId: normalUser.Id, IssuedAtTime: time.Now(), IsAdmin: normalUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, normalUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
 		t.Fatal(err)
@@ -211,12 +272,12 @@ func TestUserRouterNewUserHandlerUserDataNotValid(t *testing.T) {
 	}
 
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -255,12 +316,12 @@ func TestUserRouterNewUserHandlerErrorAddingUser(t *testing.T) {
 	}
 
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -288,12 +349,12 @@ func TestUserRouterNewUserHandlerErrorAddingUser(t *testing.T) {
 
 func TestDeleteUserHandler(t *testing.T) {
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -344,14 +405,14 @@ func TestDeleteUserHandlerInvalidAccessToken(t *testing.T) {
 
 func TestDeleteUserHandlerNotAdmin(t *testing.T) {
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 	addSession(t, *userRouter.Services, adminUser)
 
 	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
-	accessPayload := &token.AccessTokenPayload{UserId: normalUser.Id, IssuedAtTime: time.Now(), IsAdmin: normalUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, normalUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
 		t.Fatal(err)
@@ -378,12 +439,12 @@ func TestDeleteUserHandlerNotAdmin(t *testing.T) {
 
 func TestDeleteUserHandlerInvalidId(t *testing.T) {
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -416,12 +477,12 @@ func TestDeleteUserHandlerInvalidId(t *testing.T) {
 
 func TestDeleteUserHandlerDeleteHimself(t *testing.T) {
 	userRouter := createUserRouter()
-	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName("admin")
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	accessPayload := &token.AccessTokenPayload{UserId: adminUser.Id, IssuedAtTime: time.Now(), IsAdmin: adminUser.IsAdmin}
+	accessPayload := newTestAccessPayload(t, userRouter.Services, adminUser)
 	addSession(t, *userRouter.Services, adminUser)
 	accessToken, err := userRouter.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
@@ -451,3 +512,547 @@ func TestDeleteUserHandlerDeleteHimself(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", want, expected)
 	}
 }
+
+func createAccessToken(t *testing.T, services *validator.Services, u *user.User) string {
+	accessPayload := newTestAccessPayload(t, services, u)
+	accessToken, err := services.AccessTokenGenerator.CreateToken(accessPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accessToken
+}
+
+func serveUpdateUser(userRouter *UserRouter, accessToken string, id string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/users/%s", id), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", userRouter.UpdateUserHandler)
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUpdateUserHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken := createAccessToken(t, userRouter.Services, adminUser)
+	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
+
+	rr := serveUpdateUser(userRouter, accessToken, normalUser.Id, `{"name": "user3", "roles": ["admin"]}`)
+
+	if status := rr.Code; status != http.StatusOK {
+		body := strings.TrimSpace(rr.Body.String())
+		t.Errorf("handler returned wrong status code: got %v want %v , body %s",
+			status, http.StatusOK, body)
+	}
+	var updatedUser user.User
+	json.Unmarshal(rr.Body.Bytes(), &updatedUser)
+	if updatedUser.Id != normalUser.Id || updatedUser.Name != "user3" || !updatedUser.HasRole(user.AdminRole) {
+		t.Errorf("unexpected updated user, got %+v", updatedUser)
+	}
+	sessions, err := userRouter.Services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Errorf("expected the user to keep their sessions, got %v", sessions)
+	}
+}
+
+func TestUpdateUserHandlerRoleAppliedOnRefresh(t *testing.T) {
+	userRouter := createUserRouter()
+	refreshRouter := &RefreshRouter{Services: userRouter.Services}
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken := createAccessToken(t, userRouter.Services, adminUser)
+	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
+	sessions, err := userRouter.Services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken, err := userRouter.Services.RefreshTokenGenerator.CreateToken(&sessions[0].UserToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serveUpdateUser(userRouter, accessToken, normalUser.Id, `{"roles": ["admin"]}`)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	req, err := http.NewRequest("POST", "/auth/refresh", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Cookie", fmt.Sprintf("refreshToken=%s", refreshToken))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(refreshRouter.Handler).ServeHTTP(rr, req)
+
+	var authResponse response.AuthResponse
+	json.Unmarshal(rr.Body.Bytes(), &authResponse)
+	if authResponse.UserData.UserId != normalUser.Id || !authResponse.UserData.HasPermission(user.PermissionUsersDelete) {
+		t.Errorf("expected the refreshed access token to be admin, got %+v", authResponse.UserData)
+	}
+}
+
+func TestUpdateUserHandlerErrors(t *testing.T) {
+	userRouter := createUserRouter()
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminToken := createAccessToken(t, userRouter.Services, adminUser)
+	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
+	normalToken := createAccessToken(t, userRouter.Services, normalUser)
+
+	updateTests := []struct {
+		name        string
+		accessToken string
+		id          string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"invalid access token", "123.123.123", normalUser.Id, `{"roles": ["admin"]}`, http.StatusUnauthorized, ""},
+		{"not admin", normalToken, normalUser.Id, `{"roles": ["admin"]}`, http.StatusForbidden, ""},
+		{"invalid data", adminToken, normalUser.Id, `{"password": "abc"}`, http.StatusBadRequest, `{"error":"User data not valid"}`},
+		{"invalid id", adminToken, "1111", `{"roles": ["admin"]}`, http.StatusBadRequest, `{"error":"User id not valid"}`},
+		{"name already registered", adminToken, normalUser.Id, `{"name": "admin"}`, http.StatusBadRequest, `{"error":"User name already registered"}`},
+		{"name already registered in another case", adminToken, normalUser.Id, `{"name": "ADMIN"}`, http.StatusBadRequest, `{"error":"User name already registered"}`},
+		{"invalid name", adminToken, normalUser.Id, `{"name": "ad  min"}`, http.StatusBadRequest, `{"error":"User name not valid"}`},
+		{"unknown role", adminToken, normalUser.Id, `{"roles": ["nobody"]}`, http.StatusBadRequest, `{"error":"Role not found"}`},
+		{"remove own admin role", adminToken, adminUser.Id, `{"roles": []}`, http.StatusBadRequest, `{"error":"An user cannot remove their own admin role"}`},
+	}
+	for _, updateTest := range updateTests {
+		rr := serveUpdateUser(userRouter, updateTest.accessToken, updateTest.id, updateTest.body)
+		if status := rr.Code; status != updateTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", updateTest.name, status, updateTest.status)
+		}
+		if body := strings.TrimSpace(rr.Body.String()); body != updateTest.expected {
+			t.Errorf("%s: handler returned unexpected body: got %v want %v", updateTest.name, body, updateTest.expected)
+		}
+	}
+}
+
+func serveChangePassword(userRouter *UserRouter, accessToken string, refreshToken string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/users/me/password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s; refreshToken=%s", accessToken, refreshToken))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(userRouter.ChangePasswordHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	changeTests := []struct {
+		name              string
+		body              string
+		remainingSessions int
+	}{
+		{"revoke other sessions by default", `{"currentPassword": "user2", "newPassword": "new password"}`, 1},
+		{"keep other sessions", `{"currentPassword": "user2", "newPassword": "new password", "revokeOtherSessions": false}`, 3},
+	}
+	for _, changeTest := range changeTests {
+		userRouter := createUserRouter()
+		normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
+		for i := 0; i < 2; i++ {
+			time.Sleep(1 * time.Millisecond)
+			addSession(t, *userRouter.Services, normalUser)
+		}
+		sessions, err := userRouter.Services.SessionsHandler.GetUserSessions(normalUser.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		currentSession := sessions[0]
+		refreshToken, err := userRouter.Services.RefreshTokenGenerator.CreateToken(&currentSession.UserToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := serveChangePassword(userRouter, createAccessToken(t, userRouter.Services, normalUser), refreshToken, changeTest.body)
+
+		if status := rr.Code; status != http.StatusOK {
+			body := strings.TrimSpace(rr.Body.String())
+			t.Errorf("%s: handler returned wrong status code: got %v want %v , body %s",
+				changeTest.name, status, http.StatusOK, body)
+		}
+		if !userRouter.Services.UserService.IsPasswordValid(user.DefaultOrganizationId, "user2", "new password") {
+			t.Errorf("%s: expected the password to be changed", changeTest.name)
+		}
+		sessions, err = userRouter.Services.SessionsHandler.GetUserSessions(normalUser.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != changeTest.remainingSessions {
+			t.Errorf("%s: expected %d sessions, got %d", changeTest.name, changeTest.remainingSessions, len(sessions))
+		}
+		if _, err = userRouter.Services.SessionsHandler.GetSessionById(currentSession.Id); err != nil {
+			t.Errorf("%s: expected the current session to stay alive, got %s", changeTest.name, err)
+		}
+	}
+}
+
+func TestChangePasswordHandlerErrors(t *testing.T) {
+	userRouter := createUserRouter()
+	normalUser := addUserAndSession(t, *userRouter.Services, "user2", "user2", false)
+	accessToken := createAccessToken(t, userRouter.Services, normalUser)
+
+	changeTests := []struct {
+		name        string
+		accessToken string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"invalid access token", "123.123.123", `{"currentPassword": "user2", "newPassword": "abc"}`, http.StatusUnauthorized, ""},
+		{"invalid data", accessToken, `{"currentPassword": "user2"}`, http.StatusBadRequest, `{"error":"Password data not valid"}`},
+		{"wrong current password", accessToken, `{"currentPassword": "user3", "newPassword": "abc"}`, http.StatusBadRequest, `{"error":"Current password not valid"}`},
+	}
+	for _, changeTest := range changeTests {
+		rr := serveChangePassword(userRouter, changeTest.accessToken, "", changeTest.body)
+		if status := rr.Code; status != changeTest.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", changeTest.name, status, changeTest.status)
+		}
+		if body := strings.TrimSpace(rr.Body.String()); body != changeTest.expected {
+			t.Errorf("%s: handler returned unexpected body: got %v want %v", changeTest.name, body, changeTest.expected)
+		}
+	}
+	if !userRouter.Services.UserService.IsPasswordValid(user.DefaultOrganizationId, "user2", "user2") {
+		t.Error("expected the password to be unchanged")
+	}
+}
+
+func TestUserRouterNewUserHandlerPasswordPolicy(t *testing.T) {
+	userRouter := createUserRouter()
+	userRouter.Services.UserService.SetPasswordPolicy(password.NewDefaultPolicy())
+	adminUser, err := userRouter.Services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/users", strings.NewReader(`{"name": "user2", "password": "user2"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", createAccessToken(t, userRouter.Services, adminUser)))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(userRouter.NewUserHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	var errorResponse response.ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	if errorResponse.Error != "Password not valid" || len(errorResponse.Fields) != 2 {
+		t.Fatalf("unexpected error response, got %+v", errorResponse)
+	}
+	for i, code := range []string{password.CodeTooShort, password.CodeSimilarToUsername} {
+		if errorResponse.Fields[i].Field != "password" || errorResponse.Fields[i].Code != code {
+			t.Errorf("expected field error %s, got %+v", code, errorResponse.Fields[i])
+		}
+	}
+}
+
+func TestUserRouterUnlockUserHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	services.UserService.SetLockoutPolicy(&user.LockoutPolicy{MaxFailedAttempts: 2, Duration: time.Hour})
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(password string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/auth/login", nil)
+		req.SetBasicAuth("user2", password)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(loginRouter.Handler).ServeHTTP(rr, req)
+		return rr
+	}
+	for i := 0; i < 2; i++ {
+		login("wrong")
+	}
+	for _, password := range []string{"user2", "wrong"} {
+		rr := login(password)
+		expected := `{"error":"Account temporarily locked"}`
+		if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != expected {
+			t.Errorf("expected the same locked response with any password, got %v %s", rr.Code, body)
+		}
+	}
+
+	unlock := func(accessToken string, id string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/users/%s/unlock", id), nil)
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{id}/unlock", userRouter.UnlockUserHandler)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := unlock(createAccessToken(t, services, normalUser), normalUser.Id); rr.Code != http.StatusForbidden {
+		t.Errorf("expected users without users:write to be forbidden, got %v", rr.Code)
+	}
+	adminToken := createAccessToken(t, services, adminUser)
+	if rr := unlock(adminToken, "12345"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown user to fail, got %v", rr.Code)
+	}
+	if rr := unlock(adminToken, normalUser.Id); rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr := login("user2"); rr.Code != http.StatusOK {
+		t.Errorf("expected the user to log in once unlocked, got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUserRouterDisableTOTPHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrolled, err := services.UserService.EnrollTOTP(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.Code(enrolled.Secret, totp.Counter(time.Now()))
+	if err = services.UserService.ConfirmTOTP(normalUser.Id, code); err != nil {
+		t.Fatal(err)
+	}
+
+	disable := func(accessToken string, id string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/users/%s/totp", id), nil)
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{id}/totp", userRouter.DisableTOTPHandler)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := disable(createAccessToken(t, services, normalUser), normalUser.Id); rr.Code != http.StatusForbidden {
+		t.Errorf("expected users without users:write to be forbidden, got %v", rr.Code)
+	}
+	adminToken := createAccessToken(t, services, adminUser)
+	if rr := disable(adminToken, "12345"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown user to fail, got %v", rr.Code)
+	}
+	if rr := disable(adminToken, normalUser.Id); rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if enabled, _ := services.UserService.IsTOTPEnabled(normalUser.Id); enabled {
+		t.Error("expected the TOTP to be disabled")
+	}
+}
+
+func TestUserRouterSetStatusHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/auth/login", nil)
+		req.SetBasicAuth("user2", "user2")
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(loginRouter.Handler).ServeHTTP(rr, req)
+		return rr
+	}
+	setStatus := func(accessToken string, id string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/users/%s/status", id), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/{id}/status", userRouter.SetStatusHandler)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	adminToken := createAccessToken(t, services, adminUser)
+	if rr := setStatus(createAccessToken(t, services, normalUser), normalUser.Id, `{"status": "disabled"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("expected users without users:write to be forbidden, got %v", rr.Code)
+	}
+	if rr := setStatus(adminToken, adminUser.Id, `{"status": "disabled"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an user changing his own status to fail, got %v", rr.Code)
+	}
+	if rr := setStatus(adminToken, normalUser.Id, `{"status": "suspended"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a suspension without end to fail, got %v", rr.Code)
+	}
+
+	rr := setStatus(adminToken, normalUser.Id, `{"status": "disabled", "reason": "left the company"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var disabled user.User
+	if err = json.Unmarshal(rr.Body.Bytes(), &disabled); err != nil {
+		t.Fatal(err)
+	}
+	if disabled.Status != user.UserStatusDisabled || disabled.StatusReason != "left the company" {
+		t.Errorf("unexpected user status, got %+v", disabled)
+	}
+	sessions, err := services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected the sessions of the disabled user to be revoked, got %d", len(sessions))
+	}
+	if rr := login(); rr.Code != http.StatusBadRequest || strings.TrimSpace(rr.Body.String()) != `{"error":"Account disabled"}` {
+		t.Errorf("expected the disabled user not to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	until := time.Now().Add(time.Hour).Format(time.RFC3339)
+	if rr := setStatus(adminToken, normalUser.Id, `{"status": "suspended", "suspendedUntil": "`+until+`"}`); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr := login(); rr.Code != http.StatusBadRequest || strings.TrimSpace(rr.Body.String()) != `{"error":"Account suspended"}` {
+		t.Errorf("expected the suspended user not to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := setStatus(adminToken, normalUser.Id, `{"status": "enabled"}`); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr := login(); rr.Code != http.StatusOK {
+		t.Errorf("expected the enabled user to log in, got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func serveUserImport(userRouter *UserRouter, method string, url string, accessToken string, contentType string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/export", userRouter.ExportUsersHandler).Methods("GET")
+	router.HandleFunc("/users/import", userRouter.ImportUsersHandler).Methods("POST")
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUserRouterImportUsersHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, adminUser)
+	accessToken := createAccessToken(t, services, adminUser)
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+
+	file := "name,email,roles,password\nimported1,imported1@example.com,,imported1\nimported2,,admin,imported2\nuser2,,,user2\n"
+	rr := serveUserImport(userRouter, "POST", "/users/import?dryRun=true", accessToken, "text/csv", file)
+	expected := `{"dryRun":true,"total":3,"created":2,"errors":[{"row":3,"name":"user2","error":"User name already registered"}]}`
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusOK || body != expected {
+		t.Fatalf("unexpected dry run, got %v %s", rr.Code, body)
+	}
+	if _, err = services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "imported1"); err != user.ErrUserNotFound {
+		t.Errorf("expected the dry run not to add users, got %v", err)
+	}
+
+	rr = serveUserImport(userRouter, "POST", "/users/import", accessToken, "text/csv", file)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"created":2`) {
+		t.Fatalf("unexpected import, got %v %s", rr.Code, rr.Body.String())
+	}
+	imported, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "imported2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported.HasRole(user.AdminRole) || !services.UserService.IsPasswordValid(user.DefaultOrganizationId, "imported2", "imported2") {
+		t.Errorf("unexpected imported user, got %+v", imported)
+	}
+
+	importTests := []struct {
+		name        string
+		accessToken string
+		url         string
+		body        string
+		status      int
+	}{
+		{"no permission", createAccessToken(t, services, normalUser), "/users/import", `{"users": [{"name": "a", "password": "a"}]}`, http.StatusForbidden},
+		{"other organization", accessToken, "/users/import", `{"users": [{"name": "a", "password": "a", "organizationId": "other"}]}`, http.StatusForbidden},
+		{"super admin role", accessToken, "/users/import", `{"users": [{"name": "a", "password": "a", "roles": ["super-admin"]}]}`, http.StatusForbidden},
+		{"password hash", accessToken, "/users/import", `{"users": [{"name": "a", "passwordHash": "$2a$10$abcdefghijklmnopqrstuuDq0pAHWdqXq5XkrbZcPpXFd1Z1EDrD6"}]}`, http.StatusForbidden},
+		{"file", accessToken, "/users/import", `{"users": [{"name": "a", "isAdmin": true}]}`, http.StatusBadRequest},
+	}
+	for _, importTest := range importTests {
+		if rr = serveUserImport(userRouter, "POST", importTest.url, importTest.accessToken, "application/json", importTest.body); rr.Code != importTest.status {
+			t.Errorf("%s: expected status %v, got %v %s", importTest.name, importTest.status, rr.Code, rr.Body.String())
+		}
+	}
+
+	superAdmin := addSuperAdmin(t, services)
+	addSession(t, *services, superAdmin)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("hashed"), bcrypt.MinCost)
+	body := fmt.Sprintf(`{"users": [{"name": "hashed", "passwordHash": %q}]}`, hash)
+	rr = serveUserImport(userRouter, "POST", "/users/import", createAccessToken(t, services, superAdmin), "application/json", body)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"created":1`) {
+		t.Fatalf("expected the super administrators to import password hashes, got %v %s", rr.Code, rr.Body.String())
+	}
+	if !services.UserService.IsPasswordValid(user.DefaultOrganizationId, "hashed", "hashed") {
+		t.Error("expected the imported hash to be kept")
+	}
+}
+
+func TestUserRouterExportUsersHandler(t *testing.T) {
+	userRouter := createUserRouter()
+	services := userRouter.Services
+	adminUser, err := services.UserService.GetRepository().GetByName(user.DefaultOrganizationId, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSession(t, *services, adminUser)
+	accessToken := createAccessToken(t, services, adminUser)
+	superAdmin := addSuperAdmin(t, services)
+	addSession(t, *services, superAdmin)
+	otherAdmin := addOrganizationAdmin(t, services, "other")
+
+	rr := serveUserImport(userRouter, "GET", "/users/export", accessToken, "", "")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("unexpected export, got %v %s", rr.Code, rr.Body.String())
+	}
+	records, err := user.ReadUserRecords(rr.Body, user.FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Name != "admin" || records[0].PasswordHash != "" {
+		t.Errorf("expected the users of the organization without hashes, got %+v", records)
+	}
+
+	if rr = serveUserImport(userRouter, "GET", "/users/export?passwordHashes=true", accessToken, "", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("expected the hashes to be forbidden to administrators, got %v", rr.Code)
+	}
+	if rr = serveUserImport(userRouter, "GET", "/users/export?organizationId=other", accessToken, "", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("expected other organizations to be forbidden, got %v", rr.Code)
+	}
+
+	rr = serveUserImport(userRouter, "GET", "/users/export?format=json&passwordHashes=true", createAccessToken(t, services, superAdmin), "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected export, got %v %s", rr.Code, rr.Body.String())
+	}
+	records, err = user.ReadUserRecords(rr.Body, user.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2].Id != otherAdmin.Id || records[2].PasswordHash == "" {
+		t.Errorf("expected every user with the hashes, got %+v", records)
+	}
+}
@@ -0,0 +1,136 @@
+package router
+
+import (
+	response "authGo/router/response"
+	"authGo/user"
+	"authGo/validator"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// WebAuthnRouter lets the users register passkeys and security keys, see LoginRouter for the logins
+// with them.
+type WebAuthnRouter struct {
+	Services *validator.Services
+}
+
+func (a *WebAuthnRouter) RegistrationOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: a.Services}}
+	webAuthnV := validator.WebAuthnValidator{Validator: validator.Validator{Writer: w, Request: r, Services: a.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	u, err := a.Services.UserService.GetRepository().GetById(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	challengeToken, options, err := webAuthnV.CreateRegistrationOptions(u)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteWebAuthnCreationOptions(w, challengeToken, options)
+}
+
+func (a *WebAuthnRouter) RegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: a.Services}}
+	webAuthnV := validator.WebAuthnValidator{Validator: validator.Validator{Writer: w, Request: r, Services: a.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	registration, challenge, err := webAuthnV.GetRegistration(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrWebAuthnChallengeInvalid) {
+			response.WriteError(w, "Challenge token not valid")
+		} else {
+			response.WriteError(w, "Credential data not valid")
+		}
+		return
+	}
+
+	credential, err := webAuthnV.VerifyRegistration(registration, challenge)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, validator.ErrWebAuthnCredentialNotValid) {
+			response.WriteError(w, "Credential not valid")
+		} else if errors.Is(err, user.ErrWebAuthnCredentialAlreadyExists) {
+			response.WriteError(w, "Credential already registered")
+		} else if errors.Is(err, user.ErrWebAuthnNameNotValid) {
+			response.WriteError(w, "Credential name not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	codes, err := a.Services.UserService.EnsureRecoveryCodes(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteWebAuthnRegistration(w, credential, codes)
+}
+
+func (a *WebAuthnRouter) GetCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: a.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	credentials, err := a.Services.UserService.GetWebAuthnCredentials(payload.UserId)
+	if err != nil {
+		log.Print(err)
+		response.WriteGeneralError(w)
+		return
+	}
+
+	response.WriteWebAuthnCredentialList(w, credentials)
+}
+
+func (a *WebAuthnRouter) DeleteCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	tokenV := validator.AccessTokenValidator{Validator: validator.Validator{Writer: w, Request: r, Services: a.Services}}
+
+	payload, err := tokenV.ValidateAccessToken()
+	if err != nil {
+		log.Print(err)
+		response.WriteTokenError(w)
+		return
+	}
+
+	if err = a.Services.UserService.DeleteWebAuthnCredential(payload.UserId, mux.Vars(r)["id"]); err != nil {
+		log.Print(err)
+		if errors.Is(err, user.ErrWebAuthnCredentialNotFound) {
+			response.WriteError(w, "Credential id not valid")
+		} else {
+			response.WriteGeneralError(w)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,281 @@
+package router
+
+import (
+	"authGo/token"
+	"authGo/validator"
+	"authGo/webauthn"
+	"authGo/webauthn/webauthntest"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func createWebAuthnServices() *validator.Services {
+	services := createUserRouter().Services
+	services.WebAuthn = &webauthn.RelyingParty{ID: "localhost", Name: "authGo", Origins: []string{"http://localhost:4200"}, Timeout: time.Minute * 5}
+	services.WebAuthnChallengeTokenGenerator = &token.TokenGenerator[token.WebAuthnChallengePayload]{Password: []byte("webAuthnChallengeKey"), Duration: time.Minute * 5}
+	return services
+}
+
+func serveJSON(handler http.HandlerFunc, accessToken string, body any) *httptest.ResponseRecorder {
+	encoded, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(string(encoded)))
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+type testChallengeResponse struct {
+	ChallengeToken string `json:"challengeToken"`
+	PublicKey      struct {
+		Challenge        string `json:"challenge"`
+		AllowCredentials []struct {
+			ID string `json:"id"`
+		} `json:"allowCredentials"`
+		UserVerification string `json:"userVerification"`
+	} `json:"publicKey"`
+}
+
+func decodeChallenge(t *testing.T, rr *httptest.ResponseRecorder) testChallengeResponse {
+	var challenge testChallengeResponse
+	if err := json.NewDecoder(rr.Body).Decode(&challenge); err != nil || rr.Code != http.StatusOK || challenge.ChallengeToken == "" {
+		t.Fatalf("expected a challenge, got %v %v", rr.Code, err)
+	}
+	return challenge
+}
+
+func registerTestPasskey(t *testing.T, webAuthnRouter *WebAuthnRouter, accessToken string, authenticator *webauthntest.Authenticator) {
+	challenge := decodeChallenge(t, serveJSON(webAuthnRouter.RegistrationOptionsHandler, accessToken, nil))
+	clientData, attestationObject := authenticator.Create(challenge.PublicKey.Challenge)
+	rr := serveJSON(webAuthnRouter.RegistrationHandler, accessToken, map[string]any{
+		"challengeToken": challenge.ChallengeToken,
+		"name":           "laptop",
+		"credential": map[string]any{
+			"id":       webauthntest.Base64(authenticator.CredentialId),
+			"rawId":    webauthntest.Base64(authenticator.CredentialId),
+			"type":     "public-key",
+			"response": map[string]any{"clientDataJSON": webauthntest.Base64(clientData), "attestationObject": webauthntest.Base64(attestationObject), "transports": []string{"internal"}},
+		},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the registration, got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func assertionBody(challenge testChallengeResponse, authenticator *webauthntest.Authenticator, userHandle string) map[string]any {
+	clientData, authData, signature := authenticator.Get(challenge.PublicKey.Challenge)
+	return map[string]any{
+		"challengeToken": challenge.ChallengeToken,
+		"credential": map[string]any{
+			"id":    webauthntest.Base64(authenticator.CredentialId),
+			"rawId": webauthntest.Base64(authenticator.CredentialId),
+			"type":  "public-key",
+			"response": map[string]any{
+				"clientDataJSON":    webauthntest.Base64(clientData),
+				"authenticatorData": webauthntest.Base64(authData),
+				"signature":         webauthntest.Base64(signature),
+				"userHandle":        webauthntest.Base64([]byte(userHandle)),
+			},
+		},
+	}
+}
+
+func TestWebAuthnRouterRegistration(t *testing.T) {
+	services := createWebAuthnServices()
+	webAuthnRouter := &WebAuthnRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	otherUser := addUserAndSession(t, *services, "user3", "user3", false)
+	accessToken := createAccessToken(t, services, normalUser)
+	authenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+
+	if rr := serveJSON(webAuthnRouter.RegistrationOptionsHandler, "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected the access token to be required, got %v", rr.Code)
+	}
+	challenge := decodeChallenge(t, serveJSON(webAuthnRouter.RegistrationOptionsHandler, createAccessToken(t, services, otherUser), nil))
+	clientData, attestationObject := authenticator.Create(challenge.PublicKey.Challenge)
+	rr := serveJSON(webAuthnRouter.RegistrationHandler, accessToken, map[string]any{
+		"challengeToken": challenge.ChallengeToken,
+		"credential": map[string]any{
+			"response": map[string]any{"clientDataJSON": webauthntest.Base64(clientData), "attestationObject": webauthntest.Base64(attestationObject)},
+		},
+	})
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"error":"Challenge token not valid"}` {
+		t.Errorf("expected the challenge of other users to be rejected, got %s", body)
+	}
+
+	registerTestPasskey(t, webAuthnRouter, accessToken, authenticator)
+
+	req, _ := http.NewRequest("GET", "/users/me/webauthn/credentials", nil)
+	req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(webAuthnRouter.GetCredentialsHandler).ServeHTTP(rr, req)
+	var list struct {
+		Credentials []struct {
+			Id   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"credentials"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil || len(list.Credentials) != 1 || list.Credentials[0].Name != "laptop" {
+		t.Fatalf("expected the registered credential, got %+v %v", list, err)
+	}
+
+	deleteCredential := func(accessToken string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("DELETE", "/users/me/webauthn/credentials/"+list.Credentials[0].Id, nil)
+		req.Header.Set("Cookie", fmt.Sprintf("accessToken=%s", accessToken))
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/users/me/webauthn/credentials/{id}", webAuthnRouter.DeleteCredentialHandler)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr = deleteCredential(createAccessToken(t, services, otherUser)); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected the credentials of other users not to be deleted, got %v", rr.Code)
+	}
+	if rr = deleteCredential(accessToken); rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestWebAuthnLoginSecondFactor(t *testing.T) {
+	services := createWebAuthnServices()
+	webAuthnRouter := &WebAuthnRouter{Services: services}
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	authenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	registerTestPasskey(t, webAuthnRouter, createAccessToken(t, services, normalUser), authenticator)
+
+	var mfaChallenge struct {
+		ChallengeToken string   `json:"challengeToken"`
+		SecondFactors  []string `json:"secondFactors"`
+	}
+	rr := serveLogin(services, "user2", "user2")
+	if err := json.NewDecoder(rr.Body).Decode(&mfaChallenge); err != nil || len(mfaChallenge.SecondFactors) != 2 || mfaChallenge.SecondFactors[0] != validator.SecondFactorWebAuthn || mfaChallenge.SecondFactors[1] != validator.SecondFactorRecoveryCode {
+		t.Fatalf("expected the webauthn second factor, got %+v %v", mfaChallenge, err)
+	}
+
+	challenge := decodeChallenge(t, serveJSON(loginRouter.WebAuthnOptionsHandler, "", map[string]string{"challengeToken": mfaChallenge.ChallengeToken}))
+	if len(challenge.PublicKey.AllowCredentials) != 1 || challenge.PublicKey.AllowCredentials[0].ID != webauthntest.Base64(authenticator.CredentialId) {
+		t.Errorf("expected the credentials of the user to be allowed, got %+v", challenge.PublicKey)
+	}
+
+	otherAuthenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	rr = serveJSON(loginRouter.WebAuthnHandler, "", assertionBody(challenge, otherAuthenticator, ""))
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"error":"Credential not valid"}` {
+		t.Errorf("expected an unknown credential to be rejected, got %s", body)
+	}
+
+	// Every challenge is answered once, a rejected assertion needs a new one.
+	rr = serveJSON(loginRouter.WebAuthnHandler, "", assertionBody(challenge, authenticator, normalUser.Id))
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"error":"Challenge token not valid"}` {
+		t.Errorf("expected a used challenge to be rejected, got %s", body)
+	}
+	challenge = decodeChallenge(t, serveJSON(loginRouter.WebAuthnOptionsHandler, "", map[string]string{"challengeToken": mfaChallenge.ChallengeToken}))
+
+	// The user verification is not required for a second factor.
+	authenticator.UserVerified = false
+	body := assertionBody(challenge, authenticator, normalUser.Id)
+	if rr = serveJSON(loginRouter.WebAuthnHandler, "", body); rr.Code != http.StatusOK {
+		t.Fatalf("expected the login, got %v %s", rr.Code, rr.Body.String())
+	}
+	if cookies := rr.Header().Values("Set-Cookie"); len(cookies) != 2 {
+		t.Errorf("expected the session cookies, got %v", cookies)
+	}
+	if rr = serveJSON(loginRouter.WebAuthnHandler, "", body); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a replayed assertion to be rejected, got %v", rr.Code)
+	}
+}
+
+func TestWebAuthnLoginPasswordless(t *testing.T) {
+	services := createWebAuthnServices()
+	webAuthnRouter := &WebAuthnRouter{Services: services}
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	authenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	registerTestPasskey(t, webAuthnRouter, createAccessToken(t, services, normalUser), authenticator)
+
+	if rr := serveJSON(loginRouter.WebAuthnOptionsHandler, "", map[string]string{"challengeToken": "aa.bb.cc"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid MFA challenge token to be rejected, got %v", rr.Code)
+	}
+	newChallenge := func() testChallengeResponse {
+		return decodeChallenge(t, serveJSON(loginRouter.WebAuthnOptionsHandler, "", map[string]string{}))
+	}
+	challenge := newChallenge()
+	if len(challenge.PublicKey.AllowCredentials) != 0 || challenge.PublicKey.UserVerification != "required" {
+		t.Errorf("expected any discoverable credential with user verification, got %+v", challenge.PublicKey)
+	}
+
+	authenticator.UserVerified = false
+	if rr := serveJSON(loginRouter.WebAuthnHandler, "", assertionBody(challenge, authenticator, normalUser.Id)); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected the user verification to be required, got %v", rr.Code)
+	}
+	authenticator.UserVerified = true
+	if rr := serveJSON(loginRouter.WebAuthnHandler, "", assertionBody(newChallenge(), authenticator, "other")); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a user handle of another user to be rejected, got %v", rr.Code)
+	}
+	rr := serveJSON(loginRouter.WebAuthnHandler, "", assertionBody(newChallenge(), authenticator, normalUser.Id))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), normalUser.Id) {
+		t.Errorf("expected the login of the user of the credential, got %v %s", rr.Code, rr.Body.String())
+	}
+	sessions, err := services.SessionsHandler.GetUserSessions(normalUser.Id)
+	if err != nil || len(sessions) != 2 {
+		t.Errorf("expected a new session, got %v %v", sessions, err)
+	}
+}
+
+func TestWebAuthnLoginReplay(t *testing.T) {
+	services := createWebAuthnServices()
+	webAuthnRouter := &WebAuthnRouter{Services: services}
+	loginRouter := &LoginRouter{Services: services}
+	normalUser := addUserAndSession(t, *services, "user2", "user2", false)
+	authenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	registerTestPasskey(t, webAuthnRouter, createAccessToken(t, services, normalUser), authenticator)
+
+	challenge := decodeChallenge(t, serveJSON(loginRouter.WebAuthnOptionsHandler, "", map[string]string{}))
+	body := assertionBody(challenge, authenticator, normalUser.Id)
+	if rr := serveJSON(loginRouter.WebAuthnHandler, "", body); rr.Code != http.StatusOK {
+		t.Fatalf("expected the login, got %v %s", rr.Code, rr.Body.String())
+	}
+
+	// The passkeys without a counter always keep a sign count of 0, the sign count can't stop the replay.
+	if err := services.UserService.UpdateWebAuthnSignCount(webauthntest.Base64(authenticator.CredentialId), 0); err != nil {
+		t.Fatal(err)
+	}
+	rr := serveJSON(loginRouter.WebAuthnHandler, "", body)
+	if body := strings.TrimSpace(rr.Body.String()); rr.Code != http.StatusBadRequest || body != `{"error":"Challenge token not valid"}` {
+		t.Errorf("expected a replayed assertion to be rejected, got %v %s", rr.Code, body)
+	}
+
+	accessToken := createAccessToken(t, services, normalUser)
+	registration := decodeChallenge(t, serveJSON(webAuthnRouter.RegistrationOptionsHandler, accessToken, nil))
+	other := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	clientData, attestationObject := other.Create(registration.PublicKey.Challenge)
+	registrationBody := map[string]any{
+		"challengeToken": registration.ChallengeToken,
+		"name":           "phone",
+		"credential": map[string]any{
+			"id":       webauthntest.Base64(other.CredentialId),
+			"rawId":    webauthntest.Base64(other.CredentialId),
+			"type":     "public-key",
+			"response": map[string]any{"clientDataJSON": webauthntest.Base64(clientData), "attestationObject": webauthntest.Base64(attestationObject)},
+		},
+	}
+	if rr = serveJSON(webAuthnRouter.RegistrationHandler, accessToken, registrationBody); rr.Code != http.StatusOK {
+		t.Fatalf("expected the registration, got %v %s", rr.Code, rr.Body.String())
+	}
+	if err := services.UserService.DeleteWebAuthnCredential(normalUser.Id, webauthntest.Base64(other.CredentialId)); err != nil {
+		t.Fatal(err)
+	}
+	if rr = serveJSON(webAuthnRouter.RegistrationHandler, accessToken, registrationBody); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a replayed registration to be rejected, got %v %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"authGo/token"
+	"sync"
+)
+
+type MemoryStore struct {
+	mutex    sync.RWMutex
+	sessions []*Session
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make([]*Session, 0)}
+}
+
+func (m *MemoryStore) Get(userToken token.RefreshTokenPayload) (*Session, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	session, _ := m.getSession(userToken)
+	if session == nil {
+		return nil, ErrUserTokenNotFound
+	}
+	return session, nil
+}
+
+func (m *MemoryStore) GetById(id string) (*Session, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, session := range m.sessions {
+		if session.Id == id {
+			return session, nil
+		}
+	}
+	return nil, ErrUserTokenNotFound
+}
+
+func (m *MemoryStore) GetAll() ([]*Session, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	sessions := make([]*Session, len(m.sessions))
+	copy(sessions, m.sessions)
+	return sessions, nil
+}
+
+func (m *MemoryStore) GetByUserId(userId string) ([]*Session, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	sessions := make([]*Session, 0)
+	for _, session := range m.sessions {
+		if session.UserToken.UserId == userId {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *MemoryStore) Add(session *Session) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if existing, _ := m.getSession(session.UserToken); existing != nil {
+		return ErrSessionAlreadyExists
+	}
+	m.sessions = append(m.sessions, session)
+	return nil
+}
+
+func (m *MemoryStore) Update(session *Session) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, i := m.getSession(session.UserToken)
+	if i == -1 {
+		return ErrUserTokenNotFound
+	}
+	m.sessions[i] = session
+	return nil
+}
+
+func (m *MemoryStore) Delete(session *Session) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, i := m.getSession(session.UserToken)
+	if i == -1 {
+		return ErrUserTokenNotFound
+	}
+	lastIndex := len(m.sessions) - 1
+	m.sessions[i] = m.sessions[lastIndex]
+	m.sessions[lastIndex] = nil
+	m.sessions = m.sessions[:lastIndex]
+	return nil
+}
+
+func (m *MemoryStore) getSession(userToken token.RefreshTokenPayload) (*Session, int) {
+	for i, session := range m.sessions {
+		if userToken.UserId == session.UserToken.UserId && userToken.IssuedAtTime.Equal(session.UserToken.IssuedAtTime) {
+			return session, i
+		}
+	}
+	return nil, -1
+}
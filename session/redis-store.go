@@ -0,0 +1,241 @@
+package session
+
+import (
+	"authGo/token"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultRedisKeyPrefix = "authGo:"
+	revocationSeparator   = "|"
+)
+
+var (
+	ErrRedisStore = errors.New("redis session store: request failed")
+)
+
+// RedisStore keeps the sessions in any server speaking the Redis protocol so that several instances
+// can share them. Every session expires together with its refresh token.
+type RedisStore struct {
+	client     redis.UniversalClient
+	ttl        time.Duration
+	prefix     string
+	instanceId string
+}
+
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		client:     client,
+		ttl:        ttl,
+		prefix:     DefaultRedisKeyPrefix,
+		instanceId: strings.ReplaceAll(uuid.NewString(), "-", ""),
+	}
+}
+
+func (r *RedisStore) Get(userToken token.RefreshTokenPayload) (*Session, error) {
+	ctx := context.Background()
+	id, err := r.client.Get(ctx, r.tokenKey(userToken)).Result()
+	if err == redis.Nil {
+		return nil, ErrUserTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	return r.GetById(id)
+}
+
+func (r *RedisStore) GetById(id string) (*Session, error) {
+	sessionJson, err := r.client.Get(context.Background(), r.sessionKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrUserTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	session := &Session{}
+	if err = json.Unmarshal(sessionJson, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *RedisStore) GetAll() ([]*Session, error) {
+	return r.getSessions(r.allKey())
+}
+
+func (r *RedisStore) GetByUserId(userId string) ([]*Session, error) {
+	return r.getSessions(r.userKey(userId))
+}
+
+func (r *RedisStore) Add(session *Session) error {
+	ctx := context.Background()
+	sessionJson, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	ttl := r.remainingTTL(session)
+	tokenKey := r.tokenKey(session.UserToken)
+	// the token key is watched so that the session is written at once and only when no other one has
+	// the same token
+	err = r.client.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, tokenKey).Result()
+		if err != nil {
+			return err
+		}
+		if exists != 0 {
+			return ErrSessionAlreadyExists
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, tokenKey, session.Id, ttl)
+			pipe.Set(ctx, r.sessionKey(session.Id), sessionJson, ttl)
+			pipe.SAdd(ctx, r.userKey(session.UserToken.UserId), session.Id)
+			pipe.SAdd(ctx, r.allKey(), session.Id)
+			return nil
+		})
+		return err
+	}, tokenKey)
+	if err == ErrSessionAlreadyExists || err == redis.TxFailedErr {
+		return ErrSessionAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	return nil
+}
+
+func (r *RedisStore) Update(session *Session) error {
+	sessionJson, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	updated, err := r.client.SetXX(context.Background(), r.sessionKey(session.Id), sessionJson, redis.KeepTTL).Result()
+	if err != nil {
+		return fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	if !updated {
+		return ErrUserTokenNotFound
+	}
+	return nil
+}
+
+func (r *RedisStore) Delete(session *Session) error {
+	ctx := context.Background()
+	var deleted *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, r.sessionKey(session.Id))
+		pipe.Del(ctx, r.tokenKey(session.UserToken))
+		pipe.SRem(ctx, r.userKey(session.UserToken.UserId), session.Id)
+		pipe.SRem(ctx, r.allKey(), session.Id)
+		pipe.Publish(ctx, r.revocationsChannel(), r.instanceId+revocationSeparator+session.Id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	if deleted.Val() == 0 {
+		return ErrUserTokenNotFound
+	}
+	return nil
+}
+
+// SubscribeRevocations blocks until the context is done, calling the listener with the id of every
+// session revoked by another instance.
+func (r *RedisStore) SubscribeRevocations(ctx context.Context, listener func(sessionId string)) error {
+	pubsub := r.client.Subscribe(ctx, r.revocationsChannel())
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			instanceId, sessionId, found := strings.Cut(message.Payload, revocationSeparator)
+			if found && instanceId != r.instanceId {
+				listener(sessionId)
+			}
+		}
+	}
+}
+
+// getSessions loads every session whose id is in the given set, the ids of the expired sessions
+// are removed from the set.
+func (r *RedisStore) getSessions(setKey string) ([]*Session, error) {
+	ctx := context.Background()
+	ids, err := r.client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	sessions := make([]*Session, 0, len(ids))
+	if len(ids) == 0 {
+		return sessions, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.sessionKey(id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrRedisStore, err)
+	}
+	expired := make([]interface{}, 0)
+	for i, value := range values {
+		sessionJson, ok := value.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		session := &Session{}
+		if err = json.Unmarshal([]byte(sessionJson), session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		if err = r.client.SRem(ctx, setKey, expired...).Err(); err != nil {
+			return nil, fmt.Errorf("%w, %s", ErrRedisStore, err)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *RedisStore) remainingTTL(session *Session) time.Duration {
+	ttl := time.Until(session.UserToken.IssuedAtTime.Add(r.ttl))
+	if ttl < time.Second {
+		return time.Second
+	}
+	return ttl
+}
+
+func (r *RedisStore) sessionKey(id string) string {
+	return r.prefix + "session:" + id
+}
+
+func (r *RedisStore) tokenKey(userToken token.RefreshTokenPayload) string {
+	return fmt.Sprintf("%stoken:%s:%d", r.prefix, userToken.UserId, userToken.IssuedAtTime.UnixNano())
+}
+
+func (r *RedisStore) userKey(userId string) string {
+	return r.prefix + "user:" + userId
+}
+
+func (r *RedisStore) allKey() string {
+	return r.prefix + "sessions"
+}
+
+func (r *RedisStore) revocationsChannel() string {
+	return r.prefix + "revocations"
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"authGo/token"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStoreExpiration(t *testing.T) {
+	store, server := createTestRedisStore(t)
+	sessionHandler := NewSessionHandlerWithStore(store)
+
+	issuedTime := time.Now().Add(-time.Hour * 24 * 364)
+	err := addTestSession(sessionHandler, "user1", issuedTime)
+	if err != nil {
+		t.Fatalf("error adding session, %s", err)
+	}
+	err = addTestSession(sessionHandler, "user2", time.Now())
+	if err != nil {
+		t.Fatalf("error adding session, %s", err)
+	}
+
+	ttl := server.TTL(store.sessionKey(mustGetSession(t, sessionHandler, "user1", issuedTime).Id))
+	if ttl <= 0 || ttl > time.Hour*24 {
+		t.Errorf("expected the session to expire within a day, got %s", ttl)
+	}
+
+	server.FastForward(time.Hour * 25)
+
+	_, err = sessionHandler.GetSession(token.RefreshTokenPayload{UserId: "user1", IssuedAtTime: issuedTime})
+	if err != ErrUserTokenNotFound {
+		t.Errorf("expected err to be ErrUserTokenNotFound, got %s", err)
+	}
+	sessions, err := sessionHandler.GetAllSessions()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if len(sessions) != 1 {
+		t.Errorf("expected sessions len to be 1, got %v", sessions)
+	}
+	members, err := server.Members(store.allKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 {
+		t.Errorf("expected the expired session id to be removed, got %v", members)
+	}
+}
+
+func TestRedisStoreSharedBetweenInstances(t *testing.T) {
+	store1, server := createTestRedisStore(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store2 := NewRedisStore(client, time.Hour*24*365)
+	instance1 := NewSessionHandlerWithStore(store1)
+	instance2 := NewSessionHandlerWithStore(store2)
+
+	revoked := make(chan string, 1)
+	instance2.OnRevoked(func(sessionId string) {
+		revoked <- sessionId
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listening := make(chan error, 1)
+	go func() {
+		listening <- instance2.ListenRevocations(ctx)
+	}()
+
+	issuedTime := time.Now()
+	err := addTestSession(instance1, "user1", issuedTime)
+	if err != nil {
+		t.Fatalf("error adding session, %s", err)
+	}
+	session := mustGetSession(t, instance2, "user1", issuedTime)
+
+	channel := store2.revocationsChannel()
+	for i := 0; i < 100 && server.PubSubNumSub(channel)[channel] == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	err = instance1.DeleteSession(session.UserToken)
+	if err != nil {
+		t.Fatalf("error deleting session, %s", err)
+	}
+
+	select {
+	case sessionId := <-revoked:
+		if sessionId != session.Id {
+			t.Errorf("expected revoked session to be %s, got %s", session.Id, sessionId)
+		}
+	case <-time.After(time.Second * 2):
+		t.Error("revocation was not received by the other instance")
+	}
+	if _, err = instance2.GetSessionById(session.Id); err != ErrUserTokenNotFound {
+		t.Errorf("expected err to be ErrUserTokenNotFound, got %s", err)
+	}
+
+	cancel()
+	if err = <-listening; err != nil {
+		t.Errorf("expected err to be nil, got %s", err)
+	}
+}
+
+func mustGetSession(t *testing.T, sessionHandler *SessionsHandler, userId string, issuedTime time.Time) *Session {
+	session, err := sessionHandler.GetSession(token.RefreshTokenPayload{UserId: userId, IssuedAtTime: issuedTime})
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	return session
+}
@@ -2,8 +2,10 @@ package session
 
 import (
 	"authGo/token"
+	"context"
 	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,68 +18,106 @@ var (
 )
 
 type SessionsHandler struct {
-	sessions []*Session
+	store     SessionStore
+	mutex     sync.RWMutex
+	listeners []func(sessionId string)
 }
 
 func NewSessionHandler() *SessionsHandler {
-	return &SessionsHandler{sessions: make([]*Session, 0)}
+	return NewSessionHandlerWithStore(NewMemoryStore())
 }
 
-func (s *SessionsHandler) GetSession(userToken token.RefreshTokenPayload) (*Session, int, error) {
-	for i, session := range s.sessions {
-		if userToken.UserId == session.UserToken.UserId && userToken.IssuedAtTime.Equal(session.UserToken.IssuedAtTime) {
-			return session, i, nil
-		}
-	}
-	return nil, -1, ErrUserTokenNotFound
+func NewSessionHandlerWithStore(store SessionStore) *SessionsHandler {
+	return &SessionsHandler{store: store}
+}
+
+func (s *SessionsHandler) GetSession(userToken token.RefreshTokenPayload) (*Session, error) {
+	return s.store.Get(userToken)
 }
 
 func (s *SessionsHandler) GetSessionById(id string) (*Session, error) {
-	for _, session := range s.sessions {
-		if session.Id == id {
-			return session, nil
-		}
-	}
-	return nil, ErrUserTokenNotFound
+	return s.store.GetById(id)
 }
 
-func (s *SessionsHandler) GetAllSessions() []*Session {
-	return s.sessions
+func (s *SessionsHandler) GetAllSessions() ([]*Session, error) {
+	return s.store.GetAll()
 }
 
 func (s *SessionsHandler) AddNewSession(userToken token.RefreshTokenPayload, deviceData DeviceData) error {
-	if _, i, _ := s.GetSession(userToken); i != -1 {
-		return ErrSessionAlreadyExists
-	}
 	id := strings.ReplaceAll(uuid.NewString(), "-", "")
-	s.sessions = append(s.sessions, &Session{
+	return s.store.Add(&Session{
 		Id: id, UserToken: userToken, DeviceData: deviceData, LastUpdate: userToken.IssuedAtTime,
 	})
-	return nil
 }
 
-func (s *SessionsHandler) GetUserSessions(userId string) []*Session {
-	sessions := make([]*Session, 0)
-	for _, session := range s.sessions {
-		if session.UserToken.UserId == userId {
-			sessions = append(sessions, session)
-		}
-	}
-	return sessions
+func (s *SessionsHandler) GetUserSessions(userId string) ([]*Session, error) {
+	return s.store.GetByUserId(userId)
 }
 
 func (s *SessionsHandler) DeleteSession(userToken token.RefreshTokenPayload) error {
-	_, i, err := s.GetSession(userToken)
+	session, err := s.store.Get(userToken)
 	if err != nil {
 		return err
 	}
-	lastIndex := len(s.sessions) - 1
-	s.sessions[i] = s.sessions[lastIndex]
-	s.sessions[lastIndex] = nil
-	s.sessions = s.sessions[:lastIndex]
+	if err = s.store.Delete(session); err != nil {
+		return err
+	}
+	s.notifyRevoked(session.Id)
 	return nil
 }
 
-func (s *SessionsHandler) RefreshLastUpdate(session *Session) {
+// RevokeUserSessions deletes every session of the user except the one with the given id, which can
+// be empty to delete all of them.
+func (s *SessionsHandler) RevokeUserSessions(userId string, keepSessionId string) error {
+	sessions, err := s.store.GetByUserId(userId)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.Id == keepSessionId {
+			continue
+		}
+		if err = s.DeleteSession(session.UserToken); err != nil && err != ErrUserTokenNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SessionsHandler) RefreshLastUpdate(session *Session) error {
 	session.LastUpdate = time.Now()
+	return s.store.Update(session)
+}
+
+func (s *SessionsHandler) QuerySessions(query *SessionQuery) ([]*Session, string, error) {
+	sessions, err := s.store.GetAll()
+	if err != nil {
+		return nil, "", err
+	}
+	return query.Apply(sessions)
+}
+
+// OnRevoked registers a listener called every time a session is revoked. When the store is shared
+// between instances the listener is also called for the sessions revoked by the other instances
+// once ListenRevocations is running.
+func (s *SessionsHandler) OnRevoked(listener func(sessionId string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *SessionsHandler) ListenRevocations(ctx context.Context) error {
+	publisher, ok := s.store.(RevocationPublisher)
+	if !ok {
+		return nil
+	}
+	return publisher.SubscribeRevocations(ctx, s.notifyRevoked)
+}
+
+func (s *SessionsHandler) notifyRevoked(sessionId string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, listener := range s.listeners {
+		listener(sessionId)
+	}
 }
@@ -2,9 +2,11 @@ package session
 
 import (
 	"authGo/token"
-	"reflect"
 	"testing"
 	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 )
 
 func addTestSession(sessionHandler *SessionsHandler, userId string, issuedTime time.Time) error {
@@ -21,8 +23,28 @@ func addTestSession(sessionHandler *SessionsHandler, userId string, issuedTime t
 	return err
 }
 
-func createTestSessionHandler(t *testing.T, issuedTime time.Time) *SessionsHandler {
-	sessionHandler := NewSessionHandler()
+func createTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, time.Hour*24*365), server
+}
+
+// forEachStore runs the test against every SessionStore implementation.
+func forEachStore(t *testing.T, test func(t *testing.T, newStore func() SessionStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, func() SessionStore { return NewMemoryStore() })
+	})
+	t.Run("redis", func(t *testing.T) {
+		test(t, func() SessionStore {
+			store, _ := createTestRedisStore(t)
+			return store
+		})
+	})
+}
+
+func createTestSessionHandler(t *testing.T, store SessionStore, issuedTime time.Time) *SessionsHandler {
+	sessionHandler := NewSessionHandlerWithStore(store)
 	err := addTestSession(sessionHandler, "user1", issuedTime)
 	if err != nil {
 		t.Errorf("error adding session, %s", err)
@@ -41,140 +63,223 @@ func TestNewSessionHandler(t *testing.T) {
 }
 
 func TestGetSession(t *testing.T) {
-	now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
-	sessionHandler := createTestSessionHandler(t, now)
-	payload := token.RefreshTokenPayload{
-		UserId:       "user1",
-		IssuedAtTime: now,
-	}
-	session, id, err := sessionHandler.GetSession(payload)
-	if err != nil {
-		t.Errorf("expected err to be nil, got %s", err)
-	}
-	if session == nil {
-		t.Error("expected session to not be nil")
-	}
-	if id == -1 {
-		t.Error("expected session to not be -1")
-	}
-	if !reflect.DeepEqual(session.UserToken, payload) {
-		t.Errorf("wanted %v to be %v", session.UserToken, payload)
-	}
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+		sessionHandler := createTestSessionHandler(t, newStore(), time.Now())
+		err := addTestSession(sessionHandler, "user1", now)
+		if err != nil {
+			t.Fatalf("error adding session, %s", err)
+		}
+		payload := token.RefreshTokenPayload{
+			UserId:       "user1",
+			IssuedAtTime: now,
+		}
+		session, err := sessionHandler.GetSession(payload)
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if session == nil {
+			t.Fatal("expected session to not be nil")
+		}
+		if session.UserToken.UserId != payload.UserId || !session.UserToken.IssuedAtTime.Equal(payload.IssuedAtTime) {
+			t.Errorf("wanted %v to be %v", session.UserToken, payload)
+		}
 
-	payload2 := token.RefreshTokenPayload{
-		UserId:       "user3",
-		IssuedAtTime: now,
-	}
-	session, id, err = sessionHandler.GetSession(payload2)
-	if err != ErrUserTokenNotFound {
-		t.Errorf("expected err to be ErrUserTokenNotFound, got %s", err)
-	}
-	if session != nil {
-		t.Error("expected session to be nil")
-	}
-	if id != -1 {
-		t.Error("expected session to be -1")
-	}
+		payload2 := token.RefreshTokenPayload{
+			UserId:       "user3",
+			IssuedAtTime: now,
+		}
+		session, err = sessionHandler.GetSession(payload2)
+		if err != ErrUserTokenNotFound {
+			t.Errorf("expected err to be ErrUserTokenNotFound, got %s", err)
+		}
+		if session != nil {
+			t.Error("expected session to be nil")
+		}
+	})
 }
 
 func TestGetSessionById(t *testing.T) {
-	now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
-	sessionHandler := createTestSessionHandler(t, now)
-	sessionId := sessionHandler.sessions[0].Id
-	session, err := sessionHandler.GetSessionById(sessionId)
-	if err != nil {
-		t.Errorf("expected err to be nil, got %s", err)
-	}
-	if session == nil {
-		t.Error("expected session to not be nil")
-	}
-	session, err = sessionHandler.GetSessionById("12345678")
-	if err != ErrUserTokenNotFound {
-		t.Errorf("expected err to be ErrUserTokenNotFound, got %s", err)
-	}
-	if session != nil {
-		t.Error("expected session to be nil")
-	}
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		sessionHandler := createTestSessionHandler(t, newStore(), time.Now())
+		sessions, err := sessionHandler.GetUserSessions("user1")
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		session, err := sessionHandler.GetSessionById(sessions[0].Id)
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if session == nil {
+			t.Error("expected session to not be nil")
+		}
+		session, err = sessionHandler.GetSessionById("12345678")
+		if err != ErrUserTokenNotFound {
+			t.Errorf("expected err to be ErrUserTokenNotFound, got %s", err)
+		}
+		if session != nil {
+			t.Error("expected session to be nil")
+		}
+	})
 }
 
 func TestAddNewSession(t *testing.T) {
-	now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
-	sessionHandler := createTestSessionHandler(t, now)
-	err := addTestSession(sessionHandler, "user1", now)
-	if err != ErrSessionAlreadyExists {
-		t.Errorf("expected error to be ErrSessionAlreadyExists, got: %s", err)
-	}
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		now := time.Now()
+		sessionHandler := createTestSessionHandler(t, newStore(), now)
+		err := addTestSession(sessionHandler, "user1", now)
+		if err != ErrSessionAlreadyExists {
+			t.Errorf("expected error to be ErrSessionAlreadyExists, got: %s", err)
+		}
+		if sessions, _ := sessionHandler.GetUserSessions("user1"); len(sessions) != 1 {
+			t.Errorf("expected the refused session not to be stored, got %v", sessions)
+		}
+	})
 }
 
 func TestGetUserSessions(t *testing.T) {
-	sessionHandler := createTestSessionHandler(t, time.Now())
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		sessionHandler := createTestSessionHandler(t, newStore(), time.Now())
 
-	user1Sessions := sessionHandler.GetUserSessions("user1")
-	if len(user1Sessions) != 1 {
-		t.Errorf("expected user1Sessions len to be 1, got %d", len(user1Sessions))
-	}
+		user1Sessions, err := sessionHandler.GetUserSessions("user1")
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if len(user1Sessions) != 1 {
+			t.Errorf("expected user1Sessions len to be 1, got %d", len(user1Sessions))
+		}
 
-	time.Sleep(1 * time.Millisecond)
+		time.Sleep(1 * time.Millisecond)
 
-	err := addTestSession(sessionHandler, "user1", time.Now())
-	if err != nil {
-		t.Errorf("error adding session, %s", err)
-	}
+		err = addTestSession(sessionHandler, "user1", time.Now())
+		if err != nil {
+			t.Errorf("error adding session, %s", err)
+		}
 
-	user1Sessions = sessionHandler.GetUserSessions("user1")
-	if len(user1Sessions) != 2 {
-		t.Errorf("expected user1Sessions len to be 2, got %d", len(user1Sessions))
-	}
+		user1Sessions, err = sessionHandler.GetUserSessions("user1")
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if len(user1Sessions) != 2 {
+			t.Errorf("expected user1Sessions len to be 2, got %d", len(user1Sessions))
+		}
+	})
 }
 
 func TestDeleteSession(t *testing.T) {
-	sessionHandler := createTestSessionHandler(t, time.Now())
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		sessionHandler := createTestSessionHandler(t, newStore(), time.Now())
+		var revoked []string
+		sessionHandler.OnRevoked(func(sessionId string) {
+			revoked = append(revoked, sessionId)
+		})
 
-	time.Sleep(1 * time.Millisecond)
-	err := addTestSession(sessionHandler, "user1", time.Now())
-	if err != nil {
-		t.Errorf("error adding session, %s", err)
-	}
+		time.Sleep(1 * time.Millisecond)
+		err := addTestSession(sessionHandler, "user1", time.Now())
+		if err != nil {
+			t.Errorf("error adding session, %s", err)
+		}
 
-	user1Sessions := sessionHandler.GetUserSessions("user1")
-	if len(user1Sessions) != 2 {
-		t.Errorf("expected user1Sessions len to be 2, got %d", len(user1Sessions))
-	}
+		user1Sessions, err := sessionHandler.GetUserSessions("user1")
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if len(user1Sessions) != 2 {
+			t.Errorf("expected user1Sessions len to be 2, got %d", len(user1Sessions))
+		}
 
-	err = sessionHandler.DeleteSession(user1Sessions[0].UserToken)
-	if err != nil {
-		t.Errorf("error deleting session, %s", err)
-	}
-	user1Sessions = sessionHandler.GetUserSessions("user1")
-	if len(user1Sessions) != 1 {
-		t.Errorf("expected user1Sessions len to be 1, got %d", len(user1Sessions))
-	}
+		err = sessionHandler.DeleteSession(user1Sessions[0].UserToken)
+		if err != nil {
+			t.Errorf("error deleting session, %s", err)
+		}
+		user1Sessions, err = sessionHandler.GetUserSessions("user1")
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if len(user1Sessions) != 1 {
+			t.Errorf("expected user1Sessions len to be 1, got %d", len(user1Sessions))
+		}
+		if len(revoked) != 1 {
+			t.Errorf("expected the revocation listener to be called once, got %v", revoked)
+		}
 
-	err = sessionHandler.DeleteSession(token.RefreshTokenPayload{UserId: "user3", IssuedAtTime: time.Now()})
-	if err != ErrUserTokenNotFound {
-		t.Errorf("expected error to be ErrUserTokenNotFound, got: %s", err)
-	}
+		err = sessionHandler.DeleteSession(token.RefreshTokenPayload{UserId: "user3", IssuedAtTime: time.Now()})
+		if err != ErrUserTokenNotFound {
+			t.Errorf("expected error to be ErrUserTokenNotFound, got: %s", err)
+		}
+	})
 }
 
 func TestRefreshLastUpdate(t *testing.T) {
-	now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
-	sessionHandler := createTestSessionHandler(t, now)
-	payload := token.RefreshTokenPayload{
-		UserId:       "user1",
-		IssuedAtTime: now,
-	}
-	session, _, _ := sessionHandler.GetSession(payload)
-	sessionHandler.RefreshLastUpdate(session)
-	if session.LastUpdate == now {
-		t.Error("LastUpdate was not updated correctly")
-	}
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		now := time.Now()
+		sessionHandler := createTestSessionHandler(t, newStore(), now)
+		payload := token.RefreshTokenPayload{
+			UserId:       "user1",
+			IssuedAtTime: now,
+		}
+		session, _ := sessionHandler.GetSession(payload)
+		time.Sleep(1 * time.Millisecond)
+		err := sessionHandler.RefreshLastUpdate(session)
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		session, _ = sessionHandler.GetSession(payload)
+		if session.LastUpdate.Equal(now) {
+			t.Error("LastUpdate was not updated correctly")
+		}
+	})
 }
 
 func TestAllSessions(t *testing.T) {
-	sessionHandler := createTestSessionHandler(t, time.Now())
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		sessionHandler := createTestSessionHandler(t, newStore(), time.Now())
 
-	sessions := sessionHandler.GetAllSessions()
-	if !reflect.DeepEqual(sessions, sessionHandler.sessions) {
-		t.Errorf("expected %v to be %v", sessions, sessionHandler.sessions)
-	}
+		sessions, err := sessionHandler.GetAllSessions()
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if len(sessions) != 2 {
+			t.Errorf("expected sessions len to be 2, got %v", sessions)
+		}
+	})
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() SessionStore) {
+		sessionHandler := createTestSessionHandler(t, newStore(), time.Now())
+		for i := 0; i < 2; i++ {
+			time.Sleep(1 * time.Millisecond)
+			if err := addTestSession(sessionHandler, "user1", time.Now()); err != nil {
+				t.Fatalf("error adding session, %s", err)
+			}
+		}
+		user1Sessions, err := sessionHandler.GetUserSessions("user1")
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		keep := user1Sessions[1].Id
+
+		err = sessionHandler.RevokeUserSessions("user1", keep)
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		user1Sessions, _ = sessionHandler.GetUserSessions("user1")
+		if len(user1Sessions) != 1 || user1Sessions[0].Id != keep {
+			t.Errorf("expected only session %s to be kept, got %v", keep, user1Sessions)
+		}
+
+		err = sessionHandler.RevokeUserSessions("user1", "")
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		user1Sessions, _ = sessionHandler.GetUserSessions("user1")
+		if len(user1Sessions) != 0 {
+			t.Errorf("expected every session to be revoked, got %v", user1Sessions)
+		}
+		user2Sessions, _ := sessionHandler.GetUserSessions("user2")
+		if len(user2Sessions) != 1 {
+			t.Errorf("expected the other users to keep their sessions, got %v", user2Sessions)
+		}
+	})
 }
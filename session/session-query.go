@@ -0,0 +1,180 @@
+package session
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SortByLastUpdate = "lastUpdate"
+	SortByCreatedAt  = "createdAt"
+	SortByUserId     = "userId"
+	SortByIpAddress  = "ipAddress"
+
+	DefaultQueryLimit = 50
+	MaxQueryLimit     = 200
+)
+
+var (
+	ErrInvalidSortField = errors.New("session query: invalid sort field")
+	ErrInvalidCursor    = errors.New("session query: invalid cursor")
+)
+
+type SessionQuery struct {
+	OrganizationId string
+	UserId         string
+	IpAddress      string
+	UserAgent      string
+	LastUpdateFrom time.Time
+	LastUpdateTo   time.Time
+	SortBy         string
+	Descending     bool
+	Cursor         string
+	Limit          int
+}
+
+type sessionCursor struct {
+	SortBy     string `json:"sortBy"`
+	Descending bool   `json:"descending"`
+	Key        string `json:"key"`
+	Id         string `json:"id"`
+}
+
+func NewSessionQuery() *SessionQuery {
+	return &SessionQuery{SortBy: SortByLastUpdate, Descending: true, Limit: DefaultQueryLimit}
+}
+
+func IsValidSortField(field string) bool {
+	switch field {
+	case SortByLastUpdate, SortByCreatedAt, SortByUserId, SortByIpAddress:
+		return true
+	}
+	return false
+}
+
+func (q *SessionQuery) Matches(session *Session) bool {
+	if q.OrganizationId != "" && session.UserToken.OrganizationId != q.OrganizationId {
+		return false
+	}
+	if q.UserId != "" && session.UserToken.UserId != q.UserId {
+		return false
+	}
+	if q.IpAddress != "" && session.DeviceData.IpAddress != q.IpAddress {
+		return false
+	}
+	if q.UserAgent != "" && !strings.Contains(strings.ToLower(session.DeviceData.UserAgent), strings.ToLower(q.UserAgent)) {
+		return false
+	}
+	if !q.LastUpdateFrom.IsZero() && session.LastUpdate.Before(q.LastUpdateFrom) {
+		return false
+	}
+	if !q.LastUpdateTo.IsZero() && session.LastUpdate.After(q.LastUpdateTo) {
+		return false
+	}
+	return true
+}
+
+// Apply filters, sorts and paginates the given sessions, returning the requested page and the cursor
+// of the next one. The cursor is empty when there are no more sessions.
+func (q *SessionQuery) Apply(sessions []*Session) ([]*Session, string, error) {
+	if !IsValidSortField(q.SortBy) {
+		return nil, "", fmt.Errorf("%w, got %s", ErrInvalidSortField, q.SortBy)
+	}
+	var after *sessionCursor
+	if q.Cursor != "" {
+		cursor, err := q.decodeCursor()
+		if err != nil {
+			return nil, "", err
+		}
+		after = cursor
+	}
+
+	filtered := make([]*Session, 0)
+	for _, session := range sessions {
+		if q.Matches(session) {
+			filtered = append(filtered, session)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return q.less(filtered[i], filtered[j])
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return q.isAfter(filtered[i], after)
+		})
+	}
+	limit := q.Limit
+	if limit <= 0 || limit > MaxQueryLimit {
+		limit = DefaultQueryLimit
+	}
+	end := start + limit
+	if end >= len(filtered) {
+		return filtered[start:], "", nil
+	}
+	page := filtered[start:end]
+	return page, q.encodeCursor(page[len(page)-1]), nil
+}
+
+func (q *SessionQuery) sortKey(session *Session) string {
+	switch q.SortBy {
+	case SortByCreatedAt:
+		return timeKey(session.UserToken.IssuedAtTime)
+	case SortByUserId:
+		return session.UserToken.UserId
+	case SortByIpAddress:
+		return session.DeviceData.IpAddress
+	default:
+		return timeKey(session.LastUpdate)
+	}
+}
+
+func (q *SessionQuery) compare(key string, id string, otherKey string, otherId string) int {
+	c := strings.Compare(key, otherKey)
+	if c == 0 {
+		c = strings.Compare(id, otherId)
+	}
+	if q.Descending {
+		return -c
+	}
+	return c
+}
+
+func (q *SessionQuery) less(a *Session, b *Session) bool {
+	return q.compare(q.sortKey(a), a.Id, q.sortKey(b), b.Id) < 0
+}
+
+func (q *SessionQuery) isAfter(session *Session, cursor *sessionCursor) bool {
+	return q.compare(q.sortKey(session), session.Id, cursor.Key, cursor.Id) > 0
+}
+
+func (q *SessionQuery) encodeCursor(last *Session) string {
+	cursor, _ := json.Marshal(sessionCursor{SortBy: q.SortBy, Descending: q.Descending, Key: q.sortKey(last), Id: last.Id})
+	return b64.RawURLEncoding.EncodeToString(cursor)
+}
+
+func (q *SessionQuery) decodeCursor() (*sessionCursor, error) {
+	cursorJson, err := b64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidCursor, err)
+	}
+	var cursor sessionCursor
+	if err = json.Unmarshal(cursorJson, &cursor); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidCursor, err)
+	}
+	if cursor.SortBy != q.SortBy || cursor.Descending != q.Descending {
+		return nil, fmt.Errorf("%w, cursor was created with a different sort order", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// timeKey formats a time so that its lexicographic order matches the chronological one.
+func timeKey(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
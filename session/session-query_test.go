@@ -0,0 +1,133 @@
+package session
+
+import (
+	"authGo/token"
+	"errors"
+	"testing"
+	"time"
+)
+
+func createTestQuerySessionHandler(t *testing.T) *SessionsHandler {
+	sessionHandler := NewSessionHandler()
+	start := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+	for i, userId := range []string{"user1", "user2", "user1", "user3"} {
+		err := addTestSession(sessionHandler, userId, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("error adding session, %s", err)
+		}
+	}
+	err := sessionHandler.AddNewSession(
+		token.RefreshTokenPayload{UserId: "user1", OrganizationId: "acme", IssuedAtTime: start.Add(time.Hour * 4)},
+		DeviceData{IpAddress: "ip-user1", UserAgent: "Mozilla/5.0 Firefox"},
+	)
+	if err != nil {
+		t.Fatalf("error adding session, %s", err)
+	}
+	return sessionHandler
+}
+
+func TestQuerySessionsFilters(t *testing.T) {
+	sessionHandler := createTestQuerySessionHandler(t)
+	start := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+
+	queryTests := []struct {
+		name  string
+		query *SessionQuery
+		want  int
+	}{
+		{"no filters", NewSessionQuery(), 5},
+		{"organization", &SessionQuery{OrganizationId: "acme", SortBy: SortByLastUpdate}, 1},
+		{"user id", &SessionQuery{UserId: "user1", SortBy: SortByLastUpdate}, 3},
+		{"ip address", &SessionQuery{IpAddress: "ip-user2", SortBy: SortByLastUpdate}, 1},
+		{"user agent substring", &SessionQuery{UserAgent: "firefox", SortBy: SortByLastUpdate}, 1},
+		{"last update from", &SessionQuery{LastUpdateFrom: start.Add(time.Hour * 3), SortBy: SortByLastUpdate}, 2},
+		{"last update range", &SessionQuery{LastUpdateFrom: start.Add(time.Hour), LastUpdateTo: start.Add(time.Hour * 2), SortBy: SortByLastUpdate}, 2},
+		{"no matches", &SessionQuery{UserId: "user4", SortBy: SortByLastUpdate}, 0},
+	}
+
+	for _, queryTest := range queryTests {
+		sessions, nextCursor, err := sessionHandler.QuerySessions(queryTest.query)
+		if err != nil {
+			t.Errorf("%s: expected err to be nil, got %s", queryTest.name, err)
+		}
+		if len(sessions) != queryTest.want {
+			t.Errorf("%s: expected %d sessions, got %d", queryTest.name, queryTest.want, len(sessions))
+		}
+		if nextCursor != "" {
+			t.Errorf("%s: expected nextCursor to be empty, got %s", queryTest.name, nextCursor)
+		}
+	}
+}
+
+func TestQuerySessionsSort(t *testing.T) {
+	sessionHandler := createTestQuerySessionHandler(t)
+
+	query := NewSessionQuery()
+	sessions, _, err := sessionHandler.QuerySessions(query)
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	for i := 1; i < len(sessions); i++ {
+		if sessions[i].LastUpdate.After(sessions[i-1].LastUpdate) {
+			t.Errorf("expected sessions to be sorted by lastUpdate descending, got %v", sessions)
+		}
+	}
+
+	query = &SessionQuery{SortBy: SortByUserId}
+	sessions, _, err = sessionHandler.QuerySessions(query)
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if sessions[0].UserToken.UserId != "user1" || sessions[4].UserToken.UserId != "user3" {
+		t.Errorf("expected sessions to be sorted by userId ascending, got %v", sessions)
+	}
+
+	_, _, err = sessionHandler.QuerySessions(&SessionQuery{SortBy: "password"})
+	if !errors.Is(err, ErrInvalidSortField) {
+		t.Errorf("expected err to be ErrInvalidSortField, got %s", err)
+	}
+}
+
+func TestQuerySessionsPagination(t *testing.T) {
+	sessionHandler := createTestQuerySessionHandler(t)
+
+	query := NewSessionQuery()
+	query.Limit = 2
+	seen := make(map[string]bool)
+	pages := 0
+	for {
+		sessions, nextCursor, err := sessionHandler.QuerySessions(query)
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		pages++
+		for _, session := range sessions {
+			if seen[session.Id] {
+				t.Errorf("session %s returned twice", session.Id)
+			}
+			seen[session.Id] = true
+		}
+		if nextCursor == "" {
+			break
+		}
+		query.Cursor = nextCursor
+	}
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected 5 sessions, got %d", len(seen))
+	}
+
+	query.Descending = false
+	_, _, err := sessionHandler.QuerySessions(query)
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected err to be ErrInvalidCursor, got %s", err)
+	}
+
+	query.Cursor = "not a cursor"
+	_, _, err = sessionHandler.QuerySessions(query)
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected err to be ErrInvalidCursor, got %s", err)
+	}
+}
@@ -0,0 +1,22 @@
+package session
+
+import (
+	"authGo/token"
+	"context"
+)
+
+type SessionStore interface {
+	Get(userToken token.RefreshTokenPayload) (*Session, error)
+	GetById(id string) (*Session, error)
+	GetAll() ([]*Session, error)
+	GetByUserId(userId string) ([]*Session, error)
+	Add(session *Session) error
+	Update(session *Session) error
+	Delete(session *Session) error
+}
+
+// RevocationPublisher is implemented by the stores shared between several instances, the listener
+// is called with the sessions revoked by the other instances.
+type RevocationPublisher interface {
+	SubscribeRevocations(ctx context.Context, listener func(sessionId string)) error
+}
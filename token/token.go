@@ -10,17 +10,60 @@ type Header struct {
 var DefaultHeader = &Header{Alg: "HS256", Typ: "JWT"}
 
 type TokenPayload interface {
-	AccessTokenPayload | RefreshTokenPayload
+	AccessTokenPayload | RefreshTokenPayload | EmailVerificationPayload | MFAChallengePayload | WebAuthnChallengePayload | InvitationPayload
 }
 
 type AccessTokenPayload struct {
-	UserId       string    `json:"userId"`
-	IssuedAtTime time.Time `json:"issuedAtTime"`
-	IsAdmin      bool      `json:"isAdmin"`
+	UserId         string    `json:"userId"`
+	OrganizationId string    `json:"organizationId"`
+	IssuedAtTime   time.Time `json:"issuedAtTime"`
+	Roles          []string  `json:"roles"`
+	Permissions    []string  `json:"permissions"`
+}
+
+func (p *AccessTokenPayload) HasPermission(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
 }
 
 type RefreshTokenPayload struct {
+	UserId         string    `json:"userId"`
+	OrganizationId string    `json:"organizationId"`
+	IssuedAtTime   time.Time `json:"issuedAtTime"`
+}
+
+type EmailVerificationPayload struct {
 	UserId       string    `json:"userId"`
+	Email        string    `json:"email"`
+	IssuedAtTime time.Time `json:"issuedAtTime"`
+}
+
+// MFAChallengePayload is given after a valid password to the users with a second factor, the login
+// finishes when it's sent back with a valid code.
+type MFAChallengePayload struct {
+	UserId         string    `json:"userId"`
+	OrganizationId string    `json:"organizationId"`
+	IssuedAtTime   time.Time `json:"issuedAtTime"`
+}
+
+// WebAuthnChallengePayload keeps the challenge of a WebAuthn ceremony until the browser answers it.
+// UserId is empty on the passwordless logins, the user is the one of the credential.
+type WebAuthnChallengePayload struct {
+	UserId         string    `json:"userId"`
+	OrganizationId string    `json:"organizationId"`
+	Ceremony       string    `json:"ceremony"`
+	Challenge      string    `json:"challenge"`
+	IssuedAtTime   time.Time `json:"issuedAtTime"`
+}
+
+// InvitationPayload is the link sent to the invitees, the invitation itself is stored so it can be
+// accepted only once.
+type InvitationPayload struct {
+	InvitationId string    `json:"invitationId"`
 	IssuedAtTime time.Time `json:"issuedAtTime"`
 }
 
@@ -0,0 +1,62 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA1 test vectors of RFC 6238, keeping the last 6 of their 8 digits.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	codeTests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, codeTest := range codeTests {
+		code, err := Code(rfcSecret, Counter(time.Unix(codeTest.unix, 0)))
+		if err != nil || code != codeTest.code {
+			t.Errorf("%d: expected code %s, got %s %v", codeTest.unix, codeTest.code, code, err)
+		}
+	}
+	if _, err := Code("not base32!", 1); err != ErrInvalidSecret {
+		t.Errorf("expected err to be ErrInvalidSecret, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, _ := Code(secret, Counter(now))
+	if counter, ok := Validate(secret, code, now); !ok || counter != Counter(now) {
+		t.Errorf("expected the current code to be valid, got %d %v", counter, ok)
+	}
+	if _, ok := Validate(secret, code, now.Add(Period)); !ok {
+		t.Error("expected the previous code to be valid")
+	}
+	if _, ok := Validate(secret, code, now.Add(Period*3)); ok {
+		t.Error("expected an old code not to be valid")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("expected a short code not to be valid")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("authGo", "admin", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/authGo:admin?") || !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=authGo") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+}
@@ -0,0 +1,22 @@
+package user
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+var ErrEmailNotValid = errors.New("user: email address not valid")
+
+// NormalizeEmail returns the email address in the form it's stored, an empty address stays empty.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return "", ErrEmailNotValid
+	}
+	return email, nil
+}
@@ -0,0 +1,101 @@
+package user
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrInvitationNotFound = errors.New("invitation repository: invitation not found")
+
+// Invitation lets someone create a user of the organization with the given roles until ExpiresAt.
+// The invitee chooses the name and password, and the email address, when set, is the one the link
+// was sent to.
+type Invitation struct {
+	Id             string     `json:"id"`
+	OrganizationId string     `json:"organizationId"`
+	Email          string     `json:"email,omitempty"`
+	Roles          []string   `json:"roles"`
+	CreatedBy      string     `json:"createdBy"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	AcceptedAt     *time.Time `json:"acceptedAt,omitempty"`
+	UserId         string     `json:"userId,omitempty"`
+}
+
+type InvitationStore interface {
+	AddInvitation(invitation *Invitation) error
+	GetInvitation(id string) (*Invitation, error)
+	// GetInvitations returns the invitations of the organization from the oldest to the newest.
+	GetInvitations(organizationId string) ([]*Invitation, error)
+	// AcceptInvitation records the user created with the invitation. It returns ErrInvitationNotFound
+	// when the invitation was already accepted, so concurrent requests can't use it twice.
+	AcceptInvitation(id string, userId string, acceptedAt time.Time) error
+	DeleteInvitation(id string) error
+}
+
+type InvitationRepository struct {
+	mutex       sync.Mutex
+	invitations map[string]Invitation
+}
+
+func NewInvitationRepository() *InvitationRepository {
+	return &InvitationRepository{invitations: make(map[string]Invitation)}
+}
+
+func (r *InvitationRepository) AddInvitation(invitation *Invitation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.invitations[invitation.Id] = *invitation
+	return nil
+}
+
+func (r *InvitationRepository) GetInvitation(id string) (*Invitation, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	invitation, ok := r.invitations[id]
+	if !ok {
+		return nil, ErrInvitationNotFound
+	}
+	return &invitation, nil
+}
+
+func (r *InvitationRepository) GetInvitations(organizationId string) ([]*Invitation, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	invitations := make([]*Invitation, 0)
+	for _, invitation := range r.invitations {
+		if invitation.OrganizationId == organizationId {
+			invitation := invitation
+			invitations = append(invitations, &invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].CreatedAt.Before(invitations[j].CreatedAt)
+	})
+	return invitations, nil
+}
+
+func (r *InvitationRepository) AcceptInvitation(id string, userId string, acceptedAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.AcceptedAt != nil {
+		return ErrInvitationNotFound
+	}
+	invitation.AcceptedAt = &acceptedAt
+	invitation.UserId = userId
+	r.invitations[id] = invitation
+	return nil
+}
+
+func (r *InvitationRepository) DeleteInvitation(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.invitations[id]; !ok {
+		return ErrInvitationNotFound
+	}
+	delete(r.invitations, id)
+	return nil
+}
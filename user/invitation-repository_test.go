@@ -0,0 +1,71 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func forEachInvitationStore(t *testing.T, test func(t *testing.T, store InvitationStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewInvitationRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if err := NewSqlOrganizationRepository(r.db).AddOrganization(&Organization{Id: "other", Name: "Other"}); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlInvitationRepository(r.db))
+	})
+}
+
+func TestInvitationStore(t *testing.T) {
+	forEachInvitationStore(t, func(t *testing.T, store InvitationStore) {
+		now := time.Now()
+		invitations := []*Invitation{
+			{Id: "a", OrganizationId: DefaultOrganizationId, Email: "a@example.com", Roles: []string{AdminRole}, CreatedBy: "1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{Id: "b", OrganizationId: DefaultOrganizationId, Roles: []string{}, CreatedBy: "1", CreatedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)},
+			{Id: "c", OrganizationId: "other", Roles: []string{}, CreatedBy: "1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		}
+		for _, invitation := range invitations {
+			if err := store.AddInvitation(invitation); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		invitation, err := store.GetInvitation("a")
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if invitation.Email != "a@example.com" || len(invitation.Roles) != 1 || invitation.Roles[0] != AdminRole || !invitation.ExpiresAt.Equal(now.Add(time.Hour)) || invitation.AcceptedAt != nil {
+			t.Errorf("unexpected invitation, got %+v", invitation)
+		}
+		if _, err = store.GetInvitation("unknown"); err != ErrInvitationNotFound {
+			t.Errorf("expected err to be ErrInvitationNotFound, got %v", err)
+		}
+
+		organizationInvitations, err := store.GetInvitations(DefaultOrganizationId)
+		if err != nil || len(organizationInvitations) != 2 || organizationInvitations[0].Id != "a" || organizationInvitations[1].Id != "b" {
+			t.Errorf("expected the invitations a and b, got %v %v", organizationInvitations, err)
+		}
+
+		if err = store.AcceptInvitation("a", "2", now); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if err = store.AcceptInvitation("a", "3", now); err != ErrInvitationNotFound {
+			t.Errorf("expected an accepted invitation to fail, got %v", err)
+		}
+		if invitation, _ = store.GetInvitation("a"); invitation.AcceptedAt == nil || !invitation.AcceptedAt.Equal(now) || invitation.UserId != "2" {
+			t.Errorf("expected the invitation to be accepted by 2, got %+v", invitation)
+		}
+
+		if err = store.DeleteInvitation("b"); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if err = store.DeleteInvitation("b"); err != ErrInvitationNotFound {
+			t.Errorf("expected err to be ErrInvitationNotFound, got %v", err)
+		}
+		if err = store.AcceptInvitation("b", "2", now); err != ErrInvitationNotFound {
+			t.Errorf("expected a deleted invitation to fail, got %v", err)
+		}
+	})
+}
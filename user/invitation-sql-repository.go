@@ -0,0 +1,108 @@
+package user
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const invitationColumns = "id, organization_id, email, roles, created_by, created_at, expires_at, accepted_at, user_id"
+
+type SqlInvitationRepository struct {
+	db *sql.DB
+}
+
+// NewSqlInvitationRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlInvitationRepository(db *sql.DB) *SqlInvitationRepository {
+	return &SqlInvitationRepository{db: db}
+}
+
+func (r *SqlInvitationRepository) AddInvitation(invitation *Invitation) error {
+	roles, err := json.Marshal(invitation.Roles)
+	if err != nil {
+		return err
+	}
+	var acceptedAt int64
+	if invitation.AcceptedAt != nil {
+		acceptedAt = invitation.AcceptedAt.UnixNano()
+	}
+	_, err = r.db.Exec("INSERT INTO invitations ("+invitationColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		invitation.Id, invitation.OrganizationId, invitation.Email, string(roles), invitation.CreatedBy,
+		invitation.CreatedAt.UnixNano(), invitation.ExpiresAt.UnixNano(), acceptedAt, invitation.UserId)
+	return err
+}
+
+func (r *SqlInvitationRepository) GetInvitation(id string) (*Invitation, error) {
+	invitation, err := scanInvitation(r.db.QueryRow("SELECT "+invitationColumns+" FROM invitations WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvitationNotFound
+	}
+	return invitation, err
+}
+
+func (r *SqlInvitationRepository) GetInvitations(organizationId string) ([]*Invitation, error) {
+	rows, err := r.db.Query("SELECT "+invitationColumns+" FROM invitations WHERE organization_id = ? ORDER BY created_at, id", organizationId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	invitations := make([]*Invitation, 0)
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (r *SqlInvitationRepository) AcceptInvitation(id string, userId string, acceptedAt time.Time) error {
+	result, err := r.db.Exec("UPDATE invitations SET accepted_at = ?, user_id = ? WHERE id = ? AND accepted_at = 0", acceptedAt.UnixNano(), userId, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (r *SqlInvitationRepository) DeleteInvitation(id string) error {
+	result, err := r.db.Exec("DELETE FROM invitations WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func scanInvitation(row rowScanner) (*Invitation, error) {
+	invitation := &Invitation{}
+	var roles string
+	var createdAt, expiresAt, acceptedAt int64
+	if err := row.Scan(&invitation.Id, &invitation.OrganizationId, &invitation.Email, &roles, &invitation.CreatedBy,
+		&createdAt, &expiresAt, &acceptedAt, &invitation.UserId); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(roles), &invitation.Roles); err != nil {
+		return nil, err
+	}
+	invitation.CreatedAt = time.Unix(0, createdAt)
+	invitation.ExpiresAt = time.Unix(0, expiresAt)
+	if acceptedAt != 0 {
+		accepted := time.Unix(0, acceptedAt)
+		invitation.AcceptedAt = &accepted
+	}
+	return invitation, nil
+}
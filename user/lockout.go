@@ -0,0 +1,32 @@
+package user
+
+import "time"
+
+// LockoutPolicy locks an account for a while after too many failed logins in a row. Every failure
+// after the first lockout doubles its duration up to MaxDuration, a successful login starts again.
+type LockoutPolicy struct {
+	// MaxFailedAttempts is the number of failures in a row that locks the account, zero disables it.
+	MaxFailedAttempts int
+	Duration          time.Duration
+	// MaxDuration limits the lockout duration, it isn't limited when zero.
+	MaxDuration time.Duration
+}
+
+// LockedUntil returns when the lockout caused by the given number of failures in a row ends, or the
+// zero time when the account isn't locked.
+func (p *LockoutPolicy) LockedUntil(failures int, now time.Time) time.Time {
+	if p.MaxFailedAttempts <= 0 || failures < p.MaxFailedAttempts {
+		return time.Time{}
+	}
+	duration := p.Duration
+	for i := p.MaxFailedAttempts; i < failures; i++ {
+		if p.MaxDuration > 0 && duration >= p.MaxDuration {
+			break
+		}
+		duration *= 2
+	}
+	if p.MaxDuration > 0 && duration > p.MaxDuration {
+		duration = p.MaxDuration
+	}
+	return now.Add(duration)
+}
@@ -0,0 +1,59 @@
+package user
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginFailures are the failed logins in a row of a user.
+type LoginFailures struct {
+	UserId      string
+	Count       int
+	LockedUntil time.Time
+}
+
+type LoginFailureStore interface {
+	// GetLoginFailures returns no failures when the user has none.
+	GetLoginFailures(userId string) (*LoginFailures, error)
+	// AddLoginFailure counts a new failure and sets the end of the lockout returned by lockedUntil for
+	// the new count, both at once so that concurrent failures are all counted.
+	AddLoginFailure(userId string, lockedUntil func(count int) time.Time) (*LoginFailures, error)
+	DeleteLoginFailures(userId string) error
+}
+
+type LoginFailureRepository struct {
+	mutex    sync.Mutex
+	failures map[string]LoginFailures
+}
+
+func NewLoginFailureRepository() *LoginFailureRepository {
+	return &LoginFailureRepository{failures: make(map[string]LoginFailures)}
+}
+
+func (r *LoginFailureRepository) GetLoginFailures(userId string) (*LoginFailures, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	failures, ok := r.failures[userId]
+	if !ok {
+		return &LoginFailures{UserId: userId}, nil
+	}
+	return &failures, nil
+}
+
+func (r *LoginFailureRepository) AddLoginFailure(userId string, lockedUntil func(count int) time.Time) (*LoginFailures, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	failures := r.failures[userId]
+	failures.UserId = userId
+	failures.Count++
+	failures.LockedUntil = lockedUntil(failures.Count)
+	r.failures[userId] = failures
+	return &failures, nil
+}
+
+func (r *LoginFailureRepository) DeleteLoginFailures(userId string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.failures, userId)
+	return nil
+}
@@ -0,0 +1,86 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+// forEachLoginFailureStore runs the test against every LoginFailureStore implementation, the users
+// 1, 2 and 3 exist in the store.
+func forEachLoginFailureStore(t *testing.T, test func(t *testing.T, store LoginFailureStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewLoginFailureRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if _, err := createTestUserRepository(r); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlLoginFailureRepository(r.db))
+	})
+}
+
+func TestLoginFailureStore(t *testing.T) {
+	forEachLoginFailureStore(t, func(t *testing.T, store LoginFailureStore) {
+		failures, err := store.GetLoginFailures("1")
+		if err != nil || failures.Count != 0 || !failures.LockedUntil.IsZero() {
+			t.Errorf("expected no failures, got %+v %v", failures, err)
+		}
+
+		lockedUntil := time.Date(2022, 8, 6, 0, 15, 0, 0, time.UTC)
+		for i := 1; i <= 3; i++ {
+			failures, err = store.AddLoginFailure("1", func(count int) time.Time {
+				if count < 3 {
+					return time.Time{}
+				}
+				return lockedUntil
+			})
+			if err != nil || failures.Count != i {
+				t.Fatalf("expected %d failures, got %+v %v", i, failures, err)
+			}
+		}
+		if _, err = store.AddLoginFailure("2", func(count int) time.Time { return time.Time{} }); err != nil {
+			t.Fatal(err)
+		}
+
+		failures, err = store.GetLoginFailures("1")
+		if err != nil || failures.Count != 3 || !failures.LockedUntil.Equal(lockedUntil) {
+			t.Errorf("expected the user to be locked, got %+v %v", failures, err)
+		}
+		if err = store.DeleteLoginFailures("1"); err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if failures, _ = store.GetLoginFailures("1"); failures.Count != 0 {
+			t.Errorf("expected the failures to be deleted, got %+v", failures)
+		}
+		if failures, _ = store.GetLoginFailures("2"); failures.Count != 1 || !failures.LockedUntil.IsZero() {
+			t.Errorf("expected the failures of other users to be kept, got %+v", failures)
+		}
+	})
+}
+
+func TestLockoutPolicy(t *testing.T) {
+	policy := &LockoutPolicy{MaxFailedAttempts: 3, Duration: time.Minute, MaxDuration: time.Minute * 5}
+	now := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+	for failures, want := range map[int]time.Duration{
+		1:  0,
+		2:  0,
+		3:  time.Minute,
+		4:  time.Minute * 2,
+		5:  time.Minute * 4,
+		6:  time.Minute * 5,
+		60: time.Minute * 5,
+	} {
+		lockedUntil := policy.LockedUntil(failures, now)
+		if want == 0 && !lockedUntil.IsZero() {
+			t.Errorf("%d failures: expected no lockout, got %s", failures, lockedUntil)
+		}
+		if want != 0 && !lockedUntil.Equal(now.Add(want)) {
+			t.Errorf("%d failures: expected a lockout of %s, got %s", failures, want, lockedUntil.Sub(now))
+		}
+	}
+	disabled := &LockoutPolicy{Duration: time.Minute}
+	if lockedUntil := disabled.LockedUntil(100, now); !lockedUntil.IsZero() {
+		t.Errorf("expected no lockout when disabled, got %s", lockedUntil)
+	}
+}
@@ -0,0 +1,74 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SqlLoginFailureRepository struct {
+	db *sql.DB
+}
+
+// NewSqlLoginFailureRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlLoginFailureRepository(db *sql.DB) *SqlLoginFailureRepository {
+	return &SqlLoginFailureRepository{db: db}
+}
+
+func (r *SqlLoginFailureRepository) GetLoginFailures(userId string) (*LoginFailures, error) {
+	return getLoginFailures(r.db, userId)
+}
+
+func (r *SqlLoginFailureRepository) AddLoginFailure(userId string, lockedUntil func(count int) time.Time) (*LoginFailures, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`INSERT INTO login_failures (user_id, count) VALUES (?, 1)
+		ON CONFLICT (user_id) DO UPDATE SET count = count + 1`, userId)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := getLoginFailures(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	failures.LockedUntil = lockedUntil(failures.Count)
+	if _, err = tx.Exec("UPDATE login_failures SET locked_until = ? WHERE user_id = ?", timeToNano(failures.LockedUntil), userId); err != nil {
+		return nil, err
+	}
+	return failures, tx.Commit()
+}
+
+func (r *SqlLoginFailureRepository) DeleteLoginFailures(userId string) error {
+	_, err := r.db.Exec("DELETE FROM login_failures WHERE user_id = ?", userId)
+	return err
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func getLoginFailures(db queryRower, userId string) (*LoginFailures, error) {
+	failures := &LoginFailures{UserId: userId}
+	var lockedUntil int64
+	err := db.QueryRow("SELECT count, locked_until FROM login_failures WHERE user_id = ?", userId).Scan(&failures.Count, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return failures, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != 0 {
+		failures.LockedUntil = time.Unix(0, lockedUntil)
+	}
+	return failures, nil
+}
+
+// timeToNano stores the zero time as 0 instead of its far negative UnixNano.
+func timeToNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
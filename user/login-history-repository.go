@@ -0,0 +1,99 @@
+package user
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	LoginOutcomeSuccess = "success"
+	LoginOutcomeFailure = "failure"
+)
+
+const (
+	LoginMethodPassword     = "password"
+	LoginMethodTOTP         = "totp"
+	LoginMethodRecoveryCode = "recovery-code"
+	LoginMethodWebAuthn     = "webauthn"
+	// LoginMethodInvitation is the login of a user created by accepting an invitation.
+	LoginMethodInvitation = "invitation"
+)
+
+// LoginAttempt is a login of the user, FailureReason explains the failures. Method is the last step of
+// the login, the password or a second factor.
+type LoginAttempt struct {
+	Id            string    `json:"id"`
+	UserId        string    `json:"userId"`
+	Time          time.Time `json:"time"`
+	IpAddress     string    `json:"ipAddress"`
+	UserAgent     string    `json:"userAgent"`
+	Method        string    `json:"method"`
+	Outcome       string    `json:"outcome"`
+	FailureReason string    `json:"failureReason,omitempty"`
+}
+
+// LoginHistoryRetention limits the login attempts kept for every user, a zero MaxAge or MaxAttempts
+// doesn't limit them.
+type LoginHistoryRetention struct {
+	MaxAge      time.Duration
+	MaxAttempts int
+}
+
+// notBefore returns the time of the oldest attempt kept at the given time.
+func (r LoginHistoryRetention) notBefore(now time.Time) time.Time {
+	if r.MaxAge <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-r.MaxAge)
+}
+
+type LoginHistoryStore interface {
+	// AddLoginAttempt adds the attempt and removes the attempts of the same user made before notBefore,
+	// and the oldest ones beyond the newest maxAttempts. A zero notBefore or maxAttempts doesn't limit them.
+	AddLoginAttempt(attempt *LoginAttempt, maxAttempts int, notBefore time.Time) error
+	// GetLoginAttempts returns the attempts of the user made since notBefore, from the newest to the oldest.
+	GetLoginAttempts(userId string, notBefore time.Time) ([]*LoginAttempt, error)
+}
+
+type LoginHistoryRepository struct {
+	mutex    sync.Mutex
+	attempts map[string][]LoginAttempt
+}
+
+func NewLoginHistoryRepository() *LoginHistoryRepository {
+	return &LoginHistoryRepository{attempts: make(map[string][]LoginAttempt)}
+}
+
+func (r *LoginHistoryRepository) AddLoginAttempt(attempt *LoginAttempt, maxAttempts int, notBefore time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	attempts := make([]LoginAttempt, 0, len(r.attempts[attempt.UserId])+1)
+	for _, kept := range append(r.attempts[attempt.UserId], *attempt) {
+		if !kept.Time.Before(notBefore) {
+			attempts = append(attempts, kept)
+		}
+	}
+	sort.SliceStable(attempts, func(i, j int) bool {
+		return attempts[i].Time.Before(attempts[j].Time)
+	})
+	if maxAttempts > 0 && len(attempts) > maxAttempts {
+		attempts = attempts[len(attempts)-maxAttempts:]
+	}
+	r.attempts[attempt.UserId] = attempts
+	return nil
+}
+
+func (r *LoginHistoryRepository) GetLoginAttempts(userId string, notBefore time.Time) ([]*LoginAttempt, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored := r.attempts[userId]
+	attempts := make([]*LoginAttempt, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		if !stored[i].Time.Before(notBefore) {
+			attempt := stored[i]
+			attempts = append(attempts, &attempt)
+		}
+	}
+	return attempts, nil
+}
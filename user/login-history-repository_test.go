@@ -0,0 +1,70 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+// forEachLoginHistoryStore runs the test against every LoginHistoryStore implementation, the users 1, 2
+// and 3 exist in the store.
+func forEachLoginHistoryStore(t *testing.T, test func(t *testing.T, store LoginHistoryStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewLoginHistoryRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if _, err := createTestUserRepository(r); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlLoginHistoryRepository(r.db))
+	})
+}
+
+func TestLoginHistoryStore(t *testing.T) {
+	forEachLoginHistoryStore(t, func(t *testing.T, store LoginHistoryStore) {
+		start := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+		attempts := []*LoginAttempt{
+			{Id: "a", UserId: "1", Time: start, IpAddress: "10.0.0.1", UserAgent: "test", Method: LoginMethodPassword, Outcome: LoginOutcomeFailure, FailureReason: "password not valid"},
+			{Id: "b", UserId: "1", Time: start.Add(time.Minute), IpAddress: "10.0.0.1", UserAgent: "test", Method: LoginMethodPassword, Outcome: LoginOutcomeSuccess},
+			{Id: "c", UserId: "2", Time: start, Method: LoginMethodWebAuthn, Outcome: LoginOutcomeSuccess},
+		}
+		for _, attempt := range attempts {
+			if err := store.AddLoginAttempt(attempt, 0, time.Time{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stored, err := store.GetLoginAttempts("1", time.Time{})
+		if err != nil || len(stored) != 2 || stored[0].Id != "b" || stored[1].Id != "a" {
+			t.Fatalf("expected the attempts of the user from the newest, got %v %v", stored, err)
+		}
+		if !stored[1].Time.Equal(start) || stored[1].IpAddress != "10.0.0.1" || stored[1].UserAgent != "test" || stored[1].Method != LoginMethodPassword || stored[1].Outcome != LoginOutcomeFailure || stored[1].FailureReason != "password not valid" {
+			t.Errorf("expected the attempt to be stored, got %+v", stored[1])
+		}
+		if stored, _ = store.GetLoginAttempts("1", start.Add(time.Second)); len(stored) != 1 || stored[0].Id != "b" {
+			t.Errorf("expected the older attempts to be skipped, got %v", stored)
+		}
+		if stored, _ = store.GetLoginAttempts("3", time.Time{}); len(stored) != 0 {
+			t.Errorf("expected no attempts, got %v", stored)
+		}
+
+		third := &LoginAttempt{Id: "d", UserId: "1", Time: start.Add(time.Minute * 2), Method: LoginMethodTOTP, Outcome: LoginOutcomeSuccess}
+		if err = store.AddLoginAttempt(third, 2, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ = store.GetLoginAttempts("1", time.Time{}); len(stored) != 2 || stored[0].Id != "d" || stored[1].Id != "b" {
+			t.Errorf("expected only the newest attempts to be kept, got %v", stored)
+		}
+
+		fourth := &LoginAttempt{Id: "e", UserId: "1", Time: start.Add(time.Minute * 3), Method: LoginMethodPassword, Outcome: LoginOutcomeSuccess}
+		if err = store.AddLoginAttempt(fourth, 0, start.Add(time.Minute*2)); err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ = store.GetLoginAttempts("1", time.Time{}); len(stored) != 2 || stored[0].Id != "e" || stored[1].Id != "d" {
+			t.Errorf("expected the expired attempts to be removed, got %v", stored)
+		}
+		if stored, _ = store.GetLoginAttempts("2", time.Time{}); len(stored) != 1 {
+			t.Errorf("expected the attempts of other users to be kept, got %v", stored)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+const loginAttemptColumns = "id, user_id, time, ip_address, user_agent, method, outcome, failure_reason"
+
+type SqlLoginHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewSqlLoginHistoryRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlLoginHistoryRepository(db *sql.DB) *SqlLoginHistoryRepository {
+	return &SqlLoginHistoryRepository{db: db}
+}
+
+func (r *SqlLoginHistoryRepository) AddLoginAttempt(attempt *LoginAttempt, maxAttempts int, notBefore time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("INSERT INTO login_attempts ("+loginAttemptColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		attempt.Id, attempt.UserId, attempt.Time.UnixNano(), attempt.IpAddress, attempt.UserAgent, attempt.Method, attempt.Outcome, attempt.FailureReason)
+	if err != nil {
+		return err
+	}
+	if !notBefore.IsZero() {
+		if _, err = tx.Exec("DELETE FROM login_attempts WHERE user_id = ? AND time < ?", attempt.UserId, notBefore.UnixNano()); err != nil {
+			return err
+		}
+	}
+	if maxAttempts > 0 {
+		_, err = tx.Exec(`DELETE FROM login_attempts WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM login_attempts WHERE user_id = ? ORDER BY time DESC, rowid DESC LIMIT ?)`, attempt.UserId, attempt.UserId, maxAttempts)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SqlLoginHistoryRepository) GetLoginAttempts(userId string, notBefore time.Time) ([]*LoginAttempt, error) {
+	rows, err := r.db.Query("SELECT "+loginAttemptColumns+" FROM login_attempts WHERE user_id = ? AND time >= ? ORDER BY time DESC, rowid DESC",
+		userId, timeToNano(notBefore))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	attempts := make([]*LoginAttempt, 0)
+	for rows.Next() {
+		attempt := &LoginAttempt{}
+		var attemptTime int64
+		if err := rows.Scan(&attempt.Id, &attempt.UserId, &attemptTime, &attempt.IpAddress, &attempt.UserAgent,
+			&attempt.Method, &attempt.Outcome, &attempt.FailureReason); err != nil {
+			return nil, err
+		}
+		attempt.Time = time.Unix(0, attemptTime)
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"authGo/repository"
+)
+
+type OrganizationStore interface {
+	AddOrganization(organization *Organization) error
+	GetOrganization(id string) (*Organization, error)
+	GetOrganizations() ([]*Organization, error)
+}
+
+func getOrganizationById(organization *Organization, value string) bool {
+	return organization.Id == value
+}
+
+// OrganizationRepository keeps the organizations in memory, it starts with the default organization.
+type OrganizationRepository struct {
+	repository *repository.Repository[Organization]
+}
+
+func NewOrganizationRepository() *OrganizationRepository {
+	r := &OrganizationRepository{repository: repository.NewRepository[Organization]()}
+	r.repository.Add(&Organization{Id: DefaultOrganizationId, Name: "Default"})
+	return r
+}
+
+func (r *OrganizationRepository) AddOrganization(organization *Organization) error {
+	if _, i := r.repository.GetItem(getOrganizationById, organization.Id); i != -1 {
+		return ErrOrganizationAlreadyExists
+	}
+	r.repository.Add(organization)
+	return nil
+}
+
+func (r *OrganizationRepository) GetOrganization(id string) (*Organization, error) {
+	organization, i := r.repository.GetItem(getOrganizationById, id)
+	if i == -1 {
+		return nil, ErrOrganizationNotFound
+	}
+	return organization, nil
+}
+
+func (r *OrganizationRepository) GetOrganizations() ([]*Organization, error) {
+	return r.repository.GetAll(), nil
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SqlOrganizationRepository struct {
+	db *sql.DB
+}
+
+// NewSqlOrganizationRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlOrganizationRepository(db *sql.DB) *SqlOrganizationRepository {
+	return &SqlOrganizationRepository{db: db}
+}
+
+func (r *SqlOrganizationRepository) AddOrganization(organization *Organization) error {
+	_, err := r.db.Exec("INSERT INTO organizations (id, name) VALUES (?, ?)", organization.Id, organization.Name)
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return ErrOrganizationAlreadyExists
+	}
+	return err
+}
+
+func (r *SqlOrganizationRepository) GetOrganization(id string) (*Organization, error) {
+	organization := &Organization{}
+	err := r.db.QueryRow("SELECT id, name FROM organizations WHERE id = ?", id).Scan(&organization.Id, &organization.Name)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+func (r *SqlOrganizationRepository) GetOrganizations() ([]*Organization, error) {
+	rows, err := r.db.Query("SELECT id, name FROM organizations ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	organizations := make([]*Organization, 0)
+	for rows.Next() {
+		organization := &Organization{}
+		if err := rows.Scan(&organization.Id, &organization.Name); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, organization)
+	}
+	return organizations, rows.Err()
+}
@@ -0,0 +1,36 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultOrganizationId is the organization of the users created without one, every user existing
+// before organizations were added belongs to it.
+const DefaultOrganizationId = "default"
+
+var (
+	ErrOrganizationNotFound      = errors.New("organization: organization not found")
+	ErrOrganizationAlreadyExists = errors.New("organization: organization already exists")
+	ErrOrganizationNotValid      = errors.New("organization: organization not valid")
+)
+
+var organizationIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type Organization struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newOrganization(id string, name string) (*Organization, error) {
+	if !organizationIdPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w, invalid id %q", ErrOrganizationNotValid, id)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = id
+	}
+	return &Organization{Id: id, Name: name}, nil
+}
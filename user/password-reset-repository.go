@@ -0,0 +1,69 @@
+package user
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrPasswordResetNotFound = errors.New("password reset repository: password reset not found")
+
+type PasswordReset struct {
+	TokenHash string
+	UserId    string
+	ExpiresAt time.Time
+}
+
+type PasswordResetStore interface {
+	AddPasswordReset(reset *PasswordReset) error
+	GetPasswordReset(tokenHash string) (*PasswordReset, error)
+	DeletePasswordReset(tokenHash string) error
+	DeleteUserPasswordResets(userId string) error
+}
+
+type PasswordResetRepository struct {
+	mutex  sync.Mutex
+	resets map[string]*PasswordReset
+}
+
+func NewPasswordResetRepository() *PasswordResetRepository {
+	return &PasswordResetRepository{resets: make(map[string]*PasswordReset)}
+}
+
+func (r *PasswordResetRepository) AddPasswordReset(reset *PasswordReset) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.resets[reset.TokenHash] = reset
+	return nil
+}
+
+func (r *PasswordResetRepository) GetPasswordReset(tokenHash string) (*PasswordReset, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	reset, ok := r.resets[tokenHash]
+	if !ok {
+		return nil, ErrPasswordResetNotFound
+	}
+	return reset, nil
+}
+
+func (r *PasswordResetRepository) DeletePasswordReset(tokenHash string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.resets[tokenHash]; !ok {
+		return ErrPasswordResetNotFound
+	}
+	delete(r.resets, tokenHash)
+	return nil
+}
+
+func (r *PasswordResetRepository) DeleteUserPasswordResets(userId string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for tokenHash, reset := range r.resets {
+		if reset.UserId == userId {
+			delete(r.resets, tokenHash)
+		}
+	}
+	return nil
+}
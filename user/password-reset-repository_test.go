@@ -0,0 +1,64 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+// forEachPasswordResetStore runs the test against every PasswordResetStore implementation, the users
+// 1, 2 and 3 exist in the store.
+func forEachPasswordResetStore(t *testing.T, test func(t *testing.T, store PasswordResetStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewPasswordResetRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if _, err := createTestUserRepository(r); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlPasswordResetRepository(r.db))
+	})
+}
+
+func TestPasswordResetStore(t *testing.T) {
+	forEachPasswordResetStore(t, func(t *testing.T, store PasswordResetStore) {
+		expiresAt := time.Date(2022, 8, 6, 0, 15, 0, 0, time.UTC)
+		resets := []*PasswordReset{
+			{TokenHash: "hash1", UserId: "1", ExpiresAt: expiresAt},
+			{TokenHash: "hash2", UserId: "1", ExpiresAt: expiresAt},
+			{TokenHash: "hash3", UserId: "2", ExpiresAt: expiresAt},
+		}
+		for _, reset := range resets {
+			if err := store.AddPasswordReset(reset); err != nil {
+				t.Fatalf("expected err to be nil, got %s", err)
+			}
+		}
+
+		reset, err := store.GetPasswordReset("hash3")
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if reset.UserId != "2" || !reset.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("unexpected password reset, got %+v", reset)
+		}
+		if _, err = store.GetPasswordReset("hash4"); err != ErrPasswordResetNotFound {
+			t.Errorf("expected err to be ErrPasswordResetNotFound, got %s", err)
+		}
+
+		if err = store.DeletePasswordReset("hash3"); err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if err = store.DeletePasswordReset("hash3"); err != ErrPasswordResetNotFound {
+			t.Errorf("expected err to be ErrPasswordResetNotFound, got %s", err)
+		}
+
+		if err = store.DeleteUserPasswordResets("1"); err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		for _, tokenHash := range []string{"hash1", "hash2"} {
+			if _, err = store.GetPasswordReset(tokenHash); err != ErrPasswordResetNotFound {
+				t.Errorf("expected err to be ErrPasswordResetNotFound, got %s", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SqlPasswordResetRepository struct {
+	db *sql.DB
+}
+
+// NewSqlPasswordResetRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlPasswordResetRepository(db *sql.DB) *SqlPasswordResetRepository {
+	return &SqlPasswordResetRepository{db: db}
+}
+
+func (r *SqlPasswordResetRepository) AddPasswordReset(reset *PasswordReset) error {
+	_, err := r.db.Exec("INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES (?, ?, ?)", reset.TokenHash, reset.UserId, reset.ExpiresAt.UnixNano())
+	return err
+}
+
+func (r *SqlPasswordResetRepository) GetPasswordReset(tokenHash string) (*PasswordReset, error) {
+	reset := &PasswordReset{}
+	var expiresAt int64
+	err := r.db.QueryRow("SELECT token_hash, user_id, expires_at FROM password_resets WHERE token_hash = ?", tokenHash).Scan(&reset.TokenHash, &reset.UserId, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	reset.ExpiresAt = time.Unix(0, expiresAt)
+	return reset, nil
+}
+
+func (r *SqlPasswordResetRepository) DeletePasswordReset(tokenHash string) error {
+	result, err := r.db.Exec("DELETE FROM password_resets WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return ErrPasswordResetNotFound
+	}
+	return nil
+}
+
+func (r *SqlPasswordResetRepository) DeleteUserPasswordResets(userId string) error {
+	_, err := r.db.Exec("DELETE FROM password_resets WHERE user_id = ?", userId)
+	return err
+}
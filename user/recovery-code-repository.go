@@ -0,0 +1,84 @@
+package user
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrRecoveryCodeNotFound = errors.New("recovery code repository: recovery code not found")
+
+// RecoveryCode is a single-use code replacing the second factor of a user, only its HMAC-SHA256 digest
+// is stored as Hash.
+type RecoveryCode struct {
+	Id     string
+	UserId string
+	Hash   string
+}
+
+type RecoveryCodeStore interface {
+	// SetRecoveryCodes replaces all the codes of the user, none removes them.
+	SetRecoveryCodes(userId string, codes []*RecoveryCode) error
+	GetRecoveryCodes(userId string) ([]*RecoveryCode, error)
+	// GetRecoveryCode returns the code of the user with the given hash, or ErrRecoveryCodeNotFound.
+	GetRecoveryCode(userId string, hash string) (*RecoveryCode, error)
+	// DeleteRecoveryCode returns ErrRecoveryCodeNotFound when the code was already used, so concurrent
+	// logins can't use it twice.
+	DeleteRecoveryCode(id string) error
+}
+
+type RecoveryCodeRepository struct {
+	mutex sync.Mutex
+	codes map[string]RecoveryCode
+}
+
+func NewRecoveryCodeRepository() *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{codes: make(map[string]RecoveryCode)}
+}
+
+func (r *RecoveryCodeRepository) SetRecoveryCodes(userId string, codes []*RecoveryCode) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for id, code := range r.codes {
+		if code.UserId == userId {
+			delete(r.codes, id)
+		}
+	}
+	for _, code := range codes {
+		r.codes[code.Id] = *code
+	}
+	return nil
+}
+
+func (r *RecoveryCodeRepository) GetRecoveryCodes(userId string) ([]*RecoveryCode, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	codes := make([]*RecoveryCode, 0)
+	for _, code := range r.codes {
+		if code.UserId == userId {
+			code := code
+			codes = append(codes, &code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *RecoveryCodeRepository) GetRecoveryCode(userId string, hash string) (*RecoveryCode, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, code := range r.codes {
+		if code.UserId == userId && code.Hash == hash {
+			return &code, nil
+		}
+	}
+	return nil, ErrRecoveryCodeNotFound
+}
+
+func (r *RecoveryCodeRepository) DeleteRecoveryCode(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.codes[id]; !ok {
+		return ErrRecoveryCodeNotFound
+	}
+	delete(r.codes, id)
+	return nil
+}
@@ -0,0 +1,66 @@
+package user
+
+import "testing"
+
+// forEachRecoveryCodeStore runs the test against every RecoveryCodeStore implementation, the users 1, 2
+// and 3 exist in the store.
+func forEachRecoveryCodeStore(t *testing.T, test func(t *testing.T, store RecoveryCodeStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewRecoveryCodeRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if _, err := createTestUserRepository(r); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlRecoveryCodeRepository(r.db))
+	})
+}
+
+func TestRecoveryCodeStore(t *testing.T) {
+	forEachRecoveryCodeStore(t, func(t *testing.T, store RecoveryCodeStore) {
+		err := store.SetRecoveryCodes("1", []*RecoveryCode{{Id: "a", UserId: "1", Hash: "hashA"}, {Id: "b", UserId: "1", Hash: "hashB"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = store.SetRecoveryCodes("2", []*RecoveryCode{{Id: "c", UserId: "2", Hash: "hashC"}}); err != nil {
+			t.Fatal(err)
+		}
+		codes, err := store.GetRecoveryCodes("1")
+		if err != nil || len(codes) != 2 {
+			t.Errorf("expected 2 codes, got %v %v", codes, err)
+		}
+		if code, err := store.GetRecoveryCode("1", "hashB"); err != nil || code.Id != "b" {
+			t.Errorf("expected the code b, got %v %v", code, err)
+		}
+		if _, err = store.GetRecoveryCode("2", "hashB"); err != ErrRecoveryCodeNotFound {
+			t.Errorf("expected err to be ErrRecoveryCodeNotFound, got %v", err)
+		}
+
+		if err = store.DeleteRecoveryCode("a"); err != nil {
+			t.Fatal(err)
+		}
+		if err = store.DeleteRecoveryCode("a"); err != ErrRecoveryCodeNotFound {
+			t.Errorf("expected err to be ErrRecoveryCodeNotFound, got %v", err)
+		}
+		if codes, _ = store.GetRecoveryCodes("1"); len(codes) != 1 || codes[0].Hash != "hashB" {
+			t.Errorf("expected the unused code, got %v", codes)
+		}
+
+		if err = store.SetRecoveryCodes("1", []*RecoveryCode{{Id: "d", UserId: "1", Hash: "hashD"}}); err != nil {
+			t.Fatal(err)
+		}
+		if codes, _ = store.GetRecoveryCodes("1"); len(codes) != 1 || codes[0].Id != "d" {
+			t.Errorf("expected the codes to be replaced, got %v", codes)
+		}
+		if err = store.SetRecoveryCodes("1", nil); err != nil {
+			t.Fatal(err)
+		}
+		if codes, _ = store.GetRecoveryCodes("1"); len(codes) != 0 {
+			t.Errorf("expected no codes, got %v", codes)
+		}
+		if codes, _ = store.GetRecoveryCodes("2"); len(codes) != 1 {
+			t.Errorf("expected the codes of other users to be kept, got %v", codes)
+		}
+	})
+}
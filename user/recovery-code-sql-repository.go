@@ -0,0 +1,74 @@
+package user
+
+import "database/sql"
+
+type SqlRecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+// NewSqlRecoveryCodeRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlRecoveryCodeRepository(db *sql.DB) *SqlRecoveryCodeRepository {
+	return &SqlRecoveryCodeRepository{db: db}
+}
+
+func (r *SqlRecoveryCodeRepository) SetRecoveryCodes(userId string, codes []*RecoveryCode) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userId); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		if _, err = tx.Exec("INSERT INTO recovery_codes (id, user_id, hash) VALUES (?, ?, ?)", code.Id, code.UserId, code.Hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SqlRecoveryCodeRepository) GetRecoveryCodes(userId string) ([]*RecoveryCode, error) {
+	rows, err := r.db.Query("SELECT id, user_id, hash FROM recovery_codes WHERE user_id = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	codes := make([]*RecoveryCode, 0)
+	for rows.Next() {
+		code := &RecoveryCode{}
+		if err = rows.Scan(&code.Id, &code.UserId, &code.Hash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (r *SqlRecoveryCodeRepository) GetRecoveryCode(userId string, hash string) (*RecoveryCode, error) {
+	code := &RecoveryCode{}
+	row := r.db.QueryRow("SELECT id, user_id, hash FROM recovery_codes WHERE user_id = ? AND hash = ?", userId, hash)
+	err := row.Scan(&code.Id, &code.UserId, &code.Hash)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecoveryCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *SqlRecoveryCodeRepository) DeleteRecoveryCode(id string) error {
+	result, err := r.db.Exec("DELETE FROM recovery_codes WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"authGo/repository"
+)
+
+type RoleStore interface {
+	AddRole(role *Role) error
+	GetRole(name string) (*Role, error)
+	UpdateRole(role *Role) error
+	DeleteRole(name string) error
+	GetRoles() ([]*Role, error)
+}
+
+func getRoleByName(role *Role, value string) bool {
+	return role.Name == value
+}
+
+// RoleRepository keeps the custom roles in memory.
+type RoleRepository struct {
+	repository *repository.Repository[Role]
+}
+
+func NewRoleRepository() *RoleRepository {
+	return &RoleRepository{repository: repository.NewRepository[Role]()}
+}
+
+func (r *RoleRepository) AddRole(role *Role) error {
+	if _, i := r.repository.GetItem(getRoleByName, role.Name); i != -1 {
+		return ErrRoleAlreadyExists
+	}
+	r.repository.Add(role)
+	return nil
+}
+
+func (r *RoleRepository) GetRole(name string) (*Role, error) {
+	role, i := r.repository.GetItem(getRoleByName, name)
+	if i == -1 {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (r *RoleRepository) UpdateRole(role *Role) error {
+	_, i := r.repository.GetItem(getRoleByName, role.Name)
+	if i == -1 {
+		return ErrRoleNotFound
+	}
+	r.repository.Update(i, role)
+	return nil
+}
+
+func (r *RoleRepository) DeleteRole(name string) error {
+	_, i := r.repository.GetItem(getRoleByName, name)
+	if i == -1 {
+		return ErrRoleNotFound
+	}
+	r.repository.Delete(i)
+	return nil
+}
+
+func (r *RoleRepository) GetRoles() ([]*Role, error) {
+	return r.repository.GetAll(), nil
+}
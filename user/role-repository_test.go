@@ -0,0 +1,64 @@
+package user
+
+import (
+	"testing"
+)
+
+// forEachRoleStore runs the test against every RoleStore implementation.
+func forEachRoleStore(t *testing.T, test func(t *testing.T, store RoleStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewRoleRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, NewSqlRoleRepository(createTestSqlUserRepository(t).db))
+	})
+}
+
+func TestRoleStore(t *testing.T) {
+	forEachRoleStore(t, func(t *testing.T, store RoleStore) {
+		helpdesk := &Role{Name: "helpdesk", Description: "Support", Permissions: []string{PermissionSessionsRead, PermissionSessionsRevoke}}
+		if err := store.AddRole(helpdesk); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if err := store.AddRole(&Role{Name: "auditor", Permissions: []string{PermissionUsersRead}}); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if err := store.AddRole(&Role{Name: "helpdesk"}); err != ErrRoleAlreadyExists {
+			t.Errorf("expected err to be ErrRoleAlreadyExists, got %s", err)
+		}
+
+		role, err := store.GetRole("helpdesk")
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if role.Description != "Support" || len(role.Permissions) != 2 {
+			t.Errorf("unexpected role, got %+v", role)
+		}
+		if _, err = store.GetRole("nobody"); err != ErrRoleNotFound {
+			t.Errorf("expected err to be ErrRoleNotFound, got %s", err)
+		}
+
+		err = store.UpdateRole(&Role{Name: "helpdesk", Description: "Helpdesk", Permissions: []string{PermissionSessionsRevoke}})
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		role, _ = store.GetRole("helpdesk")
+		if role.Description != "Helpdesk" || len(role.Permissions) != 1 {
+			t.Errorf("expected the role to be updated, got %+v", role)
+		}
+		if err = store.UpdateRole(&Role{Name: "nobody"}); err != ErrRoleNotFound {
+			t.Errorf("expected err to be ErrRoleNotFound, got %s", err)
+		}
+
+		if err = store.DeleteRole("auditor"); err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		if err = store.DeleteRole("auditor"); err != ErrRoleNotFound {
+			t.Errorf("expected err to be ErrRoleNotFound, got %s", err)
+		}
+		roles, err := store.GetRoles()
+		if err != nil || len(roles) != 1 || roles[0].Name != "helpdesk" {
+			t.Errorf("expected only the helpdesk role, got %v %v", roles, err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package user
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SqlRoleRepository struct {
+	db *sql.DB
+}
+
+// NewSqlRoleRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlRoleRepository(db *sql.DB) *SqlRoleRepository {
+	return &SqlRoleRepository{db: db}
+}
+
+func (r *SqlRoleRepository) AddRole(role *Role) error {
+	permissions, err := json.Marshal(role.Permissions)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("INSERT INTO roles (name, description, permissions) VALUES (?, ?, ?)", role.Name, role.Description, string(permissions))
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return ErrRoleAlreadyExists
+	}
+	return err
+}
+
+func (r *SqlRoleRepository) GetRole(name string) (*Role, error) {
+	role, err := scanRole(r.db.QueryRow("SELECT name, description, permissions FROM roles WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+func (r *SqlRoleRepository) UpdateRole(role *Role) error {
+	permissions, err := json.Marshal(role.Permissions)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.Exec("UPDATE roles SET description = ?, permissions = ? WHERE name = ?", role.Description, string(permissions), role.Name)
+	if err != nil {
+		return err
+	}
+	return checkRoleRowsAffected(result)
+}
+
+func (r *SqlRoleRepository) DeleteRole(name string) error {
+	result, err := r.db.Exec("DELETE FROM roles WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	return checkRoleRowsAffected(result)
+}
+
+func (r *SqlRoleRepository) GetRoles() ([]*Role, error) {
+	rows, err := r.db.Query("SELECT name, description, permissions FROM roles ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := make([]*Role, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func scanRole(row rowScanner) (*Role, error) {
+	role := &Role{}
+	var permissions string
+	if err := row.Scan(&role.Name, &role.Description, &permissions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(permissions), &role.Permissions); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func checkRoleRowsAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+const (
+	PermissionUsersRead      = "users:read"
+	PermissionUsersWrite     = "users:write"
+	PermissionUsersDelete    = "users:delete"
+	PermissionSessionsRead   = "sessions:read"
+	PermissionSessionsRevoke = "sessions:revoke"
+	PermissionRolesRead      = "roles:read"
+	PermissionRolesWrite     = "roles:write"
+
+	AdminRole      = "admin"
+	SuperAdminRole = "super-admin"
+)
+
+// Permissions are all the permissions a role can be made of.
+var Permissions = []string{
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionUsersDelete,
+	PermissionSessionsRead,
+	PermissionSessionsRevoke,
+	PermissionRolesRead,
+	PermissionRolesWrite,
+}
+
+var (
+	ErrRoleNotFound      = errors.New("role: role not found")
+	ErrRoleAlreadyExists = errors.New("role: role already exists")
+	ErrRoleNotValid      = errors.New("role: role not valid")
+	ErrRoleBuiltIn       = errors.New("role: built-in roles cannot be changed")
+	ErrRoleInUse         = errors.New("role: role assigned to users")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"builtIn"`
+}
+
+// BuiltInRoles are defined in the code instead of the role store, so they always have the permissions
+// of the running version. The permissions of a role apply to the organization of the user, the
+// super-admin role also gives access to every organization.
+var BuiltInRoles = []*Role{
+	{Name: AdminRole, Description: "Full access to the organization", Permissions: Permissions, BuiltIn: true},
+	{Name: SuperAdminRole, Description: "Full access to every organization", Permissions: Permissions, BuiltIn: true},
+}
+
+func getBuiltInRole(name string) *Role {
+	for _, role := range BuiltInRoles {
+		if role.Name == name {
+			return role
+		}
+	}
+	return nil
+}
+
+func IsValidPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func newCustomRole(name string, description string, permissions []string) (*Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w, invalid name %q", ErrRoleNotValid, name)
+	}
+	for _, permission := range permissions {
+		if !IsValidPermission(permission) {
+			return nil, fmt.Errorf("%w, unknown permission %q", ErrRoleNotValid, permission)
+		}
+	}
+	return &Role{Name: name, Description: description, Permissions: normalizeNames(permissions)}, nil
+}
+
+// HasRole tells whether the role is assigned to the user.
+func (u *User) HasRole(name string) bool {
+	for _, role := range u.Roles {
+		if role == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNames removes the duplicated names and sorts them.
+func normalizeNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
@@ -0,0 +1,75 @@
+package user
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrTOTPNotFound = errors.New("totp repository: totp not found")
+
+// TOTP is the authenticator app of a user. It only protects the login once Confirmed, LastCounter is
+// the time step of the last code accepted so no code is accepted twice.
+type TOTP struct {
+	UserId      string
+	Secret      string
+	Confirmed   bool
+	LastCounter int64
+}
+
+type TOTPStore interface {
+	GetTOTP(userId string) (*TOTP, error)
+	// SetTOTP adds the TOTP of the user or replaces the existing one.
+	SetTOTP(totp *TOTP) error
+	// UseTOTPCounter sets the last counter when it's newer than the stored one, both at once so that
+	// concurrent logins can't use the same code. It returns false when the counter was already used.
+	UseTOTPCounter(userId string, counter int64) (bool, error)
+	DeleteTOTP(userId string) error
+}
+
+type TOTPRepository struct {
+	mutex sync.Mutex
+	totps map[string]TOTP
+}
+
+func NewTOTPRepository() *TOTPRepository {
+	return &TOTPRepository{totps: make(map[string]TOTP)}
+}
+
+func (r *TOTPRepository) GetTOTP(userId string) (*TOTP, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	totp, ok := r.totps[userId]
+	if !ok {
+		return nil, ErrTOTPNotFound
+	}
+	return &totp, nil
+}
+
+func (r *TOTPRepository) SetTOTP(totp *TOTP) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.totps[totp.UserId] = *totp
+	return nil
+}
+
+func (r *TOTPRepository) UseTOTPCounter(userId string, counter int64) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	totp, ok := r.totps[userId]
+	if !ok {
+		return false, ErrTOTPNotFound
+	}
+	if counter <= totp.LastCounter {
+		return false, nil
+	}
+	totp.LastCounter = counter
+	r.totps[userId] = totp
+	return true, nil
+}
+
+func (r *TOTPRepository) DeleteTOTP(userId string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.totps, userId)
+	return nil
+}
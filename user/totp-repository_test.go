@@ -0,0 +1,66 @@
+package user
+
+import "testing"
+
+// forEachTOTPStore runs the test against every TOTPStore implementation, the users 1, 2 and 3 exist
+// in the store.
+func forEachTOTPStore(t *testing.T, test func(t *testing.T, store TOTPStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewTOTPRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if _, err := createTestUserRepository(r); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlTOTPRepository(r.db))
+	})
+}
+
+func TestTOTPStore(t *testing.T) {
+	forEachTOTPStore(t, func(t *testing.T, store TOTPStore) {
+		if _, err := store.GetTOTP("1"); err != ErrTOTPNotFound {
+			t.Errorf("expected err to be ErrTOTPNotFound, got %v", err)
+		}
+		if _, err := store.UseTOTPCounter("1", 10); err != ErrTOTPNotFound {
+			t.Errorf("expected err to be ErrTOTPNotFound, got %v", err)
+		}
+
+		if err := store.SetTOTP(&TOTP{UserId: "1", Secret: "SECRET1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetTOTP(&TOTP{UserId: "1", Secret: "SECRET2", Confirmed: true}); err != nil {
+			t.Fatal(err)
+		}
+		totp, err := store.GetTOTP("1")
+		if err != nil || totp.Secret != "SECRET2" || !totp.Confirmed || totp.LastCounter != 0 {
+			t.Errorf("expected the TOTP to be replaced, got %+v %v", totp, err)
+		}
+
+		counterTests := []struct {
+			counter int64
+			used    bool
+		}{
+			{10, true},
+			{10, false},
+			{9, false},
+			{11, true},
+		}
+		for _, counterTest := range counterTests {
+			used, err := store.UseTOTPCounter("1", counterTest.counter)
+			if err != nil || used != counterTest.used {
+				t.Errorf("%d: expected used to be %v, got %v %v", counterTest.counter, counterTest.used, used, err)
+			}
+		}
+		if totp, _ = store.GetTOTP("1"); totp.LastCounter != 11 {
+			t.Errorf("expected the last counter to be 11, got %d", totp.LastCounter)
+		}
+
+		if err = store.DeleteTOTP("1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = store.GetTOTP("1"); err != ErrTOTPNotFound {
+			t.Errorf("expected err to be ErrTOTPNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package user
+
+import "database/sql"
+
+type SqlTOTPRepository struct {
+	db *sql.DB
+}
+
+// NewSqlTOTPRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlTOTPRepository(db *sql.DB) *SqlTOTPRepository {
+	return &SqlTOTPRepository{db: db}
+}
+
+func (r *SqlTOTPRepository) GetTOTP(userId string) (*TOTP, error) {
+	totp := &TOTP{UserId: userId}
+	err := r.db.QueryRow("SELECT secret, confirmed, last_counter FROM user_totp WHERE user_id = ?", userId).
+		Scan(&totp.Secret, &totp.Confirmed, &totp.LastCounter)
+	if err == sql.ErrNoRows {
+		return nil, ErrTOTPNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return totp, nil
+}
+
+func (r *SqlTOTPRepository) SetTOTP(totp *TOTP) error {
+	_, err := r.db.Exec(`INSERT INTO user_totp (user_id, secret, confirmed, last_counter) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, confirmed = excluded.confirmed, last_counter = excluded.last_counter`,
+		totp.UserId, totp.Secret, totp.Confirmed, totp.LastCounter)
+	return err
+}
+
+func (r *SqlTOTPRepository) UseTOTPCounter(userId string, counter int64) (bool, error) {
+	result, err := r.db.Exec("UPDATE user_totp SET last_counter = ? WHERE user_id = ? AND last_counter < ?", counter, userId, counter)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		if _, err = r.GetTOTP(userId); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *SqlTOTPRepository) DeleteTOTP(userId string) error {
+	_, err := r.db.Exec("DELETE FROM user_totp WHERE user_id = ?", userId)
+	return err
+}
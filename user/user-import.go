@@ -0,0 +1,332 @@
+package user
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var (
+	ErrImportFileNotValid        = errors.New("user import: file not valid")
+	ErrImportNameMissing         = errors.New("user import: name required")
+	ErrImportPasswordMissing     = errors.New("user import: either password or passwordHash required")
+	ErrImportIdAlreadyRegistered = errors.New("user import: id already registered")
+	ErrPasswordHashNotValid      = errors.New("user import: password hash not valid")
+)
+
+// UserRecord is a user of the import and export files. Password is the plain password of an imported
+// user and PasswordHash an existing hash, only exported on request.
+type UserRecord struct {
+	Id             string         `json:"id,omitempty"`
+	OrganizationId string         `json:"organizationId,omitempty"`
+	Name           string         `json:"name"`
+	Email          string         `json:"email,omitempty"`
+	EmailVerified  bool           `json:"emailVerified,omitempty"`
+	Roles          []string       `json:"roles,omitempty"`
+	Status         string         `json:"status,omitempty"`
+	StatusReason   string         `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time     `json:"suspendedUntil,omitempty"`
+	CreatedAt      *time.Time     `json:"createdAt,omitempty"`
+	DisplayName    string         `json:"displayName,omitempty"`
+	AvatarURL      string         `json:"avatarUrl,omitempty"`
+	Locale         string         `json:"locale,omitempty"`
+	Timezone       string         `json:"timezone,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	Password       string         `json:"password,omitempty"`
+	PasswordHash   string         `json:"passwordHash,omitempty"`
+}
+
+// ImportOptions apply to every row, OrganizationId is used by the rows without one and is
+// DefaultOrganizationId when empty. A DryRun validates the rows without adding the users.
+type ImportOptions struct {
+	OrganizationId string
+	DryRun         bool
+}
+
+// ImportResult counts the users added, or that would be added by a dry run, and the rows refused.
+type ImportResult struct {
+	DryRun  bool
+	Total   int
+	Created int
+	Errors  []*ImportError
+}
+
+// ImportError is the reason a row was refused, Row starts at 1 with the first user of the file.
+type ImportError struct {
+	Row  int
+	Name string
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("row %d (%s): %s", e.Row, e.Name, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+type userRecordList struct {
+	Users []*UserRecord `json:"users"`
+}
+
+// userRecordColumns are the CSV columns, the roles are separated by spaces and the metadata is a JSON
+// object. The export doesn't have the password column.
+var userRecordColumns = []string{"id", "organizationId", "name", "email", "emailVerified", "roles", "status", "statusReason",
+	"suspendedUntil", "createdAt", "displayName", "avatarUrl", "locale", "timezone", "metadata", "password", "passwordHash"}
+
+func IsValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON
+}
+
+// newUserRecord exports the user, with the password hash when includePasswordHash is true.
+func newUserRecord(user *User, includePasswordHash bool) *UserRecord {
+	record := &UserRecord{
+		Id:             user.Id,
+		OrganizationId: user.OrganizationId,
+		Name:           user.Name,
+		Email:          user.Email,
+		EmailVerified:  user.EmailVerified,
+		Roles:          user.Roles,
+		Status:         userStatus(user),
+		StatusReason:   user.StatusReason,
+		SuspendedUntil: user.SuspendedUntil,
+		DisplayName:    user.DisplayName,
+		AvatarURL:      user.AvatarURL,
+		Locale:         user.Locale,
+		Timezone:       user.Timezone,
+		Metadata:       user.Metadata,
+	}
+	if !user.CreatedAt.IsZero() {
+		createdAt := user.CreatedAt
+		record.CreatedAt = &createdAt
+	}
+	if includePasswordHash {
+		record.PasswordHash = user.Password
+	}
+	return record
+}
+
+// WriteUserRecords writes the records in the CSV or JSON format.
+func WriteUserRecords(w io.Writer, format string, records []*UserRecord) error {
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(userRecordList{Users: records})
+	}
+	columns := make([]string, 0, len(userRecordColumns))
+	for _, column := range userRecordColumns {
+		if column != "password" {
+			columns = append(columns, column)
+		}
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, record := range records {
+		fields, err := encodeUserRecord(record, columns)
+		if err != nil {
+			return err
+		}
+		if err = writer.Write(fields); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadUserRecords reads a file in the CSV or JSON format, the CSV files need a header row with the
+// name column and any of the other ones.
+func ReadUserRecords(r io.Reader, format string) ([]*UserRecord, error) {
+	if format == FormatJSON {
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		var list userRecordList
+		if err := decoder.Decode(&list); err != nil {
+			return nil, fmt.Errorf("%w, %s", ErrImportFileNotValid, err)
+		}
+		for i, record := range list.Users {
+			if record == nil {
+				return nil, fmt.Errorf("%w, row %d is null", ErrImportFileNotValid, i+1)
+			}
+		}
+		return list.Users, nil
+	}
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrImportFileNotValid, err)
+	}
+	hasName := false
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if !containsColumn(userRecordColumns, header[i]) {
+			return nil, fmt.Errorf("%w, unknown column %q", ErrImportFileNotValid, column)
+		}
+		hasName = hasName || header[i] == "name"
+	}
+	if !hasName {
+		return nil, fmt.Errorf("%w, name column missing", ErrImportFileNotValid)
+	}
+	records := make([]*UserRecord, 0)
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w, %s", ErrImportFileNotValid, err)
+		}
+		record, err := decodeUserRecord(header, fields)
+		if err != nil {
+			return nil, fmt.Errorf("%w, row %d: %s", ErrImportFileNotValid, len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
+
+func containsColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeUserRecord(record *UserRecord, columns []string) ([]string, error) {
+	fields := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "id":
+			fields[i] = record.Id
+		case "organizationId":
+			fields[i] = record.OrganizationId
+		case "name":
+			fields[i] = record.Name
+		case "email":
+			fields[i] = record.Email
+		case "emailVerified":
+			fields[i] = strconv.FormatBool(record.EmailVerified)
+		case "roles":
+			fields[i] = strings.Join(record.Roles, " ")
+		case "status":
+			fields[i] = record.Status
+		case "statusReason":
+			fields[i] = record.StatusReason
+		case "suspendedUntil":
+			fields[i] = formatRecordTime(record.SuspendedUntil)
+		case "createdAt":
+			fields[i] = formatRecordTime(record.CreatedAt)
+		case "displayName":
+			fields[i] = record.DisplayName
+		case "avatarUrl":
+			fields[i] = record.AvatarURL
+		case "locale":
+			fields[i] = record.Locale
+		case "timezone":
+			fields[i] = record.Timezone
+		case "metadata":
+			if len(record.Metadata) > 0 {
+				data, err := json.Marshal(record.Metadata)
+				if err != nil {
+					return nil, err
+				}
+				fields[i] = string(data)
+			}
+		case "password":
+			fields[i] = record.Password
+		case "passwordHash":
+			fields[i] = record.PasswordHash
+		}
+	}
+	return fields, nil
+}
+
+func decodeUserRecord(columns []string, fields []string) (*UserRecord, error) {
+	record := &UserRecord{}
+	var err error
+	for i, column := range columns {
+		value := fields[i]
+		switch column {
+		case "id":
+			record.Id = value
+		case "organizationId":
+			record.OrganizationId = value
+		case "name":
+			record.Name = value
+		case "email":
+			record.Email = value
+		case "emailVerified":
+			if value != "" {
+				if record.EmailVerified, err = strconv.ParseBool(value); err != nil {
+					return nil, fmt.Errorf("emailVerified must be true or false, got %q", value)
+				}
+			}
+		case "roles":
+			if roles := strings.Fields(value); len(roles) > 0 {
+				record.Roles = roles
+			}
+		case "status":
+			record.Status = value
+		case "statusReason":
+			record.StatusReason = value
+		case "suspendedUntil":
+			if record.SuspendedUntil, err = parseRecordTime(value); err != nil {
+				return nil, fmt.Errorf("suspendedUntil %s", err)
+			}
+		case "createdAt":
+			if record.CreatedAt, err = parseRecordTime(value); err != nil {
+				return nil, fmt.Errorf("createdAt %s", err)
+			}
+		case "displayName":
+			record.DisplayName = value
+		case "avatarUrl":
+			record.AvatarURL = value
+		case "locale":
+			record.Locale = value
+		case "timezone":
+			record.Timezone = value
+		case "metadata":
+			if value != "" {
+				if err = json.Unmarshal([]byte(value), &record.Metadata); err != nil {
+					return nil, fmt.Errorf("metadata must be a JSON object, %s", err)
+				}
+			}
+		case "password":
+			record.Password = value
+		case "passwordHash":
+			record.PasswordHash = value
+		}
+	}
+	return record, nil
+}
+
+func formatRecordTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseRecordTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, errors.New("must be an RFC 3339 date")
+	}
+	return &t, nil
+}
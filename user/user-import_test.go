@@ -0,0 +1,69 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserRecordsRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 30, 0, 500, time.UTC)
+	records := []*UserRecord{
+		{Id: "1", OrganizationId: DefaultOrganizationId, Name: "alice, \"the admin\"", Email: "alice@example.com", EmailVerified: true,
+			Roles: []string{AdminRole, "helpdesk"}, Status: UserStatusEnabled, CreatedAt: &createdAt, DisplayName: "Alice",
+			Timezone: "Europe/Madrid", Metadata: map[string]any{"department": "sales"}, PasswordHash: "$argon2id$hash"},
+		{Id: "2", OrganizationId: DefaultOrganizationId, Name: "bob", Status: UserStatusSuspended, StatusReason: "spam", SuspendedUntil: &createdAt},
+	}
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		var buffer bytes.Buffer
+		if err := WriteUserRecords(&buffer, format, records); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		read, err := ReadUserRecords(&buffer, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if len(read) != len(records) {
+			t.Fatalf("%s: expected %d records, got %d", format, len(records), len(read))
+		}
+		for i := range records {
+			if !reflect.DeepEqual(read[i], records[i]) {
+				t.Errorf("%s: expected %+v, got %+v", format, records[i], read[i])
+			}
+		}
+	}
+}
+
+func TestReadUserRecordsCSV(t *testing.T) {
+	records, err := ReadUserRecords(strings.NewReader("name, password,roles\nalice,secret,admin  helpdesk\nbob,other,\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if len(records) != 2 || records[0].Password != "secret" || len(records[0].Roles) != 2 || records[1].Name != "bob" || len(records[1].Roles) != 0 {
+		t.Errorf("unexpected records, got %+v %+v", records[0], records[1])
+	}
+
+	invalidFiles := []struct {
+		format string
+		file   string
+	}{
+		{FormatCSV, ""},
+		{FormatCSV, "email,password\na@example.com,secret\n"},
+		{FormatCSV, "name,isAdmin\nalice,true\n"},
+		{FormatCSV, "name,password\nalice\n"},
+		{FormatCSV, "name,emailVerified\nalice,maybe\n"},
+		{FormatCSV, "name,createdAt\nalice,yesterday\n"},
+		{FormatCSV, "name,metadata\nalice,[1]\n"},
+		{FormatJSON, `{"users": [{"name": "alice", "isAdmin": true}]}`},
+		{FormatJSON, `{"users": [null]}`},
+		{FormatJSON, `[{"name": "alice"}]`},
+	}
+	for _, invalidFile := range invalidFiles {
+		if _, err = ReadUserRecords(strings.NewReader(invalidFile.file), invalidFile.format); !errors.Is(err, ErrImportFileNotValid) {
+			t.Errorf("%s %q: expected err to be ErrImportFileNotValid, got %v", invalidFile.format, invalidFile.file, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package user
+
+import (
+	"authGo/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrUserNameCollision = errors.New("user migration: user names can't be told apart")
+
+var Migrations = []database.Migration{
+	{
+		Version: 1,
+		Name:    "create users",
+		Statements: []string{
+			`CREATE TABLE users (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				is_admin INTEGER NOT NULL DEFAULT 0,
+				password TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create password resets",
+		Statements: []string{
+			`CREATE TABLE password_resets (
+				token_hash TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				expires_at INTEGER NOT NULL
+			)`,
+			"CREATE INDEX password_resets_user_id ON password_resets (user_id)",
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add user emails",
+		Statements: []string{
+			"ALTER TABLE users ADD COLUMN email TEXT",
+			"ALTER TABLE users ADD COLUMN email_verified INTEGER NOT NULL DEFAULT 0",
+			"CREATE UNIQUE INDEX users_email ON users (email)",
+		},
+	},
+	{
+		Version: 4,
+		Name:    "replace the admin flag with roles",
+		Statements: []string{
+			`CREATE TABLE roles (
+				name TEXT PRIMARY KEY,
+				description TEXT NOT NULL DEFAULT '',
+				permissions TEXT NOT NULL DEFAULT '[]'
+			)`,
+			`CREATE TABLE user_roles (
+				user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role TEXT NOT NULL,
+				PRIMARY KEY (user_id, role)
+			)`,
+			"CREATE INDEX user_roles_role ON user_roles (role)",
+			"INSERT INTO user_roles (user_id, role) SELECT id, 'admin' FROM users WHERE is_admin = 1",
+			"ALTER TABLE users DROP COLUMN is_admin",
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add organizations",
+		Statements: []string{
+			`CREATE TABLE organizations (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL
+			)`,
+			"INSERT INTO organizations (id, name) VALUES ('default', 'Default')",
+			// The unique user name becomes unique per organization, SQLite needs the table to be rebuilt
+			// for it. Dropping the old table cascades to the tables referencing it, so their rows are
+			// copied before and restored after.
+			"CREATE TABLE user_roles_backup AS SELECT user_id, role FROM user_roles",
+			"CREATE TABLE password_resets_backup AS SELECT token_hash, user_id, expires_at FROM password_resets",
+			`CREATE TABLE users_new (
+				id TEXT PRIMARY KEY,
+				organization_id TEXT NOT NULL REFERENCES organizations(id),
+				name TEXT NOT NULL,
+				email TEXT,
+				email_verified INTEGER NOT NULL DEFAULT 0,
+				password TEXT NOT NULL,
+				UNIQUE (organization_id, name)
+			)`,
+			`INSERT INTO users_new (id, organization_id, name, email, email_verified, password)
+				SELECT id, 'default', name, email, email_verified, password FROM users ORDER BY rowid`,
+			"DROP TABLE users",
+			"ALTER TABLE users_new RENAME TO users",
+			"CREATE UNIQUE INDEX users_email ON users (email)",
+			"CREATE INDEX users_organization_id ON users (organization_id)",
+			"INSERT INTO user_roles (user_id, role) SELECT user_id, role FROM user_roles_backup",
+			"INSERT INTO password_resets (token_hash, user_id, expires_at) SELECT token_hash, user_id, expires_at FROM password_resets_backup",
+			"DROP TABLE user_roles_backup",
+			"DROP TABLE password_resets_backup",
+			// The administrators could see every user until now, they keep it as super administrators.
+			"INSERT INTO user_roles (user_id, role) SELECT user_id, 'super-admin' FROM user_roles WHERE role = 'admin'",
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create login failures",
+		Statements: []string{
+			`CREATE TABLE login_failures (
+				user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				count INTEGER NOT NULL,
+				locked_until INTEGER NOT NULL DEFAULT 0
+			)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create user totp",
+		Statements: []string{
+			`CREATE TABLE user_totp (
+				user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				secret TEXT NOT NULL,
+				confirmed INTEGER NOT NULL DEFAULT 0,
+				last_counter INTEGER NOT NULL DEFAULT 0
+			)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create webauthn credentials",
+		Statements: []string{
+			`CREATE TABLE webauthn_credentials (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				public_key BLOB NOT NULL,
+				sign_count INTEGER NOT NULL DEFAULT 0,
+				created_at INTEGER NOT NULL
+			)`,
+			"CREATE INDEX webauthn_credentials_user_id ON webauthn_credentials (user_id)",
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create recovery codes",
+		Statements: []string{
+			`CREATE TABLE recovery_codes (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				hash TEXT NOT NULL
+			)`,
+			"CREATE INDEX recovery_codes_user_id ON recovery_codes (user_id)",
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add user creation dates",
+		Statements: []string{
+			"ALTER TABLE users ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX users_name ON users (name, id)",
+			"CREATE INDEX users_created_at ON users (created_at, id)",
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add user status",
+		Statements: []string{
+			"ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT 'enabled'",
+			"ALTER TABLE users ADD COLUMN status_reason TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE users ADD COLUMN suspended_until INTEGER NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add user profiles",
+		Statements: []string{
+			"ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE users ADD COLUMN metadata TEXT NOT NULL DEFAULT ''",
+		},
+	},
+	{
+		Version: 13,
+		Name:    "create invitations",
+		Statements: []string{
+			`CREATE TABLE invitations (
+				id TEXT PRIMARY KEY,
+				organization_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				email TEXT NOT NULL DEFAULT '',
+				roles TEXT NOT NULL,
+				created_by TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				expires_at INTEGER NOT NULL,
+				accepted_at INTEGER NOT NULL DEFAULT 0,
+				user_id TEXT NOT NULL DEFAULT ''
+			)`,
+			"CREATE INDEX invitations_organization_id ON invitations (organization_id, created_at)",
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create login attempts",
+		Statements: []string{
+			`CREATE TABLE login_attempts (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				time INTEGER NOT NULL,
+				ip_address TEXT NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				method TEXT NOT NULL,
+				outcome TEXT NOT NULL,
+				failure_reason TEXT NOT NULL DEFAULT ''
+			)`,
+			"CREATE INDEX login_attempts_user_id ON login_attempts (user_id, time)",
+			"ALTER TABLE users ADD COLUMN last_login_at INTEGER NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add canonical user names",
+		Statements: []string{
+			"ALTER TABLE users ADD COLUMN canonical_name TEXT NOT NULL DEFAULT ''",
+		},
+		Apply: addCanonicalNames,
+	},
+	{
+		Version: 16,
+		Name:    "create webauthn challenges",
+		Statements: []string{
+			`CREATE TABLE webauthn_challenges (
+				challenge TEXT PRIMARY KEY,
+				expires_at INTEGER NOT NULL
+			)`,
+			"CREATE INDEX webauthn_challenges_expires_at ON webauthn_challenges (expires_at)",
+		},
+	},
+	{
+		// the codes hashed like the passwords can't be turned into digests, the users generate new ones
+		Version: 17,
+		Name:    "hash recovery codes with hmac",
+		Statements: []string{
+			"DELETE FROM recovery_codes",
+			"DROP INDEX recovery_codes_user_id",
+			"CREATE INDEX recovery_codes_user_id_hash ON recovery_codes (user_id, hash)",
+		},
+	},
+}
+
+// addCanonicalNames fills the canonical names of the existing users and makes them unique in every
+// organization. It fails with ErrUserNameCollision listing the names that only differ in case, width,
+// whitespace or look-alike letters, they must be renamed before migrating.
+func addCanonicalNames(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, organization_id, name FROM users ORDER BY organization_id, rowid")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	canonicalNames := make(map[string]string)
+	names := make(map[string][]string)
+	for rows.Next() {
+		var id, organizationId, name string
+		if err = rows.Scan(&id, &organizationId, &name); err != nil {
+			return err
+		}
+		canonicalNames[id] = CanonicalName(name)
+		key := organizationId + "/" + canonicalNames[id]
+		names[key] = append(names[key], fmt.Sprintf("%s %q", organizationId, name))
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	collisions := make([]string, 0)
+	for _, colliding := range names {
+		if len(colliding) > 1 {
+			collisions = append(collisions, strings.Join(colliding, ", "))
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return fmt.Errorf("%w, rename them: %s", ErrUserNameCollision, strings.Join(collisions, "; "))
+	}
+	for id, canonicalName := range canonicalNames {
+		if _, err = tx.Exec("UPDATE users SET canonical_name = ? WHERE id = ?", canonicalName, id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("CREATE UNIQUE INDEX users_canonical_name ON users (organization_id, canonical_name)")
+	return err
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrUserNameNotValid = errors.New("user: user name not valid")
+
+// scriptSets are the scripts a user name can mix, the letters of any other two scripts in the same name
+// are refused as they are mostly used to imitate other names. A name can always use a single script.
+var scriptSets = [][]*unicode.RangeTable{
+	{unicode.Latin, unicode.Han, unicode.Hiragana, unicode.Katakana},
+	{unicode.Latin, unicode.Han, unicode.Bopomofo},
+	{unicode.Latin, unicode.Han, unicode.Hangul},
+}
+
+// confusables maps the case folded Cyrillic and Greek letters looking like a Latin letter, in lowercase
+// or uppercase, to that letter.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'һ': 'h', 'н': 'h', 'і': 'i', 'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'ԝ': 'w', 'х': 'x', 'у': 'y', 'ү': 'y', 'с': 'c', 'ԁ': 'd',
+	'α': 'a', 'β': 'b', 'ϲ': 'c', 'ε': 'e', 'η': 'h', 'ι': 'i', 'ϳ': 'j', 'κ': 'k', 'μ': 'm', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ζ': 'z',
+}
+
+// NormalizeName returns the user name in the form it's stored, NFKC normalized and without surrounding
+// whitespace. It returns ErrUserNameNotValid for an empty name, a name with invisible or control
+// characters, with whitespace other than single spaces, or mixing the letters of several scripts.
+func NormalizeName(name string) (string, error) {
+	name = strings.TrimSpace(norm.NFKC.String(name))
+	if name == "" {
+		return "", ErrUserNameNotValid
+	}
+	var previous rune
+	scripts := make([]*unicode.RangeTable, 0, 1)
+	for _, r := range name {
+		if r == ' ' && previous == ' ' || r != ' ' && !unicode.IsPrint(r) {
+			return "", ErrUserNameNotValid
+		}
+		if script := letterScript(r); script != nil && !containsScript(scripts, script) {
+			scripts = append(scripts, script)
+		}
+		previous = r
+	}
+	if !isAllowedScriptMix(scripts) {
+		return "", ErrUserNameNotValid
+	}
+	return name, nil
+}
+
+// CanonicalName returns the key that keeps the user names unique in an organization, the names with the
+// same key can't be told apart. The name is NFKC normalized and case folded, its whitespace is collapsed
+// and the letters of other scripts looking like Latin ones are replaced by them.
+func CanonicalName(name string) string {
+	name = strings.Join(strings.Fields(norm.NFKC.String(name)), " ")
+	name = norm.NFKC.String(cases.Fold().String(name))
+	return strings.Map(func(r rune) rune {
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+		return r
+	}, name)
+}
+
+// letterScript returns the script of a letter, or nil for the other characters and the letters shared by
+// several scripts.
+func letterScript(r rune) *unicode.RangeTable {
+	if !unicode.IsLetter(r) {
+		return nil
+	}
+	for name, script := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(script, r) {
+			return script
+		}
+	}
+	return nil
+}
+
+func isAllowedScriptMix(scripts []*unicode.RangeTable) bool {
+	if len(scripts) <= 1 {
+		return true
+	}
+	for _, set := range scriptSets {
+		allowed := true
+		for _, script := range scripts {
+			allowed = allowed && containsScript(set, script)
+		}
+		if allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func containsScript(scripts []*unicode.RangeTable, script *unicode.RangeTable) bool {
+	for _, s := range scripts {
+		if s == script {
+			return true
+		}
+	}
+	return false
+}
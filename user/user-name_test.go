@@ -0,0 +1,62 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		err      error
+	}{
+		{name: "admin", expected: "admin"},
+		{name: "  John Doe ", expected: "John Doe"},
+		{name: "ａｄｍｉｎ", expected: "admin"},
+		{name: "ﬁle", expected: "file"},
+		{name: "José", expected: "José"},
+		{name: "Jose\u0301", expected: "José"},
+		{name: "山田 Taro", expected: "山田 Taro"},
+		{name: "やまだ タロウ 山田", expected: "やまだ タロウ 山田"},
+		{name: "Иван", expected: "Иван"},
+		{name: "", err: ErrUserNameNotValid},
+		{name: "   ", err: ErrUserNameNotValid},
+		{name: "John  Doe", err: ErrUserNameNotValid},
+		{name: "John\tDoe", err: ErrUserNameNotValid},
+		{name: "ad\u200bmin", err: ErrUserNameNotValid},
+		{name: "admіn", err: ErrUserNameNotValid},
+		{name: "Иван Smith", err: ErrUserNameNotValid},
+		{name: "한국 やまだ", err: ErrUserNameNotValid},
+	}
+	for _, test := range tests {
+		name, err := NormalizeName(test.name)
+		if !errors.Is(err, test.err) {
+			t.Errorf("expected the error of %q to be %v, got %v", test.name, test.err, err)
+		} else if name != test.expected {
+			t.Errorf("expected %q to be normalized to %q, got %q", test.name, test.expected, name)
+		}
+	}
+}
+
+func TestCanonicalName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "admin", expected: "admin"},
+		{name: "Admin", expected: "admin"},
+		{name: "ＡＤＭＩＮ", expected: "admin"},
+		{name: " John \t Doe ", expected: "john doe"},
+		{name: "Straße", expected: "strasse"},
+		{name: "аdmin", expected: "admin"},
+		{name: "АDMIN", expected: "admin"},
+		{name: "ΑΒΕ", expected: "abe"},
+		{name: "Jose\u0301", expected: "josé"},
+	}
+	for _, test := range tests {
+		if name := CanonicalName(test.name); name != test.expected {
+			t.Errorf("expected the canonical name of %q to be %q, got %q", test.name, test.expected, name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package user
+
+import (
+	"authGo/jsonschema"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+const (
+	maxDisplayNameLength = 100
+	maxAvatarURLLength   = 2048
+	// MaxMetadataSize is the size of the metadata encoded as JSON.
+	MaxMetadataSize = 16 * 1024
+)
+
+// localePattern matches the BCP 47 language tags like en, pt-BR or zh-Hant-TW.
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// MetadataError is ErrMetadataNotValid along with the reason, meant to be shown to the user.
+type MetadataError struct {
+	Reason string
+}
+
+func (e *MetadataError) Error() string {
+	return ErrMetadataNotValid.Error() + ", " + e.Reason
+}
+
+func (e *MetadataError) Unwrap() error {
+	return ErrMetadataNotValid
+}
+
+// applyProfileUpdate validates the changed fields of the profile and copies them to the user.
+func applyProfileUpdate(user *User, update ProfileUpdate, schema *jsonschema.Schema) error {
+	if update.DisplayName != nil {
+		displayName := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			return ErrDisplayNameNotValid
+		}
+		user.DisplayName = displayName
+	}
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" && !isValidAvatarURL(avatarURL) {
+			return ErrAvatarURLNotValid
+		}
+		user.AvatarURL = avatarURL
+	}
+	if update.Locale != nil {
+		locale := strings.TrimSpace(*update.Locale)
+		if locale != "" && !localePattern.MatchString(locale) {
+			return ErrLocaleNotValid
+		}
+		user.Locale = locale
+	}
+	if update.Timezone != nil {
+		timezone := strings.TrimSpace(*update.Timezone)
+		if timezone != "" && !isValidTimezone(timezone) {
+			return ErrTimezoneNotValid
+		}
+		user.Timezone = timezone
+	}
+	if update.Metadata != nil {
+		metadata, err := validateMetadata(*update.Metadata, schema)
+		if err != nil {
+			return err
+		}
+		user.Metadata = metadata
+	}
+	return nil
+}
+
+func isValidAvatarURL(value string) bool {
+	if len(value) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// isValidTimezone accepts the IANA time zone names, Local is refused as it depends on the server.
+func isValidTimezone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// validateMetadata checks the size and schema of the metadata, returning a copy decoded from its JSON
+// so the stored map only holds JSON values.
+func validateMetadata(metadata map[string]any, schema *jsonschema.Schema) (map[string]any, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, &MetadataError{Reason: err.Error()}
+	}
+	if len(data) > MaxMetadataSize {
+		return nil, &MetadataError{Reason: fmt.Sprintf("larger than %d bytes", MaxMetadataSize)}
+	}
+	var decoded map[string]any
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		return nil, &MetadataError{Reason: err.Error()}
+	}
+	if schema != nil {
+		var value any = decoded
+		if decoded == nil {
+			value = map[string]any{}
+		}
+		var validationErr *jsonschema.ValidationError
+		if err = schema.Validate(value); errors.As(err, &validationErr) {
+			path := validationErr.Path
+			if path == "" {
+				path = "/"
+			}
+			return nil, &MetadataError{Reason: path + " " + validationErr.Message}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(decoded) == 0 {
+		return nil, nil
+	}
+	return decoded, nil
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SortByName      = "name"
+	SortByCreatedAt = "createdAt"
+
+	DefaultQueryLimit = 50
+	MaxQueryLimit     = 200
+)
+
+var (
+	ErrInvalidSortField = errors.New("user query: invalid sort field")
+	ErrInvalidCursor    = errors.New("user query: invalid cursor")
+)
+
+// UserQuery selects a page of users, the empty fields don't filter. The users are sorted by SortBy and
+// then by id, and the page starts after the user of Cursor.
+type UserQuery struct {
+	// OrganizationId limits the users to an organization, all of them are returned when empty.
+	OrganizationId string
+	// Search matches the names containing it, comparing their canonical forms, see CanonicalName.
+	Search string
+	// Prefix matches the names starting with it, comparing their canonical forms.
+	Prefix string
+	Role   string
+	Status string
+	// Admin keeps only the users with the admin role when true, and only the others when false.
+	Admin      *bool
+	SortBy     string
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+// UserPage is a page of the users of a query. Total counts the users matching the filters of every
+// page, and NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []*User
+	Total      int
+	NextCursor string
+}
+
+// userCursor is the position of the last user of a page, it's only valid with the same sorting.
+type userCursor struct {
+	SortBy     string `json:"sortBy"`
+	Descending bool   `json:"descending"`
+	Name       string `json:"name,omitempty"`
+	CreatedAt  int64  `json:"createdAt,omitempty"`
+	Id         string `json:"id"`
+}
+
+func NewUserQuery() *UserQuery {
+	return &UserQuery{SortBy: SortByName, Limit: DefaultQueryLimit}
+}
+
+func IsValidSortField(field string) bool {
+	return field == SortByName || field == SortByCreatedAt
+}
+
+// prepare checks the sort field and reads the cursor, nil when the query starts at the first page.
+func (q *UserQuery) prepare() (*userCursor, error) {
+	if !IsValidSortField(q.SortBy) {
+		return nil, fmt.Errorf("%w, got %s", ErrInvalidSortField, q.SortBy)
+	}
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	return q.decodeCursor()
+}
+
+func (q *UserQuery) limit() int {
+	if q.Limit <= 0 || q.Limit > MaxQueryLimit {
+		return DefaultQueryLimit
+	}
+	return q.Limit
+}
+
+func (q *UserQuery) encodeCursor(user *User) string {
+	cursor := userCursor{SortBy: q.SortBy, Descending: q.Descending, Id: user.Id}
+	if cursor.SortBy == SortByName {
+		cursor.Name = user.Name
+	} else {
+		cursor.CreatedAt = unixNano(user.CreatedAt)
+	}
+	data, _ := json.Marshal(cursor)
+	return b64.RawURLEncoding.EncodeToString(data)
+}
+
+func (q *UserQuery) decodeCursor() (*userCursor, error) {
+	data, err := b64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidCursor, err)
+	}
+	var cursor userCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidCursor, err)
+	}
+	if cursor.SortBy != q.SortBy || cursor.Descending != q.Descending {
+		return nil, fmt.Errorf("%w, cursor was created with a different sort order", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+func (q *UserQuery) Matches(user *User) bool {
+	if q.OrganizationId != "" && user.OrganizationId != q.OrganizationId {
+		return false
+	}
+	name := CanonicalName(user.Name)
+	if q.Search != "" && !strings.Contains(name, CanonicalName(q.Search)) {
+		return false
+	}
+	if q.Prefix != "" && !strings.HasPrefix(name, CanonicalName(q.Prefix)) {
+		return false
+	}
+	if q.Role != "" && !user.HasRole(q.Role) {
+		return false
+	}
+	if q.Status != "" && userStatus(user) != q.Status {
+		return false
+	}
+	return q.Admin == nil || user.HasRole(AdminRole) == *q.Admin
+}
+
+// compare orders two users by the sort field and then by id, in the direction of the query.
+func (q *UserQuery) compare(a *userCursor, b *userCursor) int {
+	result := 0
+	if q.SortBy == SortByName {
+		result = strings.Compare(a.Name, b.Name)
+	} else if a.CreatedAt < b.CreatedAt {
+		result = -1
+	} else if a.CreatedAt > b.CreatedAt {
+		result = 1
+	}
+	if result == 0 {
+		result = strings.Compare(a.Id, b.Id)
+	}
+	if q.Descending {
+		return -result
+	}
+	return result
+}
+
+// Apply filters, sorts and paginates the given users, for the stores that can't run the query
+// themselves.
+func (q *UserQuery) Apply(users []*User) (*UserPage, error) {
+	after, err := q.prepare()
+	if err != nil {
+		return nil, err
+	}
+	matching := make([]*User, 0)
+	for _, user := range users {
+		if q.Matches(user) {
+			matching = append(matching, user)
+		}
+	}
+	key := func(user *User) *userCursor {
+		return &userCursor{Name: user.Name, CreatedAt: unixNano(user.CreatedAt), Id: user.Id}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return q.compare(key(matching[i]), key(matching[j])) < 0
+	})
+
+	page := &UserPage{Users: make([]*User, 0), Total: len(matching)}
+	for _, user := range matching {
+		if after != nil && q.compare(key(user), after) <= 0 {
+			continue
+		}
+		if len(page.Users) == q.limit() {
+			page.NextCursor = q.encodeCursor(page.Users[len(page.Users)-1])
+			break
+		}
+		page.Users = append(page.Users, user)
+	}
+	return page, nil
+}
+
+// unixNano stores the zero time as 0, the creation date of the users added before it was recorded.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeFromUnixNano(nanoseconds int64) time.Time {
+	if nanoseconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanoseconds)
+}
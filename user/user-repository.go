@@ -3,57 +3,165 @@ package user
 import (
 	"authGo/repository"
 	"errors"
+	"sync"
+	"time"
 )
 
 var ErrUserNotFound = errors.New("user repository: user not found")
 var ErrUserAlreadyRegistered = errors.New("user repository: user already registered")
+var ErrEmailAlreadyRegistered = errors.New("user repository: email already registered")
 
 func getById(user *User, value string) bool {
 	return user.Id == value
 }
 
-func getByName(user *User, value string) bool {
-	return user.Name == value
+func getByEmail(user *User, value string) bool {
+	return value != "" && user.Email == value
+}
+
+// userName is the key of a user name in its organization, see CanonicalName.
+type userName struct {
+	organizationId string
+	canonicalName  string
 }
 
 type UserRepository struct {
+	mutex      sync.RWMutex
 	repository *repository.Repository[User]
+	// names indexes the user ids by the canonical form of their name, and userNames the other way round,
+	// so the lookups by name don't normalize every stored name.
+	names     map[userName]string
+	userNames map[string]userName
 }
 
 func NewUserRepository() *UserRepository {
-	return &UserRepository{repository: repository.NewRepository[User]()}
+	return &UserRepository{
+		repository: repository.NewRepository[User](),
+		names:      make(map[userName]string),
+		userNames:  make(map[string]userName),
+	}
 }
 
 func (r *UserRepository) Add(user *User) error {
-	i, _ := r.getIndexByName(user.Name)
-	if i != -1 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	name := userName{organizationId: user.OrganizationId, canonicalName: CanonicalName(user.Name)}
+	if _, ok := r.names[name]; ok {
 		return ErrUserAlreadyRegistered
 	}
+	if registered, _, _ := r.getUser(getByEmail, user.Email); registered != nil {
+		return ErrEmailAlreadyRegistered
+	}
 	r.repository.Add(user)
+	r.setName(user.Id, &name)
 	return nil
 }
 
 func (r *UserRepository) GetById(id string) (*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	user, _, err := r.getUser(getById, id)
 	return user, err
 }
 
-func (r *UserRepository) GetByName(name string) (*User, error) {
-	user, _, err := r.getUser(getByName, name)
+func (r *UserRepository) GetByName(organizationId string, name string) (*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	id, ok := r.names[userName{organizationId: organizationId, canonicalName: CanonicalName(name)}]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user, _, err := r.getUser(getById, id)
 	return user, err
 }
 
+func (r *UserRepository) GetByEmail(email string) (*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	user, _, err := r.getUser(getByEmail, email)
+	return user, err
+}
+
+func (r *UserRepository) Update(user *User) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	i, err := r.getIndexById(user.Id)
+	if err != nil {
+		return err
+	}
+	name := userName{organizationId: user.OrganizationId, canonicalName: CanonicalName(user.Name)}
+	if id, ok := r.names[name]; ok && id != user.Id {
+		return ErrUserAlreadyRegistered
+	}
+	if registered, _, _ := r.getUser(getByEmail, user.Email); registered != nil && registered.Id != user.Id {
+		return ErrEmailAlreadyRegistered
+	}
+	r.repository.Update(i, user)
+	r.setName(user.Id, &name)
+	return nil
+}
+
+func (r *UserRepository) UpdateLastLogin(id string, lastLoginAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	user, i, err := r.getUser(getById, id)
+	if err != nil {
+		return err
+	}
+	updated := *user
+	updated.LastLoginAt = &lastLoginAt
+	r.repository.Update(i, &updated)
+	return nil
+}
+
+func (r *UserRepository) UpdatePassword(id string, passwordHash string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	user, i, err := r.getUser(getById, id)
+	if err != nil {
+		return err
+	}
+	updated := *user
+	updated.Password = passwordHash
+	r.repository.Update(i, &updated)
+	return nil
+}
+
 func (r *UserRepository) Delete(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	i, err := r.getIndexById(id)
 	if err != nil {
 		return err
 	}
 	r.repository.Delete(i)
+	r.setName(id, nil)
 	return nil
 }
 
-func (r *UserRepository) GetAll() []*User {
-	return r.repository.GetAll()
+func (r *UserRepository) GetAll() ([]*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	// copies the users, the callers keep the slice after the lock is released
+	return append(make([]*User, 0), r.repository.GetAll()...), nil
+}
+
+func (r *UserRepository) GetByOrganization(organizationId string) ([]*User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	users := make([]*User, 0)
+	for _, user := range r.repository.GetAll() {
+		if user.OrganizationId == organizationId {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *UserRepository) Query(query UserQuery) (*UserPage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return query.Apply(r.repository.GetAll())
 }
 
 func (r *UserRepository) getUser(comparableFunc repository.ComparableFunc[User], value string) (*User, int, error) {
@@ -64,12 +172,19 @@ func (r *UserRepository) getUser(comparableFunc repository.ComparableFunc[User],
 	return user, i, nil
 }
 
-func (r *UserRepository) getIndexById(id string) (int, error) {
-	_, i, err := r.getUser(getById, id)
-	return i, err
+// setName indexes the new name of the user, replacing the previous one, a nil name only removes it.
+func (r *UserRepository) setName(id string, name *userName) {
+	if previous, ok := r.userNames[id]; ok {
+		delete(r.names, previous)
+		delete(r.userNames, id)
+	}
+	if name != nil {
+		r.names[*name] = id
+		r.userNames[id] = *name
+	}
 }
 
-func (r *UserRepository) getIndexByName(name string) (int, error) {
-	_, i, err := r.getUser(getByName, name)
+func (r *UserRepository) getIndexById(id string) (int, error) {
+	_, i, err := r.getUser(getById, id)
 	return i, err
 }
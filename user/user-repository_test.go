@@ -1,16 +1,44 @@
 package user
 
 import (
-	"reflect"
+	"authGo/database"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
-func createTestUserRepository() (*UserRepository, error) {
-	r := NewUserRepository()
+// forEachStore runs the test against every UserStore implementation.
+func forEachStore(t *testing.T, test func(t *testing.T, newStore func() UserStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, func() UserStore { return NewUserRepository() })
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, func() UserStore { return createTestSqlUserRepository(t) })
+	})
+}
+
+func createTestSqlUserRepository(t *testing.T) *SqlUserRepository {
+	db, err := database.OpenSqlite(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	r, err := NewSqlUserRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func createTestUserRepository(r UserStore) (UserStore, error) {
 	users := []*User{
-		{Id: "1", Name: "test1", Password: "test1"},
-		{Id: "2", Name: "test2", Password: "test2"},
-		{Id: "3", Name: "test3", Password: "test3"},
+		{Id: "1", OrganizationId: DefaultOrganizationId, Name: "test1", Password: "test1"},
+		{Id: "2", OrganizationId: DefaultOrganizationId, Name: "test2", Password: "test2"},
+		{Id: "3", OrganizationId: DefaultOrganizationId, Name: "test3", Password: "test3", Roles: []string{AdminRole}},
 	}
 	for _, user := range users {
 		err := r.Add(user)
@@ -22,72 +50,735 @@ func createTestUserRepository() (*UserRepository, error) {
 }
 
 func TestAdd(t *testing.T) {
-	r, err := createTestUserRepository()
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		users, err := r.GetAll()
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		err = r.Add(users[0])
+		if err != ErrUserAlreadyRegistered {
+			t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %s", err)
+		}
+	})
+}
+
+func TestById(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		user, err := r.GetById("3")
+		if err != nil {
+			t.Error("expected err to be nil")
+		}
+		if user.Name != "test3" || user.Password != "test3" || !user.HasRole(AdminRole) {
+			t.Errorf("unexpected user, got %+v", user)
+		}
+		_, err = r.GetById("1111")
+		if err != ErrUserNotFound {
+			t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+		}
+	})
+}
+
+func TestGetByName(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		user, err := r.GetByName(DefaultOrganizationId, "test1")
+		if err != nil {
+			t.Error("expected err to be nil")
+		}
+		if user.Id != "1" {
+			t.Errorf("expected user id to be 1, got %s", user.Id)
+		}
+		_, err = r.GetByName(DefaultOrganizationId, "test11111")
+		if err != ErrUserNotFound {
+			t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		err = r.Delete("1")
+		if err != nil {
+			t.Error("expected err to be nil")
+		}
+		users, err := r.GetAll()
+		if err != nil {
+			t.Error("expected err to be nil")
+		}
+		if len(users) != 2 {
+			t.Errorf("expected usersLength to be 2, got %d", len(users))
+		}
+		err = r.Delete("111111111")
+		if err != ErrUserNotFound {
+			t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+		}
+	})
+}
+
+func TestGetAll(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		users, err := r.GetAll()
+		if err != nil {
+			t.Error("expected err to be nil")
+		}
+		if len(users) != 3 {
+			t.Fatalf("expected users len to be 3, got %d", len(users))
+		}
+		for i, user := range users {
+			if user.Name != fmt.Sprintf("test%d", i+1) {
+				t.Errorf("expected users to keep the insertion order, got %s at %d", user.Name, i)
+			}
+		}
+	})
+}
+
+func TestSqlUserRepositoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	db, err := database.OpenSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewSqlUserRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = createTestUserRepository(r); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = database.OpenSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	r, err = NewSqlUserRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := r.GetAll()
 	if err != nil {
 		t.Error("expected err to be nil")
 	}
-	registeredUser := r.GetAll()[0]
-	err = r.Add(registeredUser)
-	if err != ErrUserAlreadyRegistered {
-		t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %s", err)
+	if len(users) != 3 {
+		t.Errorf("expected users to survive a restart, got %d", len(users))
+	}
+	version, err := database.SchemaVersion(db)
+	if err != nil || version != Migrations[len(Migrations)-1].Version {
+		t.Errorf("expected the schema to be at the latest version, got %d %s", version, err)
 	}
 }
 
-func TestById(t *testing.T) {
-	r, err := createTestUserRepository()
+func TestSqlUserRepositoryLoadRoles(t *testing.T) {
+	r := createTestSqlUserRepository(t)
+	count := loadRolesBatchSize + 2
+	for i := 0; i < count; i++ {
+		u := &User{Id: fmt.Sprint(i), OrganizationId: DefaultOrganizationId, Name: fmt.Sprintf("user%d", i), Password: "password"}
+		if i%loadRolesBatchSize == 0 {
+			u.Roles = []string{AdminRole}
+		}
+		if err := r.Add(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users, err := r.GetAll()
+	if err != nil || len(users) != count {
+		t.Fatalf("expected %d users, got %d %v", count, len(users), err)
+	}
+	for _, u := range users {
+		expected := u.Id == "0" || u.Id == fmt.Sprint(loadRolesBatchSize)
+		if u.HasRole(AdminRole) != expected || len(u.Roles) > 1 {
+			t.Errorf("unexpected roles of user %s, got %v", u.Id, u.Roles)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		err = r.Update(&User{Id: "1", OrganizationId: DefaultOrganizationId, Name: "renamed", Password: "test1", Roles: []string{AdminRole, "helpdesk"}})
+		if err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		user, err := r.GetById("1")
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		if user.Name != "renamed" || len(user.Roles) != 2 || !user.HasRole(AdminRole) || !user.HasRole("helpdesk") {
+			t.Errorf("expected user to be updated, got %+v", user)
+		}
+		if _, err = r.GetByName(DefaultOrganizationId, "test1"); err != ErrUserNotFound {
+			t.Errorf("expected the old name to be free, got %s", err)
+		}
+
+		err = r.Update(&User{Id: "2", OrganizationId: DefaultOrganizationId, Name: "test3"})
+		if err != ErrUserAlreadyRegistered {
+			t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %s", err)
+		}
+		err = r.Update(&User{Id: "1111", OrganizationId: DefaultOrganizationId, Name: "test1111"})
+		if err != ErrUserNotFound {
+			t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+		}
+	})
+}
+
+func TestConcurrentUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				name := fmt.Sprintf("renamed%d", i)
+				if err := r.Update(&User{Id: "1", OrganizationId: DefaultOrganizationId, Name: name, Password: "test1"}); err != nil {
+					t.Errorf("expected err to be nil, got %s", err)
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				if _, err := r.GetByName(DefaultOrganizationId, "test2"); err != nil {
+					t.Errorf("expected err to be nil, got %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		user, err := r.GetById("1")
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		if found, err := r.GetByName(DefaultOrganizationId, user.Name); err != nil || found.Id != "1" {
+			t.Errorf("expected the last name to be indexed, got %v, %v", found, err)
+		}
+	})
+}
+
+func TestEmail(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		err = r.Add(&User{Id: "4", OrganizationId: DefaultOrganizationId, Name: "test4", Email: "test4@example.com", EmailVerified: true, Password: "test4"})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+
+		user, err := r.GetByEmail("test4@example.com")
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if user.Id != "4" || !user.EmailVerified {
+			t.Errorf("unexpected user, got %+v", user)
+		}
+		if _, err = r.GetByEmail(""); err != ErrUserNotFound {
+			t.Errorf("expected users without email to not be found, got %s", err)
+		}
+
+		err = r.Add(&User{Id: "5", OrganizationId: DefaultOrganizationId, Name: "test5", Email: "test4@example.com", Password: "test5"})
+		if err != ErrEmailAlreadyRegistered {
+			t.Errorf("expected err to be ErrEmailAlreadyRegistered, got %s", err)
+		}
+		user, _ = r.GetById("1")
+		updated := *user
+		updated.Email = "test4@example.com"
+		if err = r.Update(&updated); err != ErrEmailAlreadyRegistered {
+			t.Errorf("expected err to be ErrEmailAlreadyRegistered, got %s", err)
+		}
+		updated.Email = "test1@example.com"
+		if err = r.Update(&updated); err != nil {
+			t.Errorf("expected err to be nil, got %s", err)
+		}
+		user, _ = r.GetByEmail("test1@example.com")
+		if user == nil || user.Id != "1" || user.EmailVerified {
+			t.Errorf("unexpected user, got %+v", user)
+		}
+	})
+}
+
+func TestSqlUserRepositoryAdminMigration(t *testing.T) {
+	db, err := database.OpenSqlite(filepath.Join(t.TempDir(), "users.db"))
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
 	}
-	_, err = r.GetById("1")
+	defer db.Close()
+	if err = database.Migrate(db, Migrations[:3]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("INSERT INTO users (id, name, is_admin, password) VALUES ('1', 'admin', 1, 'admin'), ('2', 'user', 0, 'user')")
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
 	}
-	_, err = r.GetById("1111")
-	if err != ErrUserNotFound {
-		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+
+	r, err := NewSqlUserRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := r.GetById("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(admin.Roles) != 2 || !admin.HasRole(AdminRole) || !admin.HasRole(SuperAdminRole) {
+		t.Errorf("expected the admin to get the admin and super-admin roles, got %v", admin.Roles)
+	}
+	user, err := r.GetById("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.Roles) != 0 {
+		t.Errorf("expected the user to have no roles, got %v", user.Roles)
 	}
 }
 
-func TestGetByName(t *testing.T) {
-	r, err := createTestUserRepository()
+func TestSqlUserRepositoryOrganizationMigration(t *testing.T) {
+	db, err := database.OpenSqlite(filepath.Join(t.TempDir(), "users.db"))
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = database.Migrate(db, Migrations[:4]); err != nil {
+		t.Fatal(err)
 	}
-	_, err = r.getIndexByName("test1")
+	statements := []string{
+		"INSERT INTO users (id, name, email, password) VALUES ('1', 'user1', 'user1@example.com', 'user1'), ('2', 'user2', NULL, 'user2')",
+		"INSERT INTO user_roles (user_id, role) VALUES ('1', 'helpdesk')",
+		"INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ('hash1', '1', 0)",
+	}
+	for _, statement := range statements {
+		if _, err = db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewSqlUserRepository(db)
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
+	}
+	users, err := r.GetAll()
+	if err != nil || len(users) != 2 {
+		t.Fatalf("expected the users to be kept, got %v %v", users, err)
+	}
+	if users[0].OrganizationId != DefaultOrganizationId || users[0].Email != "user1@example.com" || !users[0].HasRole("helpdesk") {
+		t.Errorf("unexpected user, got %+v", users[0])
+	}
+	if _, err = NewSqlPasswordResetRepository(db).GetPasswordReset("hash1"); err != nil {
+		t.Errorf("expected the password resets to be kept, got %s", err)
 	}
-	_, err = r.getIndexByName("test11111")
-	if err != ErrUserNotFound {
-		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	if _, err = NewSqlOrganizationRepository(db).GetOrganization(DefaultOrganizationId); err != nil {
+		t.Errorf("expected the default organization to exist, got %s", err)
 	}
 }
 
-func TestDelete(t *testing.T) {
-	r, err := createTestUserRepository()
+func TestOrganizations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		if sqlStore, ok := r.(*SqlUserRepository); ok {
+			err = NewSqlOrganizationRepository(sqlStore.db).AddOrganization(&Organization{Id: "acme", Name: "Acme"})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = r.Add(&User{Id: "4", OrganizationId: "acme", Name: "test1", Password: "test1"})
+		if err != nil {
+			t.Fatalf("expected the same name to be free in another organization, got %s", err)
+		}
+		err = r.Add(&User{Id: "5", OrganizationId: "acme", Name: "test1", Password: "test1"})
+		if err != ErrUserAlreadyRegistered {
+			t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %s", err)
+		}
+
+		user, err := r.GetByName("acme", "test1")
+		if err != nil || user.Id != "4" {
+			t.Errorf("expected the user of the organization, got %+v %v", user, err)
+		}
+		user, err = r.GetByName(DefaultOrganizationId, "test1")
+		if err != nil || user.Id != "1" {
+			t.Errorf("expected the user of the organization, got %+v %v", user, err)
+		}
+		users, err := r.GetByOrganization("acme")
+		if err != nil || len(users) != 1 || users[0].Id != "4" {
+			t.Errorf("expected only the users of the organization, got %v %v", users, err)
+		}
+		users, err = r.GetByOrganization(DefaultOrganizationId)
+		if err != nil || len(users) != 3 {
+			t.Errorf("expected only the users of the organization, got %v %v", users, err)
+		}
+	})
+}
+
+func TestQuery(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r := newStore()
+		if sqlStore, ok := r.(*SqlUserRepository); ok {
+			if err := NewSqlOrganizationRepository(sqlStore.db).AddOrganization(&Organization{Id: "other", Name: "Other"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		users := []*User{
+			{Id: "1", OrganizationId: DefaultOrganizationId, Name: "carol", CreatedAt: createdAt.Add(time.Hour * 2)},
+			{Id: "2", OrganizationId: DefaultOrganizationId, Name: "alice", CreatedAt: createdAt.Add(time.Hour * 3), Roles: []string{AdminRole}},
+			{Id: "3", OrganizationId: DefaultOrganizationId, Name: "Bob", CreatedAt: createdAt, Roles: []string{"editor"}},
+			{Id: "4", OrganizationId: "other", Name: "albert", CreatedAt: createdAt.Add(time.Hour), Roles: []string{"editor"}},
+			{Id: "5", OrganizationId: DefaultOrganizationId, Name: "a_b%c", CreatedAt: createdAt.Add(time.Hour * 4), Status: UserStatusPending},
+		}
+		for _, user := range users {
+			if err := r.Add(user); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		isAdmin, notAdmin := true, false
+		queryTests := []struct {
+			query UserQuery
+			ids   []string
+			total int
+		}{
+			{UserQuery{}, []string{"3", "5", "4", "2", "1"}, 5},
+			{UserQuery{OrganizationId: "other"}, []string{"4"}, 1},
+			{UserQuery{Search: "L"}, []string{"4", "2", "1"}, 3},
+			{UserQuery{Search: "_b%"}, []string{"5"}, 1},
+			{UserQuery{Prefix: "AL"}, []string{"4", "2"}, 2},
+			{UserQuery{Role: "editor"}, []string{"3", "4"}, 2},
+			{UserQuery{Admin: &isAdmin}, []string{"2"}, 1},
+			{UserQuery{Status: UserStatusPending}, []string{"5"}, 1},
+			{UserQuery{Status: UserStatusEnabled}, []string{"3", "4", "2", "1"}, 4},
+			{UserQuery{Admin: &notAdmin, OrganizationId: DefaultOrganizationId}, []string{"3", "5", "1"}, 3},
+			{UserQuery{SortBy: SortByCreatedAt}, []string{"3", "4", "1", "2", "5"}, 5},
+			{UserQuery{SortBy: SortByCreatedAt, Descending: true, Limit: 2}, []string{"5", "2"}, 5},
+		}
+		for i, queryTest := range queryTests {
+			if queryTest.query.SortBy == "" {
+				queryTest.query.SortBy = SortByName
+			}
+			page, err := r.Query(queryTest.query)
+			if err != nil {
+				t.Fatalf("%d: %v", i, err)
+			}
+			ids := make([]string, len(page.Users))
+			for j, user := range page.Users {
+				ids[j] = user.Id
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(queryTest.ids) || page.Total != queryTest.total {
+				t.Errorf("%d: expected %v of %d, got %v of %d", i, queryTest.ids, queryTest.total, ids, page.Total)
+			}
+		}
+
+		for _, query := range []UserQuery{{Limit: 2, SortBy: SortByName}, {Limit: 2, SortBy: SortByCreatedAt, Descending: true}} {
+			ids := make([]string, 0)
+			for pages := 0; pages < 5; pages++ {
+				page, err := r.Query(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, user := range page.Users {
+					ids = append(ids, user.Id)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+			all, _ := r.Query(UserQuery{SortBy: query.SortBy, Descending: query.Descending})
+			if len(ids) != len(all.Users) {
+				t.Errorf("expected the pages to have every user once, got %v", ids)
+			}
+			for j, user := range all.Users {
+				if j < len(ids) && ids[j] != user.Id {
+					t.Errorf("expected the pages in order, got %v", ids)
+					break
+				}
+			}
+		}
+
+		page, _ := r.Query(UserQuery{Limit: 1, SortBy: SortByName})
+		if _, err := r.Query(UserQuery{Limit: 1, Cursor: page.NextCursor, SortBy: SortByCreatedAt}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected the cursor of another sort not to be valid, got %v", err)
+		}
+		if _, err := r.Query(UserQuery{Cursor: "not a cursor", SortBy: SortByName}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected error to be ErrInvalidCursor, got %v", err)
+		}
+		if _, err := r.Query(UserQuery{SortBy: "password"}); !errors.Is(err, ErrInvalidSortField) {
+			t.Errorf("expected error to be ErrInvalidSortField, got %v", err)
+		}
+		if user, _ := r.GetById("1"); !user.CreatedAt.Equal(users[0].CreatedAt) {
+			t.Errorf("expected the creation date to be kept, got %s", user.CreatedAt)
+		}
+	})
+}
+
+func TestQueryCanonicalName(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r := newStore()
+		for i, name := range []string{"Émile", "Straße", "ＪＯＨＮ"} {
+			if err := r.Add(&User{Id: fmt.Sprint(i + 1), OrganizationId: DefaultOrganizationId, Name: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		queryTests := []struct {
+			query UserQuery
+			ids   []string
+		}{
+			{UserQuery{Search: "ÉMI"}, []string{"1"}},
+			{UserQuery{Search: "emi"}, []string{}},
+			{UserQuery{Search: "ss"}, []string{"2"}},
+			{UserQuery{Prefix: "STRASS"}, []string{"2"}},
+			{UserQuery{Search: "john"}, []string{"3"}},
+			{UserQuery{Prefix: "ｊｏ"}, []string{"3"}},
+		}
+		for i, queryTest := range queryTests {
+			queryTest.query.SortBy = SortByName
+			page, err := r.Query(queryTest.query)
+			if err != nil {
+				t.Fatalf("%d: %v", i, err)
+			}
+			ids := make([]string, len(page.Users))
+			for j, user := range page.Users {
+				ids[j] = user.Id
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(queryTest.ids) {
+				t.Errorf("%d: expected %v, got %v", i, queryTest.ids, ids)
+			}
+		}
+	})
+}
+
+func TestUserStatus(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		until := time.Now().Add(time.Hour)
+		err = r.Update(&User{Id: "1", OrganizationId: DefaultOrganizationId, Name: "test1", Password: "test1",
+			Status: UserStatusSuspended, StatusReason: "spam", SuspendedUntil: &until})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		user, err := r.GetById("1")
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		if user.Status != UserStatusSuspended || user.StatusReason != "spam" || user.SuspendedUntil == nil || !user.SuspendedUntil.Equal(until) {
+			t.Errorf("expected the status to be stored, got %+v", user)
+		}
+
+		err = r.Update(&User{Id: "1", OrganizationId: DefaultOrganizationId, Name: "test1", Password: "test1", Status: UserStatusEnabled})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if user, _ = r.GetById("1"); user.Status != UserStatusEnabled || user.StatusReason != "" || user.SuspendedUntil != nil {
+			t.Errorf("expected the suspension to be removed, got %+v", user)
+		}
+	})
+}
+
+func TestUserProfile(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		err = r.Update(&User{Id: "1", OrganizationId: DefaultOrganizationId, Name: "test1", Password: "test1", DisplayName: "Test One",
+			AvatarURL: "https://example.com/1.png", Locale: "en-GB", Timezone: "Europe/London",
+			Metadata: map[string]any{"department": "sales", "floor": float64(3), "tags": []any{"a"}}})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		user, err := r.GetById("1")
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		if user.DisplayName != "Test One" || user.AvatarURL != "https://example.com/1.png" || user.Locale != "en-GB" || user.Timezone != "Europe/London" {
+			t.Errorf("expected the profile to be stored, got %+v", user)
+		}
+		if user.Metadata["department"] != "sales" || user.Metadata["floor"] != float64(3) || len(user.Metadata["tags"].([]any)) != 1 {
+			t.Errorf("expected the metadata to be stored, got %+v", user.Metadata)
+		}
+		if user, _ = r.GetById("2"); user.Metadata != nil || user.DisplayName != "" {
+			t.Errorf("expected an empty profile, got %+v", user)
+		}
+	})
+}
+
+func TestLastLoginAt(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		lastLogin := time.Now()
+		err = r.Update(&User{Id: "1", OrganizationId: DefaultOrganizationId, Name: "test1", Password: "test1", LastLoginAt: &lastLogin})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if user, _ := r.GetById("1"); user.LastLoginAt == nil || !user.LastLoginAt.Equal(lastLogin) {
+			t.Errorf("expected the last login to be stored, got %+v", user)
+		}
+		if user, _ := r.GetById("2"); user.LastLoginAt != nil {
+			t.Errorf("expected no last login, got %+v", user.LastLoginAt)
+		}
+
+		err = r.Update(&User{Id: "2", OrganizationId: DefaultOrganizationId, Name: "test2", Password: "changed", Status: UserStatusDisabled})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		lastLogin = lastLogin.Add(time.Minute)
+		if err = r.UpdateLastLogin("2", lastLogin); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		user, _ := r.GetById("2")
+		if user.LastLoginAt == nil || !user.LastLoginAt.Equal(lastLogin) || user.Password != "changed" || user.Status != UserStatusDisabled {
+			t.Errorf("expected only the last login to change, got %+v", user)
+		}
+		if err = r.UpdateLastLogin("1111", lastLogin); err != ErrUserNotFound {
+			t.Errorf("expected error to be ErrUserNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestUpdatePassword(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		err = r.Update(&User{Id: "2", OrganizationId: DefaultOrganizationId, Name: "test2", Password: "test2", Roles: []string{AdminRole}, Status: UserStatusDisabled})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if err = r.UpdatePassword("2", "rehashed"); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		user, _ := r.GetById("2")
+		if user.Password != "rehashed" || !user.HasRole(AdminRole) || user.Status != UserStatusDisabled {
+			t.Errorf("expected only the password to change, got %+v", user)
+		}
+		if err = r.UpdatePassword("1111", "rehashed"); err != ErrUserNotFound {
+			t.Errorf("expected error to be ErrUserNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestCanonicalUserNames(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func() UserStore) {
+		r, err := createTestUserRepository(newStore())
+		if err != nil {
+			t.Fatal("expected err to be nil")
+		}
+		for _, name := range []string{"TEST1", "ｔｅｓｔ１", "tеst1"} {
+			if user, err := r.GetByName(DefaultOrganizationId, name); err != nil || user.Id != "1" {
+				t.Errorf("expected %q to find the user 1, got %v %v", name, user, err)
+			}
+		}
+		err = r.Add(&User{Id: "4", OrganizationId: DefaultOrganizationId, Name: "Test1", Password: "test4"})
+		if err != ErrUserAlreadyRegistered {
+			t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %v", err)
+		}
+		err = r.Update(&User{Id: "2", OrganizationId: DefaultOrganizationId, Name: "TEST1", Password: "test2"})
+		if err != ErrUserAlreadyRegistered {
+			t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %v", err)
+		}
+
+		if err = r.Update(&User{Id: "2", OrganizationId: DefaultOrganizationId, Name: "Renamed", Password: "test2"}); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if _, err = r.GetByName(DefaultOrganizationId, "test2"); err != ErrUserNotFound {
+			t.Errorf("expected the previous name to be free, got: %v", err)
+		}
+		if user, err := r.GetByName(DefaultOrganizationId, "RENAMED"); err != nil || user.Id != "2" {
+			t.Errorf("expected the new name to find the user 2, got %v %v", user, err)
+		}
+		if err = r.Delete("2"); err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if _, err = r.GetByName(DefaultOrganizationId, "renamed"); err != ErrUserNotFound {
+			t.Errorf("expected the name of the deleted user to be free, got: %v", err)
+		}
+		if err = r.Add(&User{Id: "4", OrganizationId: DefaultOrganizationId, Name: "test2", Password: "test4"}); err != nil {
+			t.Errorf("expected the previous name to be registered again, got: %v", err)
+		}
+	})
+}
+
+func TestSqlUserRepositoryCanonicalNameMigration(t *testing.T) {
+	db, err := database.OpenSqlite(filepath.Join(t.TempDir(), "users.db"))
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = database.Migrate(db, Migrations[:14]); err != nil {
+		t.Fatal(err)
 	}
-	err = r.Delete("1")
+	_, err = db.Exec("INSERT INTO users (id, organization_id, name, password) VALUES ('1', ?, 'Admin', 'admin'), ('2', ?, 'user', 'user')",
+		DefaultOrganizationId, DefaultOrganizationId)
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
 	}
-	usersLength := len(r.GetAll())
-	if usersLength != 2 {
-		t.Errorf("expected usersLength to be 2, got %d", usersLength)
+
+	r, err := NewSqlUserRepository(db)
+	if err != nil {
+		t.Fatal(err)
 	}
-	err = r.Delete("111111111")
-	if err != ErrUserNotFound {
-		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	user, err := r.GetByName(DefaultOrganizationId, "ADMIN")
+	if err != nil || user.Id != "1" || user.Name != "Admin" {
+		t.Errorf("expected the canonical names of the users to be filled, got %+v %v", user, err)
 	}
 }
 
-func TestGetAll(t *testing.T) {
-	r, err := createTestUserRepository()
+func TestSqlUserRepositoryCanonicalNameMigrationCollision(t *testing.T) {
+	db, err := database.OpenSqlite(filepath.Join(t.TempDir(), "users.db"))
 	if err != nil {
-		t.Error("expected err to be nil")
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = database.Migrate(db, Migrations[:14]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("INSERT INTO users (id, organization_id, name, password) VALUES ('1', ?, 'Admin', 'admin'), ('2', ?, 'admin', 'admin')",
+		DefaultOrganizationId, DefaultOrganizationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewSqlUserRepository(db)
+	if !errors.Is(err, database.ErrMigrationFailed) {
+		t.Fatalf("expected error to be ErrMigrationFailed, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), ErrUserNameCollision.Error()) || !strings.Contains(err.Error(), `"Admin", `+DefaultOrganizationId+` "admin"`) {
+		t.Errorf("expected the error to list the colliding names, got: %s", err)
 	}
-	if !reflect.DeepEqual(r.GetAll(), r.repository.GetAll()) {
-		t.Error("expected users to be the same as repository.users")
+	var count int
+	if err = db.QueryRow("SELECT COUNT(*) FROM users WHERE name IN ('Admin', 'admin')").Scan(&count); err != nil || count != 2 {
+		t.Errorf("expected the users to be kept, got %d %v", count, err)
 	}
 }
@@ -1,68 +1,1366 @@
 package user
 
 import (
+	"authGo/jsonschema"
+	"authGo/password"
+	"authGo/totp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultPasswordResetDuration = time.Minute * 15
+
+// DefaultInvitationDuration is the lifetime of the invitations and the longest one that can be chosen.
+const DefaultInvitationDuration = time.Hour * 24 * 7
+
+// DefaultLoginHistoryRetention keeps the last 100 login attempts of every user for 90 days.
+var DefaultLoginHistoryRetention = LoginHistoryRetention{MaxAge: time.Hour * 24 * 90, MaxAttempts: 100}
+
+// maxStatusReasonLength is the number of characters kept as the reason of a status change.
+const maxStatusReasonLength = 500
+
+// RecoveryCodeCount is the number of recovery codes given at once.
+const RecoveryCodeCount = 10
+
+var (
+	ErrUserPasswordNotValid       = errors.New("user service: password not valid")
+	ErrPasswordResetTokenNotValid = errors.New("user service: password reset token not valid")
+	ErrEmailNotVerified           = errors.New("user service: email address not verified")
+	ErrEmailVerificationNotValid  = errors.New("user service: email verification not valid")
+	ErrUserLocked                 = errors.New("user service: user temporarily locked")
+	ErrTOTPAlreadyEnabled         = errors.New("user service: totp already enabled")
+	ErrTOTPNotEnabled             = errors.New("user service: totp not enabled")
+	ErrTOTPCodeNotValid           = errors.New("user service: totp code not valid")
+	ErrWebAuthnNameNotValid       = errors.New("user service: webauthn credential name not valid")
+	ErrSecondFactorNotEnabled     = errors.New("user service: no second factor enabled")
+	ErrRecoveryCodeNotValid       = errors.New("user service: recovery code not valid")
+	ErrUserStatusNotValid         = errors.New("user service: user status not valid")
+	ErrUserDisabled               = errors.New("user service: user disabled")
+	ErrUserSuspended              = errors.New("user service: user suspended")
+	ErrUserPending                = errors.New("user service: user pending approval")
+	ErrUserNotPending             = errors.New("user service: user not pending approval")
+	ErrDisplayNameNotValid        = errors.New("user service: display name not valid")
+	ErrAvatarURLNotValid          = errors.New("user service: avatar url not valid")
+	ErrLocaleNotValid             = errors.New("user service: locale not valid")
+	ErrTimezoneNotValid           = errors.New("user service: timezone not valid")
+	ErrMetadataNotValid           = errors.New("user service: metadata not valid")
+	ErrInvitationNotValid         = errors.New("user service: invitation not valid")
+	ErrInvitationExpiryNotValid   = errors.New("user service: invitation expiry not valid")
 )
 
 type UserService struct {
-	repository        *UserRepository
-	passwordValidator PasswordValidator
+	repository            UserStore
+	passwordValidator     PasswordValidator
+	passwordPolicy        *password.Policy
+	roles                 RoleStore
+	organizations         OrganizationStore
+	passwordResets        PasswordResetStore
+	loginFailures         LoginFailureStore
+	lockoutPolicy         *LockoutPolicy
+	loginHistory          LoginHistoryStore
+	loginHistoryRetention LoginHistoryRetention
+	totps                 TOTPStore
+	webAuthnCredentials   WebAuthnCredentialStore
+	webAuthnChallenges    WebAuthnChallengeStore
+	recoveryCodes         RecoveryCodeStore
+	recoveryCodeKey       []byte
+	invitations           InvitationStore
+	metadataSchema        *jsonschema.Schema
+	passwordResetDuration time.Duration
+	invitationDuration    time.Duration
+	requireVerifiedEmail  bool
 }
 
 type PasswordValidator interface {
 	compareHashAndPassword(hashedPassword, password []byte) error
-	generateFromPassword(password []byte, cost int) ([]byte, error)
+	generateFromPassword(password []byte) ([]byte, error)
+	needsRehash(hashedPassword []byte) bool
+	validateHash(hashedPassword []byte) error
 }
 
-type ServicePasswordValidator struct{}
+type ServicePasswordValidator struct {
+	hasher *password.Hasher
+}
 
 func (spv ServicePasswordValidator) compareHashAndPassword(hashedPassword, password []byte) error {
-	return bcrypt.CompareHashAndPassword(hashedPassword, password)
+	return spv.hasher.Compare(hashedPassword, password)
+}
+
+func (spv ServicePasswordValidator) generateFromPassword(password []byte) ([]byte, error) {
+	return spv.hasher.Hash(password)
 }
 
-func (spv ServicePasswordValidator) generateFromPassword(password []byte, cost int) ([]byte, error) {
-	return bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+func (spv ServicePasswordValidator) needsRehash(hashedPassword []byte) bool {
+	return spv.hasher.NeedsRehash(hashedPassword)
+}
+
+func (spv ServicePasswordValidator) validateHash(hashedPassword []byte) error {
+	return spv.hasher.ValidateHash(hashedPassword)
 }
 
 func NewUserService() *UserService {
-	return &UserService{repository: NewUserRepository(), passwordValidator: ServicePasswordValidator{}}
+	return NewUserServiceWithStore(NewUserRepository())
+}
+
+func NewUserServiceWithStore(store UserStore) *UserService {
+	return &UserService{
+		repository:            store,
+		passwordValidator:     ServicePasswordValidator{hasher: password.NewDefaultHasher()},
+		roles:                 NewRoleRepository(),
+		organizations:         NewOrganizationRepository(),
+		passwordResets:        NewPasswordResetRepository(),
+		loginFailures:         NewLoginFailureRepository(),
+		loginHistory:          NewLoginHistoryRepository(),
+		loginHistoryRetention: DefaultLoginHistoryRetention,
+		totps:                 NewTOTPRepository(),
+		webAuthnCredentials:   NewWebAuthnCredentialRepository(),
+		webAuthnChallenges:    NewWebAuthnChallengeRepository(),
+		recoveryCodes:         NewRecoveryCodeRepository(),
+		invitations:           NewInvitationRepository(),
+		passwordResetDuration: DefaultPasswordResetDuration,
+		invitationDuration:    DefaultInvitationDuration,
+	}
+}
+
+// SetPasswordPolicy sets the policy the passwords must follow when a user is created or changes
+// their password, no policy is applied when it's nil.
+func (s *UserService) SetPasswordPolicy(policy *password.Policy) {
+	s.passwordPolicy = policy
+}
+
+// SetPasswordHasher sets how the new passwords are hashed, the hashes of the other supported algorithms
+// are still verified and replaced at the next valid login.
+func (s *UserService) SetPasswordHasher(hasher *password.Hasher) {
+	s.passwordValidator = ServicePasswordValidator{hasher: hasher}
+}
+
+func (s *UserService) SetRoleStore(store RoleStore) {
+	s.roles = store
+}
+
+func (s *UserService) SetOrganizationStore(store OrganizationStore) {
+	s.organizations = store
+}
+
+func (s *UserService) SetPasswordResetStore(store PasswordResetStore) {
+	s.passwordResets = store
+}
+
+func (s *UserService) SetLoginFailureStore(store LoginFailureStore) {
+	s.loginFailures = store
+}
+
+func (s *UserService) SetLoginHistoryStore(store LoginHistoryStore) {
+	s.loginHistory = store
+}
+
+func (s *UserService) SetTOTPStore(store TOTPStore) {
+	s.totps = store
+}
+
+func (s *UserService) SetWebAuthnCredentialStore(store WebAuthnCredentialStore) {
+	s.webAuthnCredentials = store
+}
+
+func (s *UserService) SetWebAuthnChallengeStore(store WebAuthnChallengeStore) {
+	s.webAuthnChallenges = store
+}
+
+func (s *UserService) SetRecoveryCodeStore(store RecoveryCodeStore) {
+	s.recoveryCodes = store
+}
+
+// SetRecoveryCodeKey sets the key of the HMAC-SHA256 digests the recovery codes are stored as, the
+// codes generated with another key are no longer valid.
+func (s *UserService) SetRecoveryCodeKey(key []byte) {
+	s.recoveryCodeKey = key
+}
+
+func (s *UserService) SetInvitationStore(store InvitationStore) {
+	s.invitations = store
+}
+
+// SetMetadataSchema sets the JSON schema the metadata of the users must follow, any JSON object is
+// accepted when nil.
+func (s *UserService) SetMetadataSchema(schema *jsonschema.Schema) {
+	s.metadataSchema = schema
+}
+
+// SetLockoutPolicy sets the policy locking the accounts after too many failed logins, the accounts are
+// never locked when it's nil.
+func (s *UserService) SetLockoutPolicy(policy *LockoutPolicy) {
+	s.lockoutPolicy = policy
+}
+
+// SetLoginHistoryRetention sets how many login attempts are kept for every user and for how long.
+func (s *UserService) SetLoginHistoryRetention(retention LoginHistoryRetention) {
+	s.loginHistoryRetention = retention
+}
+
+func (s *UserService) SetPasswordResetDuration(duration time.Duration) {
+	s.passwordResetDuration = duration
+}
+
+func (s *UserService) GetPasswordResetDuration() time.Duration {
+	return s.passwordResetDuration
+}
+
+func (s *UserService) SetInvitationDuration(duration time.Duration) {
+	s.invitationDuration = duration
 }
 
-func (s *UserService) IsPasswordValid(name string, password string) bool {
-	user, err := s.repository.GetByName(name)
+func (s *UserService) GetInvitationDuration() time.Duration {
+	return s.invitationDuration
+}
+
+// SetRequireVerifiedEmail blocks the login of the users with an email address until it's verified.
+func (s *UserService) SetRequireVerifiedEmail(require bool) {
+	s.requireVerifiedEmail = require
+}
+
+func (s *UserService) IsVerifiedEmailRequired() bool {
+	return s.requireVerifiedEmail
+}
+
+func (s *UserService) IsPasswordValid(organizationId string, name string, password string) bool {
+	user, err := s.repository.GetByName(organizationId, name)
 	if err != nil {
 		return false
 	}
-	if err = s.passwordValidator.compareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return false
+	_, err = s.verifyPassword(user, password)
+	return err == nil
+}
+
+// Authenticate returns the user when the password is valid. The failed logins are counted and, once the
+// lockout policy locks the account, ErrUserLocked is returned without checking the password so the
+// response doesn't reveal whether it was valid. The failures of the users with TOTP are kept until the
+// code is verified, otherwise a valid password would reset the count of the failed codes.
+func (s *UserService) Authenticate(organizationId string, name string, password string) (*User, error) {
+	user, err := s.repository.GetByName(organizationId, name)
+	if err != nil {
+		return nil, err
 	}
-	return true
+	failures, err := s.checkLockout(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := s.verifyPassword(user, password)
+	if err == ErrUserPasswordNotValid {
+		if err = s.addLoginFailure(user.Id); err != nil {
+			return nil, err
+		}
+		return nil, ErrUserPasswordNotValid
+	}
+	if err != nil {
+		return nil, err
+	}
+	totpEnabled, err := s.IsTOTPEnabled(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !totpEnabled {
+		if err = s.clearLoginFailures(failures); err != nil {
+			return nil, err
+		}
+	}
+	return verified, nil
 }
 
-func (s *UserService) CreateUser(name string, password string, isAdmin bool) error {
-	id := strings.ReplaceAll(uuid.NewString(), "-", "")
-	passwordHash, err := s.getPasswordHash(password)
+// GetLoginFailures returns the failed logins in a row of the user and the end of their lockout.
+func (s *UserService) GetLoginFailures(id string) (*LoginFailures, error) {
+	if _, err := s.repository.GetById(id); err != nil {
+		return nil, err
+	}
+	return s.loginFailures.GetLoginFailures(id)
+}
+
+// RecordLoginAttempt adds the attempt to the login history of its user and removes the attempts beyond
+// the retention, a successful login also becomes the last login of the user.
+func (s *UserService) RecordLoginAttempt(attempt *LoginAttempt) error {
+	if _, err := s.repository.GetById(attempt.UserId); err != nil {
+		return err
+	}
+	attempt.Id = strings.ReplaceAll(uuid.NewString(), "-", "")
+	if attempt.Time.IsZero() {
+		attempt.Time = time.Now()
+	}
+	retention := s.loginHistoryRetention
+	if err := s.loginHistory.AddLoginAttempt(attempt, retention.MaxAttempts, retention.notBefore(attempt.Time)); err != nil {
+		return err
+	}
+	if attempt.Outcome != LoginOutcomeSuccess {
+		return nil
+	}
+	return s.repository.UpdateLastLogin(attempt.UserId, attempt.Time)
+}
+
+// GetLoginHistory returns the login attempts of the user kept by the retention, from the newest to the
+// oldest.
+func (s *UserService) GetLoginHistory(id string) ([]*LoginAttempt, error) {
+	if _, err := s.repository.GetById(id); err != nil {
+		return nil, err
+	}
+	return s.loginHistory.GetLoginAttempts(id, s.loginHistoryRetention.notBefore(time.Now()))
+}
+
+// UnlockUser ends the lockout of the user and forgets their failed logins.
+func (s *UserService) UnlockUser(id string) error {
+	if _, err := s.repository.GetById(id); err != nil {
+		return err
+	}
+	return s.loginFailures.DeleteLoginFailures(id)
+}
+
+// EnrollTOTP generates a new secret for the authenticator app of the user, it doesn't protect the login
+// until ConfirmTOTP receives a valid code of it. Enrolling again replaces the unconfirmed secret.
+func (s *UserService) EnrollTOTP(id string) (*TOTP, error) {
+	if _, err := s.repository.GetById(id); err != nil {
+		return nil, err
+	}
+	current, err := s.totps.GetTOTP(id)
+	if err != nil && err != ErrTOTPNotFound {
+		return nil, err
+	}
+	if err == nil && current.Confirmed {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	enrolled := &TOTP{UserId: id, Secret: secret}
+	if err = s.totps.SetTOTP(enrolled); err != nil {
+		return nil, err
+	}
+	return enrolled, nil
+}
+
+// ConfirmTOTP enables the enrolled TOTP of the user once they send a valid code, proving the secret
+// was added to their authenticator app.
+func (s *UserService) ConfirmTOTP(id string, code string) error {
+	current, err := s.totps.GetTOTP(id)
+	if err == ErrTOTPNotFound {
+		return ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if current.Confirmed {
+		return ErrTOTPAlreadyEnabled
+	}
+	counter, ok := totp.Validate(current.Secret, code, time.Now())
+	if !ok {
+		return ErrTOTPCodeNotValid
+	}
+	current.Confirmed = true
+	current.LastCounter = counter
+	return s.totps.SetTOTP(current)
+}
+
+func (s *UserService) IsTOTPEnabled(id string) (bool, error) {
+	current, err := s.totps.GetTOTP(id)
+	if err == ErrTOTPNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return current.Confirmed, nil
+}
+
+// VerifyTOTP checks a code of the authenticator app of the user at the second step of the login. A code
+// is accepted once, and the failed codes count towards the lockout like the wrong passwords.
+func (s *UserService) VerifyTOTP(id string, code string) error {
+	current, err := s.totps.GetTOTP(id)
+	if err == ErrTOTPNotFound || (err == nil && !current.Confirmed) {
+		return ErrTOTPNotEnabled
+	}
 	if err != nil {
 		return err
 	}
-	err = s.repository.Add(&User{Id: id, Name: name, Password: passwordHash, IsAdmin: isAdmin})
+	failures, err := s.checkLockout(id)
+	if err != nil {
+		return err
+	}
+	counter, ok := totp.Validate(current.Secret, code, time.Now())
+	if ok {
+		if ok, err = s.totps.UseTOTPCounter(id, counter); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		if err = s.addLoginFailure(id); err != nil {
+			return err
+		}
+		return ErrTOTPCodeNotValid
+	}
+	return s.clearLoginFailures(failures)
+}
+
+// DisableTOTP removes the authenticator app of the user, enrolled or confirmed.
+func (s *UserService) DisableTOTP(id string) error {
+	if _, err := s.repository.GetById(id); err != nil {
+		return err
+	}
+	if err := s.totps.DeleteTOTP(id); err != nil {
+		return err
+	}
+	return s.removeUnusableRecoveryCodes(id)
+}
+
+// AddWebAuthnCredential stores a credential verified by the registration ceremony for its user, the
+// name defaults to "Passkey".
+func (s *UserService) AddWebAuthnCredential(credential *WebAuthnCredential) (*WebAuthnCredential, error) {
+	if _, err := s.repository.GetById(credential.UserId); err != nil {
+		return nil, err
+	}
+	credential.Name = strings.TrimSpace(credential.Name)
+	if credential.Name == "" {
+		credential.Name = "Passkey"
+	}
+	if len(credential.Name) > 64 {
+		return nil, ErrWebAuthnNameNotValid
+	}
+	credential.CreatedAt = time.Now()
+	if err := s.webAuthnCredentials.AddWebAuthnCredential(credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (s *UserService) GetWebAuthnCredential(id string) (*WebAuthnCredential, error) {
+	return s.webAuthnCredentials.GetWebAuthnCredential(id)
+}
+
+func (s *UserService) GetWebAuthnCredentials(userId string) ([]*WebAuthnCredential, error) {
+	return s.webAuthnCredentials.GetUserWebAuthnCredentials(userId)
+}
+
+// UpdateWebAuthnSignCount keeps the sign count of the last authentication, a lower one later means the
+// credential was cloned.
+func (s *UserService) UpdateWebAuthnSignCount(id string, signCount uint32) error {
+	return s.webAuthnCredentials.UpdateWebAuthnSignCount(id, signCount)
+}
+
+// AddWebAuthnChallenge keeps the challenge of a new WebAuthn ceremony until it's answered or expires.
+func (s *UserService) AddWebAuthnChallenge(challenge string, expiresAt time.Time) error {
+	return s.webAuthnChallenges.AddWebAuthnChallenge(challenge, expiresAt)
+}
+
+// UseWebAuthnChallenge accepts the challenge once, it returns ErrWebAuthnChallengeNotFound when it was
+// already answered, so a captured response can't be replayed.
+func (s *UserService) UseWebAuthnChallenge(challenge string) error {
+	return s.webAuthnChallenges.UseWebAuthnChallenge(challenge, time.Now())
+}
+
+// DeleteWebAuthnCredential removes a credential of the user, the credentials of other users are not
+// found.
+func (s *UserService) DeleteWebAuthnCredential(userId string, id string) error {
+	credential, err := s.webAuthnCredentials.GetWebAuthnCredential(id)
+	if err != nil {
+		return err
+	}
+	if credential.UserId != userId {
+		return ErrWebAuthnCredentialNotFound
+	}
+	if err = s.webAuthnCredentials.DeleteWebAuthnCredential(id); err != nil {
+		return err
+	}
+	return s.removeUnusableRecoveryCodes(userId)
+}
+
+// HasSecondFactor returns whether the login of the user needs a confirmed TOTP or a passkey after the
+// password.
+func (s *UserService) HasSecondFactor(id string) (bool, error) {
+	totpEnabled, err := s.IsTOTPEnabled(id)
+	if err != nil || totpEnabled {
+		return totpEnabled, err
+	}
+	credentials, err := s.webAuthnCredentials.GetUserWebAuthnCredentials(id)
+	if err != nil {
+		return false, err
+	}
+	return len(credentials) > 0, nil
+}
+
+// EnsureRecoveryCodes gives the user their first recovery codes once they enroll a second factor, it
+// returns none when the user still has some.
+func (s *UserService) EnsureRecoveryCodes(id string) ([]string, error) {
+	codes, err := s.recoveryCodes.GetRecoveryCodes(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) > 0 {
+		return nil, nil
+	}
+	return s.generateRecoveryCodes(id)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, the current password is required so a
+// stolen session can't get a way around the second factor.
+func (s *UserService) RegenerateRecoveryCodes(id string, password string) ([]string, error) {
+	user, err := s.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkCurrentPassword(user, password); err != nil {
+		return nil, err
+	}
+	hasSecondFactor, err := s.HasSecondFactor(id)
+	if err != nil {
+		return nil, err
+	}
+	if !hasSecondFactor {
+		return nil, ErrSecondFactorNotEnabled
+	}
+	return s.generateRecoveryCodes(id)
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes of the user.
+func (s *UserService) CountRecoveryCodes(id string) (int, error) {
+	codes, err := s.recoveryCodes.GetRecoveryCodes(id)
+	if err != nil {
+		return 0, err
+	}
+	return len(codes), nil
+}
+
+// UseRecoveryCode accepts a recovery code instead of the second factor at the login, each code works
+// once. The wrong codes count towards the lockout like the wrong passwords.
+func (s *UserService) UseRecoveryCode(id string, code string) error {
+	failures, err := s.checkLockout(id)
 	if err != nil {
 		return err
 	}
+	hash := s.hashRecoveryCode(normalizeRecoveryCode(code))
+	recoveryCode, err := s.recoveryCodes.GetRecoveryCode(id, hash)
+	if err != nil && err != ErrRecoveryCodeNotFound {
+		return err
+	}
+	if err == nil && hmac.Equal([]byte(recoveryCode.Hash), []byte(hash)) {
+		err = s.recoveryCodes.DeleteRecoveryCode(recoveryCode.Id)
+		if err == nil {
+			return s.clearLoginFailures(failures)
+		}
+		if err != ErrRecoveryCodeNotFound {
+			return err
+		}
+	}
+	if err = s.addLoginFailure(id); err != nil {
+		return err
+	}
+	return ErrRecoveryCodeNotValid
+}
+
+// SetUserStatus enables, disables or suspends the user, keeping the reason of the last change. A
+// suspension ends by itself at SuspendedUntil.
+func (s *UserService) SetUserStatus(id string, update UserStatusUpdate) (*User, error) {
+	user, err := s.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	reason := strings.TrimSpace(update.Reason)
+	if utf8.RuneCountInString(reason) > maxStatusReasonLength {
+		return nil, ErrUserStatusNotValid
+	}
+	updated := *user
+	updated.Status = update.Status
+	updated.StatusReason = reason
+	updated.SuspendedUntil = nil
+	switch update.Status {
+	case UserStatusEnabled, UserStatusDisabled:
+	case UserStatusSuspended:
+		if !update.SuspendedUntil.After(time.Now()) {
+			return nil, ErrUserStatusNotValid
+		}
+		until := update.SuspendedUntil
+		updated.SuspendedUntil = &until
+	default:
+		return nil, ErrUserStatusNotValid
+	}
+	if err = s.repository.Update(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ApproveUser enables a user whose registration is pending, the other users return ErrUserNotPending.
+func (s *UserService) ApproveUser(id string) (*User, error) {
+	user, err := s.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != UserStatusPending {
+		return nil, ErrUserNotPending
+	}
+	approved := *user
+	approved.Status = UserStatusEnabled
+	if err = s.repository.Update(&approved); err != nil {
+		return nil, err
+	}
+	return &approved, nil
+}
+
+// CheckUserActive returns ErrUserDisabled, ErrUserSuspended or ErrUserPending when the user can't log
+// in because of their status.
+func (s *UserService) CheckUserActive(user *User) error {
+	switch user.Status {
+	case UserStatusDisabled:
+		return ErrUserDisabled
+	case UserStatusPending:
+		return ErrUserPending
+	case UserStatusSuspended:
+		if user.SuspendedUntil == nil || time.Now().Before(*user.SuspendedUntil) {
+			return ErrUserSuspended
+		}
+	}
 	return nil
 }
 
-func (s *UserService) GetRepository() *UserRepository {
+// QueryUsers returns a page of the users matching the query, along with the total number of matches.
+func (s *UserService) QueryUsers(query *UserQuery) (*UserPage, error) {
+	return s.repository.Query(*query)
+}
+
+// ExportUsers returns the users of the organization, or of every organization when it's empty. The
+// password hashes are only included when includePasswordHashes is true.
+func (s *UserService) ExportUsers(organizationId string, includePasswordHashes bool) ([]*UserRecord, error) {
+	var users []*User
+	var err error
+	if organizationId == "" {
+		users, err = s.repository.GetAll()
+	} else {
+		users, err = s.repository.GetByOrganization(organizationId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*UserRecord, len(users))
+	for i, user := range users {
+		records[i] = newUserRecord(user, includePasswordHashes)
+	}
+	return records, nil
+}
+
+// ImportUsers adds the users of the records, each row is validated on its own and the refused ones are
+// reported without stopping the import. A dry run validates every row without adding the users.
+func (s *UserService) ImportUsers(records []*UserRecord, options ImportOptions) *ImportResult {
+	result := &ImportResult{DryRun: options.DryRun, Total: len(records), Errors: make([]*ImportError, 0)}
+	seen := make(map[string]bool)
+	for i, record := range records {
+		user, err := s.newImportedUser(record, options, seen)
+		if err == nil && !options.DryRun {
+			err = s.repository.Add(user)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, &ImportError{Row: i + 1, Name: record.Name, Err: err})
+			continue
+		}
+		seen["id/"+user.Id] = true
+		seen["name/"+user.OrganizationId+"/"+CanonicalName(user.Name)] = true
+		if user.Email != "" {
+			seen["email/"+user.Email] = true
+		}
+		result.Created++
+	}
+	return result
+}
+
+// newImportedUser validates the record like a new user, the seen keys find the users repeated in the
+// file. The plain passwords are not hashed by a dry run.
+func (s *UserService) newImportedUser(record *UserRecord, options ImportOptions, seen map[string]bool) (*User, error) {
+	organizationId := record.OrganizationId
+	if organizationId == "" {
+		organizationId = options.OrganizationId
+	}
+	if organizationId == "" {
+		organizationId = DefaultOrganizationId
+	}
+	if strings.TrimSpace(record.Name) == "" {
+		return nil, ErrImportNameMissing
+	}
+	if _, err := s.organizations.GetOrganization(organizationId); err != nil {
+		return nil, err
+	}
+	name, err := NormalizeName(record.Name)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{
+		Id:             record.Id,
+		OrganizationId: organizationId,
+		Name:           name,
+		Roles:          normalizeNames(record.Roles),
+		CreatedAt:      time.Now(),
+		Status:         record.Status,
+		StatusReason:   strings.TrimSpace(record.StatusReason),
+	}
+	if user.Id == "" {
+		user.Id = strings.ReplaceAll(uuid.NewString(), "-", "")
+	} else if _, err := s.repository.GetById(user.Id); err == nil || seen["id/"+user.Id] {
+		return nil, ErrImportIdAlreadyRegistered
+	}
+	if _, err := s.repository.GetByName(organizationId, user.Name); err == nil || seen["name/"+organizationId+"/"+CanonicalName(user.Name)] {
+		return nil, ErrUserAlreadyRegistered
+	}
+	email, err := NormalizeEmail(record.Email)
+	if err != nil {
+		return nil, err
+	}
+	if email != "" {
+		if _, err = s.repository.GetByEmail(email); err == nil || seen["email/"+email] {
+			return nil, ErrEmailAlreadyRegistered
+		}
+		user.Email = email
+		user.EmailVerified = record.EmailVerified
+	}
+	if err = s.validateRoles(user.Roles); err != nil {
+		return nil, err
+	}
+	if record.CreatedAt != nil {
+		user.CreatedAt = *record.CreatedAt
+	}
+
+	switch user.Status {
+	case "":
+		user.Status = UserStatusEnabled
+	case UserStatusEnabled, UserStatusDisabled, UserStatusPending:
+	case UserStatusSuspended:
+		if record.SuspendedUntil == nil {
+			return nil, ErrUserStatusNotValid
+		}
+		until := *record.SuspendedUntil
+		user.SuspendedUntil = &until
+	default:
+		return nil, ErrUserStatusNotValid
+	}
+	if utf8.RuneCountInString(user.StatusReason) > maxStatusReasonLength {
+		return nil, ErrUserStatusNotValid
+	}
+
+	profile := ProfileUpdate{DisplayName: &record.DisplayName, AvatarURL: &record.AvatarURL, Locale: &record.Locale, Timezone: &record.Timezone}
+	if record.Metadata != nil {
+		profile.Metadata = &record.Metadata
+	}
+	if err = applyProfileUpdate(user, profile, s.metadataSchema); err != nil {
+		return nil, err
+	}
+
+	if (record.Password == "") == (record.PasswordHash == "") {
+		return nil, ErrImportPasswordMissing
+	}
+	if record.PasswordHash != "" {
+		if err = s.passwordValidator.validateHash([]byte(record.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("%w, %s", ErrPasswordHashNotValid, err)
+		}
+		user.Password = record.PasswordHash
+		return user, nil
+	}
+	if err = s.validatePassword(user.Name, record.Password); err != nil {
+		return nil, err
+	}
+	if !options.DryRun {
+		if user.Password, err = s.getPasswordHash(record.Password); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// CreateUser creates a user of the default organization with the built-in admin role when isAdmin is
+// true, or without roles.
+func (s *UserService) CreateUser(name string, password string, isAdmin bool) error {
+	newUser := NewUser{Name: name, Password: password}
+	if isAdmin {
+		newUser.Roles = []string{AdminRole}
+	}
+	_, err := s.AddUser(newUser)
+	return err
+}
+
+// AddUser creates the user and returns it, the email address is optional and starts unverified.
+func (s *UserService) AddUser(newUser NewUser) (*User, error) {
+	organizationId := newUser.OrganizationId
+	if organizationId == "" {
+		organizationId = DefaultOrganizationId
+	}
+	if _, err := s.organizations.GetOrganization(organizationId); err != nil {
+		return nil, err
+	}
+	name, err := NormalizeName(newUser.Name)
+	if err != nil {
+		return nil, err
+	}
+	email, err := NormalizeEmail(newUser.Email)
+	if err != nil {
+		return nil, err
+	}
+	roles := normalizeNames(newUser.Roles)
+	if err = s.validateRoles(roles); err != nil {
+		return nil, err
+	}
+	status := newUser.Status
+	if status == "" {
+		status = UserStatusEnabled
+	}
+	if status != UserStatusEnabled && status != UserStatusPending {
+		return nil, ErrUserStatusNotValid
+	}
+	if err = s.validatePassword(name, newUser.Password); err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.getPasswordHash(newUser.Password)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{
+		Id:             strings.ReplaceAll(uuid.NewString(), "-", ""),
+		OrganizationId: organizationId,
+		Name:           name,
+		Email:          email,
+		Roles:          roles,
+		Password:       passwordHash,
+		CreatedAt:      time.Now(),
+		Status:         status,
+	}
+	if err = s.repository.Add(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUser applies the non nil fields of the update to the user, the other fields keep their value.
+// A new email address has to be verified again, an empty one removes it.
+func (s *UserService) UpdateUser(id string, update UserUpdate) (*User, error) {
+	current, err := s.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *current
+	if update.Name != nil {
+		if updated.Name, err = NormalizeName(*update.Name); err != nil {
+			return nil, err
+		}
+	}
+	if update.Email != nil {
+		email, err := NormalizeEmail(*update.Email)
+		if err != nil {
+			return nil, err
+		}
+		if email != current.Email {
+			updated.Email = email
+			updated.EmailVerified = false
+		}
+	}
+	if update.Roles != nil {
+		roles := normalizeNames(*update.Roles)
+		if err = s.validateRoles(roles); err != nil {
+			return nil, err
+		}
+		updated.Roles = roles
+	}
+	if err = s.repository.Update(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// UpdateProfile changes the profile of the user. The metadata is checked against the schema when it's
+// replaced, the current one is kept as is when the schema changes.
+func (s *UserService) UpdateProfile(id string, update ProfileUpdate) (*User, error) {
+	current, err := s.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *current
+	if err = applyProfileUpdate(&updated, update, s.metadataSchema); err != nil {
+		return nil, err
+	}
+	if err = s.repository.Update(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (s *UserService) ChangePassword(id string, currentPassword string, newPassword string) error {
+	user, err := s.repository.GetById(id)
+	if err != nil {
+		return err
+	}
+	if err = s.checkCurrentPassword(user, currentPassword); err != nil {
+		return err
+	}
+	if err = s.validatePassword(user.Name, newPassword); err != nil {
+		return err
+	}
+	passwordHash, err := s.getPasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+	updated := *user
+	updated.Password = passwordHash
+	return s.repository.Update(&updated)
+}
+
+// VerifyEmail marks the email address of the user as verified, it must still be the address the
+// verification was sent to.
+func (s *UserService) VerifyEmail(id string, email string) (*User, error) {
+	user, err := s.repository.GetById(id)
+	if err == ErrUserNotFound {
+		return nil, ErrEmailVerificationNotValid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == "" || user.Email != email {
+		return nil, ErrEmailVerificationNotValid
+	}
+	if user.EmailVerified {
+		return user, nil
+	}
+	updated := *user
+	updated.EmailVerified = true
+	if err = s.repository.Update(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RequestPasswordReset creates a single use token to reset the password of the user, any previous
+// token of the user stops being valid. Only the hash of the token is stored. The user must have a
+// verified email address to receive the token.
+func (s *UserService) RequestPasswordReset(organizationId string, name string) (string, *User, error) {
+	user, err := s.repository.GetByName(organizationId, name)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.Email == "" || !user.EmailVerified {
+		return "", nil, ErrEmailNotVerified
+	}
+	tokenBytes := make([]byte, 32)
+	if _, err = rand.Read(tokenBytes); err != nil {
+		return "", nil, err
+	}
+	resetToken := b64.RawURLEncoding.EncodeToString(tokenBytes)
+	if err = s.passwordResets.DeleteUserPasswordResets(user.Id); err != nil {
+		return "", nil, err
+	}
+	err = s.passwordResets.AddPasswordReset(&PasswordReset{
+		TokenHash: hashResetToken(resetToken),
+		UserId:    user.Id,
+		ExpiresAt: time.Now().Add(s.passwordResetDuration),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return resetToken, user, nil
+}
+
+// CompletePasswordReset sets the new password of the user the token was created for and unlocks their
+// account, the token can't be used again afterwards.
+func (s *UserService) CompletePasswordReset(resetToken string, newPassword string) (*User, error) {
+	tokenHash := hashResetToken(resetToken)
+	reset, err := s.passwordResets.GetPasswordReset(tokenHash)
+	if err == ErrPasswordResetNotFound {
+		return nil, ErrPasswordResetTokenNotValid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		s.passwordResets.DeletePasswordReset(tokenHash)
+		return nil, ErrPasswordResetTokenNotValid
+	}
+	user, err := s.repository.GetById(reset.UserId)
+	if err == ErrUserNotFound {
+		return nil, ErrPasswordResetTokenNotValid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = s.validatePassword(user.Name, newPassword); err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.getPasswordHash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.passwordResets.DeletePasswordReset(tokenHash); err == ErrPasswordResetNotFound {
+		return nil, ErrPasswordResetTokenNotValid
+	} else if err != nil {
+		return nil, err
+	}
+	updated := *user
+	updated.Password = passwordHash
+	if err = s.repository.Update(&updated); err != nil {
+		return nil, err
+	}
+	if err = s.loginFailures.DeleteLoginFailures(user.Id); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// CreateInvitation creates an invitation to join the organization with the given roles. It expires after
+// the invitation duration unless an earlier ExpiresAt is given.
+func (s *UserService) CreateInvitation(newInvitation NewInvitation) (*Invitation, error) {
+	organizationId := newInvitation.OrganizationId
+	if organizationId == "" {
+		organizationId = DefaultOrganizationId
+	}
+	if _, err := s.organizations.GetOrganization(organizationId); err != nil {
+		return nil, err
+	}
+	email, err := NormalizeEmail(newInvitation.Email)
+	if err != nil {
+		return nil, err
+	}
+	if email != "" {
+		if _, err = s.repository.GetByEmail(email); err == nil {
+			return nil, ErrEmailAlreadyRegistered
+		} else if err != ErrUserNotFound {
+			return nil, err
+		}
+	}
+	roles := normalizeNames(newInvitation.Roles)
+	if err = s.validateRoles(roles); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.invitationDuration)
+	if !newInvitation.ExpiresAt.IsZero() {
+		if !newInvitation.ExpiresAt.After(now) || newInvitation.ExpiresAt.After(expiresAt) {
+			return nil, ErrInvitationExpiryNotValid
+		}
+		expiresAt = newInvitation.ExpiresAt
+	}
+	invitation := &Invitation{
+		Id:             strings.ReplaceAll(uuid.NewString(), "-", ""),
+		OrganizationId: organizationId,
+		Email:          email,
+		Roles:          roles,
+		CreatedBy:      newInvitation.CreatedBy,
+		CreatedAt:      now,
+		ExpiresAt:      expiresAt,
+	}
+	if err = s.invitations.AddInvitation(invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *UserService) GetInvitation(id string) (*Invitation, error) {
+	return s.invitations.GetInvitation(id)
+}
+
+func (s *UserService) GetInvitations(organizationId string) ([]*Invitation, error) {
+	return s.invitations.GetInvitations(organizationId)
+}
+
+func (s *UserService) DeleteInvitation(id string) error {
+	return s.invitations.DeleteInvitation(id)
+}
+
+// AcceptInvitation creates the user of a pending invitation with the chosen name and password. The
+// email address of the invitation is verified as the link was sent to it, and the invitation can't be
+// used again afterwards.
+func (s *UserService) AcceptInvitation(id string, name string, password string) (*User, error) {
+	invitation, err := s.invitations.GetInvitation(id)
+	if err == ErrInvitationNotFound {
+		return nil, ErrInvitationNotValid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationNotValid
+	}
+	user, err := s.AddUser(NewUser{
+		OrganizationId: invitation.OrganizationId,
+		Name:           name,
+		Password:       password,
+		Email:          invitation.Email,
+		Roles:          invitation.Roles,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.invitations.AcceptInvitation(id, user.Id, time.Now()); err != nil {
+		s.repository.Delete(user.Id)
+		if err == ErrInvitationNotFound {
+			return nil, ErrInvitationNotValid
+		}
+		return nil, err
+	}
+	if user.Email == "" {
+		return user, nil
+	}
+	verified := *user
+	verified.EmailVerified = true
+	if err = s.repository.Update(&verified); err != nil {
+		return nil, err
+	}
+	return &verified, nil
+}
+
+// GetPermissions returns the permissions of every role of the user, sorted and without duplicates.
+// Roles that no longer exist are ignored.
+func (s *UserService) GetPermissions(user *User) ([]string, error) {
+	permissions := make([]string, 0)
+	for _, name := range user.Roles {
+		role, err := s.GetRole(name)
+		if err == ErrRoleNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, role.Permissions...)
+	}
+	return normalizeNames(permissions), nil
+}
+
+// GetRoles returns the built-in roles followed by the custom ones.
+func (s *UserService) GetRoles() ([]*Role, error) {
+	customRoles, err := s.roles.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]*Role, 0, len(BuiltInRoles)+len(customRoles))
+	roles = append(roles, BuiltInRoles...)
+	return append(roles, customRoles...), nil
+}
+
+func (s *UserService) GetRole(name string) (*Role, error) {
+	if role := getBuiltInRole(name); role != nil {
+		return role, nil
+	}
+	return s.roles.GetRole(name)
+}
+
+func (s *UserService) CreateRole(role *Role) (*Role, error) {
+	if getBuiltInRole(role.Name) != nil {
+		return nil, ErrRoleAlreadyExists
+	}
+	created, err := newCustomRole(role.Name, role.Description, role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.roles.AddRole(created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateRole replaces the description and permissions of a custom role, the users get the new
+// permissions on their next token refresh.
+func (s *UserService) UpdateRole(role *Role) (*Role, error) {
+	if getBuiltInRole(role.Name) != nil {
+		return nil, ErrRoleBuiltIn
+	}
+	updated, err := newCustomRole(role.Name, role.Description, role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.roles.UpdateRole(updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteRole deletes a custom role, it must not be assigned to any user.
+func (s *UserService) DeleteRole(name string) error {
+	if getBuiltInRole(name) != nil {
+		return ErrRoleBuiltIn
+	}
+	if _, err := s.roles.GetRole(name); err != nil {
+		return err
+	}
+	users, err := s.repository.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.HasRole(name) {
+			return ErrRoleInUse
+		}
+	}
+	return s.roles.DeleteRole(name)
+}
+
+func (s *UserService) GetOrganizations() ([]*Organization, error) {
+	return s.organizations.GetOrganizations()
+}
+
+func (s *UserService) GetOrganization(id string) (*Organization, error) {
+	return s.organizations.GetOrganization(id)
+}
+
+// CreateOrganization creates an organization without users, the name is the id when empty.
+func (s *UserService) CreateOrganization(organization *Organization) (*Organization, error) {
+	created, err := newOrganization(organization.Id, organization.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.organizations.AddOrganization(created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *UserService) GetRepository() UserStore {
 	return s.repository
 }
 
+func (s *UserService) validateRoles(names []string) error {
+	for _, name := range names {
+		if _, err := s.GetRole(name); err == ErrRoleNotFound {
+			return fmt.Errorf("%w, %s", ErrRoleNotFound, name)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLockout returns ErrUserLocked while the lockout policy locks the user, or their failed logins.
+func (s *UserService) checkLockout(id string) (*LoginFailures, error) {
+	if s.lockoutPolicy == nil {
+		return &LoginFailures{UserId: id}, nil
+	}
+	failures, err := s.loginFailures.GetLoginFailures(id)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(failures.LockedUntil) {
+		return nil, ErrUserLocked
+	}
+	return failures, nil
+}
+
+// checkCurrentPassword confirms the password of a logged user. The wrong passwords count towards the
+// lockout like at the login, so a stolen session can't be used to guess the password. A valid one
+// leaves the failures, only a complete login forgets them.
+func (s *UserService) checkCurrentPassword(user *User, password string) error {
+	if _, err := s.checkLockout(user.Id); err != nil {
+		return err
+	}
+	if err := s.passwordValidator.compareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		if err = s.addLoginFailure(user.Id); err != nil {
+			return err
+		}
+		return ErrUserPasswordNotValid
+	}
+	return nil
+}
+
+func (s *UserService) addLoginFailure(id string) error {
+	if s.lockoutPolicy == nil {
+		return nil
+	}
+	now := time.Now()
+	_, err := s.loginFailures.AddLoginFailure(id, func(count int) time.Time {
+		return s.lockoutPolicy.LockedUntil(count, now)
+	})
+	return err
+}
+
+func (s *UserService) clearLoginFailures(failures *LoginFailures) error {
+	if failures.Count == 0 {
+		return nil
+	}
+	return s.loginFailures.DeleteLoginFailures(failures.UserId)
+}
+
+func (s *UserService) validatePassword(name string, password string) error {
+	if s.passwordPolicy == nil {
+		return nil
+	}
+	return s.passwordPolicy.Validate(name, password)
+}
+
+func (s *UserService) generateRecoveryCodes(id string) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	recoveryCodes := make([]*RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(random))
+		codes[i] = fmt.Sprintf("%s-%s-%s-%s", code[:4], code[4:8], code[8:12], code[12:])
+		recoveryCodes[i] = &RecoveryCode{Id: uuid.NewString(), UserId: id, Hash: s.hashRecoveryCode(code)}
+	}
+	if err := s.recoveryCodes.SetRecoveryCodes(id, recoveryCodes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// removeUnusableRecoveryCodes removes the recovery codes once the user has no second factor left, as
+// they would be valid again with the next one.
+func (s *UserService) removeUnusableRecoveryCodes(id string) error {
+	hasSecondFactor, err := s.HasSecondFactor(id)
+	if err != nil || hasSecondFactor {
+		return err
+	}
+	return s.recoveryCodes.SetRecoveryCodes(id, nil)
+}
+
+// hashRecoveryCode signs the normalized code with the recovery code key, the codes are random enough
+// not to need a slow password hash, which would let every login attempt cost a hashing.
+func (s *UserService) hashRecoveryCode(code string) string {
+	mac := hmac.New(sha256.New, s.recoveryCodeKey)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
+
+// verifyPassword compares the password with the hash of the user. A hash made with another algorithm or
+// outdated parameters is replaced once the password is known to be valid, so the users move to the
+// current hashing as they log in.
+func (s *UserService) verifyPassword(user *User, password string) (*User, error) {
+	if err := s.passwordValidator.compareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrUserPasswordNotValid
+	}
+	if !s.passwordValidator.needsRehash([]byte(user.Password)) {
+		return user, nil
+	}
+	passwordHash, err := s.getPasswordHash(password)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repository.UpdatePassword(user.Id, passwordHash); err != nil {
+		return nil, err
+	}
+	updated := *user
+	updated.Password = passwordHash
+	return &updated, nil
+}
+
 func (s *UserService) getPasswordHash(password string) (string, error) {
-	passwordBytes, err := s.passwordValidator.generateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passwordBytes, err := s.passwordValidator.generateFromPassword([]byte(password))
 	if err != nil {
 		return "", err
 	}
 	return string(passwordBytes), nil
 }
+
+func hashResetToken(resetToken string) string {
+	hash := sha256.Sum256([]byte(resetToken))
+	return hex.EncodeToString(hash[:])
+}
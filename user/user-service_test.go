@@ -1,8 +1,16 @@
 package user
 
 import (
+	"authGo/jsonschema"
+	"authGo/password"
+	"authGo/totp"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Login struct {
@@ -18,19 +26,27 @@ func (spv MockPasswordValidator) compareHashAndPassword(hashedPassword, password
 	return spv.ErrorToReturn
 }
 
-func (spv MockPasswordValidator) generateFromPassword(password []byte, cost int) ([]byte, error) {
+func (spv MockPasswordValidator) generateFromPassword(password []byte) ([]byte, error) {
 	return nil, spv.ErrorToReturn
 }
 
+func (spv MockPasswordValidator) needsRehash(hashedPassword []byte) bool {
+	return false
+}
+
+func (spv MockPasswordValidator) validateHash(hashedPassword []byte) error {
+	return spv.ErrorToReturn
+}
+
 func createTestUserService() (*UserService, error) {
 	users := []*User{
-		{Id: "1", Name: "test1", Password: "test1", IsAdmin: true},
-		{Id: "2", Name: "test2", Password: "test2", IsAdmin: false},
-		{Id: "3", Name: "test3", Password: "test3", IsAdmin: false},
+		{Id: "1", Name: "test1", Password: "test1", Roles: []string{AdminRole}},
+		{Id: "2", Name: "test2", Password: "test2"},
+		{Id: "3", Name: "test3", Password: "test3"},
 	}
 	service := NewUserService()
 	for _, user := range users {
-		err := service.CreateUser(user.Name, user.Password, user.IsAdmin)
+		err := service.CreateUser(user.Name, user.Password, user.HasRole(AdminRole))
 		if err != nil {
 			return nil, err
 		}
@@ -39,6 +55,21 @@ func createTestUserService() (*UserService, error) {
 
 }
 
+func addVerifiedEmail(t *testing.T, s *UserService, name string, email string) *User {
+	user, err := s.GetRepository().GetByName(DefaultOrganizationId, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.UpdateUser(user.Id, UserUpdate{Email: &email}); err != nil {
+		t.Fatal(err)
+	}
+	user, err = s.VerifyEmail(user.Id, email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
 func TestCreateUser(t *testing.T) {
 	s, err := createTestUserService()
 	if err != nil {
@@ -50,17 +81,17 @@ func TestCreateUser(t *testing.T) {
 			t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %s", err)
 		}
 	})
-	t.Run("Assign isAdmin to the new user", func(t *testing.T) {
+	t.Run("Assign the admin role to the new user", func(t *testing.T) {
 		err := s.CreateUser("admin", "admin", true)
 		if err != nil {
 			t.Errorf("expected error to be nil, got: %s", err)
 		}
-		user, err := s.GetRepository().GetByName("admin")
+		user, err := s.GetRepository().GetByName(DefaultOrganizationId, "admin")
 		if err != nil {
 			t.Errorf("expected error to be nil, got: %s", err)
 		}
-		if user.IsAdmin != true {
-			t.Error("expected IsAdmin to be true, got")
+		if !user.HasRole(AdminRole) {
+			t.Errorf("expected the admin role, got %v", user.Roles)
 		}
 	})
 	t.Run("Error hashing user password", func(t *testing.T) {
@@ -73,7 +104,7 @@ func TestCreateUser(t *testing.T) {
 		if err != hashError {
 			t.Errorf("expected error to be %s", hashError)
 		}
-		isPasswordValid := s.IsPasswordValid("test4", "test4")
+		isPasswordValid := s.IsPasswordValid(DefaultOrganizationId, "test4", "test4")
 		if isPasswordValid != false {
 			t.Error("expected isPasswordValid to be false")
 		}
@@ -101,13 +132,86 @@ func TestIsPasswordValid(t *testing.T) {
 	}
 
 	for _, loginTest := range loginTests {
-		got := s.IsPasswordValid(loginTest.login.Name, loginTest.login.Password)
+		got := s.IsPasswordValid(DefaultOrganizationId, loginTest.login.Name, loginTest.login.Password)
 		if got != loginTest.want {
 			t.Errorf("login %s password %s, got %t want %t", loginTest.login.Name, loginTest.login.Password, got, loginTest.want)
 		}
 	}
 }
 
+func TestPasswordRehash(t *testing.T) {
+	s := NewUserService()
+	bcryptHasher := password.NewDefaultHasher()
+	bcryptHasher.Algorithm = password.AlgorithmBcrypt
+	s.SetPasswordHasher(bcryptHasher)
+	if err := s.CreateUser("test1", "test1", false); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1")
+	if !strings.HasPrefix(before.Password, "$2a$") {
+		t.Fatalf("expected a bcrypt hash, got %s", before.Password)
+	}
+
+	s.SetPasswordHasher(password.NewDefaultHasher())
+	if s.IsPasswordValid(DefaultOrganizationId, "test1", "wrong") {
+		t.Fatal("expected the password not to be valid")
+	}
+	if u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1"); u.Password != before.Password {
+		t.Error("expected the hash to be kept after a wrong password")
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "test1", "test1") {
+		t.Fatal("expected the bcrypt hash to still be valid")
+	}
+	after, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1")
+	if !strings.HasPrefix(after.Password, "$argon2id$") {
+		t.Errorf("expected the hash to be replaced with argon2id, got %s", after.Password)
+	}
+
+	stronger := password.NewDefaultHasher()
+	stronger.Argon2.Iterations++
+	s.SetPasswordHasher(stronger)
+	u, err := s.Authenticate(DefaultOrganizationId, "test1", "test1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Password == after.Password || !strings.Contains(u.Password, fmt.Sprintf("t=%d,", stronger.Argon2.Iterations)) {
+		t.Errorf("expected the hash to be replaced with the new parameters, got %s", u.Password)
+	}
+	if stored, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1"); stored.Password != u.Password {
+		t.Error("expected the new hash to be stored")
+	}
+}
+
+func TestPasswordPepperRotation(t *testing.T) {
+	s := NewUserService()
+	hasher := password.NewDefaultHasher()
+	hasher.Peppers = map[string][]byte{"v1": []byte("first pepper secret")}
+	hasher.PepperId = "v1"
+	s.SetPasswordHasher(hasher)
+	if err := s.CreateUser("test1", "test1", false); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1"); !strings.HasPrefix(u.Password, "$pepper$v1$") {
+		t.Fatalf("expected the hash to be tagged with the pepper, got %s", u.Password)
+	}
+
+	rotated := password.NewDefaultHasher()
+	rotated.Peppers = map[string][]byte{"v1": []byte("first pepper secret"), "v2": []byte("second pepper secret")}
+	rotated.PepperId = "v2"
+	s.SetPasswordHasher(rotated)
+	if _, err := s.Authenticate(DefaultOrganizationId, "test1", "test1"); err != nil {
+		t.Fatalf("expected the hash of the previous pepper to be valid, got %v", err)
+	}
+	if u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1"); !strings.HasPrefix(u.Password, "$pepper$v2$") {
+		t.Errorf("expected the hash to be upgraded to the new pepper, got %s", u.Password)
+	}
+
+	delete(rotated.Peppers, "v1")
+	if !s.IsPasswordValid(DefaultOrganizationId, "test1", "test1") {
+		t.Error("expected the upgraded hash not to need the previous pepper")
+	}
+}
+
 func TestGetRepository(t *testing.T) {
 	s, err := createTestUserService()
 	if err != nil {
@@ -119,3 +223,1061 @@ func TestGetRepository(t *testing.T) {
 		t.Error("expected repository to be the same as service repository")
 	}
 }
+
+func TestUpdateUser(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+	current, err := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roles := []string{AdminRole}
+	updated, err := s.UpdateUser(current.Id, UserUpdate{Roles: &roles})
+	if err != nil {
+		t.Errorf("expected error to be nil, got: %s", err)
+	}
+	if updated.Id != current.Id || updated.Name != "test2" || !updated.HasRole(AdminRole) || updated.Password != current.Password {
+		t.Errorf("expected only Roles to change, got %+v", updated)
+	}
+
+	name := "renamed"
+	updated, err = s.UpdateUser(current.Id, UserUpdate{Name: &name})
+	if err != nil {
+		t.Errorf("expected error to be nil, got: %s", err)
+	}
+	if updated.Name != "renamed" || !updated.HasRole(AdminRole) {
+		t.Errorf("expected only Name to change, got %+v", updated)
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "renamed", "test2") {
+		t.Error("expected the renamed user to keep their password")
+	}
+
+	name = "test1"
+	_, err = s.UpdateUser(current.Id, UserUpdate{Name: &name})
+	if err != ErrUserAlreadyRegistered {
+		t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %s", err)
+	}
+	_, err = s.UpdateUser("1111", UserUpdate{Name: &name})
+	if err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+}
+
+func TestUserNames(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatalf("error creating UserService, %s", err)
+	}
+	created, err := s.AddUser(NewUser{Name: " Ｊｏｈｎ Doe ", Password: "john"})
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if created.Name != "John Doe" {
+		t.Errorf("expected the name to be normalized, got %q", created.Name)
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "JOHN  DOE", "john") {
+		t.Error("expected the user to log in with any case and whitespace of their name")
+	}
+	if _, err = s.AddUser(NewUser{Name: "TEST1", Password: "test1"}); err != ErrUserAlreadyRegistered {
+		t.Errorf("expected error to be ErrUserAlreadyRegistered, got: %v", err)
+	}
+	for _, name := range []string{"", "John  Doe", "tеst4"} {
+		if _, err = s.AddUser(NewUser{Name: name, Password: "test4"}); err != ErrUserNameNotValid {
+			t.Errorf("expected the error of %q to be ErrUserNameNotValid, got: %v", name, err)
+		}
+	}
+	name := "test\u200b4"
+	if _, err = s.UpdateUser(created.Id, UserUpdate{Name: &name}); err != ErrUserNameNotValid {
+		t.Errorf("expected error to be ErrUserNameNotValid, got: %v", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+	user, err := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.ChangePassword(user.Id, "wrong", "new password")
+	if err != ErrUserPasswordNotValid {
+		t.Errorf("expected error to be ErrUserPasswordNotValid, got: %s", err)
+	}
+	err = s.ChangePassword("1111", "test2", "new password")
+	if err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+
+	err = s.ChangePassword(user.Id, "test2", "new password")
+	if err != nil {
+		t.Errorf("expected error to be nil, got: %s", err)
+	}
+	if s.IsPasswordValid(DefaultOrganizationId, "test2", "test2") {
+		t.Error("expected the old password to be rejected")
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "test2", "new password") {
+		t.Error("expected the new password to be valid")
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+	s.SetPasswordPolicy(password.NewDefaultPolicy())
+
+	err = s.CreateUser("test4", "short", false)
+	if !errors.Is(err, password.ErrPasswordPolicy) {
+		t.Errorf("expected error to be ErrPasswordPolicy, got: %s", err)
+	}
+	if _, err = s.GetRepository().GetByName(DefaultOrganizationId, "test4"); err != ErrUserNotFound {
+		t.Error("expected the user to not be created")
+	}
+	err = s.CreateUser("test4", "a long enough password", false)
+	if err != nil {
+		t.Errorf("expected error to be nil, got: %s", err)
+	}
+
+	user, err := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.ChangePassword(user.Id, "test2", "my test2 password")
+	if !errors.Is(err, password.ErrPasswordPolicy) {
+		t.Errorf("expected error to be ErrPasswordPolicy, got: %s", err)
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "test2", "test2") {
+		t.Error("expected the password to be unchanged")
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+
+	_, _, err = s.RequestPasswordReset(DefaultOrganizationId, "test4")
+	if err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+	_, _, err = s.RequestPasswordReset(DefaultOrganizationId, "test2")
+	if err != ErrEmailNotVerified {
+		t.Errorf("expected error to be ErrEmailNotVerified, got: %s", err)
+	}
+	addVerifiedEmail(t, s, "test2", "test2@example.com")
+
+	oldToken, _, err := s.RequestPasswordReset(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	resetToken, user, err := s.RequestPasswordReset(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if user.Name != "test2" || resetToken == "" || resetToken == oldToken {
+		t.Errorf("unexpected password reset, got %s %+v", resetToken, user)
+	}
+	if _, err = s.passwordResets.GetPasswordReset(resetToken); err != ErrPasswordResetNotFound {
+		t.Error("expected the token to not be stored in plain text")
+	}
+
+	_, err = s.CompletePasswordReset(oldToken, "new password")
+	if err != ErrPasswordResetTokenNotValid {
+		t.Errorf("expected the previous token to be invalidated, got: %s", err)
+	}
+	_, err = s.CompletePasswordReset("not a token", "new password")
+	if err != ErrPasswordResetTokenNotValid {
+		t.Errorf("expected error to be ErrPasswordResetTokenNotValid, got: %s", err)
+	}
+
+	s.SetPasswordPolicy(password.NewDefaultPolicy())
+	_, err = s.CompletePasswordReset(resetToken, "short")
+	if !errors.Is(err, password.ErrPasswordPolicy) {
+		t.Errorf("expected error to be ErrPasswordPolicy, got: %s", err)
+	}
+
+	updated, err := s.CompletePasswordReset(resetToken, "new password")
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if updated.Id != user.Id || !s.IsPasswordValid(DefaultOrganizationId, "test2", "new password") {
+		t.Error("expected the password to be changed")
+	}
+	_, err = s.CompletePasswordReset(resetToken, "another password")
+	if err != ErrPasswordResetTokenNotValid {
+		t.Errorf("expected the token to be single use, got: %s", err)
+	}
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+	s.SetPasswordResetDuration(-time.Second)
+	addVerifiedEmail(t, s, "test2", "test2@example.com")
+	resetToken, _, err := s.RequestPasswordReset(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	_, err = s.CompletePasswordReset(resetToken, "new password")
+	if err != ErrPasswordResetTokenNotValid {
+		t.Errorf("expected error to be ErrPasswordResetTokenNotValid, got: %s", err)
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "test2", "test2") {
+		t.Error("expected the password to be unchanged")
+	}
+}
+
+func TestAddUserEmail(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+
+	user, err := s.AddUser(NewUser{Name: "test4", Password: "test4", Email: " Test4@Example.com "})
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if user.Email != "test4@example.com" || user.EmailVerified {
+		t.Errorf("expected an unverified normalized email, got %+v", user)
+	}
+	stored, err := s.GetRepository().GetByEmail("test4@example.com")
+	if err != nil || stored.Id != user.Id {
+		t.Errorf("expected the user to be found by email, got %v %s", stored, err)
+	}
+
+	emailTests := []struct {
+		name  string
+		email string
+		err   error
+	}{
+		{"test5", "test4@example.com", ErrEmailAlreadyRegistered},
+		{"test5", "TEST4@example.com", ErrEmailAlreadyRegistered},
+		{"test5", "not an email", ErrEmailNotValid},
+		{"test5", "Test <test5@example.com>", ErrEmailNotValid},
+		{"test5", "", nil},
+		{"test6", "", nil},
+	}
+	for _, emailTest := range emailTests {
+		_, err = s.AddUser(NewUser{Name: emailTest.name, Password: emailTest.name, Email: emailTest.email})
+		if err != emailTest.err {
+			t.Errorf("%s: expected error to be %v, got: %v", emailTest.email, emailTest.err, err)
+		}
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+	user, err := s.AddUser(NewUser{Name: "test4", Password: "test4", Email: "test4@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.VerifyEmail(user.Id, "other@example.com"); err != ErrEmailVerificationNotValid {
+		t.Errorf("expected error to be ErrEmailVerificationNotValid, got: %s", err)
+	}
+	if _, err = s.VerifyEmail("1111", "test4@example.com"); err != ErrEmailVerificationNotValid {
+		t.Errorf("expected error to be ErrEmailVerificationNotValid, got: %s", err)
+	}
+	verified, err := s.VerifyEmail(user.Id, "test4@example.com")
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if !verified.EmailVerified {
+		t.Error("expected the email to be verified")
+	}
+
+	email := "new@example.com"
+	updated, err := s.UpdateUser(user.Id, UserUpdate{Email: &email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != email || updated.EmailVerified {
+		t.Errorf("expected a new email to need a verification, got %+v", updated)
+	}
+	if _, err = s.VerifyEmail(user.Id, "test4@example.com"); err != ErrEmailVerificationNotValid {
+		t.Errorf("expected the previous address to not be verifiable, got: %s", err)
+	}
+
+	empty := ""
+	updated, err = s.UpdateUser(user.Id, UserUpdate{Email: &empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != "" {
+		t.Errorf("expected the email to be removed, got %s", updated.Email)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Errorf("error creating UserService, %s", err)
+	}
+
+	helpdesk, err := s.CreateRole(&Role{Name: "helpdesk", Permissions: []string{PermissionSessionsRevoke, PermissionSessionsRead, PermissionSessionsRead}})
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if len(helpdesk.Permissions) != 2 || helpdesk.Permissions[0] != PermissionSessionsRead {
+		t.Errorf("expected sorted permissions without duplicates, got %v", helpdesk.Permissions)
+	}
+	roleTests := []struct {
+		role *Role
+		err  error
+	}{
+		{&Role{Name: "helpdesk", Permissions: []string{PermissionUsersRead}}, ErrRoleAlreadyExists},
+		{&Role{Name: AdminRole, Permissions: []string{PermissionUsersRead}}, ErrRoleAlreadyExists},
+		{&Role{Name: "Help Desk", Permissions: []string{PermissionUsersRead}}, ErrRoleNotValid},
+		{&Role{Name: "auditor", Permissions: []string{"users:fly"}}, ErrRoleNotValid},
+	}
+	for _, roleTest := range roleTests {
+		if _, err = s.CreateRole(roleTest.role); !errors.Is(err, roleTest.err) {
+			t.Errorf("%s: expected error to be %s, got: %v", roleTest.role.Name, roleTest.err, err)
+		}
+	}
+
+	user, err := s.AddUser(NewUser{Name: "test4", Password: "test4", Roles: []string{"helpdesk", "helpdesk"}})
+	if err != nil {
+		t.Fatalf("expected error to be nil, got: %s", err)
+	}
+	if len(user.Roles) != 1 {
+		t.Errorf("expected the duplicated roles to be removed, got %v", user.Roles)
+	}
+	if _, err = s.AddUser(NewUser{Name: "test5", Password: "test5", Roles: []string{"nobody"}}); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected error to be ErrRoleNotFound, got: %s", err)
+	}
+
+	permissions, err := s.GetPermissions(user)
+	if err != nil || len(permissions) != 2 {
+		t.Errorf("expected the permissions of the helpdesk role, got %v %v", permissions, err)
+	}
+	if _, err = s.UpdateRole(&Role{Name: "helpdesk", Permissions: []string{PermissionUsersRead}}); err != nil {
+		t.Errorf("expected error to be nil, got: %s", err)
+	}
+	permissions, _ = s.GetPermissions(user)
+	if len(permissions) != 1 || permissions[0] != PermissionUsersRead {
+		t.Errorf("expected the updated permissions, got %v", permissions)
+	}
+	admin, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test1")
+	permissions, _ = s.GetPermissions(admin)
+	if len(permissions) != len(Permissions) {
+		t.Errorf("expected the admin to have every permission, got %v", permissions)
+	}
+
+	if _, err = s.UpdateRole(&Role{Name: AdminRole}); err != ErrRoleBuiltIn {
+		t.Errorf("expected error to be ErrRoleBuiltIn, got: %s", err)
+	}
+	if err = s.DeleteRole(AdminRole); err != ErrRoleBuiltIn {
+		t.Errorf("expected error to be ErrRoleBuiltIn, got: %s", err)
+	}
+	if err = s.DeleteRole("helpdesk"); err != ErrRoleInUse {
+		t.Errorf("expected error to be ErrRoleInUse, got: %s", err)
+	}
+	noRoles := []string{}
+	if _, err = s.UpdateUser(user.Id, UserUpdate{Roles: &noRoles}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.DeleteRole("helpdesk"); err != nil {
+		t.Errorf("expected error to be nil, got: %s", err)
+	}
+	roles, err := s.GetRoles()
+	if err != nil || len(roles) != len(BuiltInRoles) || roles[0].Name != AdminRole {
+		t.Errorf("expected only the built-in roles, got %v %v", roles, err)
+	}
+}
+
+func TestOrganizationUsers(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	organization, err := s.CreateOrganization(&Organization{Id: "acme"})
+	if err != nil || organization.Name != "acme" {
+		t.Fatalf("expected the organization to be named after the id, got %+v %v", organization, err)
+	}
+	if _, err = s.CreateOrganization(&Organization{Id: "acme", Name: "Acme"}); err != ErrOrganizationAlreadyExists {
+		t.Errorf("expected error to be ErrOrganizationAlreadyExists, got: %s", err)
+	}
+	if _, err = s.CreateOrganization(&Organization{Id: "Not Valid"}); !errors.Is(err, ErrOrganizationNotValid) {
+		t.Errorf("expected error to be ErrOrganizationNotValid, got: %s", err)
+	}
+
+	user, err := s.AddUser(NewUser{OrganizationId: "acme", Name: "test1", Password: "acme password"})
+	if err != nil {
+		t.Fatalf("expected the same name to be free in another organization, got %s", err)
+	}
+	if user.OrganizationId != "acme" {
+		t.Errorf("expected the user to belong to the organization, got %s", user.OrganizationId)
+	}
+	if !s.IsPasswordValid("acme", "test1", "acme password") || s.IsPasswordValid("acme", "test1", "test1") {
+		t.Error("expected the password to be checked against the user of the organization")
+	}
+	if !s.IsPasswordValid(DefaultOrganizationId, "test1", "test1") {
+		t.Error("expected the user of the default organization to keep their password")
+	}
+	if _, err = s.AddUser(NewUser{OrganizationId: "nobody", Name: "test4", Password: "test4"}); err != ErrOrganizationNotFound {
+		t.Errorf("expected error to be ErrOrganizationNotFound, got: %s", err)
+	}
+	organizations, err := s.GetOrganizations()
+	if err != nil || len(organizations) != 2 || organizations[0].Id != DefaultOrganizationId {
+		t.Errorf("expected the default and the new organization, got %v %v", organizations, err)
+	}
+}
+
+func TestAuthenticateLockout(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "wrong"); err != ErrUserPasswordNotValid {
+		t.Errorf("expected error to be ErrUserPasswordNotValid without lockout policy, got: %s", err)
+	}
+	s.SetLockoutPolicy(&LockoutPolicy{MaxFailedAttempts: 2, Duration: time.Hour})
+	if _, err = s.Authenticate(DefaultOrganizationId, "nobody", "wrong"); err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err = s.Authenticate(DefaultOrganizationId, "test2", "wrong"); err != ErrUserPasswordNotValid {
+			t.Errorf("expected error to be ErrUserPasswordNotValid, got: %s", err)
+		}
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "test2"); err != ErrUserLocked {
+		t.Errorf("expected a valid password to be rejected while locked, got: %s", err)
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "wrong"); err != ErrUserLocked {
+		t.Errorf("expected a wrong password to get the same error while locked, got: %s", err)
+	}
+	user, err := s.Authenticate(DefaultOrganizationId, "test3", "test3")
+	if err != nil || user.Name != "test3" {
+		t.Errorf("expected other users to not be locked, got %+v %v", user, err)
+	}
+
+	locked, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err = s.UnlockUser(locked.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "test2"); err != nil {
+		t.Errorf("expected the user to be unlocked, got: %s", err)
+	}
+	if err = s.UnlockUser("nobody"); err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "wrong"); err != ErrUserPasswordNotValid {
+		t.Fatal(err)
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "test2"); err != nil {
+		t.Fatal(err)
+	}
+	if failures, _ := s.GetLoginFailures(locked.Id); failures.Count != 0 {
+		t.Errorf("expected a valid login to forget the failures, got %+v", failures)
+	}
+}
+
+func TestCurrentPasswordLockout(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLockoutPolicy(&LockoutPolicy{MaxFailedAttempts: 2, Duration: time.Hour})
+	user, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err = s.ChangePassword(user.Id, "wrong", "changed"); err != ErrUserPasswordNotValid {
+		t.Errorf("expected error to be ErrUserPasswordNotValid, got: %v", err)
+	}
+	if _, err = s.RegenerateRecoveryCodes(user.Id, "wrong"); err != ErrUserPasswordNotValid {
+		t.Errorf("expected error to be ErrUserPasswordNotValid, got: %v", err)
+	}
+	if err = s.ChangePassword(user.Id, "test2", "changed"); err != ErrUserLocked {
+		t.Errorf("expected the wrong current passwords to lock the user, got: %v", err)
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "test2"); err != ErrUserLocked {
+		t.Errorf("expected the login to be locked too, got: %v", err)
+	}
+
+	if err = s.UnlockUser(user.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.ChangePassword(user.Id, "wrong", "changed"); err != ErrUserPasswordNotValid {
+		t.Errorf("expected error to be ErrUserPasswordNotValid, got: %v", err)
+	}
+	if err = s.ChangePassword(user.Id, "test2", "changed"); err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if failures, _ := s.GetLoginFailures(user.Id); failures.Count != 1 {
+		t.Errorf("expected a valid current password to keep the failures, got %+v", failures)
+	}
+}
+
+func TestLoginHistory(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLoginHistoryRetention(LoginHistoryRetention{MaxAge: time.Hour, MaxAttempts: 2})
+	user, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+
+	failure := &LoginAttempt{UserId: user.Id, Method: LoginMethodPassword, Outcome: LoginOutcomeFailure, FailureReason: "password not valid"}
+	if err = s.RecordLoginAttempt(failure); err != nil {
+		t.Fatal(err)
+	}
+	if failure.Id == "" || failure.Time.IsZero() {
+		t.Errorf("expected the attempt to get an id and a time, got %+v", failure)
+	}
+	if user, _ = s.GetRepository().GetById(user.Id); user.LastLoginAt != nil {
+		t.Errorf("expected a failure to not be the last login, got %v", user.LastLoginAt)
+	}
+	success := &LoginAttempt{UserId: user.Id, Method: LoginMethodPassword, Outcome: LoginOutcomeSuccess}
+	if err = s.RecordLoginAttempt(success); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ = s.GetRepository().GetById(user.Id); user.LastLoginAt == nil || !user.LastLoginAt.Equal(success.Time) {
+		t.Errorf("expected the success to be the last login, got %v", user.LastLoginAt)
+	}
+
+	old := &LoginAttempt{UserId: user.Id, Time: time.Now().Add(-time.Hour * 2), Method: LoginMethodTOTP, Outcome: LoginOutcomeFailure}
+	if err = s.RecordLoginAttempt(old); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err := s.GetLoginHistory(user.Id)
+	if err != nil || len(attempts) != 2 || attempts[0].Id != success.Id || attempts[1].Id != failure.Id {
+		t.Errorf("expected the attempts within the retention from the newest, got %v %v", attempts, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = s.RecordLoginAttempt(&LoginAttempt{UserId: user.Id, Method: LoginMethodPassword, Outcome: LoginOutcomeFailure}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if attempts, _ = s.GetLoginHistory(user.Id); len(attempts) != 2 || attempts[0].Id == success.Id || attempts[1].Id == success.Id {
+		t.Errorf("expected only the newest attempts to be kept, got %v", attempts)
+	}
+
+	if _, err = s.GetLoginHistory("nobody"); err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+	if err = s.RecordLoginAttempt(&LoginAttempt{UserId: "nobody", Outcome: LoginOutcomeFailure}); err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %s", err)
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if _, err = s.EnrollTOTP("nobody"); err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %v", err)
+	}
+	if err = s.VerifyTOTP(u.Id, "123456"); err != ErrTOTPNotEnabled {
+		t.Errorf("expected error to be ErrTOTPNotEnabled, got: %v", err)
+	}
+
+	enrolled, err := s.EnrollTOTP(u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := s.IsTOTPEnabled(u.Id); enabled {
+		t.Error("expected the TOTP not to be enabled before the confirmation")
+	}
+	if err = s.ConfirmTOTP(u.Id, "000000x"); err != ErrTOTPCodeNotValid {
+		t.Errorf("expected error to be ErrTOTPCodeNotValid, got: %v", err)
+	}
+	counter := totp.Counter(time.Now())
+	code, _ := totp.Code(enrolled.Secret, counter)
+	if err = s.ConfirmTOTP(u.Id, code); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := s.IsTOTPEnabled(u.Id); !enabled {
+		t.Error("expected the TOTP to be enabled")
+	}
+	if _, err = s.EnrollTOTP(u.Id); err != ErrTOTPAlreadyEnabled {
+		t.Errorf("expected error to be ErrTOTPAlreadyEnabled, got: %v", err)
+	}
+
+	if err = s.VerifyTOTP(u.Id, code); err != ErrTOTPCodeNotValid {
+		t.Errorf("expected the confirmation code not to be accepted again, got: %v", err)
+	}
+	nextCode, _ := totp.Code(enrolled.Secret, counter+1)
+	if err = s.VerifyTOTP(u.Id, nextCode); err != nil {
+		t.Errorf("expected the next code to be valid, got: %v", err)
+	}
+	if err = s.VerifyTOTP(u.Id, nextCode); err != ErrTOTPCodeNotValid {
+		t.Errorf("expected the code not to be accepted twice, got: %v", err)
+	}
+
+	if err = s.DisableTOTP(u.Id); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := s.IsTOTPEnabled(u.Id); enabled {
+		t.Error("expected the TOTP to be disabled")
+	}
+}
+
+func TestTOTPLockout(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLockoutPolicy(&LockoutPolicy{MaxFailedAttempts: 2, Duration: time.Hour})
+	u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	enrolled, _ := s.EnrollTOTP(u.Id)
+	code, _ := totp.Code(enrolled.Secret, totp.Counter(time.Now())-1)
+	if err = s.ConfirmTOTP(u.Id, code); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err = s.Authenticate(DefaultOrganizationId, "test2", "test2"); err != nil {
+			t.Fatal(err)
+		}
+		if err = s.VerifyTOTP(u.Id, "abcdef"); err != ErrTOTPCodeNotValid {
+			t.Errorf("expected error to be ErrTOTPCodeNotValid, got: %v", err)
+		}
+	}
+	if _, err = s.Authenticate(DefaultOrganizationId, "test2", "test2"); err != ErrUserLocked {
+		t.Errorf("expected the failed codes to lock the user, got: %v", err)
+	}
+	code, _ = totp.Code(enrolled.Secret, totp.Counter(time.Now()))
+	if err = s.VerifyTOTP(u.Id, code); err != ErrUserLocked {
+		t.Errorf("expected a valid code to be rejected while locked, got: %v", err)
+	}
+}
+
+func TestWebAuthnCredentials(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	other, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test3")
+
+	if _, err = s.AddWebAuthnCredential(&WebAuthnCredential{Id: "cred1", UserId: "nobody", PublicKey: []byte{1}}); err != ErrUserNotFound {
+		t.Errorf("expected error to be ErrUserNotFound, got: %v", err)
+	}
+	if _, err = s.AddWebAuthnCredential(&WebAuthnCredential{Id: "cred1", UserId: u.Id, Name: strings.Repeat("a", 65), PublicKey: []byte{1}}); err != ErrWebAuthnNameNotValid {
+		t.Errorf("expected error to be ErrWebAuthnNameNotValid, got: %v", err)
+	}
+	credential, err := s.AddWebAuthnCredential(&WebAuthnCredential{Id: "cred1", UserId: u.Id, Name: " ", PublicKey: []byte{1}})
+	if err != nil || credential.Name != "Passkey" || credential.CreatedAt.IsZero() {
+		t.Errorf("expected the default name and creation date, got %+v %v", credential, err)
+	}
+	if credentials, _ := s.GetWebAuthnCredentials(u.Id); len(credentials) != 1 {
+		t.Errorf("expected a credential, got %v", credentials)
+	}
+
+	if err = s.DeleteWebAuthnCredential(other.Id, "cred1"); err != ErrWebAuthnCredentialNotFound {
+		t.Errorf("expected the credentials of other users not to be found, got: %v", err)
+	}
+	if err = s.DeleteWebAuthnCredential(u.Id, "cred1"); err != nil {
+		t.Fatal(err)
+	}
+	if credentials, _ := s.GetWebAuthnCredentials(u.Id); len(credentials) != 0 {
+		t.Errorf("expected no credentials, got %v", credentials)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if _, err = s.RegenerateRecoveryCodes(u.Id, "test2"); err != ErrSecondFactorNotEnabled {
+		t.Errorf("expected error to be ErrSecondFactorNotEnabled, got: %v", err)
+	}
+	if _, err = s.AddWebAuthnCredential(&WebAuthnCredential{Id: "cred1", UserId: u.Id, PublicKey: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := s.EnsureRecoveryCodes(u.Id)
+	if err != nil || len(codes) != RecoveryCodeCount || len(codes[0]) != 19 {
+		t.Fatalf("expected the recovery codes, got %v %v", codes, err)
+	}
+	if again, _ := s.EnsureRecoveryCodes(u.Id); again != nil {
+		t.Errorf("expected no new codes while the user has some, got %v", again)
+	}
+	if err = s.UseRecoveryCode(u.Id, "aaaa-bbbb-cccc-dddd"); err != ErrRecoveryCodeNotValid {
+		t.Errorf("expected error to be ErrRecoveryCodeNotValid, got: %v", err)
+	}
+	if err = s.UseRecoveryCode(u.Id, " "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))); err != nil {
+		t.Errorf("expected the code to be normalized, got: %v", err)
+	}
+	if err = s.UseRecoveryCode(u.Id, codes[0]); err != ErrRecoveryCodeNotValid {
+		t.Errorf("expected a used code not to be valid, got: %v", err)
+	}
+	if count, _ := s.CountRecoveryCodes(u.Id); count != RecoveryCodeCount-1 {
+		t.Errorf("expected %d codes left, got %d", RecoveryCodeCount-1, count)
+	}
+
+	if _, err = s.RegenerateRecoveryCodes(u.Id, "wrong"); err != ErrUserPasswordNotValid {
+		t.Errorf("expected error to be ErrUserPasswordNotValid, got: %v", err)
+	}
+	newCodes, err := s.RegenerateRecoveryCodes(u.Id, "test2")
+	if err != nil || len(newCodes) != RecoveryCodeCount {
+		t.Fatalf("expected new codes, got %v %v", newCodes, err)
+	}
+	if err = s.UseRecoveryCode(u.Id, codes[1]); err != ErrRecoveryCodeNotValid {
+		t.Errorf("expected the old codes to be invalidated, got: %v", err)
+	}
+	stored, _ := s.recoveryCodes.GetRecoveryCodes(u.Id)
+	if len(stored) == 0 || len(stored[0].Hash) != 64 || strings.HasPrefix(stored[0].Hash, "$") {
+		t.Errorf("expected the codes to be stored as HMAC-SHA256 digests, got %v", stored)
+	}
+	s.SetRecoveryCodeKey([]byte("another key"))
+	if err = s.UseRecoveryCode(u.Id, newCodes[0]); err != ErrRecoveryCodeNotValid {
+		t.Errorf("expected the codes of another key not to be valid, got: %v", err)
+	}
+	s.SetRecoveryCodeKey(nil)
+	if err = s.UseRecoveryCode(u.Id, newCodes[0]); err != nil {
+		t.Errorf("expected the new code to be valid, got: %v", err)
+	}
+
+	if err = s.DeleteWebAuthnCredential(u.Id, "cred1"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := s.CountRecoveryCodes(u.Id); count != 0 {
+		t.Errorf("expected the codes to be removed with the last second factor, got %d", count)
+	}
+}
+
+func TestSetUserStatus(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Status != UserStatusEnabled || s.CheckUserActive(user) != nil {
+		t.Errorf("expected new users to be enabled, got %+v", user)
+	}
+
+	invalidUpdates := []UserStatusUpdate{
+		{Status: "locked"},
+		{Status: UserStatusSuspended},
+		{Status: UserStatusSuspended, SuspendedUntil: time.Now().Add(-time.Minute)},
+		{Status: UserStatusDisabled, Reason: strings.Repeat("a", maxStatusReasonLength+1)},
+	}
+	for _, update := range invalidUpdates {
+		if _, err = s.SetUserStatus(user.Id, update); err != ErrUserStatusNotValid {
+			t.Errorf("%+v: expected err to be ErrUserStatusNotValid, got %v", update, err)
+		}
+	}
+	if _, err = s.SetUserStatus("unknown", UserStatusUpdate{Status: UserStatusDisabled}); err != ErrUserNotFound {
+		t.Errorf("expected err to be ErrUserNotFound, got %v", err)
+	}
+
+	user, err = s.SetUserStatus(user.Id, UserStatusUpdate{Status: UserStatusDisabled, Reason: " left the company "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.StatusReason != "left the company" || s.CheckUserActive(user) != ErrUserDisabled {
+		t.Errorf("expected the user to be disabled, got %+v", user)
+	}
+
+	user, err = s.SetUserStatus(user.Id, UserStatusUpdate{Status: UserStatusSuspended, SuspendedUntil: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.CheckUserActive(user); err != ErrUserSuspended {
+		t.Errorf("expected err to be ErrUserSuspended, got %v", err)
+	}
+	ended := time.Now().Add(-time.Minute)
+	user.SuspendedUntil = &ended
+	if err = s.CheckUserActive(user); err != nil {
+		t.Errorf("expected the ended suspension to be active, got %s", err)
+	}
+
+	user, err = s.SetUserStatus(user.Id, UserStatusUpdate{Status: UserStatusEnabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.SuspendedUntil != nil || user.StatusReason != "" || s.CheckUserActive(user) != nil {
+		t.Errorf("expected the user to be enabled, got %+v", user)
+	}
+}
+
+func TestApproveUser(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.AddUser(NewUser{Name: "locked", Password: "locked", Status: UserStatusDisabled}); err != ErrUserStatusNotValid {
+		t.Errorf("expected err to be ErrUserStatusNotValid, got %v", err)
+	}
+	user, err := s.AddUser(NewUser{Name: "pending", Password: "pending", Status: UserStatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.CheckUserActive(user); err != ErrUserPending {
+		t.Errorf("expected err to be ErrUserPending, got %v", err)
+	}
+
+	user, err = s.ApproveUser(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Status != UserStatusEnabled || s.CheckUserActive(user) != nil {
+		t.Errorf("expected the user to be enabled, got %+v", user)
+	}
+	if _, err = s.ApproveUser(user.Id); err != ErrUserNotPending {
+		t.Errorf("expected err to be ErrUserNotPending, got %v", err)
+	}
+	if _, err = s.ApproveUser("unknown"); err != ErrUserNotFound {
+		t.Errorf("expected err to be ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.GetRepository().GetByName(DefaultOrganizationId, "test2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := jsonschema.Compile([]byte(`{"type": "object", "properties": {"department": {"enum": ["sales", "support"]}}, "additionalProperties": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetMetadataSchema(schema)
+
+	displayName, avatarURL, locale, timezone := " Test Two ", "https://example.com/2.png", "pt-BR", "America/Sao_Paulo"
+	metadata := map[string]any{"department": "sales"}
+	user, err = s.UpdateProfile(user.Id, ProfileUpdate{DisplayName: &displayName, AvatarURL: &avatarURL, Locale: &locale, Timezone: &timezone, Metadata: &metadata})
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if user.DisplayName != "Test Two" || user.AvatarURL != avatarURL || user.Locale != locale || user.Timezone != timezone || user.Metadata["department"] != "sales" {
+		t.Errorf("expected the profile to be updated, got %+v", user)
+	}
+
+	empty := ""
+	user, err = s.UpdateProfile(user.Id, ProfileUpdate{AvatarURL: &empty})
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if user.AvatarURL != "" || user.DisplayName != "Test Two" || user.Metadata["department"] != "sales" {
+		t.Errorf("expected only the avatar to be removed, got %+v", user)
+	}
+
+	long := strings.Repeat("a", maxDisplayNameLength+1)
+	invalidURL, invalidLocale, invalidTimezone, local := "javascript:alert(1)", "en_US", "Mars/Olympus", "Local"
+	invalidMetadata := map[string]any{"department": "marketing"}
+	largeMetadata := map[string]any{"department": "sales", "notes": strings.Repeat("a", MaxMetadataSize)}
+	profileTests := []struct {
+		update ProfileUpdate
+		err    error
+	}{
+		{ProfileUpdate{DisplayName: &long}, ErrDisplayNameNotValid},
+		{ProfileUpdate{AvatarURL: &invalidURL}, ErrAvatarURLNotValid},
+		{ProfileUpdate{Locale: &invalidLocale}, ErrLocaleNotValid},
+		{ProfileUpdate{Timezone: &invalidTimezone}, ErrTimezoneNotValid},
+		{ProfileUpdate{Timezone: &local}, ErrTimezoneNotValid},
+		{ProfileUpdate{Metadata: &invalidMetadata}, ErrMetadataNotValid},
+		{ProfileUpdate{Metadata: &largeMetadata}, ErrMetadataNotValid},
+	}
+	for _, profileTest := range profileTests {
+		if _, err = s.UpdateProfile(user.Id, profileTest.update); !errors.Is(err, profileTest.err) {
+			t.Errorf("expected err to be %s, got %v", profileTest.err, err)
+		}
+	}
+	var metadataErr *MetadataError
+	if _, err = s.UpdateProfile(user.Id, ProfileUpdate{Metadata: &invalidMetadata}); !errors.As(err, &metadataErr) || metadataErr.Reason != "/department must be one of the enum values" {
+		t.Errorf("expected the reason of the metadata error, got %v", err)
+	}
+
+	if _, err = s.UpdateProfile("unknown", ProfileUpdate{DisplayName: &displayName}); err != ErrUserNotFound {
+		t.Errorf("expected err to be ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestInvitations(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addVerifiedEmail(t, s, "test2", "test2@example.com")
+
+	invitation, err := s.CreateInvitation(NewInvitation{Email: " New@Example.com ", Roles: []string{AdminRole}, CreatedBy: "1"})
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if invitation.OrganizationId != DefaultOrganizationId || invitation.Email != "new@example.com" || len(invitation.Roles) != 1 {
+		t.Errorf("unexpected invitation, got %+v", invitation)
+	}
+	if expiry := invitation.ExpiresAt.Sub(invitation.CreatedAt); expiry != DefaultInvitationDuration {
+		t.Errorf("expected the invitation to last %s, got %s", DefaultInvitationDuration, expiry)
+	}
+
+	invitationTests := []struct {
+		newInvitation NewInvitation
+		err           error
+	}{
+		{NewInvitation{OrganizationId: "unknown"}, ErrOrganizationNotFound},
+		{NewInvitation{Email: "not an email"}, ErrEmailNotValid},
+		{NewInvitation{Email: "test2@example.com"}, ErrEmailAlreadyRegistered},
+		{NewInvitation{Roles: []string{"unknown"}}, ErrRoleNotFound},
+		{NewInvitation{ExpiresAt: time.Now().Add(-time.Minute)}, ErrInvitationExpiryNotValid},
+		{NewInvitation{ExpiresAt: time.Now().Add(DefaultInvitationDuration + time.Hour)}, ErrInvitationExpiryNotValid},
+	}
+	for _, invitationTest := range invitationTests {
+		if _, err = s.CreateInvitation(invitationTest.newInvitation); !errors.Is(err, invitationTest.err) {
+			t.Errorf("%+v: expected err to be %s, got %v", invitationTest.newInvitation, invitationTest.err, err)
+		}
+	}
+
+	if _, err = s.AcceptInvitation(invitation.Id, "test2", "new password"); err != ErrUserAlreadyRegistered {
+		t.Errorf("expected a registered name to fail, got %v", err)
+	}
+	user, err := s.AcceptInvitation(invitation.Id, "invited", "new password")
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if !user.HasRole(AdminRole) || user.Email != "new@example.com" || !user.EmailVerified || !s.IsPasswordValid(DefaultOrganizationId, "invited", "new password") {
+		t.Errorf("unexpected invited user, got %+v", user)
+	}
+	if _, err = s.AcceptInvitation(invitation.Id, "invited2", "new password"); err != ErrInvitationNotValid {
+		t.Errorf("expected an accepted invitation to fail, got %v", err)
+	}
+	if invitation, _ = s.GetInvitation(invitation.Id); invitation.AcceptedAt == nil || invitation.UserId != user.Id {
+		t.Errorf("expected the invitation to be accepted, got %+v", invitation)
+	}
+
+	short, err := s.CreateInvitation(NewInvitation{ExpiresAt: time.Now().Add(time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err = s.AcceptInvitation(short.Id, "late", "new password"); err != ErrInvitationNotValid {
+		t.Errorf("expected an expired invitation to fail, got %v", err)
+	}
+	if _, err = s.AcceptInvitation("unknown", "unknown", "new password"); err != ErrInvitationNotValid {
+		t.Errorf("expected an unknown invitation to fail, got %v", err)
+	}
+	if _, err = s.GetRepository().GetByName(DefaultOrganizationId, "late"); err != ErrUserNotFound {
+		t.Errorf("expected no user for the failed invitations, got %v", err)
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addVerifiedEmail(t, s, "test2", "test2@example.com")
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("migrated"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*UserRecord{
+		{Name: "imported", Password: "imported", Email: "Imported@Example.com", EmailVerified: true, Roles: []string{AdminRole}},
+		{Id: "legacy1", Name: "migrated", PasswordHash: string(bcryptHash), CreatedAt: &createdAt, DisplayName: "Migrated"},
+		{Name: "test1", Password: "test1"},
+		{Name: "repeated", Password: "repeated", Email: "imported@example.com"},
+		{Name: "imported", Password: "imported"},
+		{Id: "legacy1", Name: "other", Password: "other"},
+		{Name: " ", Password: "empty"},
+		{Name: "nopassword"},
+		{Name: "both", Password: "both", PasswordHash: string(bcryptHash)},
+		{Name: "badhash", PasswordHash: "$2b$10$short"},
+		{Name: "role", Password: "role", Roles: []string{"unknown"}},
+		{Name: "status", Password: "status", Status: UserStatusSuspended},
+		{Name: "timezone", Password: "timezone", Timezone: "Mars/Olympus"},
+		{Name: "organization", Password: "organization", OrganizationId: "unknown"},
+	}
+	expectedErrors := []error{ErrUserAlreadyRegistered, ErrEmailAlreadyRegistered, ErrUserAlreadyRegistered, ErrImportIdAlreadyRegistered,
+		ErrImportNameMissing, ErrImportPasswordMissing, ErrImportPasswordMissing, ErrPasswordHashNotValid, ErrRoleNotFound,
+		ErrUserStatusNotValid, ErrTimezoneNotValid, ErrOrganizationNotFound}
+
+	for _, dryRun := range []bool{true, false} {
+		result := s.ImportUsers(records, ImportOptions{DryRun: dryRun})
+		if result.DryRun != dryRun || result.Total != len(records) || result.Created != 2 || len(result.Errors) != len(expectedErrors) {
+			t.Fatalf("dry run %t: unexpected result %+v", dryRun, result)
+		}
+		for i, importErr := range result.Errors {
+			if importErr.Row != i+3 || !errors.Is(importErr, expectedErrors[i]) {
+				t.Errorf("dry run %t: expected row %d to fail with %v, got %s", dryRun, i+3, expectedErrors[i], importErr)
+			}
+		}
+		_, err = s.GetRepository().GetByName(DefaultOrganizationId, "imported")
+		if dryRun && err != ErrUserNotFound {
+			t.Errorf("expected the dry run not to add users, got %v", err)
+		}
+	}
+
+	imported, err := s.GetRepository().GetByName(DefaultOrganizationId, "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Email != "imported@example.com" || !imported.EmailVerified || !imported.HasRole(AdminRole) || !s.IsPasswordValid(DefaultOrganizationId, "imported", "imported") {
+		t.Errorf("unexpected imported user, got %+v", imported)
+	}
+	migrated, err := s.GetRepository().GetById("legacy1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.Name != "migrated" || !migrated.CreatedAt.Equal(createdAt) || migrated.DisplayName != "Migrated" || !s.IsPasswordValid(DefaultOrganizationId, "migrated", "migrated") {
+		t.Errorf("unexpected migrated user, got %+v", migrated)
+	}
+}
+
+func TestExportUsers(t *testing.T) {
+	s, err := createTestUserService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addVerifiedEmail(t, s, "test2", "test2@example.com")
+	if _, err = s.CreateOrganization(&Organization{Id: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := s.ExportUsers("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1].Name != "test2" || records[1].Email != "test2@example.com" || !records[1].EmailVerified {
+		t.Errorf("unexpected export, got %+v", records)
+	}
+	for _, record := range records {
+		if record.PasswordHash != "" || record.Password != "" {
+			t.Errorf("expected the export not to have the passwords, got %+v", record)
+		}
+	}
+
+	records, err = s.ExportUsers(DefaultOrganizationId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || !strings.HasPrefix(records[0].PasswordHash, "$argon2id$") {
+		t.Errorf("expected the export to have the hashes, got %+v", records)
+	}
+	if otherRecords, _ := s.ExportUsers("other", true); len(otherRecords) != 0 {
+		t.Errorf("expected no users in the other organization, got %+v", otherRecords)
+	}
+
+	other := NewUserService()
+	if result := other.ImportUsers(records, ImportOptions{}); result.Created != len(records) {
+		t.Errorf("expected the export to be imported, got %+v", result.Errors)
+	}
+	if !other.IsPasswordValid(DefaultOrganizationId, "test2", "test2") {
+		t.Error("expected the imported hashes to keep the passwords")
+	}
+}
@@ -0,0 +1,391 @@
+package user
+
+import (
+	"authGo/database"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const userColumns = "id, organization_id, name, email, email_verified, password, created_at, status, status_reason, suspended_until, display_name, avatar_url, locale, timezone, metadata, last_login_at"
+
+type SqlUserRepository struct {
+	db *sql.DB
+}
+
+// NewSqlUserRepository migrates the database schema to the latest version before returning the repository.
+func NewSqlUserRepository(db *sql.DB) (*SqlUserRepository, error) {
+	if err := database.Migrate(db, Migrations); err != nil {
+		return nil, err
+	}
+	return &SqlUserRepository{db: db}, nil
+}
+
+func (r *SqlUserRepository) Add(user *User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	metadata, err := encodeMetadata(user.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO users ("+userColumns+", canonical_name) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		user.Id, user.OrganizationId, user.Name, nullableString(user.Email), user.EmailVerified, user.Password, unixNano(user.CreatedAt),
+		userStatus(user), user.StatusReason, suspendedUntil(user), user.DisplayName, user.AvatarURL, user.Locale, user.Timezone, metadata, lastLoginAt(user), CanonicalName(user.Name))
+	if err = uniqueConstraintError(err); err != nil {
+		return err
+	}
+	if err = insertUserRoles(tx, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SqlUserRepository) GetById(id string) (*User, error) {
+	return r.getUser("SELECT "+userColumns+" FROM users WHERE id = ?", id)
+}
+
+func (r *SqlUserRepository) GetByName(organizationId string, name string) (*User, error) {
+	return r.getUser("SELECT "+userColumns+" FROM users WHERE organization_id = ? AND canonical_name = ?", organizationId, CanonicalName(name))
+}
+
+func (r *SqlUserRepository) GetByEmail(email string) (*User, error) {
+	if email == "" {
+		return nil, ErrUserNotFound
+	}
+	return r.getUser("SELECT "+userColumns+" FROM users WHERE email = ?", email)
+}
+
+func (r *SqlUserRepository) Update(user *User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	metadata, err := encodeMetadata(user.Metadata)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec("UPDATE users SET name = ?, canonical_name = ?, email = ?, email_verified = ?, password = ?, status = ?, status_reason = ?, suspended_until = ?, "+
+		"display_name = ?, avatar_url = ?, locale = ?, timezone = ?, metadata = ?, last_login_at = ? WHERE id = ?",
+		user.Name, CanonicalName(user.Name), nullableString(user.Email), user.EmailVerified, user.Password, userStatus(user), user.StatusReason, suspendedUntil(user),
+		user.DisplayName, user.AvatarURL, user.Locale, user.Timezone, metadata, lastLoginAt(user), user.Id)
+	if err = uniqueConstraintError(err); err != nil {
+		return err
+	}
+	if err = checkRowsAffected(result); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM user_roles WHERE user_id = ?", user.Id); err != nil {
+		return err
+	}
+	if err = insertUserRoles(tx, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SqlUserRepository) UpdateLastLogin(id string, lastLoginAt time.Time) error {
+	result, err := r.db.Exec("UPDATE users SET last_login_at = ? WHERE id = ?", unixNano(lastLoginAt), id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+func (r *SqlUserRepository) UpdatePassword(id string, passwordHash string) error {
+	result, err := r.db.Exec("UPDATE users SET password = ? WHERE id = ?", passwordHash, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+func (r *SqlUserRepository) Delete(id string) error {
+	result, err := r.db.Exec("DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+func (r *SqlUserRepository) GetAll() ([]*User, error) {
+	return r.getUsers("SELECT " + userColumns + " FROM users ORDER BY rowid")
+}
+
+func (r *SqlUserRepository) GetByOrganization(organizationId string) ([]*User, error) {
+	return r.getUsers("SELECT "+userColumns+" FROM users WHERE organization_id = ? ORDER BY rowid", organizationId)
+}
+
+// Query filters, sorts and pages the users in the database, the cursor becomes a condition on the
+// sorted columns so the pages don't need an offset.
+func (r *SqlUserRepository) Query(query UserQuery) (*UserPage, error) {
+	after, err := query.prepare()
+	if err != nil {
+		return nil, err
+	}
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	if query.OrganizationId != "" {
+		conditions = append(conditions, "organization_id = ?")
+		args = append(args, query.OrganizationId)
+	}
+	if query.Search != "" {
+		conditions = append(conditions, `canonical_name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(CanonicalName(query.Search))+"%")
+	}
+	if query.Prefix != "" {
+		conditions = append(conditions, `canonical_name LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(CanonicalName(query.Prefix))+"%")
+	}
+	if query.Role != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = ?)")
+		args = append(args, query.Role)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, query.Status)
+	}
+	if query.Admin != nil {
+		condition := "EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = ?)"
+		if !*query.Admin {
+			condition = "NOT " + condition
+		}
+		conditions = append(conditions, condition)
+		args = append(args, AdminRole)
+	}
+
+	page := &UserPage{}
+	if err = r.db.QueryRow("SELECT COUNT(*) FROM users"+whereClause(conditions), args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	column, direction, operator := "name", "ASC", ">"
+	if query.SortBy == SortByCreatedAt {
+		column = "created_at"
+	}
+	if query.Descending {
+		direction, operator = "DESC", "<"
+	}
+	if after != nil {
+		var value any = after.Name
+		if column == "created_at" {
+			value = after.CreatedAt
+		}
+		conditions = append(conditions, "("+column+" "+operator+" ? OR ("+column+" = ? AND id "+operator+" ?))")
+		args = append(args, value, value, after.Id)
+	}
+	limit := query.limit()
+	statement := "SELECT " + userColumns + " FROM users" + whereClause(conditions) + " ORDER BY " + column + " " + direction + ", id " + direction + " LIMIT ?"
+	users, err := r.getUsers(statement, append(args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	page.Users = users
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = query.encodeCursor(page.Users[limit-1])
+	}
+	return page, nil
+}
+
+func (r *SqlUserRepository) getUsers(query string, args ...any) ([]*User, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]*User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = r.loadRoles(users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *SqlUserRepository) getUser(query string, args ...any) (*User, error) {
+	user, err := scanUser(r.db.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = r.loadRoles([]*User{user}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// loadRolesBatchSize is the most user ids read at once by loadRoles, below the SQLite variable limit.
+const loadRolesBatchSize = 500
+
+// loadRoles reads the roles of the users, only for their ids.
+func (r *SqlUserRepository) loadRoles(users []*User) error {
+	byId := make(map[string]*User, len(users))
+	for _, user := range users {
+		user.Roles = []string{}
+		byId[user.Id] = user
+	}
+	for start := 0; start < len(users); start += loadRolesBatchSize {
+		end := start + loadRolesBatchSize
+		if end > len(users) {
+			end = len(users)
+		}
+		if err := r.loadRolesBatch(users[start:end], byId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SqlUserRepository) loadRolesBatch(users []*User, byId map[string]*User) error {
+	placeholders := make([]string, len(users))
+	args := make([]any, len(users))
+	for i, user := range users {
+		placeholders[i] = "?"
+		args[i] = user.Id
+	}
+	rows, err := r.db.Query("SELECT user_id, role FROM user_roles WHERE user_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY role", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId, role string
+		if err := rows.Scan(&userId, &role); err != nil {
+			return err
+		}
+		if user, ok := byId[userId]; ok {
+			user.Roles = append(user.Roles, role)
+		}
+	}
+	return rows.Err()
+}
+
+func insertUserRoles(tx *sql.Tx, user *User) error {
+	for _, role := range user.Roles {
+		if _, err := tx.Exec("INSERT INTO user_roles (user_id, role) VALUES (?, ?)", user.Id, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	user := &User{}
+	var email sql.NullString
+	var createdAt, suspendedUntil, lastLoginAt int64
+	var metadata string
+	if err := row.Scan(&user.Id, &user.OrganizationId, &user.Name, &email, &user.EmailVerified, &user.Password, &createdAt,
+		&user.Status, &user.StatusReason, &suspendedUntil, &user.DisplayName, &user.AvatarURL, &user.Locale, &user.Timezone, &metadata, &lastLoginAt); err != nil {
+		return nil, err
+	}
+	if metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &user.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	user.Email = email.String
+	user.CreatedAt = timeFromUnixNano(createdAt)
+	if suspendedUntil != 0 {
+		until := time.Unix(0, suspendedUntil)
+		user.SuspendedUntil = &until
+	}
+	if lastLoginAt != 0 {
+		last := time.Unix(0, lastLoginAt)
+		user.LastLoginAt = &last
+	}
+	return user, nil
+}
+
+func userStatus(user *User) string {
+	if user.Status == "" {
+		return UserStatusEnabled
+	}
+	return user.Status
+}
+
+func suspendedUntil(user *User) int64 {
+	if user.SuspendedUntil == nil {
+		return 0
+	}
+	return unixNano(*user.SuspendedUntil)
+}
+
+func lastLoginAt(user *User) int64 {
+	if user.LastLoginAt == nil {
+		return 0
+	}
+	return unixNano(*user.LastLoginAt)
+}
+
+// encodeMetadata stores the empty metadata as an empty string.
+func encodeMetadata(metadata map[string]any) (string, error) {
+	if len(metadata) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(metadata)
+	return string(data), err
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, with \ as the escape character.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func checkRowsAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// nullableString stores empty strings as NULL so they don't collide in unique columns.
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// uniqueConstraintError translates the unique constraint violations to the error of the column
+// already registered.
+func uniqueConstraintError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+	if strings.Contains(sqliteErr.Error(), "users.email") {
+		return ErrEmailAlreadyRegistered
+	}
+	return ErrUserAlreadyRegistered
+}
@@ -0,0 +1,20 @@
+package user
+
+import "time"
+
+type UserStore interface {
+	Add(user *User) error
+	GetById(id string) (*User, error)
+	// GetByName returns the user whose name has the same canonical form, see CanonicalName.
+	GetByName(organizationId string, name string) (*User, error)
+	GetByEmail(email string) (*User, error)
+	Update(user *User) error
+	// UpdateLastLogin only sets the last login of the user, leaving the changes made since it was read.
+	UpdateLastLogin(id string, lastLoginAt time.Time) error
+	// UpdatePassword only sets the password hash of the user, leaving the changes made since it was read.
+	UpdatePassword(id string, passwordHash string) error
+	Delete(id string) error
+	GetAll() ([]*User, error)
+	GetByOrganization(organizationId string) ([]*User, error)
+	Query(query UserQuery) (*UserPage, error)
+}
@@ -1,8 +1,80 @@
 package user
 
+import "time"
+
+const (
+	UserStatusEnabled   = "enabled"
+	UserStatusDisabled  = "disabled"
+	UserStatusSuspended = "suspended"
+	UserStatusPending   = "pending"
+)
+
 type User struct {
-	Id       string `json:"id"`
-	Name     string `json:"name"`
-	IsAdmin  bool   `json:"isAdmin"`
-	Password string `json:"-"`
+	Id             string    `json:"id"`
+	OrganizationId string    `json:"organizationId"`
+	Name           string    `json:"name"`
+	Email          string    `json:"email,omitempty"`
+	EmailVerified  bool      `json:"emailVerified"`
+	Roles          []string  `json:"roles"`
+	Password       string    `json:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// Status is UserStatusEnabled, UserStatusDisabled until enabled again, UserStatusSuspended until
+	// SuspendedUntil, or UserStatusPending until an administrator approves the registration.
+	// StatusReason records why the user was disabled or suspended.
+	Status         string     `json:"status"`
+	StatusReason   string     `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+	// The profile is edited by the user, Metadata follows the metadata schema when one is configured.
+	DisplayName string         `json:"displayName,omitempty"`
+	AvatarURL   string         `json:"avatarUrl,omitempty"`
+	Locale      string         `json:"locale,omitempty"`
+	Timezone    string         `json:"timezone,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	// LastLoginAt is the time of the last successful login.
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+type UserStatusUpdate struct {
+	Status string
+	// SuspendedUntil is required by UserStatusSuspended and must be in the future.
+	SuspendedUntil time.Time
+	Reason         string
+}
+
+type NewUser struct {
+	// OrganizationId is DefaultOrganizationId when empty.
+	OrganizationId string
+	Name           string
+	Password       string
+	Email          string
+	Roles          []string
+	// Status is UserStatusEnabled when empty, or UserStatusPending for the registrations waiting for an
+	// approval.
+	Status string
+}
+
+type NewInvitation struct {
+	// OrganizationId is DefaultOrganizationId when empty.
+	OrganizationId string
+	Email          string
+	Roles          []string
+	CreatedBy      string
+	// ExpiresAt is the end of the invitation duration when zero.
+	ExpiresAt time.Time
+}
+
+// ProfileUpdate changes the non-nil fields of the profile, the empty strings remove them and Metadata
+// replaces the whole map.
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+	Locale      *string
+	Timezone    *string
+	Metadata    *map[string]any
+}
+
+type UserUpdate struct {
+	Name  *string
+	Email *string
+	Roles *[]string
 }
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrWebAuthnChallengeNotFound = errors.New("webauthn challenge repository: challenge not found")
+
+// WebAuthnChallengeStore keeps the challenges of the WebAuthn ceremonies until they are answered, so
+// every challenge is used once.
+type WebAuthnChallengeStore interface {
+	// AddWebAuthnChallenge stores the challenge until expiresAt and removes the expired ones.
+	AddWebAuthnChallenge(challenge string, expiresAt time.Time) error
+	// UseWebAuthnChallenge removes the challenge, it returns ErrWebAuthnChallengeNotFound when the
+	// challenge was already used or expired before now.
+	UseWebAuthnChallenge(challenge string, now time.Time) error
+}
+
+type WebAuthnChallengeRepository struct {
+	mutex      sync.Mutex
+	challenges map[string]time.Time
+}
+
+func NewWebAuthnChallengeRepository() *WebAuthnChallengeRepository {
+	return &WebAuthnChallengeRepository{challenges: make(map[string]time.Time)}
+}
+
+func (r *WebAuthnChallengeRepository) AddWebAuthnChallenge(challenge string, expiresAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	for stored, storedExpiresAt := range r.challenges {
+		if !storedExpiresAt.After(now) {
+			delete(r.challenges, stored)
+		}
+	}
+	r.challenges[challenge] = expiresAt
+	return nil
+}
+
+func (r *WebAuthnChallengeRepository) UseWebAuthnChallenge(challenge string, now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	expiresAt, ok := r.challenges[challenge]
+	if !ok {
+		return ErrWebAuthnChallengeNotFound
+	}
+	delete(r.challenges, challenge)
+	if !expiresAt.After(now) {
+		return ErrWebAuthnChallengeNotFound
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+// forEachWebAuthnChallengeStore runs the test against every WebAuthnChallengeStore implementation.
+func forEachWebAuthnChallengeStore(t *testing.T, test func(t *testing.T, store WebAuthnChallengeStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewWebAuthnChallengeRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, NewSqlWebAuthnChallengeRepository(createTestSqlUserRepository(t).db))
+	})
+}
+
+func TestWebAuthnChallengeStore(t *testing.T) {
+	forEachWebAuthnChallengeStore(t, func(t *testing.T, store WebAuthnChallengeStore) {
+		now := time.Now()
+		if err := store.AddWebAuthnChallenge("challenge1", now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddWebAuthnChallenge("challenge2", now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.UseWebAuthnChallenge("challenge1", now); err != nil {
+			t.Errorf("expected err to be nil, got %v", err)
+		}
+		if err := store.UseWebAuthnChallenge("challenge1", now); err != ErrWebAuthnChallengeNotFound {
+			t.Errorf("expected a used challenge to be ErrWebAuthnChallengeNotFound, got %v", err)
+		}
+		if err := store.UseWebAuthnChallenge("challenge2", now.Add(2*time.Minute)); err != ErrWebAuthnChallengeNotFound {
+			t.Errorf("expected an expired challenge to be ErrWebAuthnChallengeNotFound, got %v", err)
+		}
+		if err := store.UseWebAuthnChallenge("unknown", now); err != ErrWebAuthnChallengeNotFound {
+			t.Errorf("expected err to be ErrWebAuthnChallengeNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SqlWebAuthnChallengeRepository struct {
+	db *sql.DB
+}
+
+// NewSqlWebAuthnChallengeRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlWebAuthnChallengeRepository(db *sql.DB) *SqlWebAuthnChallengeRepository {
+	return &SqlWebAuthnChallengeRepository{db: db}
+}
+
+func (r *SqlWebAuthnChallengeRepository) AddWebAuthnChallenge(challenge string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM webauthn_challenges WHERE expires_at <= ?", time.Now().UnixNano()); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("INSERT INTO webauthn_challenges (challenge, expires_at) VALUES (?, ?)", challenge, expiresAt.UnixNano()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SqlWebAuthnChallengeRepository) UseWebAuthnChallenge(challenge string, now time.Time) error {
+	result, err := r.db.Exec("DELETE FROM webauthn_challenges WHERE challenge = ? AND expires_at > ?", challenge, now.UnixNano())
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return ErrWebAuthnChallengeNotFound
+	}
+	return nil
+}
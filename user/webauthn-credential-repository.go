@@ -0,0 +1,100 @@
+package user
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWebAuthnCredentialNotFound      = errors.New("webauthn credential repository: credential not found")
+	ErrWebAuthnCredentialAlreadyExists = errors.New("webauthn credential repository: credential already registered")
+)
+
+// WebAuthnCredential is a passkey or security key of a user, Id is the base64url credential id given
+// by the authenticator and PublicKey its COSE_Key.
+type WebAuthnCredential struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"userId"`
+	Name      string    `json:"name"`
+	PublicKey []byte    `json:"-"`
+	SignCount uint32    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WebAuthnCredentialStore interface {
+	AddWebAuthnCredential(credential *WebAuthnCredential) error
+	GetWebAuthnCredential(id string) (*WebAuthnCredential, error)
+	// GetUserWebAuthnCredentials returns the credentials of the user by creation date.
+	GetUserWebAuthnCredentials(userId string) ([]*WebAuthnCredential, error)
+	UpdateWebAuthnSignCount(id string, signCount uint32) error
+	DeleteWebAuthnCredential(id string) error
+}
+
+type WebAuthnCredentialRepository struct {
+	mutex       sync.Mutex
+	credentials map[string]WebAuthnCredential
+}
+
+func NewWebAuthnCredentialRepository() *WebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{credentials: make(map[string]WebAuthnCredential)}
+}
+
+func (r *WebAuthnCredentialRepository) AddWebAuthnCredential(credential *WebAuthnCredential) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.credentials[credential.Id]; ok {
+		return ErrWebAuthnCredentialAlreadyExists
+	}
+	r.credentials[credential.Id] = *credential
+	return nil
+}
+
+func (r *WebAuthnCredentialRepository) GetWebAuthnCredential(id string) (*WebAuthnCredential, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	credential, ok := r.credentials[id]
+	if !ok {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	return &credential, nil
+}
+
+func (r *WebAuthnCredentialRepository) GetUserWebAuthnCredentials(userId string) ([]*WebAuthnCredential, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	credentials := make([]*WebAuthnCredential, 0)
+	for _, credential := range r.credentials {
+		if credential.UserId == userId {
+			credential := credential
+			credentials = append(credentials, &credential)
+		}
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].CreatedAt.Before(credentials[j].CreatedAt)
+	})
+	return credentials, nil
+}
+
+func (r *WebAuthnCredentialRepository) UpdateWebAuthnSignCount(id string, signCount uint32) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	credential, ok := r.credentials[id]
+	if !ok {
+		return ErrWebAuthnCredentialNotFound
+	}
+	credential.SignCount = signCount
+	r.credentials[id] = credential
+	return nil
+}
+
+func (r *WebAuthnCredentialRepository) DeleteWebAuthnCredential(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.credentials[id]; !ok {
+		return ErrWebAuthnCredentialNotFound
+	}
+	delete(r.credentials, id)
+	return nil
+}
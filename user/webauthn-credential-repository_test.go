@@ -0,0 +1,73 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+// forEachWebAuthnCredentialStore runs the test against every WebAuthnCredentialStore implementation,
+// the users 1, 2 and 3 exist in the store.
+func forEachWebAuthnCredentialStore(t *testing.T, test func(t *testing.T, store WebAuthnCredentialStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewWebAuthnCredentialRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		r := createTestSqlUserRepository(t)
+		if _, err := createTestUserRepository(r); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSqlWebAuthnCredentialRepository(r.db))
+	})
+}
+
+func TestWebAuthnCredentialStore(t *testing.T) {
+	forEachWebAuthnCredentialStore(t, func(t *testing.T, store WebAuthnCredentialStore) {
+		createdAt := time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)
+		credentials := []*WebAuthnCredential{
+			{Id: "cred2", UserId: "1", Name: "phone", PublicKey: []byte{1, 2}, CreatedAt: createdAt.Add(time.Hour)},
+			{Id: "cred1", UserId: "1", Name: "key", PublicKey: []byte{3, 4}, SignCount: 5, CreatedAt: createdAt},
+			{Id: "cred3", UserId: "2", Name: "laptop", PublicKey: []byte{5, 6}, CreatedAt: createdAt},
+		}
+		for _, credential := range credentials {
+			if err := store.AddWebAuthnCredential(credential); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.AddWebAuthnCredential(&WebAuthnCredential{Id: "cred1", UserId: "2", PublicKey: []byte{1}, CreatedAt: createdAt}); err != ErrWebAuthnCredentialAlreadyExists {
+			t.Errorf("expected err to be ErrWebAuthnCredentialAlreadyExists, got %v", err)
+		}
+
+		credential, err := store.GetWebAuthnCredential("cred1")
+		if err != nil || credential.UserId != "1" || credential.SignCount != 5 || string(credential.PublicKey) != string([]byte{3, 4}) || !credential.CreatedAt.Equal(createdAt) {
+			t.Errorf("unexpected credential %+v %v", credential, err)
+		}
+		if _, err = store.GetWebAuthnCredential("nope"); err != ErrWebAuthnCredentialNotFound {
+			t.Errorf("expected err to be ErrWebAuthnCredentialNotFound, got %v", err)
+		}
+
+		userCredentials, err := store.GetUserWebAuthnCredentials("1")
+		if err != nil || len(userCredentials) != 2 || userCredentials[0].Id != "cred1" || userCredentials[1].Id != "cred2" {
+			t.Errorf("expected the credentials of the user by creation date, got %v %v", userCredentials, err)
+		}
+
+		if err = store.UpdateWebAuthnSignCount("cred1", 6); err != nil {
+			t.Fatal(err)
+		}
+		if credential, _ = store.GetWebAuthnCredential("cred1"); credential.SignCount != 6 {
+			t.Errorf("expected the sign count to be 6, got %d", credential.SignCount)
+		}
+		if err = store.UpdateWebAuthnSignCount("nope", 1); err != ErrWebAuthnCredentialNotFound {
+			t.Errorf("expected err to be ErrWebAuthnCredentialNotFound, got %v", err)
+		}
+
+		if err = store.DeleteWebAuthnCredential("cred1"); err != nil {
+			t.Fatal(err)
+		}
+		if err = store.DeleteWebAuthnCredential("cred1"); err != ErrWebAuthnCredentialNotFound {
+			t.Errorf("expected err to be ErrWebAuthnCredentialNotFound, got %v", err)
+		}
+		if userCredentials, _ = store.GetUserWebAuthnCredentials("1"); len(userCredentials) != 1 {
+			t.Errorf("expected a credential left, got %v", userCredentials)
+		}
+	})
+}
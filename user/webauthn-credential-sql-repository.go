@@ -0,0 +1,94 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SqlWebAuthnCredentialRepository struct {
+	db *sql.DB
+}
+
+// NewSqlWebAuthnCredentialRepository expects the database to be migrated, see NewSqlUserRepository.
+func NewSqlWebAuthnCredentialRepository(db *sql.DB) *SqlWebAuthnCredentialRepository {
+	return &SqlWebAuthnCredentialRepository{db: db}
+}
+
+const webAuthnCredentialColumns = "id, user_id, name, public_key, sign_count, created_at"
+
+func (r *SqlWebAuthnCredentialRepository) AddWebAuthnCredential(credential *WebAuthnCredential) error {
+	_, err := r.db.Exec("INSERT INTO webauthn_credentials ("+webAuthnCredentialColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		credential.Id, credential.UserId, credential.Name, credential.PublicKey, credential.SignCount, credential.CreatedAt.UnixNano())
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return ErrWebAuthnCredentialAlreadyExists
+	}
+	return err
+}
+
+func (r *SqlWebAuthnCredentialRepository) GetWebAuthnCredential(id string) (*WebAuthnCredential, error) {
+	credential, err := scanWebAuthnCredential(r.db.QueryRow("SELECT "+webAuthnCredentialColumns+" FROM webauthn_credentials WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	return credential, err
+}
+
+func (r *SqlWebAuthnCredentialRepository) GetUserWebAuthnCredentials(userId string) ([]*WebAuthnCredential, error) {
+	rows, err := r.db.Query("SELECT "+webAuthnCredentialColumns+" FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	credentials := make([]*WebAuthnCredential, 0)
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+func (r *SqlWebAuthnCredentialRepository) UpdateWebAuthnSignCount(id string, signCount uint32) error {
+	result, err := r.db.Exec("UPDATE webauthn_credentials SET sign_count = ? WHERE id = ?", signCount, id)
+	if err != nil {
+		return err
+	}
+	return checkWebAuthnCredentialRowsAffected(result)
+}
+
+func (r *SqlWebAuthnCredentialRepository) DeleteWebAuthnCredential(id string) error {
+	result, err := r.db.Exec("DELETE FROM webauthn_credentials WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return checkWebAuthnCredentialRowsAffected(result)
+}
+
+func scanWebAuthnCredential(row rowScanner) (*WebAuthnCredential, error) {
+	credential := &WebAuthnCredential{}
+	var createdAt int64
+	err := row.Scan(&credential.Id, &credential.UserId, &credential.Name, &credential.PublicKey, &credential.SignCount, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	credential.CreatedAt = time.Unix(0, createdAt)
+	return credential, nil
+}
+
+func checkWebAuthnCredentialRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package validator
 
 import (
 	"authGo/token"
+	"authGo/user"
 	"errors"
 	"fmt"
+	"time"
 )
 
 type AccessTokenValidator struct {
@@ -30,3 +32,13 @@ func (v *AccessTokenValidator) ValidateAccessToken() (*token.AccessTokenPayload,
 	}
 	return payload, nil
 }
+
+// NewAccessTokenPayload returns the payload of an access token for the user, with their organization
+// and the permissions of their roles at the given time.
+func NewAccessTokenPayload(services *Services, u *user.User, issuedAtTime time.Time) (*token.AccessTokenPayload, error) {
+	permissions, err := services.UserService.GetPermissions(u)
+	if err != nil {
+		return nil, err
+	}
+	return &token.AccessTokenPayload{UserId: u.Id, OrganizationId: u.OrganizationId, IssuedAtTime: issuedAtTime, Roles: u.Roles, Permissions: permissions}, nil
+}
@@ -16,7 +16,7 @@ func TestValidateAccessToken(t *testing.T) {
 	}
 
 	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
-	payload := &token.AccessTokenPayload{UserId: "1", IssuedAtTime: time.Now(), IsAdmin: true}
+	payload := &token.AccessTokenPayload{UserId: "1", IssuedAtTime: time.Now(), Roles: []string{"admin"}, Permissions: []string{"users:read"}}
 	accessToken, err := accessTokenGenerator.CreateToken(payload)
 	if err != nil {
 		t.Fatal(err)
@@ -31,7 +31,7 @@ func TestValidateAccessToken(t *testing.T) {
 	if err != nil {
 		t.Errorf("expected err to be nil, got %s", err)
 	}
-	if requestPayload.UserId != payload.UserId || requestPayload.IsAdmin != payload.IsAdmin || !requestPayload.IssuedAtTime.Equal(payload.IssuedAtTime) {
+	if requestPayload.UserId != payload.UserId || !requestPayload.HasPermission("users:read") || !requestPayload.IssuedAtTime.Equal(payload.IssuedAtTime) {
 		t.Errorf("expected %v to be %v", requestPayload, payload)
 	}
 }
@@ -44,7 +44,7 @@ func TestErrorsValidateAccessToken(t *testing.T) {
 
 	accessTokenGenerator1 := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey1"), Duration: time.Minute * 2}
 	accessTokenGenerator2 := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey2"), Duration: time.Minute * 2}
-	payload := &token.AccessTokenPayload{UserId: "1", IssuedAtTime: time.Now(), IsAdmin: true}
+	payload := &token.AccessTokenPayload{UserId: "1", IssuedAtTime: time.Now(), Roles: []string{"admin"}, Permissions: []string{"users:read"}}
 
 	v := AccessTokenValidator{Validator: Validator{Request: req, Services: &Services{AccessTokenGenerator: accessTokenGenerator1}}}
 
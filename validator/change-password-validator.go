@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+type ChangePasswordInput struct {
+	CurrentPassword     string `json:"currentPassword"`
+	NewPassword         string `json:"newPassword"`
+	RevokeOtherSessions *bool  `json:"revokeOtherSessions"`
+}
+
+type ChangePasswordValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrChangePasswordInvalidContentType = errors.New("change password validator: invalid content-type")
+	ErrChangePasswordInvalidBody        = errors.New("change password validator: invalid body")
+	ErrChangePasswordEmptyPassword      = errors.New("change password validator: empty current or new password")
+	ErrChangePasswordSamePassword       = errors.New("change password validator: new password is the same as the current one")
+)
+
+// GetChangePassword reads the password change, the other sessions are revoked unless the input
+// explicitly disables it.
+func (v *ChangePasswordValidator) GetChangePassword() (*ChangePasswordInput, error) {
+	contentType := v.Validator.Request.Header.Get("Content-type")
+	if contentType != "application/json" {
+		return nil, ErrChangePasswordInvalidContentType
+	}
+
+	body, err := ioutil.ReadAll(v.Validator.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var changePassword ChangePasswordInput
+	if err = json.Unmarshal(body, &changePassword); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrChangePasswordInvalidBody, err)
+	}
+	if changePassword.CurrentPassword == "" || changePassword.NewPassword == "" {
+		return nil, ErrChangePasswordEmptyPassword
+	}
+	if changePassword.CurrentPassword == changePassword.NewPassword {
+		return nil, ErrChangePasswordSamePassword
+	}
+	if changePassword.RevokeOtherSessions == nil {
+		revoke := true
+		changePassword.RevokeOtherSessions = &revoke
+	}
+	return &changePassword, nil
+}
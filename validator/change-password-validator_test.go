@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetChangePassword(t *testing.T) {
+	changeTests := []struct {
+		body   string
+		revoke bool
+	}{
+		{`{"currentPassword": "user1", "newPassword": "user2"}`, true},
+		{`{"currentPassword": "user1", "newPassword": "user2", "revokeOtherSessions": false}`, false},
+	}
+	for _, changeTest := range changeTests {
+		req, err := http.NewRequest("POST", "/users/me/password", strings.NewReader(changeTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Content-Type", "application/json")
+		v := ChangePasswordValidator{Validator: Validator{Request: req}}
+		changePassword, err := v.GetChangePassword()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %s", err)
+		}
+		if changePassword.CurrentPassword != "user1" || changePassword.NewPassword != "user2" {
+			t.Errorf("unexpected passwords, got %+v", changePassword)
+		}
+		if *changePassword.RevokeOtherSessions != changeTest.revoke {
+			t.Errorf("%s: expected revokeOtherSessions to be %t", changeTest.body, changeTest.revoke)
+		}
+	}
+}
+
+func TestErrorsGetChangePassword(t *testing.T) {
+	changeTests := []struct {
+		body        string
+		contentType string
+		err         error
+	}{
+		{`{"currentPassword": "user1", "newPassword": "user2"}`, "text/plain", ErrChangePasswordInvalidContentType},
+		{`123456`, "application/json", ErrChangePasswordInvalidBody},
+		{`{"currentPassword": "user1"}`, "application/json", ErrChangePasswordEmptyPassword},
+		{`{"newPassword": "user1"}`, "application/json", ErrChangePasswordEmptyPassword},
+		{`{"currentPassword": "user1", "newPassword": "user1"}`, "application/json", ErrChangePasswordSamePassword},
+	}
+	for _, changeTest := range changeTests {
+		req, err := http.NewRequest("POST", "/users/me/password", strings.NewReader(changeTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", changeTest.contentType)
+		v := ChangePasswordValidator{Validator: Validator{Request: req}}
+		_, err = v.GetChangePassword()
+		if !errors.Is(err, changeTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", changeTest.body, changeTest.err, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package validator
+
+import (
+	"authGo/token"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type EmailVerificationRequestInput struct {
+	Name string `json:"name"`
+}
+
+type EmailVerificationCompletionInput struct {
+	Token string `json:"token"`
+}
+
+type EmailVerificationValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrEmailVerificationEmptyName  = errors.New("email verification validator: empty name")
+	ErrEmailVerificationEmptyToken = errors.New("email verification validator: empty token")
+	ErrEmailVerificationInvalid    = errors.New("email verification validator: token not valid")
+)
+
+func (v *EmailVerificationValidator) GetVerificationRequest() (*EmailVerificationRequestInput, error) {
+	var verificationRequest EmailVerificationRequestInput
+	if err := v.Validator.DecodeJSONBody(&verificationRequest); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(verificationRequest.Name) == "" {
+		return nil, ErrEmailVerificationEmptyName
+	}
+	return &verificationRequest, nil
+}
+
+// GetVerification reads the verification token of the body and returns its payload once the token
+// signature and expiration are checked.
+func (v *EmailVerificationValidator) GetVerification() (*token.EmailVerificationPayload, error) {
+	var verificationCompletion EmailVerificationCompletionInput
+	if err := v.Validator.DecodeJSONBody(&verificationCompletion); err != nil {
+		return nil, err
+	}
+	if verificationCompletion.Token == "" {
+		return nil, ErrEmailVerificationEmptyToken
+	}
+	tokenGenerator := v.Validator.Services.EmailVerificationTokenGenerator
+	if err := tokenGenerator.IsTokenValid(verificationCompletion.Token); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrEmailVerificationInvalid, err)
+	}
+	payload := &token.EmailVerificationPayload{}
+	if err := tokenGenerator.LoadPayload(verificationCompletion.Token, payload); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrEmailVerificationInvalid, err)
+	}
+	return payload, nil
+}
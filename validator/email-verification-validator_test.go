@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"authGo/token"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createEmailVerificationValidator(t *testing.T, body string) *EmailVerificationValidator {
+	req, err := http.NewRequest("POST", "/auth/email-verification/complete", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	services := &Services{
+		EmailVerificationTokenGenerator: &token.TokenGenerator[token.EmailVerificationPayload]{Password: []byte("emailKey"), Duration: time.Hour},
+	}
+	return &EmailVerificationValidator{Validator: Validator{Request: req, Services: services}}
+}
+
+func TestGetVerification(t *testing.T) {
+	tokenGenerator := &token.TokenGenerator[token.EmailVerificationPayload]{Password: []byte("emailKey")}
+	otherGenerator := &token.TokenGenerator[token.EmailVerificationPayload]{Password: []byte("otherKey")}
+	validToken, _ := tokenGenerator.CreateToken(&token.EmailVerificationPayload{UserId: "1", Email: "user1@example.com", IssuedAtTime: time.Now()})
+	expiredToken, _ := tokenGenerator.CreateToken(&token.EmailVerificationPayload{UserId: "1", Email: "user1@example.com", IssuedAtTime: time.Now().Add(-time.Hour * 2)})
+	otherToken, _ := otherGenerator.CreateToken(&token.EmailVerificationPayload{UserId: "1", Email: "user1@example.com", IssuedAtTime: time.Now()})
+
+	verificationTests := []struct {
+		name string
+		body string
+		err  error
+	}{
+		{"valid", fmt.Sprintf(`{"token": "%s"}`, validToken), nil},
+		{"expired", fmt.Sprintf(`{"token": "%s"}`, expiredToken), ErrEmailVerificationInvalid},
+		{"other key", fmt.Sprintf(`{"token": "%s"}`, otherToken), ErrEmailVerificationInvalid},
+		{"malformed", `{"token": "123.123"}`, ErrEmailVerificationInvalid},
+		{"empty", `{"token": ""}`, ErrEmailVerificationEmptyToken},
+		{"unknown field", `{"token": "abc", "email": "a"}`, ErrInvalidBody},
+	}
+	for _, verificationTest := range verificationTests {
+		v := createEmailVerificationValidator(t, verificationTest.body)
+		payload, err := v.GetVerification()
+		if !errors.Is(err, verificationTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", verificationTest.name, verificationTest.err, err)
+		}
+		if err == nil && (payload.UserId != "1" || payload.Email != "user1@example.com") {
+			t.Errorf("%s: unexpected payload, got %+v", verificationTest.name, payload)
+		}
+	}
+}
+
+func TestGetVerificationRequest(t *testing.T) {
+	v := createEmailVerificationValidator(t, `{"name": "user1"}`)
+	verificationRequest, err := v.GetVerificationRequest()
+	if err != nil || verificationRequest.Name != "user1" {
+		t.Errorf("unexpected verification request, got %v %v", verificationRequest, err)
+	}
+	v = createEmailVerificationValidator(t, `{"name": " "}`)
+	if _, err = v.GetVerificationRequest(); err != ErrEmailVerificationEmptyName {
+		t.Errorf("expected err to be ErrEmailVerificationEmptyName, got %v", err)
+	}
+}
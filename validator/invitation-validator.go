@@ -0,0 +1,64 @@
+package validator
+
+import (
+	"authGo/token"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type InvitationInput struct {
+	OrganizationId string     `json:"organizationId"`
+	Email          string     `json:"email"`
+	Roles          []string   `json:"roles"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+}
+
+type InvitationAcceptanceInput struct {
+	Token    string `json:"token"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type InvitationValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrInvitationEmptyToken        = errors.New("invitation validator: empty token")
+	ErrInvitationEmptyNamePassword = errors.New("invitation validator: empty name or password")
+	ErrInvitationInvalid           = errors.New("invitation validator: token not valid")
+)
+
+func (v *InvitationValidator) GetNewInvitation() (*InvitationInput, error) {
+	var invitation InvitationInput
+	if err := v.Validator.DecodeJSONBody(&invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetAcceptance reads the acceptance of an invitation and returns the payload of its token once the
+// signature and expiration are checked.
+func (v *InvitationValidator) GetAcceptance() (*InvitationAcceptanceInput, *token.InvitationPayload, error) {
+	var acceptance InvitationAcceptanceInput
+	if err := v.Validator.DecodeJSONBody(&acceptance); err != nil {
+		return nil, nil, err
+	}
+	if acceptance.Token == "" {
+		return nil, nil, ErrInvitationEmptyToken
+	}
+	if strings.TrimSpace(acceptance.Name) == "" || acceptance.Password == "" {
+		return nil, nil, ErrInvitationEmptyNamePassword
+	}
+	tokenGenerator := v.Validator.Services.InvitationTokenGenerator
+	if err := tokenGenerator.IsTokenValid(acceptance.Token); err != nil {
+		return nil, nil, fmt.Errorf("%w, %s", ErrInvitationInvalid, err)
+	}
+	payload := &token.InvitationPayload{}
+	if err := tokenGenerator.LoadPayload(acceptance.Token, payload); err != nil {
+		return nil, nil, fmt.Errorf("%w, %s", ErrInvitationInvalid, err)
+	}
+	return &acceptance, payload, nil
+}
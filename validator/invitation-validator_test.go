@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"authGo/token"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createInvitationValidator(t *testing.T, body string) *InvitationValidator {
+	req, err := http.NewRequest("POST", "/auth/invitations/accept", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	services := &Services{
+		InvitationTokenGenerator: &token.TokenGenerator[token.InvitationPayload]{Password: []byte("invitationKey"), Duration: time.Hour},
+	}
+	return &InvitationValidator{Validator: Validator{Request: req, Services: services}}
+}
+
+func TestGetAcceptance(t *testing.T) {
+	tokenGenerator := &token.TokenGenerator[token.InvitationPayload]{Password: []byte("invitationKey")}
+	otherGenerator := &token.TokenGenerator[token.InvitationPayload]{Password: []byte("otherKey")}
+	validToken, _ := tokenGenerator.CreateToken(&token.InvitationPayload{InvitationId: "1", IssuedAtTime: time.Now()})
+	expiredToken, _ := tokenGenerator.CreateToken(&token.InvitationPayload{InvitationId: "1", IssuedAtTime: time.Now().Add(-time.Hour * 2)})
+	otherToken, _ := otherGenerator.CreateToken(&token.InvitationPayload{InvitationId: "1", IssuedAtTime: time.Now()})
+
+	acceptanceTests := []struct {
+		name string
+		body string
+		err  error
+	}{
+		{"valid", fmt.Sprintf(`{"token": "%s", "name": "user1", "password": "user1"}`, validToken), nil},
+		{"expired", fmt.Sprintf(`{"token": "%s", "name": "user1", "password": "user1"}`, expiredToken), ErrInvitationInvalid},
+		{"other key", fmt.Sprintf(`{"token": "%s", "name": "user1", "password": "user1"}`, otherToken), ErrInvitationInvalid},
+		{"malformed", `{"token": "123.123", "name": "user1", "password": "user1"}`, ErrInvitationInvalid},
+		{"empty token", `{"name": "user1", "password": "user1"}`, ErrInvitationEmptyToken},
+		{"empty name", fmt.Sprintf(`{"token": "%s", "name": " ", "password": "user1"}`, validToken), ErrInvitationEmptyNamePassword},
+		{"empty password", fmt.Sprintf(`{"token": "%s", "name": "user1"}`, validToken), ErrInvitationEmptyNamePassword},
+		{"unknown field", `{"token": "abc", "roles": ["admin"]}`, ErrInvalidBody},
+	}
+	for _, acceptanceTest := range acceptanceTests {
+		v := createInvitationValidator(t, acceptanceTest.body)
+		acceptance, payload, err := v.GetAcceptance()
+		if !errors.Is(err, acceptanceTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", acceptanceTest.name, acceptanceTest.err, err)
+		}
+		if err == nil && (payload.InvitationId != "1" || acceptance.Name != "user1" || acceptance.Password != "user1") {
+			t.Errorf("%s: unexpected acceptance, got %+v %+v", acceptanceTest.name, acceptance, payload)
+		}
+	}
+}
+
+func TestGetNewInvitation(t *testing.T) {
+	v := createInvitationValidator(t, `{"email": "user1@example.com", "roles": ["admin"], "expiresAt": "2030-01-01T00:00:00Z"}`)
+	invitation, err := v.GetNewInvitation()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if invitation.Email != "user1@example.com" || len(invitation.Roles) != 1 || invitation.ExpiresAt == nil || invitation.ExpiresAt.Year() != 2030 {
+		t.Errorf("unexpected invitation, got %+v", invitation)
+	}
+	v = createInvitationValidator(t, `{"password": "user1"}`)
+	if _, err = v.GetNewInvitation(); !errors.Is(err, ErrInvalidBody) {
+		t.Errorf("expected err to be ErrInvalidBody, got %v", err)
+	}
+}
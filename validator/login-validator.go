@@ -5,6 +5,7 @@ import (
 	"authGo/token"
 	"authGo/user"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -13,8 +14,9 @@ type LoginValidator struct {
 }
 
 type LoginDetails struct {
-	Name     string
-	Password string
+	OrganizationId string
+	Name           string
+	Password       string
 }
 
 type JwtTokens struct {
@@ -28,6 +30,11 @@ var (
 	ErrLoginRouterEmptyNamePassword    = errors.New("login validator: empty name or password")
 	ErrLoginRouterUserNotFound         = errors.New("login validator: user not found")
 	ErrLoginRouterPasswordNotValid     = errors.New("login validator: password not valid")
+	ErrLoginRouterEmailNotVerified     = errors.New("login validator: email not verified")
+	ErrLoginRouterUserLocked           = errors.New("login validator: user temporarily locked")
+	ErrLoginRouterUserDisabled         = errors.New("login validator: user disabled")
+	ErrLoginRouterUserSuspended        = errors.New("login validator: user suspended")
+	ErrLoginRouterUserPending          = errors.New("login validator: user pending approval")
 	ErrLoginRouterCreatingAccessToken  = errors.New("login validator: error creating accessToken")
 	ErrLoginRouterCreatingRefreshToken = errors.New("login validator: error creating accessToken")
 )
@@ -37,29 +44,59 @@ func (v *LoginValidator) GetLoginDetails() (*LoginDetails, error) {
 	if !ok || name == "" || password == "" {
 		return nil, ErrLoginRouterEmptyNamePassword
 	}
-	return &LoginDetails{Name: name, Password: password}, nil
+	return &LoginDetails{OrganizationId: v.Validator.GetOrganizationId(), Name: name, Password: password}, nil
 }
 
 func (v *LoginValidator) GetUser(loginDetails *LoginDetails) (*user.User, error) {
-	u, err := v.Validator.Services.UserService.GetRepository().GetByName(loginDetails.Name)
+	u, err := v.Validator.Services.UserService.Authenticate(loginDetails.OrganizationId, loginDetails.Name, loginDetails.Password)
 	if err == user.ErrUserNotFound {
 		return nil, ErrLoginRouterUserNotFound
 	}
-
-	if isPasswordValid := v.Validator.Services.UserService.IsPasswordValid(loginDetails.Name, loginDetails.Password); !isPasswordValid {
+	if err == user.ErrUserPasswordNotValid {
 		return nil, ErrLoginRouterPasswordNotValid
 	}
+	if err == user.ErrUserLocked {
+		return nil, ErrLoginRouterUserLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = v.Validator.CheckUserActive(u); err != nil {
+		return nil, err
+	}
+	if v.Validator.Services.UserService.IsVerifiedEmailRequired() && u.Email != "" && !u.EmailVerified {
+		return nil, ErrLoginRouterEmailNotVerified
+	}
 	return u, nil
 }
 
+// CheckUserActive returns ErrLoginRouterUserDisabled, ErrLoginRouterUserSuspended or
+// ErrLoginRouterUserPending when the status of the user doesn't let them log in or keep their session.
+func (v *Validator) CheckUserActive(u *user.User) error {
+	err := v.Services.UserService.CheckUserActive(u)
+	if err == user.ErrUserDisabled {
+		return ErrLoginRouterUserDisabled
+	}
+	if err == user.ErrUserSuspended {
+		return ErrLoginRouterUserSuspended
+	}
+	if err == user.ErrUserPending {
+		return ErrLoginRouterUserPending
+	}
+	return err
+}
+
 func (v *LoginValidator) CreateTokens(user *user.User) (*JwtTokens, error) {
 	now := time.Now()
-	accessPayload := &token.AccessTokenPayload{UserId: user.Id, IssuedAtTime: now, IsAdmin: user.IsAdmin}
+	accessPayload, err := NewAccessTokenPayload(v.Validator.Services, user, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrLoginRouterCreatingAccessToken, err)
+	}
 	accessJWT, err := v.Validator.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
 		return nil, ErrLoginRouterCreatingAccessToken
 	}
-	refreshPayload := &token.RefreshTokenPayload{UserId: user.Id, IssuedAtTime: now}
+	refreshPayload := &token.RefreshTokenPayload{UserId: user.Id, OrganizationId: user.OrganizationId, IssuedAtTime: now}
 	refreshJWT, err := v.Validator.Services.RefreshTokenGenerator.CreateToken(refreshPayload)
 	if err != nil {
 		return nil, ErrLoginRouterCreatingRefreshToken
@@ -70,3 +107,56 @@ func (v *LoginValidator) CreateTokens(user *user.User) (*JwtTokens, error) {
 func (v *LoginValidator) GetDeviceData() session.DeviceData {
 	return session.DeviceData{IpAddress: v.Validator.Request.RemoteAddr, UserAgent: v.Validator.Request.UserAgent()}
 }
+
+// GetLoginUserId returns the id of the user of the login details, or an empty string when there is no
+// such user.
+func (v *LoginValidator) GetLoginUserId(loginDetails *LoginDetails) string {
+	u, err := v.Validator.Services.UserService.GetRepository().GetByName(loginDetails.OrganizationId, loginDetails.Name)
+	if err != nil {
+		return ""
+	}
+	return u.Id
+}
+
+// RecordLoginAttempt adds the login of the request to the history of the user with the given method,
+// loginErr is the reason of a failed login.
+func (v *LoginValidator) RecordLoginAttempt(userId string, method string, loginErr error) error {
+	device := v.GetDeviceData()
+	attempt := &user.LoginAttempt{
+		UserId:    userId,
+		IpAddress: device.IpAddress,
+		UserAgent: device.UserAgent,
+		Method:    method,
+		Outcome:   user.LoginOutcomeSuccess,
+	}
+	if loginErr != nil {
+		attempt.Outcome = user.LoginOutcomeFailure
+		attempt.FailureReason = loginFailureReason(loginErr)
+	}
+	return v.Validator.Services.UserService.RecordLoginAttempt(attempt)
+}
+
+// loginFailureReason describes the error of a failed login in the login history.
+func loginFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrLoginRouterPasswordNotValid):
+		return "password not valid"
+	case errors.Is(err, ErrMFACodeNotValid):
+		return "code not valid"
+	case errors.Is(err, ErrWebAuthnCredentialNotValid):
+		return "credential not valid"
+	case errors.Is(err, ErrLoginRouterUserLocked):
+		return "account locked"
+	case errors.Is(err, ErrLoginRouterEmailNotVerified):
+		return "email not verified"
+	case errors.Is(err, ErrLoginRouterUserDisabled):
+		return "account disabled"
+	case errors.Is(err, ErrLoginRouterUserSuspended):
+		return "account suspended"
+	case errors.Is(err, ErrLoginRouterUserPending):
+		return "account pending approval"
+	case errors.Is(err, ErrMFAChallengeNotExpected):
+		return "second factor not enabled"
+	}
+	return "internal error"
+}
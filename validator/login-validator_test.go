@@ -95,10 +95,10 @@ func TestCreateTokens(t *testing.T) {
 	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
 	refreshTokenGenerator := &token.TokenGenerator[token.RefreshTokenPayload]{Password: []byte("refreshKey"), Duration: time.Hour * 24 * 365}
 	v := LoginValidator{Validator: Validator{Request: req, Services: &Services{
-		AccessTokenGenerator: accessTokenGenerator, RefreshTokenGenerator: refreshTokenGenerator}},
+		UserService: user.NewUserService(), AccessTokenGenerator: accessTokenGenerator, RefreshTokenGenerator: refreshTokenGenerator}},
 	}
 
-	jwtTokens, err := v.CreateTokens(&user.User{Id: "1", Name: "user1", Password: "user1", IsAdmin: true})
+	jwtTokens, err := v.CreateTokens(&user.User{Id: "1", Name: "user1", Password: "user1", Roles: []string{user.AdminRole}})
 	if err != nil {
 		t.Errorf("expected err to be nil, got %s", err)
 	}
@@ -0,0 +1,164 @@
+package validator
+
+import (
+	"authGo/token"
+	"authGo/user"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SecondFactorTOTP is the second factor of the users with a confirmed authenticator app.
+const SecondFactorTOTP = "totp"
+
+// SecondFactorRecoveryCode is offered while the user has unused recovery codes, in case the other
+// second factors are lost.
+const SecondFactorRecoveryCode = "recovery-code"
+
+type MFALoginInput struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+type MFAValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrMFAEmptyTokenCode       = errors.New("mfa validator: empty challenge token or code")
+	ErrMFATwoCodes             = errors.New("mfa validator: both code and recovery code given")
+	ErrMFAChallengeInvalid     = errors.New("mfa validator: challenge token not valid")
+	ErrMFACreatingChallenge    = errors.New("mfa validator: error creating challenge token")
+	ErrMFACodeNotValid         = errors.New("mfa validator: code not valid")
+	ErrMFAChallengeNotExpected = errors.New("mfa validator: user without second factor")
+)
+
+// GetSecondFactors returns the second factors the user must choose from to finish the login, none
+// when the password is enough.
+func (v *MFAValidator) GetSecondFactors(u *user.User) ([]string, error) {
+	totpEnabled, err := v.Validator.Services.UserService.IsTOTPEnabled(u.Id)
+	if err != nil {
+		return nil, err
+	}
+	webAuthnCredentials, err := v.Validator.Services.UserService.GetWebAuthnCredentials(u.Id)
+	if err != nil {
+		return nil, err
+	}
+	secondFactors := make([]string, 0)
+	if totpEnabled {
+		secondFactors = append(secondFactors, SecondFactorTOTP)
+	}
+	if len(webAuthnCredentials) > 0 {
+		secondFactors = append(secondFactors, SecondFactorWebAuthn)
+	}
+	if len(secondFactors) == 0 {
+		return secondFactors, nil
+	}
+	recoveryCodes, err := v.Validator.Services.UserService.CountRecoveryCodes(u.Id)
+	if err != nil {
+		return nil, err
+	}
+	if recoveryCodes > 0 {
+		secondFactors = append(secondFactors, SecondFactorRecoveryCode)
+	}
+	return secondFactors, nil
+}
+
+func (v *MFAValidator) CreateChallenge(u *user.User) (string, error) {
+	challenge := &token.MFAChallengePayload{UserId: u.Id, OrganizationId: u.OrganizationId, IssuedAtTime: time.Now()}
+	challengeJWT, err := v.Validator.Services.MFAChallengeTokenGenerator.CreateToken(challenge)
+	if err != nil {
+		return "", fmt.Errorf("%w, %s", ErrMFACreatingChallenge, err)
+	}
+	return challengeJWT, nil
+}
+
+// GetMFALogin reads the second step of the login and returns the payload of its challenge token once
+// the token signature and expiration are checked.
+func (v *MFAValidator) GetMFALogin() (*MFALoginInput, *token.MFAChallengePayload, error) {
+	var mfaLogin MFALoginInput
+	if err := v.Validator.DecodeJSONBody(&mfaLogin); err != nil {
+		return nil, nil, err
+	}
+	if mfaLogin.ChallengeToken == "" || (mfaLogin.Code == "" && mfaLogin.RecoveryCode == "") {
+		return nil, nil, ErrMFAEmptyTokenCode
+	}
+	if mfaLogin.Code != "" && mfaLogin.RecoveryCode != "" {
+		return nil, nil, ErrMFATwoCodes
+	}
+	payload, err := v.LoadChallenge(mfaLogin.ChallengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mfaLogin, payload, nil
+}
+
+// LoadChallenge returns the payload of the challenge token once its signature and expiration are checked.
+func (v *MFAValidator) LoadChallenge(challengeToken string) (*token.MFAChallengePayload, error) {
+	tokenGenerator := v.Validator.Services.MFAChallengeTokenGenerator
+	if err := tokenGenerator.IsTokenValid(challengeToken); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrMFAChallengeInvalid, err)
+	}
+	payload := &token.MFAChallengePayload{}
+	if err := tokenGenerator.LoadPayload(challengeToken, payload); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrMFAChallengeInvalid, err)
+	}
+	return payload, nil
+}
+
+// VerifyCode checks the code of the second step of the login and returns the user of the challenge.
+func (v *MFAValidator) VerifyCode(challenge *token.MFAChallengePayload, code string) (*user.User, error) {
+	u, err := v.getChallengeUser(challenge)
+	if err != nil {
+		return nil, err
+	}
+	err = v.Validator.Services.UserService.VerifyTOTP(u.Id, code)
+	if err == user.ErrTOTPNotEnabled {
+		return nil, ErrMFAChallengeNotExpected
+	}
+	if err == user.ErrTOTPCodeNotValid {
+		return nil, ErrMFACodeNotValid
+	}
+	if err == user.ErrUserLocked {
+		return nil, ErrLoginRouterUserLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// VerifyRecoveryCode uses a recovery code instead of the second factor and returns the user of the
+// challenge, the code is not valid anymore afterwards.
+func (v *MFAValidator) VerifyRecoveryCode(challenge *token.MFAChallengePayload, code string) (*user.User, error) {
+	u, err := v.getChallengeUser(challenge)
+	if err != nil {
+		return nil, err
+	}
+	err = v.Validator.Services.UserService.UseRecoveryCode(u.Id, code)
+	if err == user.ErrRecoveryCodeNotValid {
+		return nil, ErrMFACodeNotValid
+	}
+	if err == user.ErrUserLocked {
+		return nil, ErrLoginRouterUserLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (v *MFAValidator) getChallengeUser(challenge *token.MFAChallengePayload) (*user.User, error) {
+	u, err := v.Validator.Services.UserService.GetRepository().GetById(challenge.UserId)
+	if err == user.ErrUserNotFound {
+		return nil, ErrLoginRouterUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = v.Validator.CheckUserActive(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
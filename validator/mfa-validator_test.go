@@ -0,0 +1,53 @@
+package validator
+
+import (
+	"authGo/token"
+	"authGo/user"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetMFALogin(t *testing.T) {
+	services := &Services{
+		MFAChallengeTokenGenerator: &token.TokenGenerator[token.MFAChallengePayload]{Password: []byte("mfaChallengeKey"), Duration: time.Minute * 5},
+	}
+	v := MFAValidator{Validator: Validator{Services: services}}
+	challengeToken, err := v.CreateChallenge(&user.User{Id: "1", OrganizationId: user.DefaultOrganizationId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherGenerator := &token.TokenGenerator[token.MFAChallengePayload]{Password: []byte("otherKey"), Duration: time.Minute * 5}
+	otherToken, _ := otherGenerator.CreateToken(&token.MFAChallengePayload{UserId: "1", IssuedAtTime: time.Now()})
+	expiredToken, _ := services.MFAChallengeTokenGenerator.CreateToken(&token.MFAChallengePayload{UserId: "1", IssuedAtTime: time.Now().Add(-time.Hour)})
+
+	mfaTests := []struct {
+		body string
+		err  error
+	}{
+		{fmt.Sprintf(`{"challengeToken": %q, "code": "123456"}`, challengeToken), nil},
+		{fmt.Sprintf(`{"challengeToken": %q}`, challengeToken), ErrMFAEmptyTokenCode},
+		{`{"code": "123456"}`, ErrMFAEmptyTokenCode},
+		{fmt.Sprintf(`{"challengeToken": %q, "code": "123456", "recoveryCode": "abcd"}`, challengeToken), ErrMFATwoCodes},
+		{fmt.Sprintf(`{"challengeToken": %q, "code": "123456"}`, otherToken), ErrMFAChallengeInvalid},
+		{fmt.Sprintf(`{"challengeToken": %q, "code": "123456"}`, expiredToken), ErrMFAChallengeInvalid},
+	}
+	for i, mfaTest := range mfaTests {
+		req, err := http.NewRequest("POST", "/auth/login/mfa", strings.NewReader(mfaTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		v.Validator.Request = req
+		mfaLogin, challenge, err := v.GetMFALogin()
+		if !errors.Is(err, mfaTest.err) {
+			t.Errorf("%d: expected err to be %s, got %s", i, mfaTest.err, err)
+		}
+		if err == nil && (mfaLogin.Code != "123456" || challenge.UserId != "1" || challenge.OrganizationId != user.DefaultOrganizationId) {
+			t.Errorf("%d: unexpected login %+v %+v", i, mfaLogin, challenge)
+		}
+	}
+}
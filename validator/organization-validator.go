@@ -0,0 +1,30 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+)
+
+type OrganizationInput struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type OrganizationValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrOrganizationEmptyId = errors.New("organization validator: empty id")
+)
+
+func (v *OrganizationValidator) GetNewOrganization() (*OrganizationInput, error) {
+	var organization OrganizationInput
+	if err := v.Validator.DecodeJSONBody(&organization); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(organization.Id) == "" {
+		return nil, ErrOrganizationEmptyId
+	}
+	return &organization, nil
+}
@@ -0,0 +1,50 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetNewOrganization(t *testing.T) {
+	organizationTests := []struct {
+		body string
+		err  error
+	}{
+		{`{"id": "acme", "name": "Acme"}`, nil},
+		{`{"id": " ", "name": "Acme"}`, ErrOrganizationEmptyId},
+		{`{"id": "acme", "name": "Acme", "users": []}`, ErrInvalidBody},
+	}
+	for _, organizationTest := range organizationTests {
+		req, err := http.NewRequest("POST", "/organizations", strings.NewReader(organizationTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		v := OrganizationValidator{Validator: Validator{Request: req}}
+		organization, err := v.GetNewOrganization()
+		if !errors.Is(err, organizationTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", organizationTest.body, organizationTest.err, err)
+		}
+		if err == nil && (organization.Id != "acme" || organization.Name != "Acme") {
+			t.Errorf("unexpected organization, got %+v", organization)
+		}
+	}
+}
+
+func TestGetOrganizationId(t *testing.T) {
+	req, err := http.NewRequest("POST", "/auth/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := Validator{Request: req}
+	if organizationId := v.GetOrganizationId(); organizationId != user.DefaultOrganizationId {
+		t.Errorf("expected the default organization without header, got %s", organizationId)
+	}
+	req.Header.Set(OrganizationHeader, " acme ")
+	if organizationId := v.GetOrganizationId(); organizationId != "acme" {
+		t.Errorf("expected the organization of the header, got %s", organizationId)
+	}
+}
@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+)
+
+type PasswordResetRequestInput struct {
+	Name string `json:"name"`
+}
+
+type PasswordResetCompletionInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+type PasswordResetValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrPasswordResetEmptyName          = errors.New("password reset validator: empty name")
+	ErrPasswordResetEmptyTokenPassword = errors.New("password reset validator: empty token or new password")
+)
+
+func (v *PasswordResetValidator) GetResetRequest() (*PasswordResetRequestInput, error) {
+	var resetRequest PasswordResetRequestInput
+	if err := v.Validator.DecodeJSONBody(&resetRequest); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(resetRequest.Name) == "" {
+		return nil, ErrPasswordResetEmptyName
+	}
+	return &resetRequest, nil
+}
+
+func (v *PasswordResetValidator) GetResetCompletion() (*PasswordResetCompletionInput, error) {
+	var resetCompletion PasswordResetCompletionInput
+	if err := v.Validator.DecodeJSONBody(&resetCompletion); err != nil {
+		return nil, err
+	}
+	if resetCompletion.Token == "" || resetCompletion.NewPassword == "" {
+		return nil, ErrPasswordResetEmptyTokenPassword
+	}
+	return &resetCompletion, nil
+}
@@ -0,0 +1,63 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetResetRequest(t *testing.T) {
+	resetTests := []struct {
+		body        string
+		contentType string
+		err         error
+	}{
+		{`{"name": "user1"}`, "application/json", nil},
+		{`{"name": "user1"}`, "text/plain", ErrInvalidContentType},
+		{`{"name": "user1", "password": "user1"}`, "application/json", ErrInvalidBody},
+		{`{"name": " "}`, "application/json", ErrPasswordResetEmptyName},
+	}
+	for _, resetTest := range resetTests {
+		req, err := http.NewRequest("POST", "/auth/password-reset", strings.NewReader(resetTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", resetTest.contentType)
+		v := PasswordResetValidator{Validator: Validator{Request: req}}
+		resetRequest, err := v.GetResetRequest()
+		if !errors.Is(err, resetTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", resetTest.body, resetTest.err, err)
+		}
+		if err == nil && resetRequest.Name != "user1" {
+			t.Errorf("expected name to be user1, got %s", resetRequest.Name)
+		}
+	}
+}
+
+func TestGetResetCompletion(t *testing.T) {
+	resetTests := []struct {
+		body string
+		err  error
+	}{
+		{`{"token": "abc", "newPassword": "user1"}`, nil},
+		{`[]`, ErrInvalidBody},
+		{`{"token": "abc"}`, ErrPasswordResetEmptyTokenPassword},
+		{`{"newPassword": "user1"}`, ErrPasswordResetEmptyTokenPassword},
+	}
+	for _, resetTest := range resetTests {
+		req, err := http.NewRequest("POST", "/auth/password-reset/complete", strings.NewReader(resetTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		v := PasswordResetValidator{Validator: Validator{Request: req}}
+		resetCompletion, err := v.GetResetCompletion()
+		if !errors.Is(err, resetTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", resetTest.body, resetTest.err, err)
+		}
+		if err == nil && (resetCompletion.Token != "abc" || resetCompletion.NewPassword != "user1") {
+			t.Errorf("unexpected reset completion, got %+v", resetCompletion)
+		}
+	}
+}
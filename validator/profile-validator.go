@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+)
+
+type ProfileInput struct {
+	DisplayName *string         `json:"displayName"`
+	AvatarURL   *string         `json:"avatarUrl"`
+	Locale      *string         `json:"locale"`
+	Timezone    *string         `json:"timezone"`
+	Metadata    *map[string]any `json:"metadata"`
+}
+
+type ProfileValidator struct {
+	Validator Validator
+}
+
+var ErrProfileEmpty = errors.New("profile validator: no fields to update")
+
+// GetProfileUpdate reads the changed fields of a profile, the service checks their values.
+func (v *ProfileValidator) GetProfileUpdate() (*user.ProfileUpdate, error) {
+	var input ProfileInput
+	if err := v.Validator.DecodeJSONBody(&input); err != nil {
+		return nil, err
+	}
+	if input.DisplayName == nil && input.AvatarURL == nil && input.Locale == nil && input.Timezone == nil && input.Metadata == nil {
+		return nil, ErrProfileEmpty
+	}
+	return &user.ProfileUpdate{
+		DisplayName: input.DisplayName,
+		AvatarURL:   input.AvatarURL,
+		Locale:      input.Locale,
+		Timezone:    input.Timezone,
+		Metadata:    input.Metadata,
+	}, nil
+}
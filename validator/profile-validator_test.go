@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetProfileUpdate(t *testing.T) {
+	body := `{"displayName": "User One", "timezone": "", "metadata": {"department": "sales"}}`
+	req, err := http.NewRequest("PATCH", "/users/me/profile", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	v := ProfileValidator{Validator: Validator{Request: req}}
+	update, err := v.GetProfileUpdate()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if *update.DisplayName != "User One" || *update.Timezone != "" || (*update.Metadata)["department"] != "sales" {
+		t.Errorf("unexpected update, got %+v", update)
+	}
+	if update.AvatarURL != nil || update.Locale != nil {
+		t.Errorf("expected the missing fields to be nil, got %+v", update)
+	}
+}
+
+func TestErrorsGetProfileUpdate(t *testing.T) {
+	profileTests := []struct {
+		body        string
+		contentType string
+		err         error
+	}{
+		{`{"displayName": "User One"}`, "text/plain", ErrInvalidContentType},
+		{`{"name": "user1"}`, "application/json", ErrInvalidBody},
+		{`{"metadata": [1, 2]}`, "application/json", ErrInvalidBody},
+		{`{}`, "application/json", ErrProfileEmpty},
+	}
+	for _, profileTest := range profileTests {
+		req, err := http.NewRequest("PATCH", "/users/me/profile", strings.NewReader(profileTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Content-Type", profileTest.contentType)
+		v := ProfileValidator{Validator: Validator{Request: req}}
+		if _, err = v.GetProfileUpdate(); !errors.Is(err, profileTest.err) {
+			t.Errorf("%s: expected err to be %s, got %v", profileTest.body, profileTest.err, err)
+		}
+	}
+}
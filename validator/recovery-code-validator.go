@@ -0,0 +1,24 @@
+package validator
+
+import "errors"
+
+type RecoveryCodesInput struct {
+	Password string `json:"password"`
+}
+
+type RecoveryCodeValidator struct {
+	Validator Validator
+}
+
+var ErrRecoveryCodeEmptyPassword = errors.New("recovery code validator: empty password")
+
+func (v *RecoveryCodeValidator) GetRegeneration() (*RecoveryCodesInput, error) {
+	var input RecoveryCodesInput
+	if err := v.Validator.DecodeJSONBody(&input); err != nil {
+		return nil, err
+	}
+	if input.Password == "" {
+		return nil, ErrRecoveryCodeEmptyPassword
+	}
+	return &input, nil
+}
@@ -37,7 +37,7 @@ func (v *RefreshValidator) ValidateRefreshToken() (*session.Session, error) {
 	if err != nil {
 		return nil, err
 	}
-	session, _, err := v.Validator.Services.SessionsHandler.GetSession(payload)
+	session, err := v.Validator.Services.SessionsHandler.GetSession(payload)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +46,10 @@ func (v *RefreshValidator) ValidateRefreshToken() (*session.Session, error) {
 
 func (v *RefreshValidator) CreateAccessToken(user *user.User) (*AccessJwtToken, error) {
 	now := time.Now()
-	accessPayload := &token.AccessTokenPayload{UserId: user.Id, IssuedAtTime: now, IsAdmin: user.IsAdmin}
+	accessPayload, err := NewAccessTokenPayload(v.Validator.Services, user, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrRefreshCreatingAccessToken, err)
+	}
 	accessJWT, err := v.Validator.Services.AccessTokenGenerator.CreateToken(accessPayload)
 	if err != nil {
 		return nil, ErrRefreshCreatingAccessToken
@@ -85,15 +85,18 @@ func TestErrorsValidateRefreshToken(t *testing.T) {
 
 func TestCreateAccessToken(t *testing.T) {
 	accessTokenGenerator := &token.TokenGenerator[token.AccessTokenPayload]{Password: []byte("accessKey"), Duration: time.Minute * 2}
-	user := &user.User{Id: "1", Name: "user1", Password: "user1", IsAdmin: true}
+	u := &user.User{Id: "1", Name: "user1", Password: "user1", Roles: []string{user.AdminRole}}
 
-	v := RefreshValidator{Validator: Validator{Services: &Services{AccessTokenGenerator: accessTokenGenerator}}}
+	v := RefreshValidator{Validator: Validator{Services: &Services{UserService: user.NewUserService(), AccessTokenGenerator: accessTokenGenerator}}}
 
-	accessToken, err := v.CreateAccessToken(user)
+	accessToken, err := v.CreateAccessToken(u)
 	if err != nil {
 		t.Errorf("expected err to be nil, got %s", err)
 	}
 	if accessToken == nil {
-		t.Error("expected accessToken to not be nil")
+		t.Fatal("expected accessToken to not be nil")
+	}
+	if !accessToken.AccessPayload.HasPermission(user.PermissionUsersDelete) {
+		t.Errorf("expected the permissions of the admin role, got %v", accessToken.AccessPayload.Permissions)
 	}
 }
@@ -0,0 +1,33 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+)
+
+type RegistrationInput struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RegistrationValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrRegistrationEmptyNamePassword = errors.New("registration validator: empty name or password")
+)
+
+// GetRegistration reads the user registering themselves, the organization comes from the
+// X-Organization-Id header like for the logins.
+func (v *RegistrationValidator) GetRegistration() (*RegistrationInput, error) {
+	var registration RegistrationInput
+	if err := v.Validator.DecodeJSONBody(&registration); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(registration.Name) == "" || registration.Password == "" {
+		return nil, ErrRegistrationEmptyNamePassword
+	}
+	return &registration, nil
+}
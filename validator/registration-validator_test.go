@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetRegistration(t *testing.T) {
+	registrationTests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         error
+	}{
+		{"valid", "application/json", `{"name": "user1", "email": "user1@example.com", "password": "user1"}`, nil},
+		{"empty name", "application/json", `{"name": " ", "password": "user1"}`, ErrRegistrationEmptyNamePassword},
+		{"empty password", "application/json", `{"name": "user1"}`, ErrRegistrationEmptyNamePassword},
+		{"roles", "application/json", `{"name": "user1", "password": "user1", "roles": ["admin"]}`, ErrInvalidBody},
+		{"content type", "text/plain", `{"name": "user1", "password": "user1"}`, ErrInvalidContentType},
+	}
+	for _, registrationTest := range registrationTests {
+		req, err := http.NewRequest("POST", "/auth/register", strings.NewReader(registrationTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", registrationTest.contentType)
+		v := RegistrationValidator{Validator: Validator{Request: req}}
+		registration, err := v.GetRegistration()
+		if !errors.Is(err, registrationTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", registrationTest.name, registrationTest.err, err)
+		}
+		if err == nil && (registration.Name != "user1" || registration.Email != "user1@example.com" || registration.Password != "user1") {
+			t.Errorf("%s: unexpected registration, got %+v", registrationTest.name, registration)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+)
+
+type RoleInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleUpdateInput struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrRoleEmptyName        = errors.New("role validator: empty name")
+	ErrRoleEmptyPermissions = errors.New("role validator: empty permissions")
+)
+
+func (v *RoleValidator) GetNewRole() (*RoleInput, error) {
+	var role RoleInput
+	if err := v.Validator.DecodeJSONBody(&role); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(role.Name) == "" {
+		return nil, ErrRoleEmptyName
+	}
+	if len(role.Permissions) == 0 {
+		return nil, ErrRoleEmptyPermissions
+	}
+	return &role, nil
+}
+
+func (v *RoleValidator) GetRoleUpdate() (*RoleUpdateInput, error) {
+	var role RoleUpdateInput
+	if err := v.Validator.DecodeJSONBody(&role); err != nil {
+		return nil, err
+	}
+	if len(role.Permissions) == 0 {
+		return nil, ErrRoleEmptyPermissions
+	}
+	return &role, nil
+}
@@ -0,0 +1,47 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetNewRole(t *testing.T) {
+	roleTests := []struct {
+		body string
+		err  error
+	}{
+		{`{"name": "helpdesk", "description": "Support", "permissions": ["sessions:revoke"]}`, nil},
+		{`{"name": " ", "permissions": ["sessions:revoke"]}`, ErrRoleEmptyName},
+		{`{"name": "helpdesk"}`, ErrRoleEmptyPermissions},
+		{`{"name": "helpdesk", "permissions": ["sessions:revoke"], "builtIn": true}`, ErrInvalidBody},
+	}
+	for _, roleTest := range roleTests {
+		req, err := http.NewRequest("POST", "/roles", strings.NewReader(roleTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		v := RoleValidator{Validator: Validator{Request: req}}
+		role, err := v.GetNewRole()
+		if !errors.Is(err, roleTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", roleTest.body, roleTest.err, err)
+		}
+		if err == nil && (role.Name != "helpdesk" || role.Description != "Support" || len(role.Permissions) != 1) {
+			t.Errorf("unexpected role, got %+v", role)
+		}
+	}
+}
+
+func TestGetRoleUpdate(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/roles/helpdesk", strings.NewReader(`{"permissions": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	v := RoleValidator{Validator: Validator{Request: req}}
+	if _, err = v.GetRoleUpdate(); err != ErrRoleEmptyPermissions {
+		t.Errorf("expected err to be ErrRoleEmptyPermissions, got %v", err)
+	}
+}
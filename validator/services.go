@@ -1,14 +1,22 @@
 package validator
 
 import (
+	"authGo/mailer"
 	"authGo/session"
 	"authGo/token"
 	"authGo/user"
+	"authGo/webauthn"
 )
 
 type Services struct {
-	UserService           *user.UserService
-	AccessTokenGenerator  *token.TokenGenerator[token.AccessTokenPayload]
-	RefreshTokenGenerator *token.TokenGenerator[token.RefreshTokenPayload]
-	SessionsHandler       *session.SessionsHandler
+	UserService                     *user.UserService
+	AccessTokenGenerator            *token.TokenGenerator[token.AccessTokenPayload]
+	RefreshTokenGenerator           *token.TokenGenerator[token.RefreshTokenPayload]
+	SessionsHandler                 *session.SessionsHandler
+	Mailer                          mailer.Mailer
+	EmailVerificationTokenGenerator *token.TokenGenerator[token.EmailVerificationPayload]
+	MFAChallengeTokenGenerator      *token.TokenGenerator[token.MFAChallengePayload]
+	WebAuthn                        *webauthn.RelyingParty
+	WebAuthnChallengeTokenGenerator *token.TokenGenerator[token.WebAuthnChallengePayload]
+	InvitationTokenGenerator        *token.TokenGenerator[token.InvitationPayload]
 }
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"authGo/session"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type SessionQueryValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrSessionQueryInvalidSort  = errors.New("session query validator: invalid sort field")
+	ErrSessionQueryInvalidOrder = errors.New("session query validator: invalid order, must be asc or desc")
+	ErrSessionQueryInvalidDate  = errors.New("session query validator: invalid date, must be RFC 3339")
+	ErrSessionQueryInvalidLimit = errors.New("session query validator: invalid limit")
+)
+
+func (v *SessionQueryValidator) GetSessionQuery() (*session.SessionQuery, error) {
+	values := v.Validator.Request.URL.Query()
+	query := session.NewSessionQuery()
+	query.OrganizationId = values.Get("organizationId")
+	query.UserId = values.Get("userId")
+	query.IpAddress = values.Get("ip")
+	query.UserAgent = values.Get("userAgent")
+	query.Cursor = values.Get("cursor")
+
+	if sortBy := values.Get("sort"); sortBy != "" {
+		if !session.IsValidSortField(sortBy) {
+			return nil, fmt.Errorf("%w, got %s", ErrSessionQueryInvalidSort, sortBy)
+		}
+		query.SortBy = sortBy
+	}
+
+	switch order := values.Get("order"); order {
+	case "":
+	case "asc":
+		query.Descending = false
+	case "desc":
+		query.Descending = true
+	default:
+		return nil, fmt.Errorf("%w, got %s", ErrSessionQueryInvalidOrder, order)
+	}
+
+	var err error
+	if query.LastUpdateFrom, err = parseQueryDate(values.Get("lastUpdateFrom")); err != nil {
+		return nil, err
+	}
+	if query.LastUpdateTo, err = parseQueryDate(values.Get("lastUpdateTo")); err != nil {
+		return nil, err
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > session.MaxQueryLimit {
+			return nil, fmt.Errorf("%w, got %s", ErrSessionQueryInvalidLimit, limit)
+		}
+	}
+	return query, nil
+}
+
+func parseQueryDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w, got %s", ErrSessionQueryInvalidDate, value)
+	}
+	return date, nil
+}
@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"authGo/session"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetSessionQuery(t *testing.T) {
+	req, err := http.NewRequest("GET", "/sessions?userId=1&ip=10.0.0.1&userAgent=firefox&lastUpdateFrom=2022-08-06T00:00:00Z&sort=createdAt&order=asc&limit=10&cursor=abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := SessionQueryValidator{Validator: Validator{Request: req}}
+	query, err := v.GetSessionQuery()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if query.UserId != "1" || query.IpAddress != "10.0.0.1" || query.UserAgent != "firefox" || query.Cursor != "abc" {
+		t.Errorf("unexpected query filters, got %+v", query)
+	}
+	if query.SortBy != session.SortByCreatedAt || query.Descending || query.Limit != 10 {
+		t.Errorf("unexpected query sorting, got %+v", query)
+	}
+	if !query.LastUpdateFrom.Equal(time.Date(2022, 8, 6, 0, 0, 0, 0, time.UTC)) || !query.LastUpdateTo.IsZero() {
+		t.Errorf("unexpected query dates, got %+v", query)
+	}
+}
+
+func TestErrorsGetSessionQuery(t *testing.T) {
+	queryTests := []struct {
+		url string
+		err error
+	}{
+		{"/sessions?sort=password", ErrSessionQueryInvalidSort},
+		{"/sessions?order=up", ErrSessionQueryInvalidOrder},
+		{"/sessions?lastUpdateFrom=yesterday", ErrSessionQueryInvalidDate},
+		{"/sessions?lastUpdateTo=2022-08-06", ErrSessionQueryInvalidDate},
+		{"/sessions?limit=0", ErrSessionQueryInvalidLimit},
+		{"/sessions?limit=abc", ErrSessionQueryInvalidLimit},
+		{"/sessions?limit=1000", ErrSessionQueryInvalidLimit},
+	}
+	for _, queryTest := range queryTests {
+		req, err := http.NewRequest("GET", queryTest.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := SessionQueryValidator{Validator: Validator{Request: req}}
+		_, err = v.GetSessionQuery()
+		if !errors.Is(err, queryTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", queryTest.url, queryTest.err, err)
+		}
+	}
+}
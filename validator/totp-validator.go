@@ -0,0 +1,28 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+)
+
+type TOTPCodeInput struct {
+	Code string `json:"code"`
+}
+
+type TOTPValidator struct {
+	Validator Validator
+}
+
+var ErrTOTPEmptyCode = errors.New("totp validator: empty code")
+
+func (v *TOTPValidator) GetCode() (*TOTPCodeInput, error) {
+	var codeInput TOTPCodeInput
+	if err := v.Validator.DecodeJSONBody(&codeInput); err != nil {
+		return nil, err
+	}
+	codeInput.Code = strings.TrimSpace(codeInput.Code)
+	if codeInput.Code == "" {
+		return nil, ErrTOTPEmptyCode
+	}
+	return &codeInput, nil
+}
@@ -0,0 +1,34 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetTOTPCode(t *testing.T) {
+	codeTests := []struct {
+		body string
+		err  error
+	}{
+		{`{"code": " 123456 "}`, nil},
+		{`{"code": "123456", "secret": "abc"}`, ErrInvalidBody},
+		{`{"code": " "}`, ErrTOTPEmptyCode},
+	}
+	for _, codeTest := range codeTests {
+		req, err := http.NewRequest("POST", "/users/me/totp/confirm", strings.NewReader(codeTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		v := TOTPValidator{Validator: Validator{Request: req}}
+		codeInput, err := v.GetCode()
+		if !errors.Is(err, codeTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", codeTest.body, codeTest.err, err)
+		}
+		if err == nil && codeInput.Code != "123456" {
+			t.Errorf("expected code to be 123456, got %s", codeInput.Code)
+		}
+	}
+}
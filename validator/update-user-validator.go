@@ -0,0 +1,54 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+type UpdateUserInput struct {
+	Name  *string   `json:"name"`
+	Email *string   `json:"email"`
+	Roles *[]string `json:"roles"`
+}
+
+type UpdateUserValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrUpdateUserInvalidContentType = errors.New("update user validator: invalid content-type")
+	ErrUpdateUserInvalidBody        = errors.New("update user validator: invalid body")
+	ErrUpdateUserEmpty              = errors.New("update user validator: no fields to update")
+	ErrUpdateUserEmptyName          = errors.New("update user validator: empty name")
+)
+
+func (v *UpdateUserValidator) GetUpdateUser() (*UpdateUserInput, error) {
+	contentType := v.Validator.Request.Header.Get("Content-type")
+	if contentType != "application/json" {
+		return nil, ErrUpdateUserInvalidContentType
+	}
+
+	body, err := ioutil.ReadAll(v.Validator.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var updateUser UpdateUserInput
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&updateUser); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrUpdateUserInvalidBody, err)
+	}
+
+	if updateUser.Name == nil && updateUser.Email == nil && updateUser.Roles == nil {
+		return nil, ErrUpdateUserEmpty
+	}
+	if updateUser.Name != nil && strings.TrimSpace(*updateUser.Name) == "" {
+		return nil, ErrUpdateUserEmptyName
+	}
+	return &updateUser, nil
+}
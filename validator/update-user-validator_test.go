@@ -0,0 +1,54 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetUpdateUser(t *testing.T) {
+	req, err := http.NewRequest("PATCH", "/users/1", strings.NewReader(`{"roles": ["admin"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	v := UpdateUserValidator{Validator: Validator{Request: req}}
+	updateUser, err := v.GetUpdateUser()
+	if err != nil {
+		t.Errorf("expected err to be nil, got %s", err)
+	}
+	if updateUser.Name != nil {
+		t.Errorf("expected name to be nil, got %s", *updateUser.Name)
+	}
+	if updateUser.Roles == nil || len(*updateUser.Roles) != 1 || (*updateUser.Roles)[0] != "admin" {
+		t.Errorf("expected roles to be [admin], got %v", updateUser.Roles)
+	}
+}
+
+func TestErrorsGetUpdateUser(t *testing.T) {
+	updateTests := []struct {
+		body        string
+		contentType string
+		err         error
+	}{
+		{`{"name": "user1"}`, "text/plain", ErrUpdateUserInvalidContentType},
+		{`123456`, "application/json", ErrUpdateUserInvalidBody},
+		{`{"password": "user1"}`, "application/json", ErrUpdateUserInvalidBody},
+		{`{}`, "application/json", ErrUpdateUserEmpty},
+		{`{"name": null}`, "application/json", ErrUpdateUserEmpty},
+		{`{"name": "  "}`, "application/json", ErrUpdateUserEmptyName},
+	}
+	for _, updateTest := range updateTests {
+		req, err := http.NewRequest("PATCH", "/users/1", strings.NewReader(updateTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", updateTest.contentType)
+		v := UpdateUserValidator{Validator: Validator{Request: req}}
+		_, err = v.GetUpdateUser()
+		if !errors.Is(err, updateTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", updateTest.body, updateTest.err, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// MaxUserImportSize is the largest import file accepted, in bytes.
+const MaxUserImportSize = 10 << 20
+
+type UserImportInput struct {
+	Records []*user.UserRecord
+	// OrganizationId is used by the records without one, it's empty when not given.
+	OrganizationId string
+	DryRun         bool
+}
+
+type UserExportInput struct {
+	Format                string
+	OrganizationId        string
+	IncludePasswordHashes bool
+}
+
+type UserImportValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrUserImportInvalidFormat         = errors.New("user import validator: invalid format, must be csv or json")
+	ErrUserImportInvalidDryRun         = errors.New("user import validator: invalid dryRun, must be true or false")
+	ErrUserImportInvalidPasswordHashes = errors.New("user import validator: invalid passwordHashes, must be true or false")
+)
+
+// GetImport reads the users of the body, a CSV file with the text/csv content type or a JSON document
+// with application/json. The dryRun and organizationId query parameters are optional.
+func (v *UserImportValidator) GetImport() (*UserImportInput, error) {
+	values := v.Validator.Request.URL.Query()
+	input := &UserImportInput{OrganizationId: values.Get("organizationId")}
+	if dryRun := values.Get("dryRun"); dryRun != "" {
+		var err error
+		if input.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return nil, fmt.Errorf("%w, got %s", ErrUserImportInvalidDryRun, dryRun)
+		}
+	}
+
+	var format string
+	switch contentType := v.Validator.Request.Header.Get("Content-type"); contentType {
+	case "text/csv":
+		format = user.FormatCSV
+	case "application/json":
+		format = user.FormatJSON
+	default:
+		return nil, ErrInvalidContentType
+	}
+	body := http.MaxBytesReader(v.Validator.Writer, v.Validator.Request.Body, MaxUserImportSize)
+	records, err := user.ReadUserRecords(body, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidBody, err)
+	}
+	input.Records = records
+	return input, nil
+}
+
+// GetExport reads the format, csv by default, the organizationId and whether to include the password
+// hashes from the query parameters.
+func (v *UserImportValidator) GetExport() (*UserExportInput, error) {
+	values := v.Validator.Request.URL.Query()
+	input := &UserExportInput{Format: user.FormatCSV, OrganizationId: values.Get("organizationId")}
+	if format := values.Get("format"); format != "" {
+		if !user.IsValidFormat(format) {
+			return nil, fmt.Errorf("%w, got %s", ErrUserImportInvalidFormat, format)
+		}
+		input.Format = format
+	}
+	if passwordHashes := values.Get("passwordHashes"); passwordHashes != "" {
+		var err error
+		if input.IncludePasswordHashes, err = strconv.ParseBool(passwordHashes); err != nil {
+			return nil, fmt.Errorf("%w, got %s", ErrUserImportInvalidPasswordHashes, passwordHashes)
+		}
+	}
+	return input, nil
+}
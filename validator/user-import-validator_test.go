@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetImport(t *testing.T) {
+	importTests := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		records     int
+		err         error
+	}{
+		{"csv", "/users/import?dryRun=true", "text/csv", "name,password\nuser1,user1\nuser2,user2\n", 2, nil},
+		{"json", "/users/import", "application/json", `{"users": [{"name": "user1", "password": "user1"}]}`, 1, nil},
+		{"content type", "/users/import", "text/plain", "name\nuser1\n", 0, ErrInvalidContentType},
+		{"dry run", "/users/import?dryRun=maybe", "text/csv", "name\nuser1\n", 0, ErrUserImportInvalidDryRun},
+		{"file", "/users/import", "text/csv", "isAdmin\ntrue\n", 0, ErrInvalidBody},
+		{"size", "/users/import", "text/csv", "name\n" + strings.Repeat("a", MaxUserImportSize), 0, ErrInvalidBody},
+	}
+	for _, importTest := range importTests {
+		req, err := http.NewRequest("POST", importTest.url, strings.NewReader(importTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", importTest.contentType)
+		v := UserImportValidator{Validator: Validator{Writer: httptest.NewRecorder(), Request: req}}
+		input, err := v.GetImport()
+		if !errors.Is(err, importTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", importTest.name, importTest.err, err)
+		}
+		if err == nil && (len(input.Records) != importTest.records || input.DryRun != strings.Contains(importTest.url, "dryRun=true")) {
+			t.Errorf("%s: unexpected input, got %+v", importTest.name, input)
+		}
+	}
+}
+
+func TestGetExport(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/users/export", nil)
+	v := UserImportValidator{Validator: Validator{Request: req}}
+	input, err := v.GetExport()
+	if err != nil || input.Format != user.FormatCSV || input.IncludePasswordHashes || input.OrganizationId != "" {
+		t.Errorf("unexpected default export, got %+v %v", input, err)
+	}
+
+	req, _ = http.NewRequest("GET", "/users/export?format=json&passwordHashes=true&organizationId=acme", nil)
+	v.Validator.Request = req
+	input, err = v.GetExport()
+	if err != nil || input.Format != user.FormatJSON || !input.IncludePasswordHashes || input.OrganizationId != "acme" {
+		t.Errorf("unexpected export, got %+v %v", input, err)
+	}
+
+	exportTests := []struct {
+		url string
+		err error
+	}{
+		{"/users/export?format=xml", ErrUserImportInvalidFormat},
+		{"/users/export?passwordHashes=maybe", ErrUserImportInvalidPasswordHashes},
+	}
+	for _, exportTest := range exportTests {
+		req, _ = http.NewRequest("GET", exportTest.url, nil)
+		v.Validator.Request = req
+		if _, err = v.GetExport(); !errors.Is(err, exportTest.err) {
+			t.Errorf("%s: expected err to be %v, got %v", exportTest.url, exportTest.err, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+type UserQueryValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrUserQueryInvalidSort   = errors.New("user query validator: invalid sort field")
+	ErrUserQueryInvalidOrder  = errors.New("user query validator: invalid order, must be asc or desc")
+	ErrUserQueryInvalidAdmin  = errors.New("user query validator: invalid admin, must be true or false")
+	ErrUserQueryInvalidLimit  = errors.New("user query validator: invalid limit")
+	ErrUserQueryInvalidStatus = errors.New("user query validator: invalid status")
+)
+
+func (v *UserQueryValidator) GetUserQuery() (*user.UserQuery, error) {
+	values := v.Validator.Request.URL.Query()
+	query := user.NewUserQuery()
+	query.OrganizationId = values.Get("organizationId")
+	query.Search = values.Get("search")
+	query.Prefix = values.Get("prefix")
+	query.Role = values.Get("role")
+	query.Cursor = values.Get("cursor")
+
+	if sortBy := values.Get("sort"); sortBy != "" {
+		if !user.IsValidSortField(sortBy) {
+			return nil, fmt.Errorf("%w, got %s", ErrUserQueryInvalidSort, sortBy)
+		}
+		query.SortBy = sortBy
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+		query.Descending = false
+	case "desc":
+		query.Descending = true
+	default:
+		return nil, fmt.Errorf("%w, got %s", ErrUserQueryInvalidOrder, order)
+	}
+
+	switch status := values.Get("status"); status {
+	case "", user.UserStatusEnabled, user.UserStatusDisabled, user.UserStatusSuspended, user.UserStatusPending:
+		query.Status = status
+	default:
+		return nil, fmt.Errorf("%w, got %s", ErrUserQueryInvalidStatus, status)
+	}
+
+	if admin := values.Get("admin"); admin != "" {
+		isAdmin, err := strconv.ParseBool(admin)
+		if err != nil {
+			return nil, fmt.Errorf("%w, got %s", ErrUserQueryInvalidAdmin, admin)
+		}
+		query.Admin = &isAdmin
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		var err error
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > user.MaxQueryLimit {
+			return nil, fmt.Errorf("%w, got %s", ErrUserQueryInvalidLimit, limit)
+		}
+	}
+	return query, nil
+}
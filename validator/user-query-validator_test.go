@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGetUserQuery(t *testing.T) {
+	req, err := http.NewRequest("GET", "/users?search=ali&prefix=a&role=editor&status=pending&admin=false&sort=createdAt&order=desc&limit=10&cursor=abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := UserQueryValidator{Validator: Validator{Request: req}}
+	query, err := v.GetUserQuery()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %s", err)
+	}
+	if query.Search != "ali" || query.Prefix != "a" || query.Role != "editor" || query.Status != user.UserStatusPending || query.Admin == nil || *query.Admin || query.Cursor != "abc" {
+		t.Errorf("unexpected query filters, got %+v", query)
+	}
+	if query.SortBy != user.SortByCreatedAt || !query.Descending || query.Limit != 10 {
+		t.Errorf("unexpected query sorting, got %+v", query)
+	}
+
+	req, _ = http.NewRequest("GET", "/users", nil)
+	v.Validator.Request = req
+	query, err = v.GetUserQuery()
+	if err != nil || query.SortBy != user.SortByName || query.Descending || query.Admin != nil || query.Limit != user.DefaultQueryLimit {
+		t.Errorf("unexpected default query, got %+v %v", query, err)
+	}
+}
+
+func TestErrorsGetUserQuery(t *testing.T) {
+	queryTests := []struct {
+		url string
+		err error
+	}{
+		{"/users?sort=password", ErrUserQueryInvalidSort},
+		{"/users?order=up", ErrUserQueryInvalidOrder},
+		{"/users?admin=maybe", ErrUserQueryInvalidAdmin},
+		{"/users?status=locked", ErrUserQueryInvalidStatus},
+		{"/users?limit=0", ErrUserQueryInvalidLimit},
+		{"/users?limit=abc", ErrUserQueryInvalidLimit},
+		{"/users?limit=1000", ErrUserQueryInvalidLimit},
+	}
+	for _, queryTest := range queryTests {
+		req, err := http.NewRequest("GET", queryTest.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := UserQueryValidator{Validator: Validator{Request: req}}
+		_, err = v.GetUserQuery()
+		if !errors.Is(err, queryTest.err) {
+			t.Errorf("%s: expected err to be %s, got %s", queryTest.url, queryTest.err, err)
+		}
+	}
+}
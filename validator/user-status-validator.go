@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"time"
+)
+
+type UserStatusInput struct {
+	Status         string     `json:"status"`
+	SuspendedUntil *time.Time `json:"suspendedUntil"`
+	Reason         string     `json:"reason"`
+}
+
+type UserStatusValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrUserStatusInvalidStatus         = errors.New("user status validator: invalid status")
+	ErrUserStatusInvalidSuspendedUntil = errors.New("user status validator: suspendedUntil must be a future date of a suspension")
+)
+
+// GetUserStatus reads the status change, suspendedUntil is required by suspensions and refused
+// otherwise.
+func (v *UserStatusValidator) GetUserStatus() (*user.UserStatusUpdate, error) {
+	var input UserStatusInput
+	if err := v.Validator.DecodeJSONBody(&input); err != nil {
+		return nil, err
+	}
+	update := &user.UserStatusUpdate{Status: input.Status, Reason: input.Reason}
+	switch input.Status {
+	case user.UserStatusEnabled, user.UserStatusDisabled:
+		if input.SuspendedUntil != nil {
+			return nil, ErrUserStatusInvalidSuspendedUntil
+		}
+	case user.UserStatusSuspended:
+		if input.SuspendedUntil == nil || !input.SuspendedUntil.After(time.Now()) {
+			return nil, ErrUserStatusInvalidSuspendedUntil
+		}
+		update.SuspendedUntil = *input.SuspendedUntil
+	default:
+		return nil, ErrUserStatusInvalidStatus
+	}
+	return update, nil
+}
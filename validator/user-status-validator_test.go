@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"authGo/user"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetUserStatus(t *testing.T) {
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	statusTests := []struct {
+		body   string
+		status string
+		until  time.Time
+	}{
+		{`{"status": "enabled"}`, user.UserStatusEnabled, time.Time{}},
+		{`{"status": "disabled", "reason": "left the company"}`, user.UserStatusDisabled, time.Time{}},
+		{`{"status": "suspended", "suspendedUntil": "` + until.Format(time.RFC3339) + `"}`, user.UserStatusSuspended, until},
+	}
+	for _, statusTest := range statusTests {
+		req, err := http.NewRequest("PUT", "/users/1/status", strings.NewReader(statusTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Content-Type", "application/json")
+		v := UserStatusValidator{Validator: Validator{Request: req}}
+		update, err := v.GetUserStatus()
+		if err != nil {
+			t.Fatalf("%s: expected err to be nil, got %s", statusTest.body, err)
+		}
+		if update.Status != statusTest.status || !update.SuspendedUntil.Equal(statusTest.until) {
+			t.Errorf("%s: unexpected update, got %+v", statusTest.body, update)
+		}
+	}
+}
+
+func TestErrorsGetUserStatus(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	statusTests := []struct {
+		body        string
+		contentType string
+		err         error
+	}{
+		{`{"status": "disabled"}`, "text/plain", ErrInvalidContentType},
+		{`{"status": "disabled", "other": 1}`, "application/json", ErrInvalidBody},
+		{`{"status": "locked"}`, "application/json", ErrUserStatusInvalidStatus},
+		{`{}`, "application/json", ErrUserStatusInvalidStatus},
+		{`{"status": "suspended"}`, "application/json", ErrUserStatusInvalidSuspendedUntil},
+		{`{"status": "suspended", "suspendedUntil": "` + past + `"}`, "application/json", ErrUserStatusInvalidSuspendedUntil},
+		{`{"status": "disabled", "suspendedUntil": "` + future + `"}`, "application/json", ErrUserStatusInvalidSuspendedUntil},
+	}
+	for _, statusTest := range statusTests {
+		req, err := http.NewRequest("PUT", "/users/1/status", strings.NewReader(statusTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Content-Type", statusTest.contentType)
+		v := UserStatusValidator{Validator: Validator{Request: req}}
+		if _, err = v.GetUserStatus(); !errors.Is(err, statusTest.err) {
+			t.Errorf("%s: expected err to be %s, got %v", statusTest.body, statusTest.err, err)
+		}
+	}
+}
@@ -1,15 +1,28 @@
 package validator
 
 import (
+	"authGo/user"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"strings"
 )
 
 type NewUserInput struct {
-	Name     string `json:"name"`
-	IsAdmin  bool   `json:"isAdmin"`
-	Password string `json:"password"`
+	OrganizationId string   `json:"organizationId"`
+	Name           string   `json:"name"`
+	Email          string   `json:"email"`
+	Roles          []string `json:"roles"`
+	IsAdmin        bool     `json:"isAdmin"`
+	Password       string   `json:"password"`
+}
+
+// GetRoles returns the roles of the new user, isAdmin is kept as a shorthand for the admin role.
+func (n *NewUserInput) GetRoles() []string {
+	if n.IsAdmin {
+		return append(n.Roles, user.AdminRole)
+	}
+	return n.Roles
 }
 
 type UserValidator struct {
@@ -18,6 +31,7 @@ type UserValidator struct {
 
 var (
 	ErrUserInvalidContentType = errors.New("user validator: invalid content-type")
+	ErrUserEmptyNamePassword  = errors.New("user validator: empty name or password")
 )
 
 func (v *UserValidator) GetNewUser() (*NewUserInput, error) {
@@ -36,5 +50,8 @@ func (v *UserValidator) GetNewUser() (*NewUserInput, error) {
 	if err != nil {
 		return nil, err
 	}
+	if strings.TrimSpace(newUser.Name) == "" || newUser.Password == "" {
+		return nil, ErrUserEmptyNamePassword
+	}
 	return &newUser, nil
 }
@@ -45,4 +45,15 @@ func TestErrorsGetNewUser(t *testing.T) {
 	if err == nil {
 		t.Error("expected err to not be nil")
 	}
+
+	req, err = http.NewRequest("POST", "/users", strings.NewReader(`{"name": "user1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	v = UserValidator{Validator: Validator{Request: req}}
+	_, err = v.GetNewUser()
+	if err != ErrUserEmptyNamePassword {
+		t.Errorf("expected err to be ErrUserEmptyNamePassword, got %s", err)
+	}
 }
@@ -1,9 +1,56 @@
 package validator
 
-import "net/http"
+import (
+	"authGo/user"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
 
 type Validator struct {
 	Writer   http.ResponseWriter
 	Request  *http.Request
 	Services *Services
 }
+
+// OrganizationHeader selects the organization of the user in the requests made before logging in.
+const OrganizationHeader = "X-Organization-Id"
+
+var (
+	ErrInvalidContentType = errors.New("validator: invalid content-type")
+	ErrInvalidBody        = errors.New("validator: invalid body")
+)
+
+// DecodeJSONBody reads the JSON body of the request into the target, fields unknown to the target are
+// not accepted.
+func (v *Validator) DecodeJSONBody(target any) error {
+	contentType := v.Request.Header.Get("Content-type")
+	if contentType != "application/json" {
+		return ErrInvalidContentType
+	}
+
+	body, err := ioutil.ReadAll(v.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(target); err != nil {
+		return fmt.Errorf("%w, %s", ErrInvalidBody, err)
+	}
+	return nil
+}
+
+// GetOrganizationId returns the organization of the OrganizationHeader, or the default organization
+// when the header is not set.
+func (v *Validator) GetOrganizationId() string {
+	if organizationId := strings.TrimSpace(v.Request.Header.Get(OrganizationHeader)); organizationId != "" {
+		return organizationId
+	}
+	return user.DefaultOrganizationId
+}
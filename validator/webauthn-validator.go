@@ -0,0 +1,312 @@
+package validator
+
+import (
+	"authGo/token"
+	"authGo/user"
+	"authGo/webauthn"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SecondFactorWebAuthn is the second factor of the users with a registered passkey or security key.
+const SecondFactorWebAuthn = "webauthn"
+
+const (
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnCredentialInput is the PublicKeyCredential as serialized by its toJSON method, the binary
+// values are in base64url.
+type WebAuthnCredentialInput struct {
+	Id                      string                `json:"id"`
+	RawId                   string                `json:"rawId"`
+	Type                    string                `json:"type"`
+	Response                WebAuthnResponseInput `json:"response"`
+	AuthenticatorAttachment string                `json:"authenticatorAttachment"`
+	ClientExtensionResults  map[string]any        `json:"clientExtensionResults"`
+}
+
+type WebAuthnResponseInput struct {
+	ClientDataJSON     string   `json:"clientDataJSON"`
+	AttestationObject  string   `json:"attestationObject"`
+	AuthenticatorData  string   `json:"authenticatorData"`
+	Signature          string   `json:"signature"`
+	UserHandle         string   `json:"userHandle"`
+	Transports         []string `json:"transports"`
+	PublicKey          string   `json:"publicKey"`
+	PublicKeyAlgorithm int      `json:"publicKeyAlgorithm"`
+}
+
+type WebAuthnRegistrationInput struct {
+	ChallengeToken string                  `json:"challengeToken"`
+	Name           string                  `json:"name"`
+	Credential     WebAuthnCredentialInput `json:"credential"`
+}
+
+type WebAuthnLoginOptionsInput struct {
+	ChallengeToken string `json:"challengeToken"`
+}
+
+type WebAuthnLoginInput struct {
+	ChallengeToken string                  `json:"challengeToken"`
+	Credential     WebAuthnCredentialInput `json:"credential"`
+}
+
+type WebAuthnValidator struct {
+	Validator Validator
+}
+
+var (
+	ErrWebAuthnEmptyTokenCredential = errors.New("webauthn validator: empty challenge token or credential")
+	ErrWebAuthnChallengeInvalid     = errors.New("webauthn validator: challenge token not valid")
+	ErrWebAuthnCreatingChallenge    = errors.New("webauthn validator: error creating challenge token")
+	ErrWebAuthnCredentialNotValid   = errors.New("webauthn validator: credential not valid")
+)
+
+// CreateRegistrationOptions returns the options to register a new credential of the user and the
+// challenge token to send back with the credential.
+func (v *WebAuthnValidator) CreateRegistrationOptions(u *user.User) (string, *webauthn.CreationOptions, error) {
+	credentials, err := v.Validator.Services.UserService.GetWebAuthnCredentials(u.Id)
+	if err != nil {
+		return "", nil, err
+	}
+	challengeToken, challenge, err := v.createChallenge(u.Id, u.OrganizationId, webAuthnCeremonyRegistration)
+	if err != nil {
+		return "", nil, err
+	}
+	return challengeToken, v.Validator.Services.WebAuthn.CreationOptions(challenge, u.Id, u.Name, credentialIds(credentials)), nil
+}
+
+// GetRegistration reads the registered credential of the caller.
+func (v *WebAuthnValidator) GetRegistration(userId string) (*WebAuthnRegistrationInput, *token.WebAuthnChallengePayload, error) {
+	var registration WebAuthnRegistrationInput
+	if err := v.Validator.DecodeJSONBody(&registration); err != nil {
+		return nil, nil, err
+	}
+	if registration.ChallengeToken == "" || registration.Credential.Response.AttestationObject == "" {
+		return nil, nil, ErrWebAuthnEmptyTokenCredential
+	}
+	challenge, err := v.loadChallenge(registration.ChallengeToken, webAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge.UserId != userId {
+		return nil, nil, ErrWebAuthnChallengeInvalid
+	}
+	if err = v.useChallenge(challenge); err != nil {
+		return nil, nil, err
+	}
+	return &registration, challenge, nil
+}
+
+// VerifyRegistration checks the credential against the challenge and stores it for the user.
+func (v *WebAuthnValidator) VerifyRegistration(registration *WebAuthnRegistrationInput, challenge *token.WebAuthnChallengePayload) (*user.WebAuthnCredential, error) {
+	clientData, err := webauthn.DecodeBase64(registration.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnCredentialNotValid, err)
+	}
+	attestationObject, err := webauthn.DecodeBase64(registration.Credential.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnCredentialNotValid, err)
+	}
+	credential, err := v.Validator.Services.WebAuthn.VerifyRegistration(challenge.Challenge, &webauthn.AttestationResponse{ClientDataJSON: clientData, AttestationObject: attestationObject})
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnCredentialNotValid, err)
+	}
+	return v.Validator.Services.UserService.AddWebAuthnCredential(&user.WebAuthnCredential{
+		Id:        webauthn.Encoding.EncodeToString(credential.ID),
+		UserId:    challenge.UserId,
+		Name:      registration.Name,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	})
+}
+
+// GetLoginOptionsUser returns the user of the MFA challenge token when the passkey is the second factor,
+// or no user for a passwordless login.
+func (v *WebAuthnValidator) GetLoginOptionsUser() (*user.User, error) {
+	var loginOptions WebAuthnLoginOptionsInput
+	if err := v.Validator.DecodeJSONBody(&loginOptions); err != nil {
+		return nil, err
+	}
+	if loginOptions.ChallengeToken == "" {
+		return nil, nil
+	}
+	mfaV := MFAValidator{Validator: v.Validator}
+	challenge, err := mfaV.LoadChallenge(loginOptions.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	u, err := v.Validator.Services.UserService.GetRepository().GetById(challenge.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrMFAChallengeInvalid, err)
+	}
+	return u, nil
+}
+
+// CreateLoginOptions returns the options to authenticate with a credential of the user, or with any
+// discoverable credential when there is no user, and the challenge token to send back with the
+// assertion. The passwordless logins require the user verification of the authenticator.
+func (v *WebAuthnValidator) CreateLoginOptions(u *user.User) (string, *webauthn.RequestOptions, error) {
+	if u == nil {
+		challengeToken, challenge, err := v.createChallenge("", "", webAuthnCeremonyLogin)
+		if err != nil {
+			return "", nil, err
+		}
+		return challengeToken, v.Validator.Services.WebAuthn.RequestOptions(challenge, nil, "required"), nil
+	}
+	credentials, err := v.Validator.Services.UserService.GetWebAuthnCredentials(u.Id)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(credentials) == 0 {
+		return "", nil, ErrMFAChallengeNotExpected
+	}
+	challengeToken, challenge, err := v.createChallenge(u.Id, u.OrganizationId, webAuthnCeremonyLogin)
+	if err != nil {
+		return "", nil, err
+	}
+	return challengeToken, v.Validator.Services.WebAuthn.RequestOptions(challenge, credentialIds(credentials), "preferred"), nil
+}
+
+func (v *WebAuthnValidator) GetLogin() (*WebAuthnLoginInput, *token.WebAuthnChallengePayload, error) {
+	var login WebAuthnLoginInput
+	if err := v.Validator.DecodeJSONBody(&login); err != nil {
+		return nil, nil, err
+	}
+	if login.ChallengeToken == "" || login.Credential.Id == "" || login.Credential.Response.Signature == "" {
+		return nil, nil, ErrWebAuthnEmptyTokenCredential
+	}
+	challenge, err := v.loadChallenge(login.ChallengeToken, webAuthnCeremonyLogin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = v.useChallenge(challenge); err != nil {
+		return nil, nil, err
+	}
+	return &login, challenge, nil
+}
+
+// VerifyLogin checks the assertion with the stored credential and returns its user. The credential must
+// belong to the user of the challenge when the passkey is the second factor.
+func (v *WebAuthnValidator) VerifyLogin(login *WebAuthnLoginInput, challenge *token.WebAuthnChallengePayload) (*user.User, error) {
+	userService := v.Validator.Services.UserService
+	credential, err := userService.GetWebAuthnCredential(login.Credential.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnCredentialNotValid, err)
+	}
+	passwordless := challenge.UserId == ""
+	if !passwordless && credential.UserId != challenge.UserId {
+		return nil, ErrWebAuthnCredentialNotValid
+	}
+
+	response := &webauthn.AssertionResponse{}
+	fields := []struct {
+		value  string
+		target *[]byte
+	}{
+		{login.Credential.Response.ClientDataJSON, &response.ClientDataJSON},
+		{login.Credential.Response.AuthenticatorData, &response.AuthenticatorData},
+		{login.Credential.Response.Signature, &response.Signature},
+		{login.Credential.Response.UserHandle, &response.UserHandle},
+	}
+	for _, field := range fields {
+		if *field.target, err = webauthn.DecodeBase64(field.value); err != nil {
+			return nil, fmt.Errorf("%w, %s", ErrWebAuthnCredentialNotValid, err)
+		}
+	}
+	if len(response.UserHandle) > 0 && string(response.UserHandle) != credential.UserId {
+		return nil, ErrWebAuthnCredentialNotValid
+	}
+
+	storedCredential := &webauthn.Credential{PublicKey: credential.PublicKey, SignCount: credential.SignCount}
+	signCount, err := v.Validator.Services.WebAuthn.VerifyAssertion(challenge.Challenge, storedCredential, response, passwordless)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnCredentialNotValid, err)
+	}
+	if err = userService.UpdateWebAuthnSignCount(credential.Id, signCount); err != nil {
+		return nil, err
+	}
+
+	u, err := userService.GetRepository().GetById(credential.UserId)
+	if err == user.ErrUserNotFound {
+		return nil, ErrLoginRouterUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = v.Validator.CheckUserActive(u); err != nil {
+		return nil, err
+	}
+	if passwordless && userService.IsVerifiedEmailRequired() && u.Email != "" && !u.EmailVerified {
+		return nil, ErrLoginRouterEmailNotVerified
+	}
+	return u, nil
+}
+
+// GetLoginUserId returns the id of the user trying to log in, the one of the challenge or, on a
+// passwordless login, the owner of the credential. It's an empty string when the credential is unknown.
+func (v *WebAuthnValidator) GetLoginUserId(login *WebAuthnLoginInput, challenge *token.WebAuthnChallengePayload) string {
+	if challenge.UserId != "" {
+		return challenge.UserId
+	}
+	credential, err := v.Validator.Services.UserService.GetWebAuthnCredential(login.Credential.Id)
+	if err != nil {
+		return ""
+	}
+	return credential.UserId
+}
+
+func (v *WebAuthnValidator) createChallenge(userId string, organizationId string, ceremony string) (string, string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", "", fmt.Errorf("%w, %s", ErrWebAuthnCreatingChallenge, err)
+	}
+	tokenGenerator := v.Validator.Services.WebAuthnChallengeTokenGenerator
+	payload := &token.WebAuthnChallengePayload{UserId: userId, OrganizationId: organizationId, Ceremony: ceremony, Challenge: challenge, IssuedAtTime: time.Now()}
+	challengeToken, err := tokenGenerator.CreateToken(payload)
+	if err != nil {
+		return "", "", fmt.Errorf("%w, %s", ErrWebAuthnCreatingChallenge, err)
+	}
+	if err = v.Validator.Services.UserService.AddWebAuthnChallenge(challenge, payload.IssuedAtTime.Add(tokenGenerator.Duration)); err != nil {
+		return "", "", fmt.Errorf("%w, %s", ErrWebAuthnCreatingChallenge, err)
+	}
+	return challengeToken, challenge, nil
+}
+
+func (v *WebAuthnValidator) loadChallenge(challengeToken string, ceremony string) (*token.WebAuthnChallengePayload, error) {
+	tokenGenerator := v.Validator.Services.WebAuthnChallengeTokenGenerator
+	if err := tokenGenerator.IsTokenValid(challengeToken); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnChallengeInvalid, err)
+	}
+	payload := &token.WebAuthnChallengePayload{}
+	if err := tokenGenerator.LoadPayload(challengeToken, payload); err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWebAuthnChallengeInvalid, err)
+	}
+	if payload.Ceremony != ceremony {
+		return nil, ErrWebAuthnChallengeInvalid
+	}
+	return payload, nil
+}
+
+// useChallenge accepts the challenge of the token only once, so a captured answer can't be sent again
+// while the token is valid.
+func (v *WebAuthnValidator) useChallenge(challenge *token.WebAuthnChallengePayload) error {
+	err := v.Validator.Services.UserService.UseWebAuthnChallenge(challenge.Challenge)
+	if err == user.ErrWebAuthnChallengeNotFound {
+		return fmt.Errorf("%w, %s", ErrWebAuthnChallengeInvalid, err)
+	}
+	return err
+}
+
+func credentialIds(credentials []*user.WebAuthnCredential) [][]byte {
+	ids := make([][]byte, 0, len(credentials))
+	for _, credential := range credentials {
+		if id, err := webauthn.DecodeBase64(credential.Id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
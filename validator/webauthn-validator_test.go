@@ -0,0 +1,75 @@
+package validator
+
+import (
+	"authGo/token"
+	"authGo/user"
+	"authGo/webauthn"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createWebAuthnValidator() *WebAuthnValidator {
+	services := &Services{
+		UserService:                     user.NewUserService(),
+		WebAuthn:                        &webauthn.RelyingParty{ID: "localhost", Origins: []string{"http://localhost:4200"}},
+		WebAuthnChallengeTokenGenerator: &token.TokenGenerator[token.WebAuthnChallengePayload]{Password: []byte("webAuthnChallengeKey"), Duration: time.Minute * 5},
+	}
+	return &WebAuthnValidator{Validator: Validator{Services: services}}
+}
+
+func TestGetWebAuthnRegistration(t *testing.T) {
+	v := createWebAuthnValidator()
+	u := &user.User{Id: "1", Name: "user1", OrganizationId: user.DefaultOrganizationId}
+	registrationToken, options, err := v.CreateRegistrationOptions(u)
+	if err != nil || options.Challenge == "" || options.User.ID != webauthn.Encoding.EncodeToString([]byte("1")) {
+		t.Fatalf("unexpected options %+v %v", options, err)
+	}
+	loginToken, _, err := v.CreateLoginOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registrationTests := []struct {
+		body   string
+		userId string
+		err    error
+	}{
+		{fmt.Sprintf(`{"challengeToken": %q, "credential": {"response": {"attestationObject": "o2Nm"}}}`, registrationToken), "1", nil},
+		{fmt.Sprintf(`{"challengeToken": %q, "credential": {"response": {"attestationObject": "o2Nm"}}}`, registrationToken), "2", ErrWebAuthnChallengeInvalid},
+		{fmt.Sprintf(`{"challengeToken": %q, "credential": {"response": {"attestationObject": "o2Nm"}}}`, loginToken), "", ErrWebAuthnChallengeInvalid},
+		{fmt.Sprintf(`{"challengeToken": %q, "credential": {"response": {}}}`, registrationToken), "1", ErrWebAuthnEmptyTokenCredential},
+		{`{"challengeToken": "aa.bb.cc", "credential": {"response": {"attestationObject": "o2Nm"}}}`, "1", ErrWebAuthnChallengeInvalid},
+		{`{"credential": {"unknown": true}}`, "1", ErrInvalidBody},
+	}
+	for i, registrationTest := range registrationTests {
+		req, err := http.NewRequest("POST", "/users/me/webauthn/registration", strings.NewReader(registrationTest.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		v.Validator.Request = req
+		_, challenge, err := v.GetRegistration(registrationTest.userId)
+		if !errors.Is(err, registrationTest.err) {
+			t.Errorf("%d: expected err to be %v, got %v", i, registrationTest.err, err)
+		}
+		if err == nil && challenge.Challenge != options.Challenge {
+			t.Errorf("%d: expected the challenge of the options, got %s", i, challenge.Challenge)
+		}
+	}
+}
+
+func TestCreateWebAuthnLoginOptions(t *testing.T) {
+	v := createWebAuthnValidator()
+	u := &user.User{Id: "1", Name: "user1"}
+	if _, _, err := v.CreateLoginOptions(u); err != ErrMFAChallengeNotExpected {
+		t.Errorf("expected users without credentials not to get options, got %v", err)
+	}
+	_, options, err := v.CreateLoginOptions(nil)
+	if err != nil || options.UserVerification != "required" || len(options.AllowCredentials) != 0 || options.RelyingPartyId != "localhost" {
+		t.Errorf("unexpected passwordless options %+v %v", options, err)
+	}
+}
@@ -0,0 +1,145 @@
+package webauthn
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidCBOR = errors.New("webauthn: invalid cbor")
+
+// maxCBORDepth bounds the nesting of the decoded values, the attestation objects and keys only nest a
+// few levels.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the first value of the data and returns the number of bytes it takes. Only the
+// definite length items used by WebAuthn are supported: integers are int64, byte strings []byte, text
+// strings string, arrays []any and maps map[any]any with integer or text keys.
+func decodeCBOR(data []byte) (any, int, error) {
+	d := &cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth || d.pos >= len(d.data) {
+		return nil, ErrInvalidCBOR
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25, 26, 27:
+			// Floats are not used by WebAuthn, they are skipped.
+			_, err := d.read(1 << (info - 24))
+			return nil, err
+		}
+		return nil, ErrInvalidCBOR
+	}
+
+	argument, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if argument > math.MaxInt64 {
+			return nil, ErrInvalidCBOR
+		}
+		return int64(argument), nil
+	case 1:
+		if argument > math.MaxInt64 {
+			return nil, ErrInvalidCBOR
+		}
+		return -1 - int64(argument), nil
+	case 2:
+		value, err := d.read(argument)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), value...), nil
+	case 3:
+		value, err := d.read(argument)
+		if err != nil {
+			return nil, err
+		}
+		return string(value), nil
+	case 4:
+		// Every item takes at least a byte, longer arrays can't be valid.
+		if argument > uint64(len(d.data)-d.pos) {
+			return nil, ErrInvalidCBOR
+		}
+		items := make([]any, argument)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case 5:
+		if argument > uint64(len(d.data)-d.pos)/2 {
+			return nil, ErrInvalidCBOR
+		}
+		items := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, ErrInvalidCBOR
+			}
+			if items[key], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case 6:
+		return d.decode(depth + 1)
+	}
+	return nil, ErrInvalidCBOR
+}
+
+func (d *cborDecoder) readArgument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, ErrInvalidCBOR
+	}
+	value, err := d.read(1 << (info - 24))
+	if err != nil {
+		return 0, err
+	}
+	var argument uint64
+	for _, b := range value {
+		argument = argument<<8 | uint64(b)
+	}
+	return argument, nil
+}
+
+func (d *cborDecoder) read(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.pos) {
+		return nil, ErrInvalidCBOR
+	}
+	value := d.data[d.pos : d.pos+int(length)]
+	d.pos += int(length)
+	return value, nil
+}
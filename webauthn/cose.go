@@ -0,0 +1,91 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// The COSE algorithms accepted for the credentials.
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+)
+
+var (
+	ErrUnsupportedKey   = errors.New("webauthn: unsupported public key")
+	ErrInvalidSignature = errors.New("webauthn: invalid signature")
+)
+
+type publicKey struct {
+	algorithm int64
+	key       crypto.PublicKey
+}
+
+// parsePublicKey reads a COSE_Key of one of the accepted algorithms.
+func parsePublicKey(coseKey []byte) (*publicKey, error) {
+	value, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := value.(map[any]any)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	keyType, _ := fields[int64(1)].(int64)
+	algorithm, _ := fields[int64(3)].(int64)
+
+	switch {
+	case keyType == 2 && algorithm == AlgorithmES256:
+		curve, _ := fields[int64(-1)].(int64)
+		x, _ := fields[int64(-2)].([]byte)
+		y, _ := fields[int64(-3)].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, ErrUnsupportedKey
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrUnsupportedKey
+		}
+		return &publicKey{algorithm: algorithm, key: key}, nil
+	case keyType == 1 && algorithm == AlgorithmEdDSA:
+		curve, _ := fields[int64(-1)].(int64)
+		x, _ := fields[int64(-2)].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+		return &publicKey{algorithm: algorithm, key: ed25519.PublicKey(x)}, nil
+	case keyType == 3 && algorithm == AlgorithmRS256:
+		n, _ := fields[int64(-1)].([]byte)
+		e, _ := fields[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrUnsupportedKey
+		}
+		exponent := new(big.Int).SetBytes(e)
+		return &publicKey{algorithm: algorithm, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}}, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+func (p *publicKey) verify(data []byte, signature []byte) error {
+	digest := sha256.Sum256(data)
+	valid := false
+	switch key := p.key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
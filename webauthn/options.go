@@ -0,0 +1,88 @@
+package webauthn
+
+// The options are given to navigator.credentials.create and navigator.credentials.get, with the binary
+// values in base64url as the browsers parse them with PublicKeyCredential.parseCreationOptionsFromJSON.
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RelyingPartyId   string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions asks for a discoverable credential, so it can be used without a user name, excluding
+// the credentials the user already has on the authenticator.
+func (rp *RelyingParty) CreationOptions(challenge string, userId string, userName string, excludeCredentials [][]byte) *CreationOptions {
+	return &CreationOptions{
+		Challenge:    challenge,
+		RelyingParty: RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:         UserEntity{ID: Encoding.EncodeToString([]byte(userId)), Name: userName, DisplayName: userName},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Algorithm: AlgorithmES256},
+			{Type: "public-key", Algorithm: AlgorithmEdDSA},
+			{Type: "public-key", Algorithm: AlgorithmRS256},
+		},
+		Timeout:                rp.Timeout.Milliseconds(),
+		ExcludeCredentials:     credentialDescriptors(excludeCredentials),
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "preferred", UserVerification: "preferred"},
+		Attestation:            "none",
+	}
+}
+
+// RequestOptions lets the browser choose among the discoverable credentials when allowCredentials is
+// empty.
+func (rp *RelyingParty) RequestOptions(challenge string, allowCredentials [][]byte, userVerification string) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RelyingPartyId:   rp.ID,
+		AllowCredentials: credentialDescriptors(allowCredentials),
+		UserVerification: userVerification,
+	}
+}
+
+func credentialDescriptors(ids [][]byte) []CredentialDescriptor {
+	descriptors := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		descriptors[i] = CredentialDescriptor{Type: "public-key", ID: Encoding.EncodeToString(id)}
+	}
+	return descriptors
+}
@@ -0,0 +1,223 @@
+// Package webauthn verifies the registration and authentication ceremonies of WebAuthn, the passkeys
+// and security keys. The attestation statements are not verified, the options ask the browsers for none.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+var (
+	ErrInvalidClientData        = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch        = errors.New("webauthn: challenge mismatch")
+	ErrOriginNotAllowed         = errors.New("webauthn: origin not allowed")
+	ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+	ErrRelyingPartyMismatch     = errors.New("webauthn: relying party mismatch")
+	ErrUserNotPresent           = errors.New("webauthn: user not present")
+	ErrUserNotVerified          = errors.New("webauthn: user not verified")
+	ErrInvalidAttestation       = errors.New("webauthn: invalid attestation object")
+	ErrSignCount                = errors.New("webauthn: sign count not increased, the authenticator may be cloned")
+)
+
+// Encoding is the base64url encoding without padding of the binary values in the JSON messages.
+var Encoding = base64.RawURLEncoding
+
+// RelyingParty is this service as the browsers know it. ID is the domain of the web application, the
+// ceremonies are only accepted from the Origins.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	Timeout time.Duration
+}
+
+// Credential is the public part of a credential registered by an authenticator.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIdHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialId []byte
+	publicKey    []byte
+}
+
+// NewChallenge returns a random challenge encoded as the browsers send it back in the client data.
+func NewChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return Encoding.EncodeToString(challenge), nil
+}
+
+// DecodeBase64 reads a base64url value, with or without padding.
+func DecodeBase64(value string) ([]byte, error) {
+	return Encoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create for the challenge and returns
+// the new credential.
+func (rp *RelyingParty) VerifyRegistration(challenge string, response *AttestationResponse) (*Credential, error) {
+	if err := rp.verifyClientData(response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+	value, _, err := decodeCBOR(response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidAttestation
+	}
+	attestation, ok := value.(map[any]any)
+	if !ok {
+		return nil, ErrInvalidAttestation
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidAttestation
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err = rp.verifyAuthenticatorData(authData, false); err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedData == 0 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	if _, err = parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{ID: authData.credentialId, PublicKey: authData.publicKey, SignCount: authData.signCount}, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get for the challenge with the stored
+// credential and returns its new sign count. The user must have been verified, with a PIN or biometrics,
+// when requireUserVerification is true.
+func (rp *RelyingParty) VerifyAssertion(challenge string, credential *Credential, response *AssertionResponse, requireUserVerification bool) (uint32, error) {
+	if err := rp.verifyClientData(response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return 0, err
+	}
+	authData, err := parseAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err = rp.verifyAuthenticatorData(authData, requireUserVerification); err != nil {
+		return 0, err
+	}
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(response.ClientDataJSON)
+	signed := append(append([]byte(nil), response.AuthenticatorData...), clientDataHash[:]...)
+	if err = key.verify(signed, response.Signature); err != nil {
+		return 0, err
+	}
+	// The authenticators without a counter always send 0.
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCount
+	}
+	return authData.signCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(rawClientData []byte, ceremony string, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(rawClientData, &data); err != nil {
+		return ErrInvalidClientData
+	}
+	if data.Type != ceremony {
+		return ErrInvalidClientData
+	}
+	if challenge == "" || subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	}
+	for _, origin := range rp.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOriginNotAllowed
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData, requireUserVerification bool) error {
+	rpIdHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIdHash, rpIdHash[:]) {
+		return ErrRelyingPartyMismatch
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	if requireUserVerification && authData.flags&flagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// parseAuthenticatorData reads the rpIdHash, flags and signCount, followed by the credential when the
+// attested data flag is set.
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	authData := &authenticatorData{rpIdHash: data[:32], flags: data[32], signCount: binary.BigEndian.Uint32(data[33:37])}
+	if authData.flags&flagAttestedData == 0 {
+		return authData, nil
+	}
+	// aaguid (16) and credentialIdLength (2)
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	authData.credentialId = append([]byte(nil), rest[:idLength]...)
+	rest = rest[idLength:]
+	_, keyLength, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	authData.publicKey = append([]byte(nil), rest[:keyLength]...)
+	return authData, nil
+}
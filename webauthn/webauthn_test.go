@@ -0,0 +1,120 @@
+package webauthn
+
+import (
+	"authGo/webauthn/webauthntest"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func createTestRelyingParty() *RelyingParty {
+	return &RelyingParty{ID: "localhost", Name: "authGo", Origins: []string{"http://localhost:4200"}}
+}
+
+func registerTestCredential(t *testing.T, rp *RelyingParty, authenticator *webauthntest.Authenticator) *Credential {
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientData, attestationObject := authenticator.Create(challenge)
+	credential, err := rp.VerifyRegistration(challenge, &AttestationResponse{ClientDataJSON: clientData, AttestationObject: attestationObject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	cborTests := []struct {
+		data  []byte
+		value any
+	}{
+		{[]byte{0x17}, int64(23)},
+		{[]byte{0x19, 0x01, 0x00}, int64(256)},
+		{[]byte{0x38, 0x18}, int64(-25)},
+		{[]byte{0x43, 1, 2, 3}, []byte{1, 2, 3}},
+		{[]byte{0x62, 'h', 'i'}, "hi"},
+		{[]byte{0x82, 0x01, 0xf5}, []any{int64(1), true}},
+		{[]byte{0xa1, 0x01, 0x20}, map[any]any{int64(1): int64(-1)}},
+	}
+	for _, cborTest := range cborTests {
+		value, length, err := decodeCBOR(cborTest.data)
+		if err != nil || length != len(cborTest.data) || !reflect.DeepEqual(value, cborTest.value) {
+			t.Errorf("%x: expected %v, got %v %d %v", cborTest.data, cborTest.value, value, length, err)
+		}
+	}
+	for _, data := range [][]byte{{}, {0x43, 1}, {0x9f}, {0x9a, 0xff, 0xff, 0xff, 0xff}, {0xa1, 0x41, 0x00, 0x01}, {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}} {
+		if _, _, err := decodeCBOR(data); err != ErrInvalidCBOR {
+			t.Errorf("%x: expected err to be ErrInvalidCBOR, got %v", data, err)
+		}
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	rp := createTestRelyingParty()
+	authenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	credential := registerTestCredential(t, rp, authenticator)
+	if !reflect.DeepEqual(credential.ID, authenticator.CredentialId) || !reflect.DeepEqual(credential.PublicKey, authenticator.PublicKey()) {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+
+	challenge, _ := NewChallenge()
+	registrationTests := []struct {
+		authenticator *webauthntest.Authenticator
+		challenge     string
+		err           error
+	}{
+		{authenticator, "other", ErrChallengeMismatch},
+		{webauthntest.NewAuthenticator("localhost", "http://evil.example"), challenge, ErrOriginNotAllowed},
+		{webauthntest.NewAuthenticator("evil.example", "http://localhost:4200"), challenge, ErrRelyingPartyMismatch},
+	}
+	for i, registrationTest := range registrationTests {
+		clientData, attestationObject := registrationTest.authenticator.Create(challenge)
+		_, err := rp.VerifyRegistration(registrationTest.challenge, &AttestationResponse{ClientDataJSON: clientData, AttestationObject: attestationObject})
+		if !errors.Is(err, registrationTest.err) {
+			t.Errorf("%d: expected err to be %v, got %v", i, registrationTest.err, err)
+		}
+	}
+
+	clientData, _ := authenticator.Create(challenge)
+	if _, err := rp.VerifyRegistration(challenge, &AttestationResponse{ClientDataJSON: clientData, AttestationObject: []byte{0xa0}}); err != ErrInvalidAttestation {
+		t.Errorf("expected err to be ErrInvalidAttestation, got %v", err)
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	rp := createTestRelyingParty()
+	authenticator := webauthntest.NewAuthenticator("localhost", "http://localhost:4200")
+	credential := registerTestCredential(t, rp, authenticator)
+
+	challenge, _ := NewChallenge()
+	clientData, authData, signature := authenticator.Get(challenge)
+	signCount, err := rp.VerifyAssertion(challenge, credential, &AssertionResponse{ClientDataJSON: clientData, AuthenticatorData: authData, Signature: signature}, true)
+	if err != nil || signCount != 1 {
+		t.Fatalf("expected the assertion to be valid, got %d %v", signCount, err)
+	}
+	credential.SignCount = signCount
+
+	if _, err = rp.VerifyAssertion(challenge, credential, &AssertionResponse{ClientDataJSON: clientData, AuthenticatorData: authData, Signature: signature}, true); err != ErrSignCount {
+		t.Errorf("expected a replayed assertion to fail the sign count, got %v", err)
+	}
+	clientData, authData, signature = authenticator.Get(challenge)
+	signature[len(signature)-1] ^= 0xff
+	if _, err = rp.VerifyAssertion(challenge, credential, &AssertionResponse{ClientDataJSON: clientData, AuthenticatorData: authData, Signature: signature}, true); err != ErrInvalidSignature {
+		t.Errorf("expected err to be ErrInvalidSignature, got %v", err)
+	}
+	clientData, _ = authenticator.Create(challenge)
+	if _, err = rp.VerifyAssertion(challenge, credential, &AssertionResponse{ClientDataJSON: clientData, AuthenticatorData: authData, Signature: signature}, true); err != ErrInvalidClientData {
+		t.Errorf("expected a registration client data to be rejected, got %v", err)
+	}
+
+	authenticator.UserVerified = false
+	clientData, authData, signature = authenticator.Get(challenge)
+	response := &AssertionResponse{ClientDataJSON: clientData, AuthenticatorData: authData, Signature: signature}
+	if _, err = rp.VerifyAssertion(challenge, credential, response, true); err != ErrUserNotVerified {
+		t.Errorf("expected err to be ErrUserNotVerified, got %v", err)
+	}
+	if _, err = rp.VerifyAssertion(challenge, credential, response, false); err != nil {
+		t.Errorf("expected the user verification to be optional, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Package webauthntest provides a software authenticator to test the WebAuthn ceremonies.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+)
+
+// Authenticator holds a single ES256 credential.
+type Authenticator struct {
+	RelyingPartyId string
+	Origin         string
+	CredentialId   []byte
+	SignCount      uint32
+	// UserVerified sets the user verified flag, like after a PIN or biometrics.
+	UserVerified bool
+	key          *ecdsa.PrivateKey
+}
+
+func NewAuthenticator(relyingPartyId string, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	credentialId := make([]byte, 16)
+	rand.Read(credentialId)
+	return &Authenticator{RelyingPartyId: relyingPartyId, Origin: origin, CredentialId: credentialId, UserVerified: true, key: key}
+}
+
+// Create returns the clientDataJSON and attestationObject of a registration.
+func (a *Authenticator) Create(challenge string) ([]byte, []byte) {
+	clientData := a.clientData("webauthn.create", challenge)
+	authData := a.authenticatorData(0x40)
+	idLength := make([]byte, 2)
+	binary.BigEndian.PutUint16(idLength, uint16(len(a.CredentialId)))
+	authData = append(authData, make([]byte, 16)...)
+	authData = append(authData, idLength...)
+	authData = append(authData, a.CredentialId...)
+	authData = append(authData, a.PublicKey()...)
+	attestationObject := EncodeCBOR(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": authData})
+	return clientData, attestationObject
+}
+
+// Get returns the clientDataJSON, authenticatorData and signature of an authentication, the sign count
+// is increased first.
+func (a *Authenticator) Get(challenge string) ([]byte, []byte, []byte) {
+	a.SignCount++
+	clientData := a.clientData("webauthn.get", challenge)
+	authData := a.authenticatorData(0)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return clientData, authData, signature
+}
+
+// PublicKey returns the COSE_Key of the credential.
+func (a *Authenticator) PublicKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	return EncodeCBOR(map[any]any{int64(1): int64(2), int64(3): int64(-7), int64(-1): int64(1), int64(-2): x, int64(-3): y})
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge string) []byte {
+	clientData, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": a.Origin})
+	return clientData
+}
+
+func (a *Authenticator) authenticatorData(flags byte) []byte {
+	flags |= 0x01
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	rpIdHash := sha256.Sum256([]byte(a.RelyingPartyId))
+	signCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(signCount, a.SignCount)
+	authData := append(rpIdHash[:], flags)
+	return append(authData, signCount...)
+}
+
+// Base64 encodes the binary values of the JSON messages.
+func Base64(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// EncodeCBOR encodes the int64, []byte, string and map[any]any values.
+func EncodeCBOR(value any) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case map[any]any:
+		keys := make([][]byte, 0, len(v))
+		values := make(map[string][]byte, len(v))
+		for key, item := range v {
+			encodedKey := EncodeCBOR(key)
+			keys = append(keys, encodedKey)
+			values[string(encodedKey)] = EncodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+		encoded := cborHeader(5, uint64(len(v)))
+		for _, key := range keys {
+			encoded = append(encoded, key...)
+			encoded = append(encoded, values[string(key)]...)
+		}
+		return encoded
+	}
+	panic("webauthntest: unsupported cbor value")
+}
+
+func cborHeader(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument < 1<<8:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument < 1<<16:
+		return []byte{major<<5 | 25, byte(argument >> 8), byte(argument)}
+	}
+	header := make([]byte, 5)
+	header[0] = major<<5 | 26
+	binary.BigEndian.PutUint32(header[1:], uint32(argument))
+	return header
+}